@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -27,6 +28,33 @@ const (
 
 	// HostCheckPlaybookAnnotation store which playbook is used to check hosts.
 	HostCheckPlaybookAnnotation = "playbook.kubekey.kubesphere.io/host-check"
+	// CertsCheckPlaybookAnnotation store which playbook is used to check the expiration of certificates.
+	CertsCheckPlaybookAnnotation = "playbook.kubekey.kubesphere.io/certs-check"
+	// CertsRenewPlaybookAnnotation store which playbook is used to renew certificates.
+	CertsRenewPlaybookAnnotation = "playbook.kubekey.kubesphere.io/certs-renew"
+)
+
+const (
+	// InventoryCertsCheckedCondition reports the result of the last expiry-check playbook.
+	InventoryCertsCheckedCondition = "CertificatesChecked"
+	// InventoryCertsRotatedCondition reports the progress of the certificate rotation.
+	// Its lastTransitionTime is the last rotation time when it is true.
+	InventoryCertsRotatedCondition = "CertificatesRotated"
+
+	// InventoryCertsCheckFailedReason the expiry-check playbook failed.
+	InventoryCertsCheckFailedReason = "CheckFailed"
+	// InventoryCertsValidReason no certificate expires within the renew-before window.
+	InventoryCertsValidReason = "Valid"
+	// InventoryCertsExpiringReason some certificates expire within the renew-before window.
+	InventoryCertsExpiringReason = "Expiring"
+	// InventoryCertsWaitingReason the rotation is waiting for the maintenance window.
+	InventoryCertsWaitingReason = "WaitingMaintenanceWindow"
+	// InventoryCertsRotatingReason the renewal playbook of a batch of hosts is running.
+	InventoryCertsRotatingReason = "Rotating"
+	// InventoryCertsRotateFailedReason the renewal playbook failed.
+	InventoryCertsRotateFailedReason = "RotateFailed"
+	// InventoryCertsRotatedReason all certificates have been renewed.
+	InventoryCertsRotatedReason = "Rotated"
 )
 
 // InventoryPhase of inventory. it's always use in capkk to judge if host has checked.
@@ -64,6 +92,61 @@ type InventorySpec struct {
 	// Groups nodes. a group contains repeated nodes
 	// +optional
 	Groups map[string]InventoryGroup `json:"groups,omitempty"`
+	// CertRotation renews the certificates of hosts automatically before they expire.
+	// +optional
+	CertRotation *InventoryCertRotation `json:"certRotation,omitempty"`
+}
+
+// InventoryCertRotation is the policy to rotate the certificates of hosts.
+// The expiry-check playbook runs every CheckInterval. When the certificates of some hosts expire within RenewBefore,
+// renewal playbooks are created in the MaintenanceWindow, one batch of hosts after another.
+type InventoryCertRotation struct {
+	// RenewBefore renews the certificates of a host when any of them expires within it. default 720h.
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+	// CheckInterval is the interval to run the expiry-check playbook. default 24h.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+	// MaintenanceWindow restricts when the renewal playbooks are created. default any time.
+	// +optional
+	MaintenanceWindow *InventoryMaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// BatchSize is the number of hosts renewed by each renewal playbook. The batches are renewed serially. default 1.
+	// +optional
+	BatchSize int `json:"batchSize,omitempty"`
+	// Project of the expiry-check and renewal playbooks. default the builtin project.
+	// +optional
+	Project PlaybookProject `json:"project,omitempty"`
+	// Config is the config spec of the expiry-check and renewal playbooks.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Config runtime.RawExtension `json:"config,omitempty"`
+	// Volumes in job pod. The work_dir which stores the CA should be mounted.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Volumes []corev1.Volume `json:"workVolume,omitempty"`
+	// VolumeMounts in job pod.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	// ServiceAccountName is the name of the ServiceAccount to use to run the playbook pods.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// InventoryMaintenanceWindow is a recurring window of time.
+type InventoryMaintenanceWindow struct {
+	// Start is the time of day when the window starts, such as "02:00".
+	Start string `json:"start"`
+	// Duration of the window, such as "4h".
+	Duration metav1.Duration `json:"duration"`
+	// Days of week when the window starts, such as ["Sat", "Sun"]. default every day.
+	// +optional
+	Days []string `json:"days,omitempty"`
+	// TimeZone of Start, such as "Asia/Shanghai". default UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // InventoryStatus of Inventory
@@ -72,6 +155,11 @@ type InventoryStatus struct {
 	Ready bool `json:"ready,omitempty"`
 	// Phase is the inventory phase.
 	Phase InventoryPhase `json:"phase,omitempty"`
+	// Conditions of inventory, such as the certificate rotation.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -75,6 +75,10 @@ type PlaybookSpec struct {
 	// SkipTags is the tags of playbook which skip execute
 	// +optional
 	SkipTags []string `json:"skipTags,omitempty"`
+	// Limit restricts the hosts of each play to the given host patterns.
+	// The localhost is always kept.
+	// +optional
+	Limit []string `json:"limit,omitempty"`
 	// Volumes in job pod.
 	// +optional
 	Volumes []corev1.Volume `json:"workVolume,omitempty"`
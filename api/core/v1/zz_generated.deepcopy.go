@@ -22,6 +22,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -48,7 +49,7 @@ func (in *Inventory) DeepCopyInto(out *Inventory) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Inventory.
@@ -69,6 +70,52 @@ func (in *Inventory) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryCertRotation) DeepCopyInto(out *InventoryCertRotation) {
+	*out = *in
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(InventoryMaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	out.Project = in.Project
+	in.Config.DeepCopyInto(&out.Config)
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryCertRotation.
+func (in *InventoryCertRotation) DeepCopy() *InventoryCertRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryCertRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InventoryGroup) DeepCopyInto(out *InventoryGroup) {
 	*out = *in
@@ -148,6 +195,27 @@ func (in *InventoryList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryMaintenanceWindow) DeepCopyInto(out *InventoryMaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryMaintenanceWindow.
+func (in *InventoryMaintenanceWindow) DeepCopy() *InventoryMaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryMaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InventorySpec) DeepCopyInto(out *InventorySpec) {
 	*out = *in
@@ -166,6 +234,11 @@ func (in *InventorySpec) DeepCopyInto(out *InventorySpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.CertRotation != nil {
+		in, out := &in.CertRotation, &out.CertRotation
+		*out = new(InventoryCertRotation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventorySpec.
@@ -181,6 +254,13 @@ func (in *InventorySpec) DeepCopy() *InventorySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InventoryStatus) DeepCopyInto(out *InventoryStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryStatus.
@@ -287,6 +367,11 @@ func (in *PlaybookSpec) DeepCopyInto(out *PlaybookSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Limit != nil {
+		in, out := &in.Limit, &out.Limit
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make([]corev1.Volume, len(*in))
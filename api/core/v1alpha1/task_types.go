@@ -42,6 +42,27 @@ const (
 	TaskAnnotationRelativePath = "kubesphere.io/rel-path"
 )
 
+const (
+	// TaskDebuggerNever never invoke the debugger. It's the default.
+	TaskDebuggerNever = "never"
+	// TaskDebuggerAlways always invoke the debugger after the task run.
+	TaskDebuggerAlways = "always"
+	// TaskDebuggerOnFailed invoke the debugger when the task failed.
+	TaskDebuggerOnFailed = "on_failed"
+	// TaskDebuggerOnSkipped invoke the debugger when the task skipped in all hosts.
+	TaskDebuggerOnSkipped = "on_skipped"
+)
+
+// IsSupportedTaskDebugger checks if the debugger mode is supported. Empty debugger means TaskDebuggerNever.
+func IsSupportedTaskDebugger(debugger string) bool {
+	switch debugger {
+	case "", TaskDebuggerNever, TaskDebuggerAlways, TaskDebuggerOnFailed, TaskDebuggerOnSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
 // TaskSpec of Task
 type TaskSpec struct {
 	Name        string   `json:"name,omitempty"`
@@ -49,6 +70,8 @@ type TaskSpec struct {
 	DelegateTo  string   `yaml:"delegate_to,omitempty"`
 	IgnoreError *bool    `json:"ignoreError,omitempty"`
 	Retries     int      `json:"retries,omitempty"`
+	// Debugger defines when to invoke the interactive debugger. see TaskDebuggerNever.
+	Debugger string `json:"debugger,omitempty"`
 
 	When       []string             `json:"when,omitempty"`
 	FailedWhen []string             `json:"failedWhen,omitempty"`
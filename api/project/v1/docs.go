@@ -39,8 +39,8 @@ Play
 |  13  |   fact_path            |     ✘      |
 |  14  |   force_handlers       |     ✘      |
 |  15  |   gather_facts         |     ✔︎      |
-|  16  |   gather_subset        |     ✘      |
-|  17  |   gather_timeout       |     ✘      |
+|  16  |   gather_subset        |     ✔︎      |
+|  17  |   gather_timeout       |     ✔︎      |
 |  18  |   handlers             |     ✘      |
 |  19  |   hosts                |     ✔︎      |
 |  20  |   ignore_errors        |     ✔︎      |
@@ -49,7 +49,7 @@ Play
 |  23  |   module_defaults      |     ✘      |
 |  24  |   name                 |     ✔︎      |
 |  25  |   no_log               |     ✘      |
-|  26  |   order                |     ✘      |
+|  26  |   order                |     ✔︎      |
 |  27  |   port                 |     ✘      |
 |  28  |   post_task            |     ✔︎      |
 |  29  |   pre_tasks            |     ✔︎      |
@@ -155,7 +155,7 @@ Task
 |  10  |   changed_when         |     ✘      |
 |  11  |   check_mode           |     ✘      |
 |  12  |   collections          |     ✘      |
-|  13  |   debugger             |     ✘      |
+|  13  |   debugger             |     ✔︎      |
 |  14  |   delay                |     ✘      |
 |  15  |   delegate_facts       |     ✘      |
 |  16  |   delegate_to          |     ✘      |
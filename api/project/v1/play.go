@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	"strings"
+
 	"github.com/cockroachdb/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -34,9 +36,10 @@ type Play struct {
 	// Facts
 	GatherFacts bool `yaml:"gather_facts,omitempty"`
 
-	// defaults to be deprecated, should be 'None' in future
-	//GatherSubset []GatherSubset
-	//GatherTimeout int
+	// GatherSubset selects the fact subsets to gather. such as "network", "!hardware".
+	GatherSubset PlayGatherSubset `yaml:"gather_subset,omitempty"`
+	// GatherTimeout is the timeout in seconds to gather facts of each host.
+	GatherTimeout int `yaml:"gather_timeout,omitempty"`
 	//FactPath string
 
 	// Variable Attribute
@@ -60,6 +63,29 @@ type Play struct {
 	Order             string     `yaml:"order,omitempty"`
 }
 
+const (
+	// PlayOrderInventory run hosts in the order they are resolved from inventory. It's the default order.
+	PlayOrderInventory = "inventory"
+	// PlayOrderReverseInventory run hosts in the reverse order of PlayOrderInventory.
+	PlayOrderReverseInventory = "reverse_inventory"
+	// PlayOrderSorted run hosts sorted alphabetically by name.
+	PlayOrderSorted = "sorted"
+	// PlayOrderReverseSorted run hosts sorted by name in reverse alphabetical order.
+	PlayOrderReverseSorted = "reverse_sorted"
+	// PlayOrderShuffle run hosts randomly ordered on each run.
+	PlayOrderShuffle = "shuffle"
+)
+
+// IsSupportedPlayOrder checks if the order is supported by play. Empty order means PlayOrderInventory.
+func IsSupportedPlayOrder(order string) bool {
+	switch order {
+	case "", PlayOrderInventory, PlayOrderReverseInventory, PlayOrderSorted, PlayOrderReverseSorted, PlayOrderShuffle:
+		return true
+	default:
+		return false
+	}
+}
+
 // PlaySerial defined in project.
 type PlaySerial struct {
 	Data []any
@@ -79,6 +105,29 @@ func (s *PlaySerial) UnmarshalYAML(node *yaml.Node) error {
 	}
 }
 
+// PlayGatherSubset defined in project.
+type PlayGatherSubset struct {
+	Subsets []string
+}
+
+// UnmarshalYAML yaml string or string array to gather subset. the string is split by ",".
+func (g *PlayGatherSubset) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		for _, s := range strings.Split(node.Value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				g.Subsets = append(g.Subsets, s)
+			}
+		}
+
+		return nil
+	case yaml.SequenceNode:
+		return node.Decode(&g.Subsets)
+	default:
+		return errors.New("unsupported type, excepted string or string array")
+	}
+}
+
 // PlayHost defined in project.
 type PlayHost struct {
 	Hosts []string
@@ -76,3 +76,38 @@ host1`,
 		})
 	}
 }
+
+func TestUnmarshalGatherSubset(t *testing.T) {
+	testcases := []struct {
+		name    string
+		content string
+		except  []string
+	}{
+		{
+			name:    "test single string",
+			content: `network`,
+			except:  []string{"network"},
+		},
+		{
+			name:    "test comma separated string",
+			content: `"network, !hardware"`,
+			except:  []string{"network", "!hardware"},
+		},
+		{
+			name: "test string array",
+			content: `
+- min
+- cgroup`,
+			except: []string{"min", "cgroup"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var subset PlayGatherSubset
+			err := yaml.Unmarshal([]byte(tc.content), &subset)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.except, subset.Subsets)
+		})
+	}
+}
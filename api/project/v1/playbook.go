@@ -43,6 +43,9 @@ func (p *Playbook) Validate() error {
 		if len(play.PlayHost.Hosts) == 0 {
 			return errors.New("playbook's hosts must not be empty")
 		}
+		if !IsSupportedPlayOrder(play.Order) {
+			return errors.Errorf("playbook's order %q is not supported", play.Order)
+		}
 		newPlay = append(newPlay, play)
 	}
 	p.Play = newPlay
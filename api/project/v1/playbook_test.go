@@ -38,6 +38,18 @@ func TestValidate(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "order is not supported",
+			playbook: Playbook{Play: []Play{
+				{
+					Base: Base{
+						Name: "test",
+					},
+					PlayHost: PlayHost{Hosts: []string{"localhost"}},
+					Order:    "unknown",
+				},
+			}},
+		},
 	}
 
 	for _, tc := range testcases {
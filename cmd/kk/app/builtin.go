@@ -27,6 +27,7 @@ func init() {
 	registerInternalCommand(builtin.NewCertsCommand())
 	registerInternalCommand(builtin.NewCreateCommand())
 	registerInternalCommand(builtin.NewDeleteCommand())
+	registerInternalCommand(builtin.NewFactsCommand())
 	registerInternalCommand(builtin.NewInitCommand())
 	registerInternalCommand(builtin.NewPreCheckCommand())
 }
@@ -20,13 +20,15 @@ limitations under the License.
 package builtin
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options/builtin"
 )
 
 // NewArtifactCommand creates a new cobra.Command for managing KubeKey offline installation packages.
-// It adds subcommands for exporting artifacts and managing images.
+// It adds subcommands for exporting, comparing, applying, inspecting, verifying and signing artifacts and managing images.
 func NewArtifactCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "artifact",
@@ -34,6 +36,11 @@ func NewArtifactCommand() *cobra.Command {
 	}
 	cmd.AddCommand(newArtifactExportCommand())
 	cmd.AddCommand(newArtifactImagesCommand())
+	cmd.AddCommand(newArtifactDiffCommand())
+	cmd.AddCommand(newArtifactApplyCommand())
+	cmd.AddCommand(newArtifactInspectCommand())
+	cmd.AddCommand(newArtifactVerifyCommand())
+	cmd.AddCommand(newArtifactSignCommand())
 
 	return cmd
 }
@@ -83,3 +90,111 @@ func newArtifactImagesCommand() *cobra.Command {
 
 	return cmd
 }
+
+func newArtifactDiffCommand() *cobra.Command {
+	o := builtin.NewArtifactDiffOptions()
+
+	cmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Compare the images and files of two artifacts",
+		Long: `Compare the manifests of two artifacts, and print the changed images and files,
+and the size of the delta exported by "kk artifact export --base OLD".
+Each artifact is an artifact archive, an unpacked artifact dir or a manifest file.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.Run(os.Stdout, args[0], args[1])
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func newArtifactApplyCommand() *cobra.Command {
+	o := builtin.NewArtifactApplyOptions()
+
+	cmd := &cobra.Command{
+		Use:   "apply DELTA DIR",
+		Short: "Merge a delta artifact into an unpacked artifact",
+		Long: `Merge the delta artifact archive exported by "kk artifact export --base" into the unpacked base artifact in DIR,
+such as the binary dir "<workdir>/kubekey". The delta is checked by the trust policy in "download.artifact_trust"
+of the config, and the files of the delta are verified against its manifest. The new artifact is staged beside DIR
+and replaces DIR at the end, and the files which are removed from the new artifact are deleted.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.Run(os.Stdout, args[0], args[1])
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func newArtifactInspectCommand() *cobra.Command {
+	o := builtin.NewArtifactInspectOptions()
+
+	cmd := &cobra.Command{
+		Use:   "inspect ARTIFACT",
+		Short: "Show the kubernetes versions, architectures, binaries and images of an artifact",
+		Long: `Read the manifest of an artifact, and print its kubernetes versions, architectures,
+binaries and images with digests. The artifact is an artifact archive, an unpacked artifact dir or a manifest file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.Run(os.Stdout, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func newArtifactVerifyCommand() *cobra.Command {
+	o := builtin.NewArtifactVerifyOptions()
+
+	cmd := &cobra.Command{
+		Use:   "verify ARTIFACT",
+		Short: "Verify the integrity and signature of an artifact",
+		Long: `Verify every file of an artifact archive or an unpacked artifact dir against its embedded manifest.
+When --key is set, the detached signature created by "kk artifact sign" is verified too.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.Run(os.Stdout, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
+
+func newArtifactSignCommand() *cobra.Command {
+	o := builtin.NewArtifactSignOptions()
+
+	cmd := &cobra.Command{
+		Use:   "sign ARTIFACT",
+		Short: "Sign an artifact with a private key",
+		Long: `Verify the integrity of an artifact archive or an unpacked artifact dir, and sign the digest of its manifest
+with a private key. The detached signature is written beside the artifact with ".sig" suffix by default.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.Run(os.Stdout, args[0])
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
@@ -20,6 +20,8 @@ limitations under the License.
 package builtin
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options/builtin"
@@ -34,6 +36,7 @@ func NewCertsCommand() *cobra.Command {
 		Short: "cluster certs",
 	}
 	cmd.AddCommand(newCertsRenewCommand())
+	cmd.AddCommand(newCertsCheckExpirationCommand())
 
 	return cmd
 }
@@ -60,3 +63,35 @@ func newCertsRenewCommand() *cobra.Command {
 
 	return cmd
 }
+
+func newCertsCheckExpirationCommand() *cobra.Command {
+	o := builtin.NewCertsCheckExpirationOptions()
+
+	cmd := &cobra.Command{
+		Use:   "check-expiration",
+		Short: "check the expiration of cluster certs",
+		Long: `Collect the kubernetes and etcd certificates and the client certificates of kubeconfig from the hosts,
+and print the subject, issuer, SANs, expiry and residual time of each certificate.
+The command exits with non-zero code when any certificate expires within the threshold.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			playbook, err := o.Complete(cmd, []string{"playbooks/certs_check_expiration.yaml"})
+			if err != nil {
+				return err
+			}
+			if err := o.Run(cmd.Context(), playbook); err != nil {
+				// print the certificates fetched from the hosts which succeeded.
+				_ = o.Report(os.Stdout)
+
+				return err
+			}
+
+			return o.Report(os.Stdout)
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
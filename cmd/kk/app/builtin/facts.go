@@ -0,0 +1,65 @@
+//go:build builtin
+// +build builtin
+
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options/builtin"
+)
+
+// NewFactsCommand creates a new cobra command for managing the cached facts of hosts.
+// The subcommands show, refresh or clear the cached facts for a host pattern.
+func NewFactsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "facts",
+		Short: "Manage the cached facts of hosts",
+	}
+	cmd.AddCommand(newFactsCommand("show", "Show the facts of hosts. Gather them if not cached"))
+	cmd.AddCommand(newFactsCommand("refresh", "Gather the facts of hosts again and cache them"))
+	cmd.AddCommand(newFactsCommand("clear", "Clear the cached facts of hosts"))
+
+	return cmd
+}
+
+func newFactsCommand(action, short string) *cobra.Command {
+	o := builtin.NewFactsOptions(action)
+
+	cmd := &cobra.Command{
+		Use:   action + " [host pattern]",
+		Short: short,
+		Long:  "the host pattern selects hosts in inventory, the same as --limit. default all hosts.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			playbook, err := o.Complete(cmd, append(args, "playbooks/facts.yaml"))
+			if err != nil {
+				return err
+			}
+
+			return o.Run(cmd.Context(), playbook)
+		},
+	}
+	flags := cmd.Flags()
+	for _, f := range o.Flags().FlagSets {
+		flags.AddFlagSet(f)
+	}
+
+	return cmd
+}
@@ -20,15 +20,26 @@ limitations under the License.
 package builtin
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/cockroachdb/errors"
 	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	cliflag "k8s.io/component-base/cli/flag"
+	"sigs.k8s.io/yaml"
 
 	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options"
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
 )
 
 // ======================================================================================
@@ -40,8 +51,20 @@ type ArtifactExportOptions struct {
 	options.CommonOptions
 	// kubernetes version which the cluster will install.
 	Kubernetes []string
+	// Base is the base artifact of a delta artifact, which is an artifact archive, an unpacked artifact dir or a manifest file.
+	Base string
+	// Arch selects the CPU architectures of binaries and image platforms in the artifact.
+	// It overrides "download.arch", and the architectures of hosts are not added.
+	Arch []string
 }
 
+// supportedArches are the CPU architectures which the artifact can be exported for.
+var supportedArches = []string{"amd64", "arm64"}
+
+// artifactBaseDir is the dir in workdir where the manifest of the base artifact is saved,
+// because the playbook resets the artifact_dir which may contain the base artifact.
+const artifactBaseDir = "artifact-base"
+
 // NewArtifactExportOptions for newArtifactExportCommand
 func NewArtifactExportOptions() *ArtifactExportOptions {
 	// set default value
@@ -56,6 +79,8 @@ func (o *ArtifactExportOptions) Flags() cliflag.NamedFlagSets {
 	fss := o.CommonOptions.Flags()
 	kfs := fss.FlagSet("config")
 	kfs.StringSliceVar(&o.Kubernetes, "with-kubernetes", o.Kubernetes, fmt.Sprintf("Specify a supported version of kubernetes. default is %s", o.Kubernetes))
+	kfs.StringSliceVar(&o.Arch, "arch", o.Arch, fmt.Sprintf("Only export the binaries and image platforms of the CPU architectures. support %s", strings.Join(supportedArches, ", ")))
+	kfs.StringVar(&o.Base, "base", o.Base, "Export a delta artifact which only contains the files not in the base artifact. support artifact archive, unpacked artifact dir and manifest file")
 
 	return fss
 }
@@ -87,10 +112,415 @@ func (o *ArtifactExportOptions) Complete(cmd *cobra.Command, args []string) (*kk
 	if err := o.CommonOptions.Complete(playbook); err != nil {
 		return nil, err
 	}
+	if err := o.completeArch(playbook); err != nil {
+		return nil, err
+	}
+	if o.Base != "" {
+		base, err := o.completeBase()
+		if err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedField(playbook.Spec.Config.Value(), base, "download", "artifact_base"); err != nil {
+			return nil, errors.Wrapf(err, "failed to set %q to config", "download.artifact_base")
+		}
+	}
 
 	return playbook, nil
 }
 
+// completeArch overrides "download.arch" by Arch, and stops adding the architectures of hosts to it.
+func (o *ArtifactExportOptions) completeArch(playbook *kkcorev1.Playbook) error {
+	if len(o.Arch) == 0 {
+		return nil
+	}
+	for _, arch := range o.Arch {
+		if !slices.Contains(supportedArches, arch) {
+			return errors.Errorf("unsupported arch %q. support %s", arch, strings.Join(supportedArches, ", "))
+		}
+	}
+	if err := unstructured.SetNestedStringSlice(playbook.Spec.Config.Value(), o.Arch, "download", "arch"); err != nil {
+		return errors.Wrapf(err, "failed to set %q to config", "download.arch")
+	}
+	if err := unstructured.SetNestedField(playbook.Spec.Config.Value(), false, "download", "host_arch"); err != nil {
+		return errors.Wrapf(err, "failed to set %q to config", "download.host_arch")
+	}
+
+	return nil
+}
+
+// completeBase reads the manifest of the Base artifact, and saves it in workdir.
+// It returns the dir of the saved manifest.
+func (o *ArtifactExportOptions) completeBase() (string, error) {
+	base, err := artifact.ReadManifest(o.Base)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(o.Workdir, artifactBaseDir)
+	if err := os.MkdirAll(dir, _const.PermDirPublic); err != nil {
+		return "", errors.Wrapf(err, "failed to create dir %q", dir)
+	}
+
+	return dir, artifact.WriteManifest(dir, base)
+}
+
+// ======================================================================================
+//                                    artifact diff
+// ======================================================================================
+
+const (
+	// ArtifactOutputTable prints the result as a table.
+	ArtifactOutputTable = "table"
+	// ArtifactOutputJSON prints the result as json.
+	ArtifactOutputJSON = "json"
+	// ArtifactOutputYAML prints the result as yaml.
+	ArtifactOutputYAML = "yaml"
+)
+
+// ArtifactDiffOptions for NewArtifactDiffOptions
+type ArtifactDiffOptions struct {
+	// Format of the changes. support table, json and yaml.
+	Format string
+}
+
+// NewArtifactDiffOptions for newArtifactDiffCommand
+func NewArtifactDiffOptions() *ArtifactDiffOptions {
+	return &ArtifactDiffOptions{Format: ArtifactOutputTable}
+}
+
+// Flags add to newArtifactDiffCommand
+func (o *ArtifactDiffOptions) Flags() cliflag.NamedFlagSets {
+	fss := cliflag.NamedFlagSets{}
+	dfs := fss.FlagSet("diff")
+	dfs.StringVarP(&o.Format, "output", "o", o.Format, fmt.Sprintf("the format of changes. support %s, %s and %s", ArtifactOutputTable, ArtifactOutputJSON, ArtifactOutputYAML))
+
+	return fss
+}
+
+// Run compares the manifests of the old and new artifacts, and writes the changes to w in Format.
+// Each artifact is an artifact archive, an unpacked artifact dir or a manifest file.
+func (o *ArtifactDiffOptions) Run(w io.Writer, oldArtifact, newArtifact string) error {
+	oldManifest, err := artifact.ReadManifest(oldArtifact)
+	if err != nil {
+		return err
+	}
+	newManifest, err := artifact.ReadManifest(newArtifact)
+	if err != nil {
+		return err
+	}
+	diff := artifact.Compare(oldManifest, newManifest)
+
+	return writeArtifactOutput(w, o.Format, diff, func() error { return writeArtifactDiff(w, diff) })
+}
+
+// writeArtifactOutput writes v to w as json or yaml, or calls table for the table format.
+func writeArtifactOutput(w io.Writer, format string, v any, table func() error) error {
+	switch format {
+	case ArtifactOutputJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal output to json")
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+
+		return errors.Wrap(err, "failed to write output")
+	case ArtifactOutputYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal output to yaml")
+		}
+		_, err = w.Write(data)
+
+		return errors.Wrap(err, "failed to write output")
+	case ArtifactOutputTable:
+		return table()
+	default:
+		return errors.Errorf("unsupported output %q. support %s, %s and %s", format, ArtifactOutputTable, ArtifactOutputJSON, ArtifactOutputYAML)
+	}
+}
+
+// writeArtifactDiff writes the changes as a table. The files of images are summarized,
+// because the changed images are listed.
+func writeArtifactDiff(w io.Writer, diff artifact.Diff) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KIND\tNAME\tCHANGE\tOLD\tNEW")
+	for _, c := range diff.Images {
+		_, _ = fmt.Fprintf(tw, "image\t%s\t%s\t%s\t%s\n", c.Name, c.Type, shortDigest(c.Old), shortDigest(c.New))
+	}
+	var imageFiles int
+	for _, c := range diff.Files {
+		if strings.HasPrefix(c.Name, _const.BinaryImagesDir+"/") {
+			imageFiles++
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "file\t%s\t%s\t%s\t%s\n", c.Name, c.Type, shortDigest(c.Old), shortDigest(c.New))
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write changes")
+	}
+	_, err := fmt.Fprintf(w, "\n%d images and %d files changed, %d of the files are blobs and manifests of images. delta size: %s\n",
+		len(diff.Images), len(diff.Files), imageFiles, formatSize(diff.DeltaSize))
+
+	return errors.Wrap(err, "failed to write changes")
+}
+
+// shortDigest returns the first 12 characters of the digest hex, or "-" for an empty digest.
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "-"
+	}
+	if _, hex, ok := strings.Cut(digest, ":"); ok && len(hex) > 12 {
+		return hex[:12]
+	}
+
+	return digest
+}
+
+// formatSize returns the human-readable size in binary units.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ======================================================================================
+//                                   artifact apply
+// ======================================================================================
+
+// ArtifactApplyOptions for NewArtifactApplyOptions
+type ArtifactApplyOptions struct {
+	// ConfigFile is the config file, whose "download.artifact_trust" checks the delta before it is applied.
+	ConfigFile string
+}
+
+// NewArtifactApplyOptions for newArtifactApplyCommand
+func NewArtifactApplyOptions() *ArtifactApplyOptions {
+	return &ArtifactApplyOptions{}
+}
+
+// Flags add to newArtifactApplyCommand
+func (o *ArtifactApplyOptions) Flags() cliflag.NamedFlagSets {
+	fss := cliflag.NamedFlagSets{}
+	afs := fss.FlagSet("apply")
+	afs.StringVarP(&o.ConfigFile, "config", "c", o.ConfigFile, "the config file path. the delta is checked by the trust policy in \"download.artifact_trust\" of it")
+
+	return fss
+}
+
+// Run checks the delta by the trust policy of the config, and merges it into the unpacked artifact in dir.
+func (o *ArtifactApplyOptions) Run(w io.Writer, delta, dir string) error {
+	if o.ConfigFile != "" {
+		data, err := os.ReadFile(o.ConfigFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get config from file %q", o.ConfigFile)
+		}
+		config := &kkcorev1.Config{}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal config from file %q", o.ConfigFile)
+		}
+		policy, err := options.ArtifactTrustPolicy(config.Value())
+		if err != nil {
+			return err
+		}
+		if policy != nil {
+			if err := policy.Check(delta); err != nil {
+				return errors.Wrapf(err, "artifact %q is not trusted", delta)
+			}
+		}
+	}
+	m, err := artifact.Apply(dir, delta)
+	if err != nil {
+		return err
+	}
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "artifact in %s is updated to %s\n", dir, digest)
+
+	return errors.Wrap(err, "failed to write result")
+}
+
+// ======================================================================================
+//                                   artifact inspect
+// ======================================================================================
+
+// ArtifactInspectOptions for NewArtifactInspectOptions
+type ArtifactInspectOptions struct {
+	// Format of the summary. support table, json and yaml.
+	Format string
+}
+
+// NewArtifactInspectOptions for newArtifactInspectCommand
+func NewArtifactInspectOptions() *ArtifactInspectOptions {
+	return &ArtifactInspectOptions{Format: ArtifactOutputTable}
+}
+
+// Flags add to newArtifactInspectCommand
+func (o *ArtifactInspectOptions) Flags() cliflag.NamedFlagSets {
+	fss := cliflag.NamedFlagSets{}
+	ifs := fss.FlagSet("inspect")
+	ifs.StringVarP(&o.Format, "output", "o", o.Format, fmt.Sprintf("the format of summary. support %s, %s and %s", ArtifactOutputTable, ArtifactOutputJSON, ArtifactOutputYAML))
+
+	return fss
+}
+
+// Run reads the manifest of the artifact, and writes its kubernetes versions, architectures, binaries and images to w in Format.
+// The artifact is an artifact archive, an unpacked artifact dir or a manifest file.
+func (o *ArtifactInspectOptions) Run(w io.Writer, p string) error {
+	m, err := artifact.ReadManifest(p)
+	if err != nil {
+		return err
+	}
+	summary, err := artifact.Inspect(m)
+	if err != nil {
+		return err
+	}
+
+	return writeArtifactOutput(w, o.Format, summary, func() error { return writeArtifactSummary(w, summary) })
+}
+
+// writeArtifactSummary writes the summary as tables.
+func writeArtifactSummary(w io.Writer, s *artifact.Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Digest:\t%s\n", s.Digest)
+	if s.Base != "" {
+		_, _ = fmt.Fprintf(tw, "Base:\t%s\n", s.Base)
+	}
+	_, _ = fmt.Fprintf(tw, "Kubernetes:\t%s\n", strings.Join(s.KubeVersions, ", "))
+	_, _ = fmt.Fprintf(tw, "Arches:\t%s\n", strings.Join(s.Arches, ", "))
+	_, _ = fmt.Fprintf(tw, "Size:\t%s in %d files\n", formatSize(s.Size), s.Files)
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write summary")
+	}
+	if len(s.Binaries) > 0 {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(tw, "BINARY\tVERSION\tARCH\tDIGEST")
+		for _, b := range s.Binaries {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Name, b.Version, b.Arch, b.Digest)
+		}
+		if err := tw.Flush(); err != nil {
+			return errors.Wrap(err, "failed to write summary")
+		}
+	}
+	if len(s.Images) > 0 {
+		_, _ = fmt.Fprintln(w)
+		_, _ = fmt.Fprintln(tw, "IMAGE\tDIGEST")
+		for _, i := range s.Images {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\n", i.Name, i.Digest)
+		}
+		if err := tw.Flush(); err != nil {
+			return errors.Wrap(err, "failed to write summary")
+		}
+	}
+
+	return nil
+}
+
+// ======================================================================================
+//                                   artifact verify
+// ======================================================================================
+
+// ArtifactVerifyOptions for NewArtifactVerifyOptions
+type ArtifactVerifyOptions struct {
+	// Keys are the public key files to verify the signature. The signature is not verified when empty.
+	Keys []string
+	// Signature is the detached signature file. default is the artifact path with ".sig" suffix.
+	Signature string
+}
+
+// NewArtifactVerifyOptions for newArtifactVerifyCommand
+func NewArtifactVerifyOptions() *ArtifactVerifyOptions {
+	return &ArtifactVerifyOptions{}
+}
+
+// Flags add to newArtifactVerifyCommand
+func (o *ArtifactVerifyOptions) Flags() cliflag.NamedFlagSets {
+	fss := cliflag.NamedFlagSets{}
+	vfs := fss.FlagSet("verify")
+	vfs.StringSliceVar(&o.Keys, "key", o.Keys, "PEM encoded public key files which are trusted to sign the artifact. the signature is verified by any of them")
+	vfs.StringVar(&o.Signature, "signature", o.Signature, "Detached signature file of the artifact. default is the artifact path with \".sig\" suffix")
+
+	return fss
+}
+
+// Run verifies the integrity of the artifact against its embedded manifest, and the signature when Keys is set.
+func (o *ArtifactVerifyOptions) Run(w io.Writer, p string) error {
+	policy := artifact.TrustPolicy{Policy: artifact.TrustPolicyIntegrity, Keys: o.Keys, Signature: o.Signature}
+	if len(o.Keys) > 0 {
+		policy.Policy = artifact.TrustPolicySigned
+	}
+	if err := policy.Check(p); err != nil {
+		return err
+	}
+	m, err := artifact.ReadManifest(p)
+	if err != nil {
+		return err
+	}
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+	if len(o.Keys) > 0 {
+		_, err = fmt.Fprintf(w, "artifact %s (%s) is intact and signed by the trusted keys\n", p, digest)
+	} else {
+		_, err = fmt.Fprintf(w, "artifact %s (%s) is intact, the signature is not verified without --key\n", p, digest)
+	}
+
+	return errors.Wrap(err, "failed to write result")
+}
+
+// ======================================================================================
+//                                    artifact sign
+// ======================================================================================
+
+// ArtifactSignOptions for NewArtifactSignOptions
+type ArtifactSignOptions struct {
+	// Key is the PEM encoded private key file to sign the artifact.
+	Key string
+	// Signature is the detached signature file to write. default is the artifact path with ".sig" suffix.
+	Signature string
+}
+
+// NewArtifactSignOptions for newArtifactSignCommand
+func NewArtifactSignOptions() *ArtifactSignOptions {
+	return &ArtifactSignOptions{}
+}
+
+// Flags add to newArtifactSignCommand
+func (o *ArtifactSignOptions) Flags() cliflag.NamedFlagSets {
+	fss := cliflag.NamedFlagSets{}
+	sfs := fss.FlagSet("sign")
+	sfs.StringVar(&o.Key, "key", o.Key, "PEM encoded private key file to sign the artifact. support ECDSA, RSA and Ed25519 keys")
+	sfs.StringVar(&o.Signature, "signature", o.Signature, "Detached signature file to write. default is the artifact path with \".sig\" suffix")
+
+	return fss
+}
+
+// Run verifies the integrity of the artifact and writes the signature of its manifest.
+func (o *ArtifactSignOptions) Run(w io.Writer, p string) error {
+	if o.Key == "" {
+		return errors.New("--key is required to sign the artifact")
+	}
+	signature := o.Signature
+	if signature == "" {
+		signature = artifact.SignaturePath(p)
+	}
+	if err := artifact.Sign(p, o.Key, signature); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "artifact %s is signed, signature is written to %s\n", p, signature)
+
+	return errors.Wrap(err, "failed to write result")
+}
+
 // ======================================================================================
 //                                   artifact image
 // ======================================================================================
@@ -0,0 +1,235 @@
+//go:build builtin
+// +build builtin
+
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
+)
+
+// writeTestManifest writes the files to an artifact dir with its manifest.
+func writeTestManifest(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+	}
+	m, err := artifact.NewManifest(dir)
+	require.NoError(t, err)
+	require.NoError(t, artifact.WriteManifest(dir, m))
+
+	return dir
+}
+
+func TestArtifactDiffRun(t *testing.T) {
+	oldDir := writeTestManifest(t, map[string]string{
+		"kube/v1.33.0/amd64/kubelet":                    "kubelet-v1.33.0",
+		"images/blobs/sha256:aaa":                       "layer-a",
+		"images/docker.io/calico/node/manifests/layout": `{"v3.28.0": "sha256:m1"}`,
+	})
+	newDir := writeTestManifest(t, map[string]string{
+		"kube/v1.33.1/amd64/kubelet":                    "kubelet-v1.33.1",
+		"images/blobs/sha256:aaa":                       "layer-a",
+		"images/blobs/sha256:bbb":                       "layer-b",
+		"images/docker.io/calico/node/manifests/layout": `{"v3.28.0": "sha256:m1", "v3.29.0": "sha256:m2"}`,
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewArtifactDiffOptions().Run(&buf, oldDir, filepath.Join(newDir, artifact.ManifestFile)))
+		out := buf.String()
+		assert.Contains(t, out, "image   docker.io/calico/node:v3.29.0   added")
+		assert.Contains(t, out, "file    kube/v1.33.0/amd64/kubelet")
+		assert.Contains(t, out, "file    kube/v1.33.1/amd64/kubelet")
+		assert.NotContains(t, out, "sha256:bbb")
+		assert.Contains(t, out, "1 images and 4 files changed, 2 of the files are blobs and manifests of images.")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		o := NewArtifactDiffOptions()
+		o.Format = ArtifactOutputJSON
+		require.NoError(t, o.Run(&buf, oldDir, newDir))
+		var diff artifact.Diff
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &diff))
+		assert.Len(t, diff.Images, 1)
+		assert.Len(t, diff.Files, 4)
+	})
+
+	t.Run("unsupported output", func(t *testing.T) {
+		o := NewArtifactDiffOptions()
+		o.Format = "xml"
+		require.Error(t, o.Run(&bytes.Buffer{}, oldDir, newDir))
+	})
+}
+
+func TestArtifactInspectRun(t *testing.T) {
+	dir := writeTestManifest(t, map[string]string{
+		"kube/v1.33.1/amd64/kubelet":                    "kubelet-v1.33.1",
+		"kube/v1.33.1/arm64/kubelet":                    "kubelet-v1.33.1-arm64",
+		"images/blobs/sha256:aaa":                       "layer-a",
+		"images/docker.io/calico/node/manifests/layout": `{"v3.28.0": "sha256:m1"}`,
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewArtifactInspectOptions().Run(&buf, dir))
+		out := buf.String()
+		assert.Contains(t, out, "Kubernetes:   v1.33.1")
+		assert.Contains(t, out, "Arches:       amd64, arm64")
+		assert.Contains(t, out, "kube     v1.33.1   arm64")
+		assert.Contains(t, out, "docker.io/calico/node:v3.28.0   sha256:m1")
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		o := NewArtifactInspectOptions()
+		o.Format = ArtifactOutputYAML
+		require.NoError(t, o.Run(&buf, dir))
+		assert.Contains(t, buf.String(), "kubeVersions:\n- v1.33.1\n")
+	})
+}
+
+func TestArtifactVerifyRun(t *testing.T) {
+	dir := writeTestManifest(t, map[string]string{"kube/v1.33.1/amd64/kubelet": "kubelet-v1.33.1"})
+
+	var buf bytes.Buffer
+	require.NoError(t, NewArtifactVerifyOptions().Run(&buf, dir))
+	assert.Contains(t, buf.String(), "is intact, the signature is not verified without --key")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kube/v1.33.1/amd64/kubelet"), []byte("tampered"), 0644))
+	require.Error(t, NewArtifactVerifyOptions().Run(&bytes.Buffer{}, dir))
+	require.ErrorContains(t, NewArtifactSignOptions().Run(&bytes.Buffer{}, dir), "--key is required")
+}
+
+func TestArtifactApplyRun(t *testing.T) {
+	dir := writeTestManifest(t, map[string]string{"kube/v1.33.0/amd64/kubelet": "kubelet-v1.33.0"})
+	newDir := writeTestManifest(t, map[string]string{"kube/v1.33.1/amd64/kubelet": "kubelet-v1.33.1"})
+	// the whole artifact archive of newDir.
+	archive := filepath.Join(t.TempDir(), "artifact.tgz")
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{artifact.ManifestFile, "kube/v1.33.1/amd64/kubelet"} {
+		data, err := os.ReadFile(filepath.Join(newDir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	require.NoError(t, f.Close())
+	// the config trusts the artifacts signed by key, and the archive is not signed.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "artifact.pub")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0644))
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("spec:\n  download:\n    artifact_trust:\n      keys: ["+keyFile+"]\n"), 0644))
+
+	o := NewArtifactApplyOptions()
+	o.ConfigFile = configFile
+	require.ErrorContains(t, o.Run(&bytes.Buffer{}, archive, dir), "is not trusted")
+	assert.FileExists(t, filepath.Join(dir, "kube/v1.33.0/amd64/kubelet"))
+
+	var buf bytes.Buffer
+	require.NoError(t, NewArtifactApplyOptions().Run(&buf, archive, dir))
+	assert.Contains(t, buf.String(), "is updated to")
+	assert.NoFileExists(t, filepath.Join(dir, "kube/v1.33.0/amd64/kubelet"))
+	assert.FileExists(t, filepath.Join(dir, "kube/v1.33.1/amd64/kubelet"))
+}
+
+func TestArtifactExportCompleteArch(t *testing.T) {
+	testcases := []struct {
+		name     string
+		arch     []string
+		expected map[string]any
+		errMsg   string
+	}{
+		{
+			name:     "no arch",
+			expected: map[string]any{},
+		},
+		{
+			name: "arm64 only",
+			arch: []string{"arm64"},
+			expected: map[string]any{
+				"download": map[string]any{"arch": []any{"arm64"}, "host_arch": false},
+			},
+		},
+		{
+			name:   "unsupported arch",
+			arch:   []string{"amd64", "mips"},
+			errMsg: "unsupported arch",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := NewArtifactExportOptions()
+			o.Arch = tc.arch
+			playbook := &kkcorev1.Playbook{}
+			err := o.completeArch(playbook)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, playbook.Spec.Config.Value())
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testcases := []struct {
+		size     int64
+		excepted string
+	}{
+		{size: 512, excepted: "512B"},
+		{size: 1536, excepted: "1.5KiB"},
+		{size: 10 * 1024 * 1024 * 1024, excepted: "10.0GiB"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.excepted, formatSize(tc.size))
+	}
+}
@@ -20,13 +20,25 @@ limitations under the License.
 package builtin
 
 import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
 	cliflag "k8s.io/component-base/cli/flag"
+	"sigs.k8s.io/yaml"
 
 	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options"
 )
@@ -79,3 +91,260 @@ func (o *CertsRenewOptions) Complete(cmd *cobra.Command, args []string) (*kkcore
 
 	return playbook, o.CommonOptions.Complete(playbook)
 }
+
+const (
+	// certsExpirationDir is the dir in workdir where the playbook fetches the certificates of each host.
+	certsExpirationDir = "certs-expiration"
+
+	// ExpirationOutputTable prints the certificates as a table.
+	ExpirationOutputTable = "table"
+	// ExpirationOutputJSON prints the certificates as json.
+	ExpirationOutputJSON = "json"
+	// ExpirationOutputYAML prints the certificates as yaml.
+	ExpirationOutputYAML = "yaml"
+)
+
+// NewCertsCheckExpirationOptions for newCertsCheckExpirationCommand
+func NewCertsCheckExpirationOptions() *CertsCheckExpirationOptions {
+	// set default value
+	o := &CertsCheckExpirationOptions{
+		CommonOptions: options.NewCommonOptions(),
+		Format:        ExpirationOutputTable,
+		Threshold:     30 * 24 * time.Hour,
+	}
+	o.GetInventoryFunc = getInventory
+
+	return o
+}
+
+// CertsCheckExpirationOptions for NewCertsCheckExpirationOptions
+type CertsCheckExpirationOptions struct {
+	options.CommonOptions
+	// Format of the certificates report. support table, json and yaml.
+	Format string
+	// Threshold of the residual time. The command fails when any certificate expires within it.
+	Threshold time.Duration
+}
+
+// CertificateExpiration is the expiration of a certificate on a host.
+type CertificateExpiration struct {
+	// Host is the inventory host which the certificate is collected from.
+	Host string `json:"host"`
+	// Path is the certificate file or kubeconfig file of the certificate.
+	Path string `json:"path"`
+	// Subject is the distinguished name of the certificate subject.
+	Subject string `json:"subject"`
+	// Issuer is the distinguished name of the certificate issuer.
+	Issuer string `json:"issuer"`
+	// SANs are the DNS names, IP addresses, emails and URIs of the certificate.
+	SANs []string `json:"sans,omitempty"`
+	// NotAfter is the expiry of the certificate.
+	NotAfter time.Time `json:"notAfter"`
+	// ResidualTime is the human-readable time until the expiry, or "<invalid>" when it has expired.
+	ResidualTime string `json:"residualTime"`
+	// ResidualSeconds is the seconds until the expiry. It is negative when the certificate has expired.
+	ResidualSeconds int64 `json:"residualSeconds"`
+	// Expiring reports whether the residual time is below the threshold.
+	Expiring bool `json:"expiring"`
+}
+
+// Flags add to newCertsCheckExpirationCommand
+func (o *CertsCheckExpirationOptions) Flags() cliflag.NamedFlagSets {
+	fss := o.CommonOptions.Flags()
+	cfs := fss.FlagSet("certs")
+	cfs.StringVar(&o.Format, "format", o.Format, fmt.Sprintf("the format of certificates report. support %s, %s and %s", ExpirationOutputTable, ExpirationOutputJSON, ExpirationOutputYAML))
+	cfs.DurationVar(&o.Threshold, "threshold", o.Threshold, "exit with non-zero code when any certificate expires within the threshold, such as 720h")
+
+	return fss
+}
+
+// Complete options. create Playbook, Config and Inventory
+func (o *CertsCheckExpirationOptions) Complete(cmd *cobra.Command, args []string) (*kkcorev1.Playbook, error) {
+	switch o.Format {
+	case ExpirationOutputTable:
+	case ExpirationOutputJSON, ExpirationOutputYAML:
+		// keep stdout for the report only.
+		o.LogOutput = os.Stderr
+	default:
+		return nil, errors.Errorf("unsupported format %q. support %s, %s and %s", o.Format, ExpirationOutputTable, ExpirationOutputJSON, ExpirationOutputYAML)
+	}
+	playbook := &kkcorev1.Playbook{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "certs-check-expiration-",
+			Namespace:    o.Namespace,
+			Annotations: map[string]string{
+				kkcorev1.BuiltinsProjectAnnotation: "",
+			},
+		},
+	}
+	// complete playbook. now only support one playbook
+	if len(args) != 1 {
+		return nil, errors.Errorf("%s\nSee '%s -h' for help and examples", cmd.Use, cmd.CommandPath())
+	}
+	o.Playbook = args[0]
+	playbook.Spec = kkcorev1.PlaybookSpec{
+		Playbook: o.Playbook,
+	}
+
+	return playbook, o.CommonOptions.Complete(playbook)
+}
+
+// Run removes the certificates fetched by the last run, and executes the playbook.
+func (o *CertsCheckExpirationOptions) Run(ctx context.Context, playbook *kkcorev1.Playbook) error {
+	if err := os.RemoveAll(filepath.Join(o.Workdir, certsExpirationDir)); err != nil {
+		return errors.Wrapf(err, "failed to clean dir %q", filepath.Join(o.Workdir, certsExpirationDir))
+	}
+
+	return o.CommonOptions.Run(ctx, playbook)
+}
+
+// Report writes the fetched certificates to w in Format.
+// It returns error when any certificate expires within the Threshold.
+func (o *CertsCheckExpirationOptions) Report(w io.Writer) error {
+	certs, err := loadCertificateExpirations(filepath.Join(o.Workdir, certsExpirationDir), time.Now(), o.Threshold)
+	if err != nil {
+		return err
+	}
+	if err := writeCertificateExpirations(w, o.Format, certs); err != nil {
+		return err
+	}
+	var expiring int
+	for _, c := range certs {
+		if c.Expiring {
+			expiring++
+		}
+	}
+	if expiring > 0 {
+		return errors.Errorf("%d certificates expire within %s", expiring, o.Threshold)
+	}
+
+	return nil
+}
+
+// loadCertificateExpirations parses the "<host>.pem" files in dir, which are fetched by the playbook.
+// Each file contains "# <path>" lines, and each line is followed by the certificates of the path.
+func loadCertificateExpirations(dir string, now time.Time, threshold time.Duration) ([]CertificateExpiration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read dir %q", dir)
+	}
+	certs := make([]CertificateExpiration, 0)
+	// entries are sorted by filename.
+	for _, entry := range entries {
+		host, ok := strings.CutSuffix(entry.Name(), ".pem")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read file %q", filepath.Join(dir, entry.Name()))
+		}
+		hostCerts, err := parseCertificateExpirations(host, data, now, threshold)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, hostCerts...)
+	}
+
+	return certs, nil
+}
+
+// parseCertificateExpirations parses the certificates collected from the host.
+func parseCertificateExpirations(host string, data []byte, now time.Time, threshold time.Duration) ([]CertificateExpiration, error) {
+	var certs []CertificateExpiration
+	var path string
+	var block []byte
+	parse := func() error {
+		for rest := block; ; {
+			var p *pem.Block
+			if p, rest = pem.Decode(rest); p == nil {
+				break
+			}
+			if p.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(p.Bytes)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse certificate %q of host %q", path, host)
+			}
+			certs = append(certs, newCertificateExpiration(host, path, cert, now, threshold))
+		}
+		block = nil
+
+		return nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if p, ok := strings.CutPrefix(line, "# "); ok {
+			if err := parse(); err != nil {
+				return nil, err
+			}
+			path = strings.TrimSpace(p)
+
+			continue
+		}
+		block = append(block, line+"\n"...)
+	}
+
+	return certs, parse()
+}
+
+// newCertificateExpiration converts the certificate to CertificateExpiration.
+func newCertificateExpiration(host, path string, cert *x509.Certificate, now time.Time, threshold time.Duration) CertificateExpiration {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses)+len(cert.URIs))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	residual := cert.NotAfter.Sub(now)
+	residualTime := "<invalid>"
+	if residual > 0 {
+		residualTime = duration.ShortHumanDuration(residual)
+	}
+
+	return CertificateExpiration{
+		Host:            host,
+		Path:            path,
+		Subject:         cert.Subject.String(),
+		Issuer:          cert.Issuer.String(),
+		SANs:            sans,
+		NotAfter:        cert.NotAfter.UTC(),
+		ResidualTime:    residualTime,
+		ResidualSeconds: int64(residual / time.Second),
+		Expiring:        residual < threshold,
+	}
+}
+
+// writeCertificateExpirations writes the certificates to w in the format.
+func writeCertificateExpirations(w io.Writer, format string, certs []CertificateExpiration) error {
+	switch format {
+	case ExpirationOutputJSON:
+		data, err := json.MarshalIndent(certs, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal certificates to json")
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+
+		return errors.Wrap(err, "failed to write certificates")
+	case ExpirationOutputYAML:
+		data, err := yaml.Marshal(certs)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal certificates to yaml")
+		}
+		_, err = w.Write(data)
+
+		return errors.Wrap(err, "failed to write certificates")
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "HOST\tCERTIFICATE\tSUBJECT\tISSUER\tSANS\tEXPIRES\tRESIDUAL TIME")
+		for _, c := range certs {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Host, c.Path, c.Subject, c.Issuer,
+				strings.Join(c.SANs, ","), c.NotAfter.Format("Jan 02, 2006 15:04 MST"), c.ResidualTime)
+		}
+
+		return errors.Wrap(tw.Flush(), "failed to write certificates")
+	}
+}
@@ -0,0 +1,217 @@
+//go:build builtin
+// +build builtin
+
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+// newTestCertPEM creates a self-signed certificate which expires at notAfter.
+func newTestCertPEM(t *testing.T, cn string, notAfter time.Time, dnsNames []string, ips []net.IP) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"kubekey"}},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestParseCertificateExpirations(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	apiserver := newTestCertPEM(t, "kube-apiserver", now.Add(300*24*time.Hour), []string{"kubernetes"}, []net.IP{net.ParseIP("10.233.0.1")})
+	admin := newTestCertPEM(t, "kubernetes-admin", now.Add(10*24*time.Hour), nil, nil)
+	expired := newTestCertPEM(t, "etcd", now.Add(-time.Hour), nil, nil)
+
+	testcases := []struct {
+		name           string
+		data           string
+		exceptPaths    []string
+		exceptExpire   []bool
+		exceptResidual []string
+	}{
+		{
+			name:           "empty file",
+			data:           "",
+			exceptPaths:    nil,
+			exceptExpire:   nil,
+			exceptResidual: nil,
+		},
+		{
+			name:           "certificates of files",
+			data:           "# /etc/kubernetes/pki/apiserver.crt\n" + apiserver + "# /etc/kubernetes/admin.conf\n" + admin,
+			exceptPaths:    []string{"/etc/kubernetes/pki/apiserver.crt", "/etc/kubernetes/admin.conf"},
+			exceptExpire:   []bool{false, true},
+			exceptResidual: []string{"300d", "10d"},
+		},
+		{
+			name:           "chain in a file and file without certificate",
+			data:           "# /etc/kubernetes/pki/ca.crt\n# /etc/ssl/etcd/ssl/server.crt\n" + expired + apiserver,
+			exceptPaths:    []string{"/etc/ssl/etcd/ssl/server.crt", "/etc/ssl/etcd/ssl/server.crt"},
+			exceptExpire:   []bool{true, false},
+			exceptResidual: []string{"<invalid>", "300d"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			certs, err := parseCertificateExpirations("node1", []byte(tc.data), now, 30*24*time.Hour)
+			require.NoError(t, err)
+			require.Len(t, certs, len(tc.exceptPaths))
+			for i, c := range certs {
+				assert.Equal(t, "node1", c.Host)
+				assert.Equal(t, tc.exceptPaths[i], c.Path)
+				assert.Equal(t, tc.exceptExpire[i], c.Expiring)
+				assert.Equal(t, tc.exceptResidual[i], c.ResidualTime)
+			}
+		})
+	}
+}
+
+func TestCertificateExpirationFields(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := "# /etc/kubernetes/pki/apiserver.crt\n" +
+		newTestCertPEM(t, "kube-apiserver", now.Add(48*time.Hour), []string{"kubernetes", "lb.kubesphere.local"}, []net.IP{net.ParseIP("10.233.0.1")})
+
+	certs, err := parseCertificateExpirations("node1", []byte(data), now, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.Equal(t, "CN=kube-apiserver,O=kubekey", certs[0].Subject)
+	assert.Equal(t, "CN=kube-apiserver,O=kubekey", certs[0].Issuer)
+	assert.Equal(t, []string{"kubernetes", "lb.kubesphere.local", "10.233.0.1"}, certs[0].SANs)
+	assert.Equal(t, now.Add(48*time.Hour), certs[0].NotAfter)
+	assert.Equal(t, int64(48*3600), certs[0].ResidualSeconds)
+	assert.Equal(t, "2d", certs[0].ResidualTime)
+	assert.False(t, certs[0].Expiring)
+}
+
+func TestWriteCertificateExpirations(t *testing.T) {
+	certs := []CertificateExpiration{
+		{
+			Host:            "node1",
+			Path:            "/etc/kubernetes/pki/apiserver.crt",
+			Subject:         "CN=kube-apiserver",
+			Issuer:          "CN=kubernetes",
+			SANs:            []string{"kubernetes", "10.233.0.1"},
+			NotAfter:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			ResidualTime:    "365d",
+			ResidualSeconds: 365 * 24 * 3600,
+		},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, writeCertificateExpirations(buf, ExpirationOutputJSON, certs))
+		var got []CertificateExpiration
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, certs, got)
+	})
+	t.Run("yaml", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, writeCertificateExpirations(buf, ExpirationOutputYAML, certs))
+		var got []CertificateExpiration
+		require.NoError(t, yaml.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, certs, got)
+	})
+	t.Run("table", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, writeCertificateExpirations(buf, ExpirationOutputTable, certs))
+		assert.Contains(t, buf.String(), "RESIDUAL TIME")
+		assert.Contains(t, buf.String(), "kubernetes,10.233.0.1")
+		assert.Contains(t, buf.String(), "Jan 01, 2025 00:00 UTC")
+	})
+}
+
+func TestCertsCheckExpirationReport(t *testing.T) {
+	testcases := []struct {
+		name      string
+		notAfter  time.Duration
+		exceptErr bool
+	}{
+		{
+			name:      "valid certificates",
+			notAfter:  365 * 24 * time.Hour,
+			exceptErr: false,
+		},
+		{
+			name:      "certificates below threshold",
+			notAfter:  24 * time.Hour,
+			exceptErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := NewCertsCheckExpirationOptions()
+			o.Workdir = t.TempDir()
+			o.Format = ExpirationOutputJSON
+			dir := filepath.Join(o.Workdir, certsExpirationDir)
+			require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+			data := "# /etc/kubernetes/pki/ca.crt\n" + newTestCertPEM(t, "kubernetes", time.Now().Add(tc.notAfter), nil, nil)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "node1.pem"), []byte(data), os.ModePerm))
+
+			buf := &bytes.Buffer{}
+			err := o.Report(buf)
+			if tc.exceptErr {
+				require.ErrorContains(t, err, "1 certificates expire within")
+			} else {
+				require.NoError(t, err)
+			}
+			var got []CertificateExpiration
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+			require.Len(t, got, 1)
+			assert.Equal(t, "node1", got[0].Host)
+		})
+	}
+}
+
+func TestCertsCheckExpirationFlags(t *testing.T) {
+	o := NewCertsCheckExpirationOptions()
+	fss := o.Flags()
+	require.NoError(t, fss.FlagSet("certs").Parse([]string{"--format", "yaml", "--threshold", "48h"}))
+	assert.Equal(t, ExpirationOutputYAML, o.Format)
+	assert.Empty(t, o.Output)
+	assert.Equal(t, 48*time.Hour, o.Threshold)
+}
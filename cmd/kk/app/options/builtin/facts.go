@@ -0,0 +1,84 @@
+//go:build builtin
+// +build builtin
+
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builtin
+
+import (
+	"github.com/cockroachdb/errors"
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/kubesphere/kubekey/v4/cmd/kk/app/options"
+)
+
+// NewFactsOptions for newFactsCommand
+func NewFactsOptions(action string) *FactsOptions {
+	// set default value
+	o := &FactsOptions{
+		CommonOptions: options.NewCommonOptions(),
+		Action:        action,
+	}
+	o.GetInventoryFunc = getInventory
+
+	return o
+}
+
+// FactsOptions for NewFactsOptions
+type FactsOptions struct {
+	options.CommonOptions
+	// Action to the cached facts. support show, refresh, clear.
+	Action string
+}
+
+// Flags add to newFactsCommand
+func (o *FactsOptions) Flags() cliflag.NamedFlagSets {
+	return o.CommonOptions.Flags()
+}
+
+// Complete options. create Playbook, Config and Inventory.
+// args are the playbook and an optional host pattern, which restricts the hosts like "--limit".
+func (o *FactsOptions) Complete(cmd *cobra.Command, args []string) (*kkcorev1.Playbook, error) {
+	playbook := &kkcorev1.Playbook{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "facts-" + o.Action + "-",
+			Namespace:    o.Namespace,
+			Annotations: map[string]string{
+				kkcorev1.BuiltinsProjectAnnotation: "",
+			},
+		},
+	}
+	// complete playbook. now only support one playbook
+	switch len(args) {
+	case 1:
+		o.Playbook = args[0]
+	case 2:
+		o.Limit = append(o.Limit, args[0])
+		o.Playbook = args[1]
+	default:
+		return nil, errors.Errorf("%s\nSee '%s -h' for help and examples", cmd.Use, cmd.CommandPath())
+	}
+	playbook.Spec = kkcorev1.PlaybookSpec{
+		Playbook: o.Playbook,
+		Tags:     []string{o.Action},
+	}
+
+	return playbook, o.CommonOptions.Complete(playbook)
+}
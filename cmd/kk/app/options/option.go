@@ -19,6 +19,7 @@ package options
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -30,6 +31,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/rest"
 	cliflag "k8s.io/component-base/cli/flag"
@@ -37,9 +39,19 @@ import (
 	"k8s.io/utils/ptr"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
 	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
 	"github.com/kubesphere/kubekey/v4/pkg/manager"
 	"github.com/kubesphere/kubekey/v4/pkg/proxy"
+	"github.com/kubesphere/kubekey/v4/pkg/report"
+)
+
+const (
+	// OutputText writes the human-readable progress to stdout.
+	OutputText = "text"
+	// OutputJSON writes each event of playbook execution as a line of json to stdout.
+	OutputJSON = "json"
 )
 
 // InventoryFunc defines a function type that returns a pointer to a kkcorev1.Inventory and an error.
@@ -68,6 +80,22 @@ type CommonOptions struct {
 	Artifact string
 	// Namespace specifies the namespace for all resources.
 	Namespace string
+	// Limit restricts the hosts of each play to the given host patterns.
+	// A value prefixed by "@" is a file which contains one host pattern per line.
+	Limit []string
+
+	// Output is the format of playbook progress on stdout. support text and json.
+	// When it's json, each event is written as a line of json and the human-readable progress is written to stderr.
+	Output string
+	// Callbacks are the names of registered callbacks which receive the events of playbook execution.
+	Callbacks []string
+	// Reports to generate when the playbook ends. format: junit=path,html=path
+	Reports []string
+
+	// ExecutorOptions for the playbook executor.
+	ExecutorOptions []executor.Option
+	// LogOutput is where the human-readable progress is written. default os.Stdout
+	LogOutput io.Writer
 
 	// Config is the kubekey core configuration.
 	Config *kkcorev1.Config
@@ -155,11 +183,16 @@ func (o *CommonOptions) Run(ctx context.Context, playbook *kkcorev1.Playbook) er
 		return errors.Wrap(err, "failed to create playbook")
 	}
 
+	// the answers of step and debugger are read from the terminal of command line only.
+	executorOptions := append([]executor.Option{executor.WithInput(os.Stdin)}, o.ExecutorOptions...)
+
 	return manager.NewCommandManager(manager.CommandManagerOptions{
-		Playbook:  playbook,
-		Config:    o.Config,
-		Inventory: o.Inventory,
-		Client:    client,
+		Playbook:        playbook,
+		Config:          o.Config,
+		Inventory:       o.Inventory,
+		Client:          client,
+		ExecutorOptions: executorOptions,
+		LogOutput:       o.LogOutput,
 	}).Run(ctx)
 }
 
@@ -175,6 +208,10 @@ func (o *CommonOptions) Flags() cliflag.NamedFlagSets {
 	gfs.StringArrayVar(&o.Set, "set", o.Set, "set value in config. format --set key=val or --set k1=v1,k2=v2")
 	gfs.StringVarP(&o.InventoryFile, "inventory", "i", o.InventoryFile, "the host list file path. support *.yaml")
 	gfs.StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "the namespace which playbook will be executed, all reference resources(playbook, config, inventory, task) should in the same namespace")
+	gfs.StringArrayVarP(&o.Limit, "limit", "l", o.Limit, "limit the hosts of each play to the given host pattern. format --limit node1,node2 or --limit @hosts.txt (one host per line)")
+	gfs.StringVar(&o.Output, "output", o.Output, "the format of playbook progress. support text and json. json writes each event as a line of json to stdout")
+	gfs.StringArrayVar(&o.Callbacks, "callback", o.Callbacks, "the name of registered callback which receives the events of playbook execution")
+	gfs.StringArrayVar(&o.Reports, "report", o.Reports, "generate reports when the playbook ends. support junit and html. format --report junit=path,html=path")
 
 	return fss
 }
@@ -220,6 +257,10 @@ func (o *CommonOptions) Complete(playbook *kkcorev1.Playbook) error {
 	if err := o.completeConfig(); err != nil {
 		return err
 	}
+	// Refuse the untrusted artifact before the playbook runs.
+	if err := o.completeArtifactTrust(); err != nil {
+		return err
+	}
 	playbook.Spec.Config = ptr.Deref(o.Config, kkcorev1.Config{})
 	// Complete the inventory reference.
 	if err := o.completeInventory(o.Inventory); err != nil {
@@ -233,10 +274,72 @@ func (o *CommonOptions) Complete(playbook *kkcorev1.Playbook) error {
 		APIVersion:      o.Inventory.APIVersion,
 		ResourceVersion: o.Inventory.ResourceVersion,
 	}
+	// Complete the limit hosts.
+	limit, err := o.completeLimit()
+	if err != nil {
+		return err
+	}
+	playbook.Spec.Limit = limit
+
+	return o.completeOutput()
+}
+
+// completeOutput sets the executor callbacks by Output, Callbacks and Reports.
+func (o *CommonOptions) completeOutput() error {
+	switch o.Output {
+	case "", OutputText:
+	case OutputJSON:
+		// keep stdout for events only.
+		o.LogOutput = os.Stderr
+		o.ExecutorOptions = append(o.ExecutorOptions, executor.WithCallbacks(executor.NewJSONCallback(os.Stdout)))
+	default:
+		return errors.Errorf("unsupported output %q. support %s and %s", o.Output, OutputText, OutputJSON)
+	}
+	for _, name := range o.Callbacks {
+		cb := executor.FindCallback(name)
+		if cb == nil {
+			return errors.Errorf("callback %q is not registered", name)
+		}
+		o.ExecutorOptions = append(o.ExecutorOptions, executor.WithCallbacks(cb))
+	}
+	reports, err := report.ParseCallbacks(o.Reports)
+	if err != nil {
+		return err
+	}
+	if len(reports) > 0 {
+		o.ExecutorOptions = append(o.ExecutorOptions, executor.WithCallbacks(reports...))
+	}
 
 	return nil
 }
 
+// completeLimit converts Limit to host patterns. A value prefixed by "@" is read from file,
+// each non-empty line (except comments start with "#") is a host pattern.
+func (o *CommonOptions) completeLimit() ([]string, error) {
+	var limit []string
+	for _, l := range o.Limit {
+		filename, ok := strings.CutPrefix(l, "@")
+		if !ok {
+			if l = strings.TrimSpace(l); l != "" {
+				limit = append(limit, l)
+			}
+
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read limit file %q", filename)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				limit = append(limit, line)
+			}
+		}
+	}
+
+	return limit, nil
+}
+
 // genConfig generate config by ConfigFile and set value by command args.
 func (o *CommonOptions) completeConfig() error {
 	// set value by command args
@@ -268,6 +371,42 @@ func (o *CommonOptions) completeConfig() error {
 	return nil
 }
 
+// completeArtifactTrust checks the artifact ("download.artifact_file") by the trust policy in "download.artifact_trust".
+// Nothing is checked when no artifact or no trust policy is configured.
+func (o *CommonOptions) completeArtifactTrust() error {
+	artifactFile, _, _ := unstructured.NestedString(o.Config.Value(), "download", "artifact_file")
+	if artifactFile == "" {
+		return nil
+	}
+	policy, err := ArtifactTrustPolicy(o.Config.Value())
+	if err != nil || policy == nil {
+		return err
+	}
+	if err := policy.Check(artifactFile); err != nil {
+		return errors.Wrapf(err, "artifact %q is not trusted", artifactFile)
+	}
+
+	return nil
+}
+
+// ArtifactTrustPolicy returns the trust policy in "download.artifact_trust" of the config value.
+// It returns nil when no trust policy is configured.
+func ArtifactTrustPolicy(config map[string]any) (*artifact.TrustPolicy, error) {
+	trust, ok, err := unstructured.NestedMap(config, "download", "artifact_trust")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %q from config", "download.artifact_trust")
+	}
+	if !ok {
+		return nil, nil
+	}
+	policy := &artifact.TrustPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(trust, policy); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert %q in config", "download.artifact_trust")
+	}
+
+	return policy, nil
+}
+
 // genConfig generate config by ConfigFile and set value by command args.
 func (o *CommonOptions) completeInventory(inventory *kkcorev1.Inventory) error {
 	// set value by command args
@@ -18,9 +18,14 @@ package options
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
 )
 
 func TestParseKey(t *testing.T) {
@@ -286,3 +291,173 @@ func TestMultipleValuesWithDots(t *testing.T) {
 		})
 	}
 }
+
+func TestCompleteLimit(t *testing.T) {
+	limitFile := filepath.Join(t.TempDir(), "hosts.txt")
+	if err := os.WriteFile(limitFile, []byte("node1\n# comment\n\nnode2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		limit    []string
+		expected []string
+	}{
+		{
+			name:     "host pattern",
+			limit:    []string{"kube_control_plane:!node1"},
+			expected: []string{"kube_control_plane:!node1"},
+		},
+		{
+			name:     "limit file",
+			limit:    []string{"@" + limitFile, "node3"},
+			expected: []string{"node1", "node2", "node3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &CommonOptions{Limit: tt.limit}
+			result, err := o.completeLimit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(result, tt.expected) {
+				t.Errorf("completeLimit() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	t.Run("limit file not found", func(t *testing.T) {
+		o := &CommonOptions{Limit: []string{"@" + filepath.Join(t.TempDir(), "missing")}}
+		if _, err := o.completeLimit(); err == nil {
+			t.Error("completeLimit() expected error for missing file")
+		}
+	})
+}
+
+func TestCompleteOutput(t *testing.T) {
+	tests := []struct {
+		name            string
+		output          string
+		callbacks       []string
+		reports         []string
+		executorOptions int
+		logToStderr     bool
+		wantErr         bool
+	}{
+		{
+			name:   "default output",
+			output: "",
+		},
+		{
+			name:   "text output",
+			output: OutputText,
+		},
+		{
+			name:            "json output",
+			output:          OutputJSON,
+			executorOptions: 1,
+			logToStderr:     true,
+		},
+		{
+			name:    "unsupported output",
+			output:  "xml",
+			wantErr: true,
+		},
+		{
+			name:            "reports",
+			reports:         []string{"junit=report.xml,html=report.html"},
+			executorOptions: 1,
+		},
+		{
+			name:    "unsupported report",
+			reports: []string{"pdf=report.pdf"},
+			wantErr: true,
+		},
+		{
+			name:      "callback not registered",
+			callbacks: []string{"not-registered"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &CommonOptions{Output: tt.output, Callbacks: tt.callbacks, Reports: tt.reports}
+			err := o.completeOutput()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("completeOutput() expected error")
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(o.ExecutorOptions) != tt.executorOptions {
+				t.Errorf("completeOutput() executor options = %d, want %d", len(o.ExecutorOptions), tt.executorOptions)
+			}
+			if (o.LogOutput == os.Stderr) != tt.logToStderr {
+				t.Errorf("completeOutput() log output to stderr = %v, want %v", o.LogOutput == os.Stderr, tt.logToStderr)
+			}
+		})
+	}
+}
+
+func TestCompleteArtifactTrust(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kubelet"), []byte("kubelet"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := artifact.NewManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := artifact.WriteManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "kubelet"), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options CommonOptions
+		wantErr bool
+	}{
+		{
+			name:    "no trust policy",
+			options: CommonOptions{Artifact: dir},
+		},
+		{
+			name:    "no artifact",
+			options: CommonOptions{Set: []string{"download.artifact_trust.policy=integrity"}},
+		},
+		{
+			name:    "corrupted artifact",
+			options: CommonOptions{Artifact: dir, Set: []string{"download.artifact_trust.policy=integrity"}},
+			wantErr: true,
+		},
+		{
+			name:    "unsigned artifact",
+			options: CommonOptions{Artifact: dir, Set: []string{"download.artifact_trust.keys[0]=" + filepath.Join(dir, "cosign.pub")}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.options
+			o.Config = &kkcorev1.Config{}
+			if err := o.completeConfig(); err != nil {
+				t.Fatal(err)
+			}
+			err := o.completeArtifactTrust()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("completeArtifactTrust() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -21,10 +21,13 @@ import (
 
 	"github.com/cockroachdb/errors"
 	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	kkcorev1alpha1 "github.com/kubesphere/kubekey/api/core/v1alpha1"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
 )
 
 // KubeKeyRunOptions for NewKubeKeyRunOptions
@@ -49,6 +52,12 @@ type KubeKeyRunOptions struct {
 	Tags []string
 	// SkipTags is the tags of playbook which skip execute
 	SkipTags []string
+	// Step confirms each task before running it.
+	Step bool
+	// StartAtTask skips all tasks until the task with this name.
+	StartAtTask string
+	// Debugger is the default debugger mode for tasks. support never, always, on_failed, on_skipped.
+	Debugger string
 }
 
 // NewKubeKeyRunOptions for newRunCommand
@@ -77,6 +86,11 @@ func (o *KubeKeyRunOptions) Flags() cliflag.NamedFlagSets {
 	tfs.StringArrayVar(&o.Tags, "tags", o.Tags, "the tags of playbook which to execute")
 	tfs.StringArrayVar(&o.SkipTags, "skip-tags", o.SkipTags, "the tags of playbook which skip execute")
 
+	dfs := fss.FlagSet("debug")
+	dfs.BoolVar(&o.Step, "step", o.Step, "confirm each task before running it")
+	dfs.StringVar(&o.StartAtTask, "start-at-task", o.StartAtTask, "skip all tasks until the task with this name")
+	dfs.StringVar(&o.Debugger, "debugger", o.Debugger, "the default debugger mode for tasks. support never, always, on_failed, on_skipped")
+
 	return fss
 }
 
@@ -109,6 +123,15 @@ func (o *KubeKeyRunOptions) Complete(cmd *cobra.Command, args []string) (*kkcore
 		SkipTags: o.SkipTags,
 	}
 
+	if !kkcorev1alpha1.IsSupportedTaskDebugger(o.Debugger) {
+		return nil, errors.Errorf("unsupported debugger %q. support never, always, on_failed, on_skipped", o.Debugger)
+	}
+	o.ExecutorOptions = append(o.ExecutorOptions,
+		executor.WithStep(o.Step),
+		executor.WithStartAtTask(o.StartAtTask),
+		executor.WithDebugger(o.Debugger),
+	)
+
 	if o.InventoryFile != "" {
 		data, err := os.ReadFile(o.InventoryFile)
 		if err != nil {
@@ -0,0 +1,321 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package artifact describes the content of KubeKey offline artifacts with a manifest, which lists the digest
+// of every file in the artifact. The manifest makes it possible to compare two artifacts and to ship a delta
+// artifact containing only the files which are not in the base artifact.
+package artifact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+const (
+	// ManifestFile is the name of the manifest at the root of an artifact.
+	ManifestFile = "artifact.json"
+	// ManifestVersion is the version of the manifest format.
+	ManifestVersion = "v1"
+
+	// imageLayoutFile maps the tags of a repository to the digests of its manifests in the local image directory.
+	imageLayoutFile = "layout"
+)
+
+// Manifest lists the files and images of an artifact.
+type Manifest struct {
+	// Version of the manifest format.
+	Version string `json:"version"`
+	// Base is the digest of the base manifest when the artifact is a delta.
+	// A delta artifact only contains the files whose content is not in the base artifact.
+	Base string `json:"base,omitempty"`
+	// Arches are the CPU architectures which the artifact is exported for, sorted by name.
+	Arches []string `json:"arches,omitempty"`
+	// Files are all the files of the artifact, sorted by path.
+	Files []File `json:"files"`
+	// Images are the tagged images in the artifact, sorted by name.
+	Images []Image `json:"images,omitempty"`
+}
+
+// File is a file in the artifact.
+type File struct {
+	// Path is the slash separated path relative to the root of the artifact.
+	Path string `json:"path"`
+	// Digest is the sha256 digest of the file content, such as "sha256:<hex>".
+	Digest string `json:"digest"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+}
+
+// Image is a tagged image in the artifact.
+type Image struct {
+	// Name is the reference of the image, such as "docker.io/calico/node:v3.28.0".
+	Name string `json:"name"`
+	// Digest is the digest of the image manifest or index.
+	Digest string `json:"digest"`
+}
+
+// NewManifest walks the artifact in dir and returns its manifest.
+func NewManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Version: ManifestVersion, Files: make([]File, 0)}
+	if err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFile {
+			return nil
+		}
+		digest, size, err := fileDigest(p)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, File{Path: rel, Digest: digest, Size: size})
+		if path.Base(rel) == imageLayoutFile && strings.HasPrefix(rel, _const.BinaryImagesDir+"/") {
+			images, err := layoutImages(p, strings.TrimPrefix(path.Dir(path.Dir(rel)), _const.BinaryImagesDir+"/"))
+			if err != nil {
+				return err
+			}
+			m.Images = append(m.Images, images...)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to walk artifact dir %q", dir)
+	}
+	// files are walked in lexical order.
+	sort.Slice(m.Images, func(i, j int) bool { return m.Images[i].Name < m.Images[j].Name })
+
+	return m, nil
+}
+
+// SetArches records the CPU architectures which the artifact is exported for.
+func (m *Manifest) SetArches(arches []string) {
+	m.Arches = nil
+	for _, a := range arches {
+		if a != "" && !slices.Contains(m.Arches, a) {
+			m.Arches = append(m.Arches, a)
+		}
+	}
+	sort.Strings(m.Arches)
+}
+
+// layoutImages returns the images of the tags in the layout file of repository.
+func layoutImages(filename, repository string) ([]Image, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read image layout %q", filename)
+	}
+	tags := make(map[string]string)
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal image layout %q", filename)
+	}
+	images := make([]Image, 0, len(tags))
+	for tag, digest := range tags {
+		images = append(images, Image{Name: repository + ":" + tag, Digest: digest})
+	}
+
+	return images, nil
+}
+
+// Digest returns the digest of the manifest, which identifies the content of the artifact.
+// Base is not part of the digest, so a delta applied to its base has the same digest as the full artifact.
+func (m Manifest) Digest() (string, error) {
+	m.Base = ""
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal artifact manifest")
+	}
+	hash := sha256.Sum256(data)
+
+	return "sha256:" + hex.EncodeToString(hash[:]), nil
+}
+
+// digests returns the files of the manifest by digest.
+func (m *Manifest) digests() map[string]File {
+	files := make(map[string]File, len(m.Files))
+	for _, f := range m.Files {
+		files[f.Digest] = f
+	}
+
+	return files
+}
+
+// listed returns the paths of the files in the manifest.
+func (m *Manifest) listed() map[string]bool {
+	listed := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		listed[f.Path] = true
+	}
+
+	return listed
+}
+
+// WriteManifest writes the manifest to the ManifestFile in dir.
+func WriteManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal artifact manifest")
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, _const.PermFilePublic); err != nil {
+		return errors.Wrapf(err, "failed to write artifact manifest in %q", dir)
+	}
+
+	return nil
+}
+
+// ReadManifest reads the manifest of an artifact. The path is an artifact archive (".tgz" or ".tar.gz"),
+// an unpacked artifact directory or a manifest file.
+func ReadManifest(p string) (*Manifest, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat artifact %q", p)
+	}
+	var data []byte
+	switch {
+	case fi.IsDir():
+		data, err = os.ReadFile(filepath.Join(p, ManifestFile))
+	case isArchive(p):
+		data, err = readArchiveManifest(p)
+	default:
+		data, err = os.ReadFile(p)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest of artifact %q", p)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal manifest of artifact %q", p)
+	}
+	if m.Version != ManifestVersion {
+		return nil, errors.Errorf("unsupported manifest version %q of artifact %q", m.Version, p)
+	}
+
+	return m, nil
+}
+
+// readArchiveManifest reads the ManifestFile in the artifact archive.
+func readArchiveManifest(filename string) ([]byte, error) {
+	var data []byte
+	err := walkArchive(filename, func(name string, _ *tar.Header, r io.Reader) error {
+		if name != ManifestFile {
+			return nil
+		}
+		var err error
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		return errStopWalk
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.Errorf("%s not found, the artifact may be exported by an older version", ManifestFile)
+	}
+
+	return data, nil
+}
+
+// errStopWalk stops walkArchive without error.
+var errStopWalk = errors.New("stop walk")
+
+// walkArchive calls fn with the cleaned name of each regular file in the gzip compressed tar archive.
+func walkArchive(filename string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	return walkArchiveEntries(filename, func(name string, hdr *tar.Header, r io.Reader) error {
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		return fn(name, hdr, r)
+	})
+}
+
+// walkArchiveEntries calls fn with the cleaned name of each entry in the gzip compressed tar archive.
+func walkArchiveEntries(filename string, fn func(name string, hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open archive %q", filename)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read gzip archive %q", filename)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read tar archive %q", filename)
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if err := fn(name, hdr, tr); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// isArchive reports whether the file is a gzip compressed tar archive by its extension.
+func isArchive(filename string) bool {
+	return strings.HasSuffix(filename, ".tgz") || strings.HasSuffix(filename, ".tar.gz")
+}
+
+// fileDigest returns the sha256 digest and the size of the file.
+func fileDigest(filename string) (string, int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to open file %q", filename)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to read file %q", filename)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), size, nil
+}
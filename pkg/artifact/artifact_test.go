@@ -0,0 +1,435 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArtifact writes the files to dir.
+func writeArtifact(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+	}
+}
+
+// archiveArtifact packs dir into a gzip compressed tar archive like "tar -czf archive -C dir .".
+func archiveArtifact(t *testing.T, dir, archive string, extra ...tar.Header) {
+	t.Helper()
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+	require.NoError(t, filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + filepath.ToSlash(rel), Mode: 0755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+
+		return err
+	}))
+	for _, hdr := range extra {
+		require.NoError(t, tw.WriteHeader(&hdr))
+	}
+}
+
+// readArtifact reads all the files in dir.
+func readArtifact(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	require.NoError(t, filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		files[filepath.ToSlash(rel)] = string(data)
+
+		return err
+	}))
+
+	return files
+}
+
+var (
+	oldArtifact = map[string]string{
+		"kube/v1.33.0/amd64/kubelet":                    "kubelet-v1.33.0",
+		"helm/v3.18.0/amd64/helm.tar.gz":                "helm-v3.18.0",
+		"cni/calico/tigera-operator-v3.28.0.tgz":        "calico-v3.28.0",
+		"images/blobs/sha256:aaa":                       "layer-a",
+		"images/docker.io/calico/node/manifests/layout": `{"v3.28.0": "sha256:m1"}`,
+	}
+	newArtifact = map[string]string{
+		"kube/v1.33.1/amd64/kubelet":                    "kubelet-v1.33.1",
+		"helm/v3.18.0/amd64/helm.tar.gz":                "helm-v3.18.0",
+		"cni/calico/v3.28.0/tigera-operator.tgz":        "calico-v3.28.0",
+		"images/blobs/sha256:aaa":                       "layer-a",
+		"images/blobs/sha256:bbb":                       "layer-b",
+		"images/docker.io/calico/node/manifests/layout": `{"v3.28.0": "sha256:m2", "v3.29.0": "sha256:m3"}`,
+	}
+)
+
+func TestNewManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeArtifact(t, dir, newArtifact)
+	writeArtifact(t, dir, map[string]string{ManifestFile: "{}"})
+
+	m, err := NewManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ManifestVersion, m.Version)
+	paths := make([]string, 0, len(m.Files))
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{
+		"cni/calico/v3.28.0/tigera-operator.tgz",
+		"helm/v3.18.0/amd64/helm.tar.gz",
+		"images/blobs/sha256:aaa",
+		"images/blobs/sha256:bbb",
+		"images/docker.io/calico/node/manifests/layout",
+		"kube/v1.33.1/amd64/kubelet",
+	}, paths)
+	assert.Equal(t, []Image{
+		{Name: "docker.io/calico/node:v3.28.0", Digest: "sha256:m2"},
+		{Name: "docker.io/calico/node:v3.29.0", Digest: "sha256:m3"},
+	}, m.Images)
+
+	require.NoError(t, WriteManifest(dir, m))
+	archive := filepath.Join(t.TempDir(), "artifact.tgz")
+	archiveArtifact(t, dir, archive)
+	for _, p := range []string{dir, filepath.Join(dir, ManifestFile), archive} {
+		read, err := ReadManifest(p)
+		require.NoError(t, err, p)
+		assert.Equal(t, m, read, p)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeArtifact(t, oldDir, oldArtifact)
+	writeArtifact(t, newDir, newArtifact)
+	oldManifest, err := NewManifest(oldDir)
+	require.NoError(t, err)
+	newManifest, err := NewManifest(newDir)
+	require.NoError(t, err)
+
+	diff := Compare(oldManifest, newManifest)
+	images := make(map[string]string)
+	for _, c := range diff.Images {
+		images[c.Name] = c.Type
+	}
+	assert.Equal(t, map[string]string{
+		"docker.io/calico/node:v3.28.0": ChangeModified,
+		"docker.io/calico/node:v3.29.0": ChangeAdded,
+	}, images)
+	files := make(map[string]string)
+	for _, c := range diff.Files {
+		files[c.Name] = c.Type
+	}
+	assert.Equal(t, map[string]string{
+		"cni/calico/tigera-operator-v3.28.0.tgz":        ChangeRemoved,
+		"cni/calico/v3.28.0/tigera-operator.tgz":        ChangeAdded,
+		"images/blobs/sha256:bbb":                       ChangeAdded,
+		"images/docker.io/calico/node/manifests/layout": ChangeModified,
+		"kube/v1.33.0/amd64/kubelet":                    ChangeRemoved,
+		"kube/v1.33.1/amd64/kubelet":                    ChangeAdded,
+	}, files)
+	// the moved calico chart is not in the delta.
+	assert.Equal(t, int64(len("kubelet-v1.33.1")+len("layer-b")+len(newArtifact["images/docker.io/calico/node/manifests/layout"])), diff.DeltaSize)
+}
+
+func TestPruneAndApply(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeArtifact(t, oldDir, oldArtifact)
+	writeArtifact(t, newDir, newArtifact)
+	oldManifest, err := NewManifest(oldDir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(oldDir, oldManifest))
+	newManifest, err := NewManifest(newDir)
+	require.NoError(t, err)
+
+	// export the delta
+	deltaDir := t.TempDir()
+	writeArtifact(t, deltaDir, newArtifact)
+	delta, err := NewManifest(deltaDir)
+	require.NoError(t, err)
+	require.NoError(t, Prune(deltaDir, delta, oldManifest))
+	oldDigest, err := oldManifest.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, oldDigest, delta.Base)
+	assert.Equal(t, newManifest.Files, delta.Files)
+	deltaFiles := readArtifact(t, deltaDir)
+	delete(deltaFiles, ManifestFile)
+	assert.Equal(t, map[string]string{
+		"kube/v1.33.1/amd64/kubelet":                    "kubelet-v1.33.1",
+		"images/blobs/sha256:bbb":                       "layer-b",
+		"images/docker.io/calico/node/manifests/layout": newArtifact["images/docker.io/calico/node/manifests/layout"],
+	}, deltaFiles)
+	deltaArchive := filepath.Join(t.TempDir(), "delta.tgz")
+	archiveArtifact(t, deltaDir, deltaArchive)
+
+	// apply the delta to a dir which is not the base
+	otherDir := t.TempDir()
+	writeArtifact(t, otherDir, newArtifact)
+	otherManifest, err := NewManifest(otherDir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(otherDir, otherManifest))
+	_, err = Apply(otherDir, deltaArchive)
+	require.ErrorContains(t, err, "is based on artifact")
+
+	// apply the delta to the base, and keep files not in the artifact
+	writeArtifact(t, oldDir, map[string]string{"pki/root.crt": "root"})
+	applied, err := Apply(oldDir, deltaArchive)
+	require.NoError(t, err)
+	assert.Empty(t, applied.Base)
+	expect := readArtifact(t, newDir)
+	expect["pki/root.crt"] = "root"
+	actual := readArtifact(t, oldDir)
+	var written Manifest
+	require.NoError(t, json.Unmarshal([]byte(actual[ManifestFile]), &written))
+	delete(actual, ManifestFile)
+	assert.Equal(t, expect, actual)
+	appliedDigest, err := written.Digest()
+	require.NoError(t, err)
+	newDigest, err := newManifest.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, newDigest, appliedDigest)
+	assert.NoDirExists(t, filepath.Join(oldDir, "kube", "v1.33.0"))
+	// the staging dir is removed after the swap.
+	staging, err := filepath.Glob(filepath.Join(filepath.Dir(oldDir), ".artifact-apply-*"))
+	require.NoError(t, err)
+	assert.Empty(t, staging)
+}
+
+func TestApplyCorruptedDelta(t *testing.T) {
+	oldDir, deltaDir := t.TempDir(), t.TempDir()
+	writeArtifact(t, oldDir, oldArtifact)
+	oldManifest, err := NewManifest(oldDir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(oldDir, oldManifest))
+	writeArtifact(t, deltaDir, newArtifact)
+	delta, err := NewManifest(deltaDir)
+	require.NoError(t, err)
+	require.NoError(t, Prune(deltaDir, delta, oldManifest))
+	writeArtifact(t, deltaDir, map[string]string{"kube/v1.33.1/amd64/kubelet": "tampered"})
+	deltaArchive := filepath.Join(t.TempDir(), "delta.tgz")
+	archiveArtifact(t, deltaDir, deltaArchive)
+
+	_, err = Apply(oldDir, deltaArchive)
+	require.ErrorContains(t, err, "in manifest")
+	// the artifact is not changed
+	assert.Equal(t, "kubelet-v1.33.0", readArtifact(t, oldDir)["kube/v1.33.0/amd64/kubelet"])
+}
+
+func TestVerify(t *testing.T) {
+	testcases := []struct {
+		name   string
+		modify func(t *testing.T, dir string)
+		errMsg string
+	}{
+		{
+			name:   "intact",
+			modify: func(*testing.T, string) {},
+		},
+		{
+			name: "modified file",
+			modify: func(t *testing.T, dir string) {
+				writeArtifact(t, dir, map[string]string{"kube/v1.33.1/amd64/kubelet": "tampered"})
+			},
+			errMsg: "in manifest",
+		},
+		{
+			name: "missing file",
+			modify: func(t *testing.T, dir string) {
+				require.NoError(t, os.Remove(filepath.Join(dir, "helm/v3.18.0/amd64/helm.tar.gz")))
+			},
+			errMsg: "is missing",
+		},
+		{
+			name: "unlisted file",
+			modify: func(t *testing.T, dir string) {
+				writeArtifact(t, dir, map[string]string{"kube/v1.33.1/amd64/kubeadm": "kubeadm-v1.33.1"})
+			},
+			errMsg: "is not in the manifest",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeArtifact(t, dir, newArtifact)
+			m, err := NewManifest(dir)
+			require.NoError(t, err)
+			require.NoError(t, WriteManifest(dir, m))
+			tc.modify(t, dir)
+			archive := filepath.Join(t.TempDir(), "artifact.tgz")
+			archiveArtifact(t, dir, archive)
+
+			for _, p := range []string{dir, archive} {
+				_, err := Verify(p)
+				if tc.errMsg != "" {
+					require.ErrorContains(t, err, tc.errMsg, p)
+				} else {
+					require.NoError(t, err, p)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyArchiveEntries(t *testing.T) {
+	testcases := []struct {
+		name   string
+		extra  tar.Header
+		errMsg string
+	}{
+		{
+			name:  "dir",
+			extra: tar.Header{Name: "./kube/", Mode: 0755, Typeflag: tar.TypeDir},
+		},
+		{
+			name:   "symlink",
+			extra:  tar.Header{Name: "./kube/v1.33.1/amd64/kubeadm", Linkname: "/usr/bin/kubeadm", Typeflag: tar.TypeSymlink},
+			errMsg: "is not a regular file",
+		},
+		{
+			name:   "hardlink",
+			extra:  tar.Header{Name: "./kube/v1.33.1/amd64/kubeadm", Linkname: "kube/v1.33.1/amd64/kubelet", Typeflag: tar.TypeLink},
+			errMsg: "is not a regular file",
+		},
+		{
+			name:   "duplicate file",
+			extra:  tar.Header{Name: "kube/v1.33.1/amd64/kubelet", Mode: 0755, Typeflag: tar.TypeReg},
+			errMsg: "occurs more than once",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeArtifact(t, dir, newArtifact)
+			m, err := NewManifest(dir)
+			require.NoError(t, err)
+			require.NoError(t, WriteManifest(dir, m))
+			archive := filepath.Join(t.TempDir(), "artifact.tgz")
+			archiveArtifact(t, dir, archive, tc.extra)
+
+			_, err = Verify(archive)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyDirSymlink(t *testing.T) {
+	dir := t.TempDir()
+	writeArtifact(t, dir, newArtifact)
+	m, err := NewManifest(dir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(dir, m))
+	require.NoError(t, os.Symlink("/usr/bin/kubeadm", filepath.Join(dir, "kube/v1.33.1/amd64/kubeadm")))
+
+	_, err = Verify(dir)
+	require.ErrorContains(t, err, "is not a regular file")
+}
+
+func TestVerifyDelta(t *testing.T) {
+	oldDir, deltaDir := t.TempDir(), t.TempDir()
+	writeArtifact(t, oldDir, oldArtifact)
+	oldManifest, err := NewManifest(oldDir)
+	require.NoError(t, err)
+	writeArtifact(t, deltaDir, newArtifact)
+	delta, err := NewManifest(deltaDir)
+	require.NoError(t, err)
+	require.NoError(t, Prune(deltaDir, delta, oldManifest))
+	archive := filepath.Join(t.TempDir(), "delta.tgz")
+	archiveArtifact(t, deltaDir, archive)
+
+	_, err = Verify(archive)
+	require.NoError(t, err)
+
+	// files which are not in the manifest
+	writeArtifact(t, deltaDir, map[string]string{"extra": "extra"})
+	archiveArtifact(t, deltaDir, archive)
+	_, err = Verify(archive)
+	require.ErrorContains(t, err, "is not in the manifest")
+}
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	writeArtifact(t, dir, newArtifact)
+	writeArtifact(t, dir, map[string]string{
+		"kube/v1.33.1/arm64/kubelet":           "kubelet-v1.33.1-arm64",
+		"kube/v1.32.5/amd64/kubelet":           "kubelet-v1.32.5",
+		"repository/ubuntu-22.04-debs-amd.iso": "iso",
+	})
+	m, err := NewManifest(dir)
+	require.NoError(t, err)
+
+	s, err := Inspect(m)
+	require.NoError(t, err)
+	digest, err := m.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest, s.Digest)
+	assert.Equal(t, []string{"v1.32.5", "v1.33.1"}, s.KubeVersions)
+	assert.Equal(t, []string{"amd64", "arm64"}, s.Arches)
+	names := make([]string, 0, len(s.Binaries))
+	for _, b := range s.Binaries {
+		names = append(names, b.Name+"/"+b.Version+"/"+b.Arch)
+	}
+	assert.Equal(t, []string{"helm/v3.18.0/amd64", "kube/v1.32.5/amd64", "kube/v1.33.1/amd64", "kube/v1.33.1/arm64"}, names)
+	assert.Equal(t, m.Images, s.Images)
+	assert.Equal(t, len(m.Files), s.Files)
+
+	// the arches recorded in the manifest
+	m.SetArches([]string{"arm64"})
+	s, err = Inspect(m)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm64"}, s.Arches)
+}
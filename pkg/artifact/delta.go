@@ -0,0 +1,257 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// Prune turns the artifact in dir, whose manifest is m, into a delta of the base artifact. It sets the digest of base
+// to m and writes it as the manifest of the whole artifact, and removes the files whose content is already in the base artifact.
+func Prune(dir string, m, base *Manifest) error {
+	var err error
+	if m.Base, err = base.Digest(); err != nil {
+		return err
+	}
+	digests := base.digests()
+	for _, f := range m.Files {
+		if _, ok := digests[f.Digest]; !ok {
+			continue
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.Remove(filename); err != nil {
+			return errors.Wrapf(err, "failed to remove file %q", filename)
+		}
+		removeEmptyParents(dir, filename)
+	}
+
+	return WriteManifest(dir, m)
+}
+
+// Apply merges the delta artifact archive into the unpacked artifact in dir, which must be the base of the delta.
+// The files of the delta are verified against its manifest, and the files which are not in the new artifact are removed.
+// Files in dir which are not listed in the manifest of dir are kept.
+// A whole artifact archive can be applied too, then the content already in dir is not extracted again.
+// The new artifact is staged with its manifest in a sibling dir of dir, and swapped with dir at the end,
+// so dir is not changed when any file fails to apply.
+func Apply(dir, archive string) (*Manifest, error) {
+	dir = filepath.Clean(dir)
+	current, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ReadManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+	if m.Base != "" {
+		digest, err := current.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if digest != m.Base {
+			return nil, errors.Errorf("the delta %q is based on artifact %s, but the artifact in %q is %s", archive, m.Base, dir, digest)
+		}
+	}
+	// staging dir is in the same filesystem with dir, so that it can be renamed to dir.
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".artifact-apply-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create staging dir")
+	}
+	defer os.RemoveAll(staging)
+
+	files := make(map[string]File, len(m.Files))
+	for _, f := range m.Files {
+		files[f.Path] = f
+	}
+	staged := make(map[string]bool)
+	// extract the files of the archive
+	if err := walkArchive(archive, func(name string, hdr *tar.Header, r io.Reader) error {
+		if name == ManifestFile {
+			return nil
+		}
+		f, ok := files[name]
+		if !ok {
+			return errors.Errorf("file %q is not in the manifest of %q", name, archive)
+		}
+		if err := extractFile(filepath.Join(staging, filepath.FromSlash(name)), hdr.FileInfo().Mode().Perm(), r, f.Digest); err != nil {
+			return err
+		}
+		staged[name] = true
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	// stage the other files of the new artifact from dir
+	currentDigests := current.digests()
+	for _, f := range m.Files {
+		if staged[f.Path] {
+			continue
+		}
+		src, ok := currentDigests[f.Digest]
+		if !ok {
+			return nil, errors.Errorf("content %s of file %q is neither in %q nor in %q", f.Digest, f.Path, archive, dir)
+		}
+		if err := linkFile(filepath.Join(dir, filepath.FromSlash(src.Path)), filepath.Join(staging, filepath.FromSlash(f.Path))); err != nil {
+			return nil, err
+		}
+		staged[f.Path] = true
+	}
+	// stage the files in dir which are not listed in the manifest of dir
+	if err := stageUnlisted(dir, staging, current, staged); err != nil {
+		return nil, err
+	}
+	m.Base = ""
+	if err := WriteManifest(staging, m); err != nil {
+		return nil, err
+	}
+
+	return m, swapDir(dir, staging)
+}
+
+// stageUnlisted links the files in dir, which are neither listed in the manifest m of dir nor staged, into staging.
+// Empty dirs are not kept.
+func stageUnlisted(dir, staging string, m *Manifest, staged map[string]bool) error {
+	listed := make(map[string]bool, len(m.Files)+1)
+	listed[ManifestFile] = true
+	for _, f := range m.Files {
+		listed[f.Path] = true
+	}
+
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk dir %q", dir)
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get relative path of %q", p)
+		}
+		name := filepath.ToSlash(rel)
+		target := filepath.Join(staging, rel)
+		switch {
+		case d.IsDir() || listed[name] || staged[name]:
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read link %q", p)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), _const.PermDirPublic); err != nil {
+				return errors.Wrapf(err, "failed to create dir %q", filepath.Dir(target))
+			}
+
+			return errors.Wrapf(os.Symlink(link, target), "failed to create link %q", target)
+		default:
+			return linkFile(p, target)
+		}
+	})
+}
+
+// swapDir replaces dir with staging. dir is moved aside first, and is moved back when staging fails to be renamed.
+func swapDir(dir, staging string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat dir %q", dir)
+	}
+	if err := os.Chmod(staging, fi.Mode().Perm()); err != nil {
+		return errors.Wrapf(err, "failed to chmod dir %q", staging)
+	}
+	old := staging + ".old"
+	if err := os.Rename(dir, old); err != nil {
+		return errors.Wrapf(err, "failed to move dir %q", dir)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		if rerr := os.Rename(old, dir); rerr != nil {
+			return errors.Wrapf(err, "failed to move dir %q to %q, and the old artifact is kept in %q", staging, dir, old)
+		}
+
+		return errors.Wrapf(err, "failed to move dir %q to %q", staging, dir)
+	}
+
+	return errors.Wrapf(os.RemoveAll(old), "failed to remove old artifact %q", old)
+}
+
+// extractFile writes r to filename, and verifies its digest.
+func extractFile(filename string, mode os.FileMode, r io.Reader, digest string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), _const.PermDirPublic); err != nil {
+		return errors.Wrapf(err, "failed to create dir %q", filepath.Dir(filename))
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", filename)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), r); err != nil {
+		return errors.Wrapf(err, "failed to write file %q", filename)
+	}
+	if actual := "sha256:" + hex.EncodeToString(hash.Sum(nil)); actual != digest {
+		return errors.Errorf("digest of file %q is %s, but %s in manifest", filename, actual, digest)
+	}
+
+	return nil
+}
+
+// linkFile hard links src to dst, and copies src when the link fails.
+func linkFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), _const.PermDirPublic); err != nil {
+		return errors.Wrapf(err, "failed to create dir %q", filepath.Dir(dst))
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %q", src)
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat file %q", src)
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %q", dst)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return errors.Wrapf(err, "failed to copy file %q to %q", src, dst)
+	}
+
+	return nil
+}
+
+// removeEmptyParents removes the empty parent dirs of filename up to root.
+func removeEmptyParents(root, filename string) {
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(filename); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
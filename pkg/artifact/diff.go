@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"sort"
+)
+
+const (
+	// ChangeAdded means the file or image is only in the new artifact.
+	ChangeAdded = "added"
+	// ChangeRemoved means the file or image is only in the old artifact.
+	ChangeRemoved = "removed"
+	// ChangeModified means the file or image has a different digest in the new artifact.
+	ChangeModified = "modified"
+)
+
+// Change is a file or image which differs between two artifacts.
+type Change struct {
+	// Name is the path of the file or the name of the image.
+	Name string `json:"name"`
+	// Type is one of ChangeAdded, ChangeRemoved and ChangeModified.
+	Type string `json:"type"`
+	// Old is the digest in the old artifact.
+	Old string `json:"old,omitempty"`
+	// New is the digest in the new artifact.
+	New string `json:"new,omitempty"`
+	// Size is the size of the file in the new artifact, or in the old artifact when it is removed.
+	Size int64 `json:"size,omitempty"`
+}
+
+// Diff is the difference between two artifacts.
+type Diff struct {
+	// Images are the changed images.
+	Images []Change `json:"images"`
+	// Files are the changed files, including the blobs of images.
+	Files []Change `json:"files"`
+	// DeltaSize is the uncompressed size of the delta from the old artifact to the new one,
+	// which contains the files whose content is not in the old artifact.
+	DeltaSize int64 `json:"deltaSize"`
+}
+
+// Compare returns the difference from the old artifact to the new one.
+func Compare(oldManifest, newManifest *Manifest) Diff {
+	diff := Diff{Images: make([]Change, 0), Files: make([]Change, 0)}
+	oldImages := make(map[string]string, len(oldManifest.Images))
+	for _, img := range oldManifest.Images {
+		oldImages[img.Name] = img.Digest
+	}
+	for _, img := range newManifest.Images {
+		if digest, ok := oldImages[img.Name]; !ok {
+			diff.Images = append(diff.Images, Change{Name: img.Name, Type: ChangeAdded, New: img.Digest})
+		} else if digest != img.Digest {
+			diff.Images = append(diff.Images, Change{Name: img.Name, Type: ChangeModified, Old: digest, New: img.Digest})
+		}
+		delete(oldImages, img.Name)
+	}
+	for name, digest := range oldImages {
+		diff.Images = append(diff.Images, Change{Name: name, Type: ChangeRemoved, Old: digest})
+	}
+
+	oldFiles := make(map[string]File, len(oldManifest.Files))
+	for _, f := range oldManifest.Files {
+		oldFiles[f.Path] = f
+	}
+	oldDigests := oldManifest.digests()
+	for _, f := range newManifest.Files {
+		if old, ok := oldFiles[f.Path]; !ok {
+			diff.Files = append(diff.Files, Change{Name: f.Path, Type: ChangeAdded, New: f.Digest, Size: f.Size})
+		} else if old.Digest != f.Digest {
+			diff.Files = append(diff.Files, Change{Name: f.Path, Type: ChangeModified, Old: old.Digest, New: f.Digest, Size: f.Size})
+		}
+		delete(oldFiles, f.Path)
+		if _, ok := oldDigests[f.Digest]; !ok {
+			diff.DeltaSize += f.Size
+			// the same content is shipped once.
+			oldDigests[f.Digest] = f
+		}
+	}
+	for _, f := range oldFiles {
+		diff.Files = append(diff.Files, Change{Name: f.Path, Type: ChangeRemoved, Old: f.Digest, Size: f.Size})
+	}
+	sort.Slice(diff.Images, func(i, j int) bool { return diff.Images[i].Name < diff.Images[j].Name })
+	sort.Slice(diff.Files, func(i, j int) bool { return diff.Files[i].Name < diff.Files[j].Name })
+
+	return diff
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"sort"
+	"strings"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// kubeBinary is the name of the kubernetes binaries (kubelet, kubeadm and kubectl) in the artifact.
+const kubeBinary = "kube"
+
+// knownArches are the CPU architectures of the binaries in the artifact.
+var knownArches = map[string]bool{
+	"amd64": true, "arm64": true, "arm": true, "386": true,
+	"ppc64le": true, "s390x": true, "riscv64": true, "loong64": true,
+}
+
+// Summary describes what is inside an artifact.
+type Summary struct {
+	// Digest of the manifest.
+	Digest string `json:"digest"`
+	// Base is the digest of the base manifest when the artifact is a delta.
+	Base string `json:"base,omitempty"`
+	// KubeVersions are the kubernetes versions of the binaries in the artifact.
+	KubeVersions []string `json:"kubeVersions"`
+	// Arches are the CPU architectures recorded in the manifest, or of the binaries in the artifact
+	// when the manifest does not record them.
+	Arches []string `json:"arches"`
+	// Binaries are the files stored as "<name>/<version>/<arch>/<file>".
+	Binaries []Binary `json:"binaries"`
+	// Images are the tagged images in the artifact.
+	Images []Image `json:"images"`
+	// Files is the number of files in the artifact.
+	Files int `json:"files"`
+	// Size is the total size of the files in bytes.
+	Size int64 `json:"size"`
+}
+
+// Binary is a versioned file of a component in the artifact.
+type Binary struct {
+	// Name of the component, such as "kube" or "cni/plugins".
+	Name string `json:"name"`
+	// Version of the component.
+	Version string `json:"version"`
+	// Arch is the CPU architecture of the file.
+	Arch string `json:"arch"`
+	// Path of the file in the artifact.
+	Path string `json:"path"`
+	// Digest of the file content.
+	Digest string `json:"digest"`
+}
+
+// Inspect summarizes the manifest of an artifact.
+func Inspect(m *Manifest) (*Summary, error) {
+	digest, err := m.Digest()
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{
+		Digest:       digest,
+		Base:         m.Base,
+		KubeVersions: make([]string, 0),
+		Arches:       make([]string, 0),
+		Binaries:     make([]Binary, 0),
+		Images:       m.Images,
+		Files:        len(m.Files),
+	}
+	if s.Images == nil {
+		s.Images = make([]Image, 0)
+	}
+	versions, arches := make(map[string]bool), make(map[string]bool)
+	for _, f := range m.Files {
+		s.Size += f.Size
+		b, ok := parseBinary(f)
+		if !ok {
+			continue
+		}
+		s.Binaries = append(s.Binaries, b)
+		arches[b.Arch] = true
+		if b.Name == kubeBinary {
+			versions[b.Version] = true
+		}
+	}
+	for v := range versions {
+		s.KubeVersions = append(s.KubeVersions, v)
+	}
+	sort.Strings(s.KubeVersions)
+	if len(m.Arches) > 0 {
+		s.Arches = m.Arches
+	} else {
+		for a := range arches {
+			s.Arches = append(s.Arches, a)
+		}
+		sort.Strings(s.Arches)
+	}
+
+	return s, nil
+}
+
+// parseBinary parses the file stored as "<name>/<version>/<arch>/<file>".
+func parseBinary(f File) (Binary, bool) {
+	if strings.HasPrefix(f.Path, _const.BinaryImagesDir+"/") {
+		return Binary{}, false
+	}
+	parts := strings.Split(f.Path, "/")
+	for i := len(parts) - 2; i >= 2; i-- {
+		if knownArches[parts[i]] {
+			return Binary{
+				Name:    strings.Join(parts[:i-1], "/"),
+				Version: parts[i-1],
+				Arch:    parts[i],
+				Path:    f.Path,
+				Digest:  f.Digest,
+			}, true
+		}
+	}
+
+	return Binary{}, false
+}
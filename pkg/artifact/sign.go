@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// SignatureSuffix is appended to the artifact path to get its detached signature.
+const SignatureSuffix = ".sig"
+
+const (
+	// TrustPolicyNone accepts any artifact.
+	TrustPolicyNone = ""
+	// TrustPolicyIntegrity refuses artifacts whose files do not match the embedded manifest.
+	TrustPolicyIntegrity = "integrity"
+	// TrustPolicySigned refuses corrupted artifacts and artifacts without a valid signature of the trusted keys.
+	TrustPolicySigned = "signed"
+)
+
+// TrustPolicy decides whether an artifact is trusted.
+type TrustPolicy struct {
+	// Policy is one of TrustPolicyNone, TrustPolicyIntegrity and TrustPolicySigned.
+	// It defaults to TrustPolicySigned when Keys is not empty.
+	Policy string `json:"policy,omitempty"`
+	// Keys are the PEM encoded public key files which are trusted to sign artifacts.
+	Keys []string `json:"keys,omitempty"`
+	// Signature is the detached signature file. It defaults to the artifact path with SignatureSuffix.
+	Signature string `json:"signature,omitempty"`
+}
+
+// Check verifies the artifact by the policy.
+func (t TrustPolicy) Check(p string) error {
+	policy := t.Policy
+	if policy == TrustPolicyNone && len(t.Keys) > 0 {
+		policy = TrustPolicySigned
+	}
+	switch policy {
+	case TrustPolicyNone:
+		return nil
+	case TrustPolicyIntegrity:
+		_, err := Verify(p)
+
+		return err
+	case TrustPolicySigned:
+		if len(t.Keys) == 0 {
+			return errors.Errorf("no keys to verify the signature of artifact %q", p)
+		}
+		m, err := Verify(p)
+		if err != nil {
+			return err
+		}
+		keys := make([]crypto.PublicKey, 0, len(t.Keys))
+		for _, k := range t.Keys {
+			key, err := LoadPublicKey(k)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		signature := t.Signature
+		if signature == "" {
+			signature = SignaturePath(p)
+		}
+
+		return VerifySignature(m, signature, keys...)
+	default:
+		return errors.Errorf("unsupported trust policy %q, should be %q or %q", t.Policy, TrustPolicyIntegrity, TrustPolicySigned)
+	}
+}
+
+// SignaturePath returns the default detached signature file of the artifact.
+func SignaturePath(p string) string {
+	return filepath.Clean(p) + SignatureSuffix
+}
+
+// Sign verifies the integrity of the artifact, signs the digest of its manifest with the PEM encoded private key,
+// and writes the base64 encoded signature to the signature file.
+func Sign(p, keyFile, signature string) error {
+	m, err := Verify(p)
+	if err != nil {
+		return err
+	}
+	signer, err := loadPrivateKey(keyFile)
+	if err != nil {
+		return err
+	}
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+	var sig []byte
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		sig, err = signer.Sign(rand.Reader, []byte(digest), crypto.Hash(0))
+	} else {
+		hash := sha256.Sum256([]byte(digest))
+		sig, err = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign artifact %q", p)
+	}
+	if err := os.WriteFile(signature, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), _const.PermFilePublic); err != nil {
+		return errors.Wrapf(err, "failed to write signature %q", signature)
+	}
+
+	return nil
+}
+
+// VerifySignature verifies the detached signature of the manifest digest with the public keys.
+// It succeeds when any of the keys verifies the signature.
+func VerifySignature(m *Manifest, signature string, keys ...crypto.PublicKey) error {
+	data, err := os.ReadFile(signature)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read signature %q, the artifact may be unsigned", signature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode signature %q", signature)
+	}
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+	message := []byte(digest)
+	hash := sha256.Sum256(message)
+	for _, key := range keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, hash[:], sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig) == nil {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, message, sig) {
+				return nil
+			}
+		}
+	}
+
+	return errors.Errorf("signature %q is not signed by the trusted keys", signature)
+}
+
+// LoadPublicKey reads the PEM encoded PKIX public key from file.
+func LoadPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse public key %q", file)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.Errorf("unsupported public key type %T in %q", key, file)
+	}
+}
+
+// loadPrivateKey reads the PEM encoded PKCS#8, EC or PKCS#1 private key from file.
+func loadPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	var key any
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse private key %q", file)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type %T in %q", key, file)
+	}
+
+	return signer, nil
+}
+
+// readPEM reads the first PEM block in file.
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key %q", file)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data in key %q", file)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes the PEM encoded PKCS#8 private key and PKIX public key of the signer to dir.
+func writeKeyPair(t *testing.T, dir, name string, signer crypto.Signer) (string, string) {
+	t.Helper()
+	priv, err := x509.MarshalPKCS8PrivateKey(signer)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(signer.Public())
+	require.NoError(t, err)
+	privFile, pubFile := filepath.Join(dir, name+".key"), filepath.Join(dir, name+".pub")
+	require.NoError(t, os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0600))
+	require.NoError(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0644))
+
+	return privFile, pubFile
+}
+
+func TestSignAndVerifySignature(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name   string
+		signer crypto.Signer
+	}{
+		{name: "ecdsa", signer: ecKey},
+		{name: "rsa", signer: rsaKey},
+		{name: "ed25519", signer: edKey},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, keyDir := t.TempDir(), t.TempDir()
+			writeArtifact(t, dir, newArtifact)
+			m, err := NewManifest(dir)
+			require.NoError(t, err)
+			require.NoError(t, WriteManifest(dir, m))
+			archive := filepath.Join(t.TempDir(), "artifact.tgz")
+			archiveArtifact(t, dir, archive)
+			privFile, pubFile := writeKeyPair(t, keyDir, tc.name, tc.signer)
+			_, otherPubFile := writeKeyPair(t, keyDir, "other", otherKey)
+
+			require.NoError(t, Sign(archive, privFile, SignaturePath(archive)))
+			require.NoError(t, TrustPolicy{Keys: []string{otherPubFile, pubFile}}.Check(archive))
+			require.ErrorContains(t, TrustPolicy{Keys: []string{otherPubFile}}.Check(archive), "not signed by the trusted keys")
+
+			// the signature of the archive is the signature of the unpacked artifact
+			require.NoError(t, TrustPolicy{Keys: []string{pubFile}, Signature: SignaturePath(archive)}.Check(dir))
+		})
+	}
+}
+
+func TestTrustPolicyCheck(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, pubFile := writeKeyPair(t, t.TempDir(), "cosign", key)
+
+	dir := t.TempDir()
+	writeArtifact(t, dir, newArtifact)
+	m, err := NewManifest(dir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(dir, m))
+	writeArtifact(t, dir, map[string]string{"kube/v1.33.1/amd64/kubelet": "tampered"})
+
+	testcases := []struct {
+		name   string
+		policy TrustPolicy
+		errMsg string
+	}{
+		{
+			name:   "none",
+			policy: TrustPolicy{},
+		},
+		{
+			name:   "integrity",
+			policy: TrustPolicy{Policy: TrustPolicyIntegrity},
+			errMsg: "in manifest",
+		},
+		{
+			name:   "signed without keys",
+			policy: TrustPolicy{Policy: TrustPolicySigned},
+			errMsg: "no keys",
+		},
+		{
+			name:   "keys default to signed",
+			policy: TrustPolicy{Keys: []string{pubFile}},
+			errMsg: "in manifest",
+		},
+		{
+			name:   "unsupported",
+			policy: TrustPolicy{Policy: "unknown"},
+			errMsg: "unsupported trust policy",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(dir)
+			if tc.errMsg != "" {
+				assert.ErrorContains(t, err, tc.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyUnsignedArtifact(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, pubFile := writeKeyPair(t, t.TempDir(), "cosign", key)
+	dir := t.TempDir()
+	writeArtifact(t, dir, newArtifact)
+	m, err := NewManifest(dir)
+	require.NoError(t, err)
+	require.NoError(t, WriteManifest(dir, m))
+
+	require.ErrorContains(t, TrustPolicy{Keys: []string{pubFile}}.Check(dir), "may be unsigned")
+}
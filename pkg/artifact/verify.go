@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Verify checks the integrity of the artifact against its embedded manifest, and returns the manifest.
+// The artifact is an artifact archive or an unpacked artifact directory.
+// Each file listed in the manifest must exist with the same digest, except the files of a delta artifact,
+// which are in its base artifact. The artifact must not contain files which are not listed in the manifest,
+// or entries which are neither regular files nor directories, such as symlinks.
+func Verify(p string) (*Manifest, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat artifact %q", p)
+	}
+	m, err := ReadManifest(p)
+	if err != nil {
+		return nil, err
+	}
+	var actual map[string]File
+	switch {
+	case fi.IsDir():
+		if actual, err = dirFiles(p, m); err != nil {
+			return nil, err
+		}
+	case isArchive(p):
+		if actual, err = archiveFiles(p, m); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("the integrity of manifest file %q can not be verified, use the artifact archive or dir", p)
+	}
+	for _, f := range m.Files {
+		a, ok := actual[f.Path]
+		if !ok {
+			if m.Base != "" {
+				continue
+			}
+
+			return nil, errors.Errorf("file %q of artifact %q is missing", f.Path, p)
+		}
+		if a.Digest != f.Digest {
+			return nil, errors.Errorf("digest of file %q in artifact %q is %s, but %s in manifest", f.Path, p, a.Digest, f.Digest)
+		}
+	}
+
+	return m, nil
+}
+
+// dirFiles returns the files in the artifact dir by path.
+// It fails on the files which are not listed in the manifest, and on the entries which are neither regular files nor dirs.
+func dirFiles(dir string, m *Manifest) (map[string]File, error) {
+	listed := m.listed()
+	files := make(map[string]File, len(m.Files))
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "failed to walk artifact dir %q", dir)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get relative path of %q", p)
+		}
+		name := filepath.ToSlash(rel)
+		switch {
+		case name == ManifestFile:
+			return nil
+		case !d.Type().IsRegular():
+			return errors.Errorf("%q in artifact %q is not a regular file", name, dir)
+		case !listed[name]:
+			return errors.Errorf("file %q is not in the manifest of %q", name, dir)
+		}
+		digest, size, err := fileDigest(p)
+		if err != nil {
+			return err
+		}
+		files[name] = File{Path: name, Digest: digest, Size: size}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// archiveFiles returns the files in the artifact archive by path.
+// It fails on the files which are not listed in the manifest, on the files which occur more than once,
+// and on the entries which are neither regular files nor dirs.
+func archiveFiles(archive string, m *Manifest) (map[string]File, error) {
+	listed := m.listed()
+	files := make(map[string]File, len(m.Files))
+	seen := make(map[string]bool, len(m.Files)+1)
+	err := walkArchiveEntries(archive, func(name string, hdr *tar.Header, r io.Reader) error {
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			return nil
+		case hdr.Typeflag != tar.TypeReg:
+			return errors.Errorf("%q in archive %q is not a regular file", name, archive)
+		case seen[name]:
+			return errors.Errorf("file %q occurs more than once in archive %q", name, archive)
+		}
+		seen[name] = true
+		if name == ManifestFile {
+			return nil
+		}
+		if !listed[name] {
+			return errors.Errorf("file %q is not in the manifest of %q", name, archive)
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read file %q in archive %q", name, archive)
+		}
+		files[name] = File{Path: name, Digest: "sha256:" + hex.EncodeToString(hash.Sum(nil)), Size: size}
+
+		return nil
+	})
+
+	return files, err
+}
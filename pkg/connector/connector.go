@@ -89,7 +89,7 @@ func NewConnector(host string, v variable.Variable) (Connector, error) {
 	connectedType, _ := variable.StringVar(nil, vd, _const.VariableConnector, _const.VariableConnectorType)
 	switch connectedType {
 	case connectedLocal:
-		return newLocalConnector(wd, vd), nil
+		return newLocalConnector(wd, host, vd), nil
 	case connectedSSH:
 		return newSSHConnector(wd, host, vd), nil
 	case connectedKubernetes:
@@ -105,7 +105,7 @@ func NewConnector(host string, v variable.Variable) (Connector, error) {
 			hostParam = host
 		}
 		if host == _const.VariableLocalHost || host == localHost || isLocalIP(hostParam) {
-			return newLocalConnector(wd, vd), nil
+			return newLocalConnector(wd, host, vd), nil
 		}
 
 		return newSSHConnector(wd, host, vd), nil
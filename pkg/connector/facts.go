@@ -0,0 +1,720 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/klog/v2"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// fact subsets which can be selected by gather_subset. The os facts are always gathered.
+const (
+	// FactSubsetAll selects all fact subsets.
+	FactSubsetAll = "all"
+	// FactSubsetMin selects only the os facts.
+	FactSubsetMin = "min"
+	// FactSubsetHardware gathers cpu, memory and block devices.
+	FactSubsetHardware = "hardware"
+	// FactSubsetGPU gathers gpu devices.
+	FactSubsetGPU = "gpu"
+	// FactSubsetNetwork gathers network interfaces and default routes.
+	FactSubsetNetwork = "network"
+	// FactSubsetMounts gathers mounted filesystems and swap.
+	FactSubsetMounts = "mounts"
+	// FactSubsetCgroup gathers cgroup version and controllers.
+	FactSubsetCgroup = "cgroup"
+	// FactSubsetSELinux gathers SELinux status.
+	FactSubsetSELinux = "selinux"
+	// FactSubsetAppArmor gathers AppArmor status.
+	FactSubsetAppArmor = "apparmor"
+	// FactSubsetServiceMgr gathers the init system.
+	FactSubsetServiceMgr = "service_mgr"
+	// FactSubsetPkgMgr gathers the package manager.
+	FactSubsetPkgMgr = "pkg_mgr"
+	// FactSubsetTimeSync gathers time synchronization status.
+	FactSubsetTimeSync = "time_sync"
+)
+
+// commands and files to gather facts. They are also run by the facts script.
+const (
+	factCmdOSType        = "uname -s"
+	factCmdKernelVersion = "uname -r"
+	factCmdHostname      = "hostname"
+	factCmdArch          = "arch"
+	factCmdLsblk         = "lsblk -J -b -o NAME,SIZE,TYPE,MOUNTPOINT,FSTYPE,MODEL"
+	factCmdLvs           = "lvs --reportformat json -o lv_name,vg_name,lv_path,lv_dm_path 2>/dev/null"
+	factCmdLspci         = "lspci -mm -nn"
+	factCmdIPLink        = "ip -o link show"
+	factCmdIPAddr        = "ip -o addr show"
+	factCmdIPv4Route     = "ip -4 route show default"
+	factCmdIPv6Route     = "ip -6 route show default"
+	factCmdDF            = "df -P -B1"
+	factCmdCgroupFS      = "stat -fc %T /sys/fs/cgroup"
+	factCmdSELinux       = "command -v getenforce >/dev/null 2>&1 && getenforce || true"
+	factCmdServiceMgr    = "cat /proc/1/comm; command -v openrc openrc-init initctl 2>/dev/null || true"
+	factCmdPkgMgr        = "command -v dnf yum apt-get zypper apk pacman 2>/dev/null || true"
+	// factCmdTimeSync prints the synchronized status and the active services of chronyd, chrony, ntpd, ntp and systemd-timesyncd in the order of priority.
+	factCmdTimeSync = `echo "synchronized=$(timedatectl show -p NTPSynchronized --value 2>/dev/null || timedatectl status 2>/dev/null | awk -F': *' '/synchronized/{print $2}')"; ` +
+		`for s in chronyd chrony ntpd ntp systemd-timesyncd; do if systemctl is-active --quiet "$s" 2>/dev/null; then echo "service=$s"; fi; done`
+
+	factFileOSRelease         = "/etc/os-release"
+	factFileCPUInfo           = "/proc/cpuinfo"
+	factFileMemInfo           = "/proc/meminfo"
+	factFileMounts            = "/proc/mounts"
+	factFileSwaps             = "/proc/swaps"
+	factFileCgroupControllers = "/sys/fs/cgroup/cgroup.controllers"
+	factFileCgroups           = "/proc/cgroups"
+	factFileSELinuxConfig     = "/etc/selinux/config"
+	factFileAppArmor          = "/sys/module/apparmor/parameters/enabled"
+)
+
+// factSubsets in the order of gathering.
+var factSubsets = []string{
+	FactSubsetHardware, FactSubsetGPU, FactSubsetNetwork, FactSubsetMounts, FactSubsetCgroup,
+	FactSubsetSELinux, FactSubsetAppArmor, FactSubsetServiceMgr, FactSubsetPkgMgr, FactSubsetTimeSync,
+}
+
+// factCollector gathers a subset of facts from host. It returns the facts which merge into host info.
+type factCollector func(ctx context.Context, conn Connector, workdir string) (map[string]any, error)
+
+// factCollectors for each fact subset.
+var factCollectors = map[string]factCollector{
+	FactSubsetHardware:   gatherHardwareFacts,
+	FactSubsetGPU:        gatherGPUFacts,
+	FactSubsetNetwork:    gatherNetworkFacts,
+	FactSubsetMounts:     gatherMountFacts,
+	FactSubsetCgroup:     gatherCgroupFacts,
+	FactSubsetSELinux:    gatherSELinuxFacts,
+	FactSubsetAppArmor:   gatherAppArmorFacts,
+	FactSubsetServiceMgr: gatherServiceMgrFacts,
+	FactSubsetPkgMgr:     gatherPkgMgrFacts,
+	FactSubsetTimeSync:   gatherTimeSyncFacts,
+}
+
+// defaultFactSubsets are gathered when gather_subset is not set. They are the subsets used by the builtin playbooks:
+// hardware for the memory precheck and pkg_mgr for the package module.
+var defaultFactSubsets = []string{FactSubsetHardware, FactSubsetPkgMgr}
+
+// ResolveGatherSubset converts gather_subset to the fact subsets to gather, like ansible.
+// "all" selects all subsets, "min" selects nothing but os facts, and a subset prefixed by "!" is excluded.
+// "!all" only excludes the subsets which are not explicitly included, so "!all,network" gathers network.
+// When gather_subset is empty it uses the default subsets, and when it only has exclusions it starts from "all".
+// Each item can be a comma separated list.
+func ResolveGatherSubset(gatherSubset []string) ([]string, error) {
+	var subsets []string
+	for _, s := range gatherSubset {
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				subsets = append(subsets, v)
+			}
+		}
+	}
+	if len(subsets) == 0 {
+		return slices.Clone(defaultFactSubsets), nil
+	}
+
+	var include, exclude []string
+	var includeAll, excludeAll, hasInclude bool
+	for _, s := range subsets {
+		name, excluded := strings.CutPrefix(s, "!")
+		switch {
+		case name == FactSubsetAll:
+			if excluded {
+				excludeAll = true
+			} else {
+				hasInclude = true
+				includeAll = true
+			}
+		case name == FactSubsetMin:
+			if !excluded {
+				hasInclude = true
+			}
+		case slices.Contains(factSubsets, name):
+			if excluded {
+				exclude = append(exclude, name)
+			} else {
+				hasInclude = true
+				include = append(include, name)
+			}
+		default:
+			return nil, errors.Errorf("unsupported gather_subset %q. support %s, %s and %s", s, FactSubsetAll, FactSubsetMin, strings.Join(factSubsets, ", "))
+		}
+	}
+	// only exclusions start from "all".
+	if !hasInclude {
+		includeAll = true
+	}
+
+	result := make([]string, 0, len(factSubsets))
+	for _, s := range factSubsets {
+		if slices.Contains(exclude, s) {
+			continue
+		}
+		if slices.Contains(include, s) || (includeAll && !excludeAll) {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+// gatherSubsetFromContext returns the fact subsets set by setup module. default the default fact subsets.
+func gatherSubsetFromContext(ctx context.Context) []string {
+	if subsets, ok := ctx.Value(_const.CTXGatherSubsetKey).([]string); ok {
+		return subsets
+	}
+
+	return defaultFactSubsets
+}
+
+// coversGatherSubset checks whether the facts have gathered all the subsets.
+func coversGatherSubset(facts map[string]any, subsets []string) bool {
+	var gathered []string
+	switch v := facts[_const.VariableGatherSubset].(type) {
+	case []string:
+		gathered = v
+	case []any:
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				gathered = append(gathered, str)
+			}
+		}
+	}
+	for _, s := range subsets {
+		if !slices.Contains(gathered, s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collectFacts gathers the fact subsets from context and merges them with os facts.
+func collectFacts(ctx context.Context, conn Connector, workdir string, osVars map[string]any) (map[string]any, error) {
+	subsets := gatherSubsetFromContext(ctx)
+	facts := map[string]any{
+		_const.VariableOS:           osVars,
+		_const.VariableGatherSubset: subsets,
+	}
+	for _, s := range subsets {
+		// collectors skip the facts which failed to gather, stop when gather_timeout is reached.
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrapf(err, "failed to gather %s facts", s)
+		}
+		vars, err := factCollectors[s](ctx, conn, workdir)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			facts[k] = v
+		}
+	}
+
+	return facts, nil
+}
+
+// gatherHardwareFacts gathers cpu, memory and block devices.
+func gatherHardwareFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	procVars := make(map[string]any)
+	var cpu bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileCPUInfo, &cpu); err != nil {
+		return nil, err
+	}
+	procVars[_const.VariableProcessCPU] = convertBytesToSlice(cpu.Bytes(), ":")
+	var mem bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileMemInfo, &mem); err != nil {
+		return nil, err
+	}
+	procVars[_const.VariableProcessMemory] = convertBytesToMap(mem.Bytes(), ":")
+
+	// block devices
+	blockdevicesVars, blockErr := blockDevicesFromLsblk(ctx, conn)
+	if blockErr != nil {
+		klog.V(4).ErrorS(blockErr, "skip block device gathering")
+	}
+
+	return map[string]any{
+		_const.VariableProcess:      procVars,
+		_const.VariableBlockDevices: blockdevicesVars,
+	}, nil
+}
+
+// gatherGPUFacts gathers gpu devices by lspci.
+func gatherGPUFacts(ctx context.Context, conn Connector, workdir string) (map[string]any, error) {
+	gpuVars, gpuErr := gpuInfoFromLspci(ctx, workdir, conn)
+	if gpuErr != nil {
+		klog.V(4).ErrorS(gpuErr, "skip gpu gathering")
+	}
+
+	return map[string]any{_const.VariableGPU: gpuVars}, nil
+}
+
+// gatherNetworkFacts gathers network interfaces with their mtu, mac and addresses, and the default routes.
+func gatherNetworkFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	network := make(map[string]any)
+	links, stderr, err := conn.ExecuteCommand(ctx, factCmdIPLink)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip network gathering", "stderr", string(stderr))
+
+		return map[string]any{_const.VariableNetwork: network}, nil
+	}
+	addrs, stderr, err := conn.ExecuteCommand(ctx, factCmdIPAddr)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip network address gathering", "stderr", string(stderr))
+	}
+	network["interfaces"] = parseIPAddr(parseIPLink(links), addrs)
+	if route, _, err := conn.ExecuteCommand(ctx, factCmdIPv4Route); err == nil {
+		network["default_ipv4"] = parseDefaultRoute(route)
+	}
+	if route, _, err := conn.ExecuteCommand(ctx, factCmdIPv6Route); err == nil {
+		network["default_ipv6"] = parseDefaultRoute(route)
+	}
+
+	return map[string]any{_const.VariableNetwork: network}, nil
+}
+
+// parseIPLink parses the output of "ip -o link show" to interfaces with name, mtu, state and mac.
+// e.g. "2: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc fq_codel state UP mode DEFAULT group default qlen 1000\    link/ether 52:54:00:12:34:56 brd ff:ff:ff:ff:ff:ff"
+func parseIPLink(bs []byte) []map[string]any {
+	var interfaces []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(strings.ReplaceAll(scanner.Text(), `\`, " "))
+		if len(fields) < 2 {
+			continue
+		}
+		// veth name has the peer index. e.g. "veth1@if2:"
+		name, _, _ := strings.Cut(strings.TrimSuffix(fields[1], ":"), "@")
+		iface := map[string]any{
+			"name": name,
+			"ipv4": []string{},
+			"ipv6": []string{},
+		}
+		for i := 2; i < len(fields)-1; i++ {
+			switch {
+			case fields[i] == "mtu":
+				if mtu, err := strconv.Atoi(fields[i+1]); err == nil {
+					iface["mtu"] = mtu
+				}
+			case fields[i] == "state":
+				iface["state"] = fields[i+1]
+			case strings.HasPrefix(fields[i], "link/"):
+				iface["type"] = strings.TrimPrefix(fields[i], "link/")
+				iface["mac"] = fields[i+1]
+			}
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	return interfaces
+}
+
+// parseIPAddr parses the output of "ip -o addr show" and adds the addresses to interfaces.
+// e.g. "2: eth0    inet 10.0.0.2/24 brd 10.0.0.255 scope global eth0\       valid_lft forever preferred_lft forever"
+func parseIPAddr(interfaces []map[string]any, bs []byte) []map[string]any {
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		var family string
+		switch fields[2] {
+		case "inet":
+			family = "ipv4"
+		case "inet6":
+			family = "ipv6"
+		default:
+			continue
+		}
+		name, _, _ := strings.Cut(fields[1], "@")
+		for _, iface := range interfaces {
+			if iface["name"] == name {
+				iface[family] = append(iface[family].([]string), fields[3])
+			}
+		}
+	}
+
+	return interfaces
+}
+
+// parseDefaultRoute parses the first line of "ip route show default" to the interface and gateway.
+// e.g. "default via 10.0.0.1 dev eth0 proto dhcp src 10.0.0.2 metric 100"
+func parseDefaultRoute(bs []byte) map[string]any {
+	route := make(map[string]any)
+	line, _, _ := strings.Cut(strings.TrimSpace(string(bs)), "\n")
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "via":
+			route["gateway"] = fields[i+1]
+		case "dev":
+			route["interface"] = fields[i+1]
+		case "src":
+			route["address"] = fields[i+1]
+		}
+	}
+
+	return route
+}
+
+// pseudoFilesystems are not reported in mounts.
+var pseudoFilesystems = []string{
+	"autofs", "binfmt_misc", "bpf", "cgroup", "cgroup2", "configfs", "debugfs", "devpts", "devtmpfs", "fusectl",
+	"hugetlbfs", "mqueue", "nsfs", "proc", "pstore", "rpc_pipefs", "securityfs", "sysfs", "tracefs",
+}
+
+// gatherMountFacts gathers mounted filesystems with their size, and swap devices.
+func gatherMountFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	var mounts []map[string]any
+	var procMounts bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileMounts, &procMounts); err != nil {
+		klog.V(4).ErrorS(err, "skip mounts gathering")
+	} else {
+		df, _, err := conn.ExecuteCommand(ctx, factCmdDF)
+		if err != nil {
+			klog.V(4).ErrorS(err, "skip mounts size gathering")
+		}
+		mounts = parseProcMounts(procMounts.Bytes(), parseDF(df))
+	}
+	swap := map[string]any{"enabled": false}
+	var procSwaps bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileSwaps, &procSwaps); err != nil {
+		klog.V(4).ErrorS(err, "skip swap gathering")
+	} else {
+		swap = parseProcSwaps(procSwaps.Bytes())
+	}
+
+	return map[string]any{
+		_const.VariableMounts: mounts,
+		_const.VariableSwap:   swap,
+	}, nil
+}
+
+// parseProcMounts parses /proc/mounts, and fills the size of each mount point from df.
+// e.g. "/dev/sda1 / ext4 rw,relatime 0 0"
+func parseProcMounts(bs []byte, sizes map[string][2]int64) []map[string]any {
+	var mounts []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || slices.Contains(pseudoFilesystems, fields[2]) {
+			continue
+		}
+		mount := map[string]any{
+			"device":  unescapeMountField(fields[0]),
+			"mount":   unescapeMountField(fields[1]),
+			"fstype":  fields[2],
+			"options": fields[3],
+		}
+		if size, ok := sizes[mount["mount"].(string)]; ok {
+			mount["size_total"] = size[0]
+			mount["size_available"] = size[1]
+		}
+		mounts = append(mounts, mount)
+	}
+
+	return mounts
+}
+
+// unescapeMountField unescapes the octal characters in /proc/mounts. e.g. "\040" is space.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// parseDF parses the output of "df -P -B1" to the total and available bytes of each mount point.
+// e.g. "/dev/sda1  105089261568 5429895168 94278963200       6% /"
+func parseDF(bs []byte) map[string][2]int64 {
+	sizes := make(map[string][2]int64)
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		total, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			// header
+			continue
+		}
+		available, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		// mount point may contain space.
+		sizes[strings.Join(fields[5:], " ")] = [2]int64{total, available}
+	}
+
+	return sizes
+}
+
+// parseProcSwaps parses /proc/swaps. the size in /proc/swaps is KiB, convert it to bytes.
+// e.g. "/swap.img                               file		2097148		0		-2"
+func parseProcSwaps(bs []byte) map[string]any {
+	var devices []map[string]any
+	var total, used int64
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "Filename" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		u, _ := strconv.ParseInt(fields[3], 10, 64)
+		devices = append(devices, map[string]any{
+			"name": unescapeMountField(fields[0]),
+			"type": fields[1],
+			"size": size * 1024,
+			"used": u * 1024,
+		})
+		total += size * 1024
+		used += u * 1024
+	}
+
+	return map[string]any{
+		"enabled": len(devices) > 0,
+		"total":   total,
+		"used":    used,
+		"devices": devices,
+	}
+}
+
+// gatherCgroupFacts gathers cgroup version and the enabled controllers.
+func gatherCgroupFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	cgroup := make(map[string]any)
+	fsType, stderr, err := conn.ExecuteCommand(ctx, factCmdCgroupFS)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip cgroup gathering", "stderr", string(stderr))
+
+		return map[string]any{_const.VariableCgroup: cgroup}, nil
+	}
+	var controllers bytes.Buffer
+	if strings.TrimSpace(string(fsType)) == "cgroup2fs" {
+		cgroup["version"] = "v2"
+		if err := conn.FetchFile(ctx, factFileCgroupControllers, &controllers); err == nil {
+			cgroup["controllers"] = strings.Fields(controllers.String())
+		}
+	} else {
+		cgroup["version"] = "v1"
+		if err := conn.FetchFile(ctx, factFileCgroups, &controllers); err == nil {
+			cgroup["controllers"] = parseProcCgroups(controllers.Bytes())
+		}
+	}
+
+	return map[string]any{_const.VariableCgroup: cgroup}, nil
+}
+
+// parseProcCgroups parses /proc/cgroups to the enabled controllers.
+// e.g. "cpuset	2	1	1"
+func parseProcCgroups(bs []byte) []string {
+	controllers := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[3] == "1" {
+			controllers = append(controllers, fields[0])
+		}
+	}
+
+	return controllers
+}
+
+// gatherSELinuxFacts gathers SELinux status by getenforce and the config in /etc/selinux/config.
+func gatherSELinuxFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	mode, _, err := conn.ExecuteCommand(ctx, factCmdSELinux)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip selinux gathering")
+	}
+	var config bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileSELinuxConfig, &config); err != nil {
+		klog.V(4).InfoS("selinux config not found", "error", err)
+	}
+
+	return map[string]any{_const.VariableSELinux: parseSELinux(mode, config.Bytes())}, nil
+}
+
+// parseSELinux parses the output of getenforce and /etc/selinux/config.
+func parseSELinux(mode, config []byte) map[string]any {
+	selinux := map[string]any{"status": "not_installed"}
+	m := strings.ToLower(strings.TrimSpace(string(mode)))
+	if m == "" {
+		return selinux
+	}
+	selinux["mode"] = m
+	if m == "disabled" {
+		selinux["status"] = "disabled"
+	} else {
+		selinux["status"] = "enabled"
+	}
+	conf := convertBytesToMap(config, "=")
+	if v, ok := conf["SELINUX"]; ok {
+		selinux["config_mode"] = strings.ToLower(v)
+	}
+	if v, ok := conf["SELINUXTYPE"]; ok {
+		selinux["type"] = v
+	}
+
+	return selinux
+}
+
+// gatherAppArmorFacts gathers AppArmor status from the apparmor kernel module.
+func gatherAppArmorFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	status := "disabled"
+	var enabled bytes.Buffer
+	if err := conn.FetchFile(ctx, factFileAppArmor, &enabled); err == nil &&
+		strings.TrimSpace(enabled.String()) == "Y" {
+		status = "enabled"
+	}
+
+	return map[string]any{_const.VariableAppArmor: map[string]any{"status": status}}, nil
+}
+
+// gatherServiceMgrFacts gathers the init system by the process 1 and the init tools.
+func gatherServiceMgrFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, factCmdServiceMgr)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip service_mgr gathering", "stderr", string(stderr))
+	}
+
+	return map[string]any{_const.VariableServiceMgr: parseServiceMgr(stdout)}, nil
+}
+
+// parseServiceMgr detects the init system. The first line is the command of process 1,
+// the others are the paths of init tools.
+func parseServiceMgr(bs []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	comm := strings.TrimSpace(lines[0])
+	var tools []string
+	for _, l := range lines[1:] {
+		tools = append(tools, path.Base(strings.TrimSpace(l)))
+	}
+	switch comm {
+	case "systemd":
+		return "systemd"
+	case "openrc-init":
+		return "openrc"
+	case "runit", "runit-init":
+		return "runit"
+	case "init":
+		switch {
+		case slices.Contains(tools, "openrc") || slices.Contains(tools, "openrc-init"):
+			return "openrc"
+		case slices.Contains(tools, "initctl"):
+			return "upstart"
+		default:
+			return "sysvinit"
+		}
+	default:
+		return comm
+	}
+}
+
+// pkgMgrs in the order of priority. the key is the command and the value is the package manager.
+var pkgMgrs = [][2]string{{"dnf", "dnf"}, {"yum", "yum"}, {"apt-get", "apt"}, {"zypper", "zypper"}, {"apk", "apk"}, {"pacman", "pacman"}}
+
+// gatherPkgMgrFacts gathers the package manager by the commands which exist in host.
+func gatherPkgMgrFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, factCmdPkgMgr)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip pkg_mgr gathering", "stderr", string(stderr))
+	}
+
+	return map[string]any{_const.VariablePkgMgr: parsePkgMgr(stdout)}, nil
+}
+
+// parsePkgMgr returns the package manager with the highest priority in the command paths.
+func parsePkgMgr(bs []byte) string {
+	var commands []string
+	for _, l := range strings.Split(strings.TrimSpace(string(bs)), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			commands = append(commands, path.Base(l))
+		}
+	}
+	for _, pm := range pkgMgrs {
+		if slices.Contains(commands, pm[0]) {
+			return pm[1]
+		}
+	}
+
+	return "unknown"
+}
+
+// gatherTimeSyncFacts gathers the active time synchronization service and whether the clock is synchronized.
+func gatherTimeSyncFacts(ctx context.Context, conn Connector, _ string) (map[string]any, error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, factCmdTimeSync)
+	if err != nil {
+		klog.V(4).ErrorS(err, "skip time_sync gathering", "stderr", string(stderr))
+	}
+
+	return map[string]any{_const.VariableTimeSync: parseTimeSync(stdout)}, nil
+}
+
+// parseTimeSync parses the lines of "synchronized=<yes|no>" and "service=<name>".
+func parseTimeSync(bs []byte) map[string]any {
+	timeSync := map[string]any{
+		"service":      "",
+		"active":       false,
+		"synchronized": false,
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "synchronized":
+			timeSync["synchronized"] = value == "yes"
+		case "service":
+			if timeSync["service"] == "" {
+				timeSync["service"] = value
+				timeSync["active"] = true
+			}
+		}
+	}
+
+	return timeSync
+}
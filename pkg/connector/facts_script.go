@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// factProbes are the commands and files read by a fact subset.
+type factProbes struct {
+	commands []string
+	files    []string
+}
+
+// osFactProbes are always gathered.
+var osFactProbes = factProbes{
+	commands: []string{factCmdOSType, factCmdKernelVersion, factCmdHostname, factCmdArch},
+	files:    []string{factFileOSRelease},
+}
+
+// subsetFactProbes for each fact subset. They should be kept the same as the factCollectors.
+var subsetFactProbes = map[string]factProbes{
+	FactSubsetHardware:   {commands: []string{factCmdLsblk, factCmdLvs}, files: []string{factFileCPUInfo, factFileMemInfo}},
+	FactSubsetGPU:        {commands: []string{factCmdLspci}},
+	FactSubsetNetwork:    {commands: []string{factCmdIPLink, factCmdIPAddr, factCmdIPv4Route, factCmdIPv6Route}},
+	FactSubsetMounts:     {commands: []string{factCmdDF}, files: []string{factFileMounts, factFileSwaps}},
+	FactSubsetCgroup:     {commands: []string{factCmdCgroupFS}, files: []string{factFileCgroupControllers, factFileCgroups}},
+	FactSubsetSELinux:    {commands: []string{factCmdSELinux}, files: []string{factFileSELinuxConfig}},
+	FactSubsetAppArmor:   {files: []string{factFileAppArmor}},
+	FactSubsetServiceMgr: {commands: []string{factCmdServiceMgr}},
+	FactSubsetPkgMgr:     {commands: []string{factCmdPkgMgr}},
+	FactSubsetTimeSync:   {commands: []string{factCmdTimeSync}},
+}
+
+// factScriptResult is the json document printed by the facts script.
+// The outputs are in the same order as the commands and files in the script.
+type factScriptResult struct {
+	Commands []factScriptOutput `json:"commands"`
+	Files    []factScriptOutput `json:"files"`
+}
+
+// factScriptOutput is the exit code, base64 encoded stdout and stderr of a command or a file.
+type factScriptOutput struct {
+	RC     int    `json:"rc"`
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
+// factScriptHeader checks the tools used by the script and prepares the temp dir for outputs.
+// The script removes itself, so it leaves nothing in the host.
+const factScriptHeader = `#!/bin/sh
+# facts script generated by kubekey. It prints the outputs of commands and files as a json document.
+rm -f "$0"
+for t in base64 mktemp tr cat; do
+  command -v "$t" >/dev/null 2>&1 || { echo "$t not found" >&2; exit 127; }
+done
+kk_tmp=$(mktemp -d) || exit 1
+trap 'rm -rf "$kk_tmp"' EXIT
+kk_out() {
+  printf '{"rc":%d,"stdout":"%s","stderr":"%s"}' "$1" "$(base64 <"$kk_tmp/out" | tr -d '\n')" "$(base64 <"$kk_tmp/err" | tr -d '\n')"
+}
+`
+
+// buildFactScript generates a POSIX shell script which runs the commands, reads the files,
+// and prints all outputs in a single json document of factScriptResult.
+func buildFactScript(probes factProbes) string {
+	var b strings.Builder
+	b.WriteString(factScriptHeader)
+	b.WriteString("printf '{\"commands\":['\n")
+	for i, cmd := range probes.commands {
+		if i > 0 {
+			b.WriteString("printf ','\n")
+		}
+		fmt.Fprintf(&b, "(\n%s\n) >\"$kk_tmp/out\" 2>\"$kk_tmp/err\" </dev/null; kk_out $?\n", cmd)
+	}
+	b.WriteString("printf '],\"files\":['\n")
+	for i, file := range probes.files {
+		if i > 0 {
+			b.WriteString("printf ','\n")
+		}
+		fmt.Fprintf(&b, "cat -- '%s' >\"$kk_tmp/out\" 2>\"$kk_tmp/err\"; kk_out $?\n", strings.ReplaceAll(file, "'", `'\''`))
+	}
+	b.WriteString("printf ']}\\n'\n")
+
+	return b.String()
+}
+
+// factProbesForSubsets returns the os probes and the probes of the fact subsets.
+func factProbesForSubsets(subsets []string) factProbes {
+	probes := factProbes{
+		commands: append([]string{}, osFactProbes.commands...),
+		files:    append([]string{}, osFactProbes.files...),
+	}
+	for _, s := range subsets {
+		probes.commands = append(probes.commands, subsetFactProbes[s].commands...)
+		probes.files = append(probes.files, subsetFactProbes[s].files...)
+	}
+
+	return probes
+}
+
+// factScriptConnector replays the outputs of the facts script for ExecuteCommand and FetchFile.
+// The commands and files which are not in the script are delegated to the wrapped connector.
+type factScriptConnector struct {
+	Connector
+
+	commands map[string]factScriptOutput
+	files    map[string]factScriptOutput
+}
+
+// newFactScriptConnector uploads the facts script for the subsets and runs it in one round trip.
+// If the script cannot run, e.g. the host has no POSIX shell toolset, it returns the connector itself,
+// which gathers facts by executing each command.
+func newFactScriptConnector(ctx context.Context, conn Connector, subsets []string) Connector {
+	probes := factProbesForSubsets(subsets)
+	result, err := runFactScript(ctx, conn, probes)
+	if err != nil {
+		klog.V(4).InfoS("failed to gather facts by script, fallback to execute each command", "error", err)
+
+		return conn
+	}
+
+	return replayFactScript(conn, probes, result)
+}
+
+// runFactScript uploads and runs the facts script, then decodes its json document.
+func runFactScript(ctx context.Context, conn Connector, probes factProbes) (*factScriptResult, error) {
+	// use a relative path which is the same in PutFile and ExecuteCommand. See PutData.
+	script := ".kk.facts." + rand.String(10) + ".sh"
+	if err := conn.PutFile(ctx, []byte(buildFactScript(probes)), script, _const.PermFilePublic); err != nil {
+		return nil, errors.Wrap(err, "failed to upload facts script")
+	}
+	stdout, stderr, err := conn.ExecuteCommand(ctx, "sh ./"+script)
+	if err != nil {
+		// the script may exit before removing itself.
+		_, _, _ = conn.ExecuteCommand(ctx, "rm -f ./"+script)
+
+		return nil, errors.Wrapf(err, "failed to run facts script, stderr: %q", string(stderr))
+	}
+
+	return decodeFactScript(stdout, probes)
+}
+
+// decodeFactScript decodes the json document of the facts script. The stdout may have some prompts
+// of the connector (e.g. sudo), so only the content between the first "{" and the last "}" is decoded.
+func decodeFactScript(stdout []byte, probes factProbes) (*factScriptResult, error) {
+	start, end := bytes.IndexByte(stdout, '{'), bytes.LastIndexByte(stdout, '}')
+	if start < 0 || end < start {
+		return nil, errors.Errorf("facts script output is not json: %q", string(stdout))
+	}
+	result := &factScriptResult{}
+	if err := json.Unmarshal(stdout[start:end+1], result); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal facts script output")
+	}
+	if len(result.Commands) != len(probes.commands) || len(result.Files) != len(probes.files) {
+		return nil, errors.Errorf("facts script outputs %d commands and %d files, expected %d and %d",
+			len(result.Commands), len(result.Files), len(probes.commands), len(probes.files))
+	}
+
+	return result, nil
+}
+
+// replayFactScript maps the outputs of the facts script to the commands and files.
+func replayFactScript(conn Connector, probes factProbes, result *factScriptResult) *factScriptConnector {
+	c := &factScriptConnector{
+		Connector: conn,
+		commands:  make(map[string]factScriptOutput, len(probes.commands)),
+		files:     make(map[string]factScriptOutput, len(probes.files)),
+	}
+	for i, cmd := range probes.commands {
+		c.commands[cmd] = result.Commands[i]
+	}
+	for i, file := range probes.files {
+		c.files[file] = result.Files[i]
+	}
+
+	return c
+}
+
+// ExecuteCommand returns the output of the command in the facts script, the same as executing it in host.
+func (c *factScriptConnector) ExecuteCommand(ctx context.Context, cmd string) ([]byte, []byte, error) {
+	output, ok := c.commands[cmd]
+	if !ok {
+		return c.Connector.ExecuteCommand(ctx, cmd)
+	}
+	stdout, stderr, err := output.decode()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to decode output of command %q", cmd)
+	}
+	if output.RC != 0 {
+		return stdout, stderr, errors.Errorf("command %q exit status %d: %s", cmd, output.RC, strings.TrimSpace(string(stderr)))
+	}
+
+	return stdout, stderr, nil
+}
+
+// FetchFile writes the content of the file in the facts script to dst, the same as fetching it from host.
+func (c *factScriptConnector) FetchFile(ctx context.Context, src string, dst io.Writer) error {
+	output, ok := c.files[src]
+	if !ok {
+		return c.Connector.FetchFile(ctx, src, dst)
+	}
+	content, stderr, err := output.decode()
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode content of file %q", src)
+	}
+	if output.RC != 0 {
+		return errors.Errorf("failed to read file %q: %s", src, strings.TrimSpace(string(stderr)))
+	}
+	if _, err := dst.Write(content); err != nil {
+		return errors.Wrapf(err, "failed to write content of file %q", src)
+	}
+
+	return nil
+}
+
+// decode the base64 encoded stdout and stderr.
+func (o factScriptOutput) decode() ([]byte, []byte, error) {
+	stdout, err := base64.StdEncoding.DecodeString(o.Stdout)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode stdout")
+	}
+	stderr, err := base64.StdEncoding.DecodeString(o.Stderr)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode stderr")
+	}
+
+	return stdout, stderr, nil
+}
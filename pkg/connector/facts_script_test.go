@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// shellFactConnector executes commands by sh in dir, without sudo. PutFile with relative path writes to dir.
+type shellFactConnector struct {
+	dir      string
+	executed int
+}
+
+func (c *shellFactConnector) Init(context.Context) error { return nil }
+
+func (c *shellFactConnector) Close(context.Context) error { return nil }
+
+func (c *shellFactConnector) PutFile(_ context.Context, src []byte, dst string, mode fs.FileMode) error {
+	return os.WriteFile(filepath.Join(c.dir, dst), src, mode)
+}
+
+func (c *shellFactConnector) FetchFile(_ context.Context, src string, dst io.Writer) error {
+	c.executed++
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(data)
+
+	return err
+}
+
+func (c *shellFactConnector) ExecuteCommand(ctx context.Context, cmd string) ([]byte, []byte, error) {
+	c.executed++
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, "sh", "-c", cmd)
+	command.Dir = c.dir
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	err := command.Run()
+
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func TestFactScript(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("facts script runs in linux")
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "it's a file")
+	if err := os.WriteFile(file, []byte("line1\nline2\n"), _const.PermFilePublic); err != nil {
+		t.Fatal(err)
+	}
+	probes := factProbes{
+		commands: []string{"echo hello", "echo failed >&2; exit 3", `printf '"quoted"\n'; true`},
+		files:    []string{file, filepath.Join(dir, "not-exist")},
+	}
+	conn := &shellFactConnector{dir: dir}
+	result, err := runFactScript(context.TODO(), conn, probes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := replayFactScript(conn, probes, result)
+	// upload and run the script.
+	assert.Equal(t, 1, conn.executed)
+
+	stdout, _, err := replay.ExecuteCommand(context.TODO(), "echo hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(stdout))
+	_, stderr, err := replay.ExecuteCommand(context.TODO(), "echo failed >&2; exit 3")
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.Equal(t, "failed\n", string(stderr))
+	stdout, _, err = replay.ExecuteCommand(context.TODO(), `printf '"quoted"\n'; true`)
+	assert.NoError(t, err)
+	assert.Equal(t, "\"quoted\"\n", string(stdout))
+
+	var content bytes.Buffer
+	assert.NoError(t, replay.FetchFile(context.TODO(), file, &content))
+	assert.Equal(t, "line1\nline2\n", content.String())
+	assert.Error(t, replay.FetchFile(context.TODO(), filepath.Join(dir, "not-exist"), &content))
+	assert.Equal(t, 1, conn.executed)
+
+	// command which is not in script is delegated to the connector.
+	stdout, _, err = replay.ExecuteCommand(context.TODO(), "echo delegated")
+	assert.NoError(t, err)
+	assert.Equal(t, "delegated\n", string(stdout))
+	assert.Equal(t, 2, conn.executed)
+
+	// the script removes itself.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFactScriptSameAsCommands(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("facts script runs in linux")
+	}
+	subsets := []string{FactSubsetMounts, FactSubsetCgroup, FactSubsetSELinux, FactSubsetAppArmor, FactSubsetServiceMgr, FactSubsetPkgMgr}
+	ctx := context.WithValue(context.Background(), _const.CTXGatherSubsetKey, subsets)
+
+	byCommands, err := collectFacts(ctx, &shellFactConnector{dir: t.TempDir()}, "", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &shellFactConnector{dir: t.TempDir()}
+	script := newFactScriptConnector(ctx, conn, subsets)
+	assert.IsType(t, &factScriptConnector{}, script)
+	byScript, err := collectFacts(ctx, script, "", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, conn.executed)
+	// the available size of mounts may change between gathering.
+	for _, facts := range []map[string]any{byCommands, byScript} {
+		for _, m := range facts[_const.VariableMounts].([]map[string]any) {
+			delete(m, "size_available")
+		}
+		delete(facts, _const.VariableSwap)
+	}
+	assert.Equal(t, byCommands, byScript)
+}
+
+func TestFactScriptFallback(t *testing.T) {
+	// the fake connector cannot run the script.
+	conn := &fakeFactConnector{}
+	assert.Same(t, conn, newFactScriptConnector(context.TODO(), conn, factSubsets))
+
+	_, err := decodeFactScript([]byte("sh: base64 not found"), factProbes{})
+	assert.Error(t, err)
+	// outputs are not matched with probes.
+	_, err = decodeFactScript([]byte(`{"commands":[],"files":[]}`), factProbes{commands: []string{"hostname"}})
+	assert.Error(t, err)
+	// sudo prompt before the json document.
+	result, err := decodeFactScript([]byte(`[sudo] password for kk: {"commands":[{"rc":0,"stdout":"bm9kZTEK","stderr":""}],"files":[]}`),
+		factProbes{commands: []string{"hostname"}})
+	if assert.NoError(t, err) {
+		stdout, _, err := replayFactScript(conn, factProbes{commands: []string{"hostname"}}, result).ExecuteCommand(context.TODO(), "hostname")
+		assert.NoError(t, err)
+		assert.Equal(t, "node1\n", string(stdout))
+	}
+}
+
+func TestFactProbesForSubsets(t *testing.T) {
+	// every subset has probes.
+	for _, s := range factSubsets {
+		assert.Contains(t, subsetFactProbes, s)
+	}
+	probes := factProbesForSubsets([]string{FactSubsetNetwork})
+	assert.Equal(t, []string{factCmdOSType, factCmdKernelVersion, factCmdHostname, factCmdArch,
+		factCmdIPLink, factCmdIPAddr, factCmdIPv4Route, factCmdIPv6Route}, probes.commands)
+	assert.Equal(t, []string{factFileOSRelease}, probes.files)
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// fakeFactConnector returns the stdout of command and the content of file. Unknown command or file returns error.
+type fakeFactConnector struct {
+	commands map[string]string
+	files    map[string]string
+	executed []string
+}
+
+func (c *fakeFactConnector) Init(context.Context) error { return nil }
+
+func (c *fakeFactConnector) Close(context.Context) error { return nil }
+
+func (c *fakeFactConnector) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (c *fakeFactConnector) FetchFile(_ context.Context, src string, dst io.Writer) error {
+	c.executed = append(c.executed, src)
+	content, ok := c.files[src]
+	if !ok {
+		return os.ErrNotExist
+	}
+	_, err := io.WriteString(dst, content)
+
+	return err
+}
+
+func (c *fakeFactConnector) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	c.executed = append(c.executed, cmd)
+	stdout, ok := c.commands[cmd]
+	if !ok {
+		return nil, []byte("command not found"), errors.New("exit status 127")
+	}
+
+	return []byte(stdout), nil, nil
+}
+
+func TestResolveGatherSubset(t *testing.T) {
+	testcases := []struct {
+		name    string
+		subset  []string
+		except  []string
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			except: []string{"hardware", "pkg_mgr"},
+		},
+		{
+			name:   "all",
+			subset: []string{"all"},
+			except: factSubsets,
+		},
+		{
+			name:   "min",
+			subset: []string{"min"},
+			except: []string{},
+		},
+		{
+			name:   "not all",
+			subset: []string{"!all"},
+			except: []string{},
+		},
+		{
+			name:   "only exclusion",
+			subset: []string{"!hardware", "!gpu"},
+			except: []string{"network", "mounts", "cgroup", "selinux", "apparmor", "service_mgr", "pkg_mgr", "time_sync"},
+		},
+		{
+			name:   "inclusion in gather order",
+			subset: []string{"pkg_mgr", "network"},
+			except: []string{"network", "pkg_mgr"},
+		},
+		{
+			name:   "min with inclusion",
+			subset: []string{"min", "cgroup"},
+			except: []string{"cgroup"},
+		},
+		{
+			name:   "not all with inclusion",
+			subset: []string{"!all", "network"},
+			except: []string{"network"},
+		},
+		{
+			name:   "not all with inclusion in one item",
+			subset: []string{"!all,network"},
+			except: []string{"network"},
+		},
+		{
+			name:   "all with exclusion",
+			subset: []string{"all", "!gpu", "!time_sync"},
+			except: []string{"hardware", "network", "mounts", "cgroup", "selinux", "apparmor", "service_mgr", "pkg_mgr"},
+		},
+		{
+			name:   "comma separated",
+			subset: []string{"network, mounts"},
+			except: []string{"network", "mounts"},
+		},
+		{
+			name:    "unsupported",
+			subset:  []string{"virtual"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ResolveGatherSubset(tc.subset)
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.except, actual)
+		})
+	}
+}
+
+func TestCollectFacts(t *testing.T) {
+	conn := &fakeFactConnector{
+		commands: map[string]string{
+			"ip -o link show": `1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 qdisc noqueue state UNKNOWN mode DEFAULT group default qlen 1000\    link/loopback 00:00:00:00:00:00 brd 00:00:00:00:00:00
+2: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1450 qdisc fq_codel state UP mode DEFAULT group default qlen 1000\    link/ether 52:54:00:12:34:56 brd ff:ff:ff:ff:ff:ff
+3: veth1@if2: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc noqueue state UP mode DEFAULT group default\    link/ether aa:bb:cc:dd:ee:ff brd ff:ff:ff:ff:ff:ff link-netnsid 0
+`,
+			"ip -o addr show": `1: lo    inet 127.0.0.1/8 scope host lo\       valid_lft forever preferred_lft forever
+2: eth0    inet 10.0.0.2/24 brd 10.0.0.255 scope global eth0\       valid_lft forever preferred_lft forever
+2: eth0    inet6 fe80::5054:ff:fe12:3456/64 scope link \       valid_lft forever preferred_lft forever
+`,
+			"ip -4 route show default": "default via 10.0.0.1 dev eth0 proto dhcp src 10.0.0.2 metric 100\n",
+			"ip -6 route show default": "",
+			"df -P -B1": `Filesystem        1-blocks       Used    Available Capacity Mounted on
+/dev/sda1     105089261568 5429895168  94278963200       6% /
+/dev/sdb1       1000000000          0   1000000000       0% /data dir
+`,
+			"stat -fc %T /sys/fs/cgroup": "cgroup2fs\n",
+		},
+		files: map[string]string{
+			"/proc/mounts": `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sda1 / ext4 rw,relatime 0 0
+/dev/sdb1 /data\040dir xfs rw,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev 0 0
+`,
+			"/proc/swaps": `Filename				Type		Size		Used		Priority
+/swap.img                               file		2097148		1024		-2
+`,
+			"/sys/fs/cgroup/cgroup.controllers": "cpuset cpu io memory pids\n",
+		},
+	}
+	ctx := context.WithValue(context.Background(), _const.CTXGatherSubsetKey, []string{FactSubsetNetwork, FactSubsetMounts, FactSubsetCgroup})
+	facts, err := collectFacts(ctx, conn, "", map[string]any{_const.VariableOSType: "Linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]any{_const.VariableOSType: "Linux"}, facts[_const.VariableOS])
+	assert.Equal(t, []string{FactSubsetNetwork, FactSubsetMounts, FactSubsetCgroup}, facts[_const.VariableGatherSubset])
+	// only selected subsets are gathered.
+	assert.NotContains(t, facts, _const.VariableProcess)
+	assert.NotContains(t, conn.executed, "/proc/cpuinfo")
+
+	assert.Equal(t, map[string]any{
+		"interfaces": []map[string]any{
+			{"name": "lo", "mtu": 65536, "state": "UNKNOWN", "type": "loopback", "mac": "00:00:00:00:00:00", "ipv4": []string{"127.0.0.1/8"}, "ipv6": []string{}},
+			{"name": "eth0", "mtu": 1450, "state": "UP", "type": "ether", "mac": "52:54:00:12:34:56", "ipv4": []string{"10.0.0.2/24"}, "ipv6": []string{"fe80::5054:ff:fe12:3456/64"}},
+			{"name": "veth1", "mtu": 1500, "state": "UP", "type": "ether", "mac": "aa:bb:cc:dd:ee:ff", "ipv4": []string{}, "ipv6": []string{}},
+		},
+		"default_ipv4": map[string]any{"gateway": "10.0.0.1", "interface": "eth0", "address": "10.0.0.2"},
+		"default_ipv6": map[string]any{},
+	}, facts[_const.VariableNetwork])
+
+	assert.Equal(t, []map[string]any{
+		{"device": "/dev/sda1", "mount": "/", "fstype": "ext4", "options": "rw,relatime", "size_total": int64(105089261568), "size_available": int64(94278963200)},
+		{"device": "/dev/sdb1", "mount": "/data dir", "fstype": "xfs", "options": "rw,relatime", "size_total": int64(1000000000), "size_available": int64(1000000000)},
+		{"device": "tmpfs", "mount": "/run", "fstype": "tmpfs", "options": "rw,nosuid,nodev"},
+	}, facts[_const.VariableMounts])
+	assert.Equal(t, map[string]any{
+		"enabled": true,
+		"total":   int64(2097148 * 1024),
+		"used":    int64(1024 * 1024),
+		"devices": []map[string]any{{"name": "/swap.img", "type": "file", "size": int64(2097148 * 1024), "used": int64(1024 * 1024)}},
+	}, facts[_const.VariableSwap])
+	assert.Equal(t, map[string]any{
+		"version":     "v2",
+		"controllers": []string{"cpuset", "cpu", "io", "memory", "pids"},
+	}, facts[_const.VariableCgroup])
+}
+
+func TestCollectFactsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), _const.CTXGatherSubsetKey, []string{FactSubsetNetwork}))
+	cancel()
+	_, err := collectFacts(ctx, &fakeFactConnector{}, "", map[string]any{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCoversGatherSubset(t *testing.T) {
+	assert.True(t, coversGatherSubset(map[string]any{_const.VariableGatherSubset: []string{"network", "mounts"}}, []string{"network"}))
+	// from json or yaml cache.
+	assert.True(t, coversGatherSubset(map[string]any{_const.VariableGatherSubset: []any{"network", "mounts"}}, []string{"mounts"}))
+	assert.False(t, coversGatherSubset(map[string]any{_const.VariableGatherSubset: []any{"network"}}, []string{"network", "cgroup"}))
+	// cache without gather_subset.
+	assert.False(t, coversGatherSubset(map[string]any{}, []string{"network"}))
+	assert.True(t, coversGatherSubset(map[string]any{}, []string{}))
+}
+
+func TestParseCgroupV1(t *testing.T) {
+	assert.Equal(t, []string{"cpuset", "memory"}, parseProcCgroups([]byte(`#subsys_name	hierarchy	num_cgroups	enabled
+cpuset	2	1	1
+cpu	3	1	0
+memory	4	100	1
+`)))
+}
+
+func TestParseSELinux(t *testing.T) {
+	config := []byte("SELINUX=enforcing\nSELINUXTYPE=targeted\n")
+	assert.Equal(t, map[string]any{"status": "not_installed"}, parseSELinux(nil, nil))
+	assert.Equal(t, map[string]any{"status": "enabled", "mode": "permissive", "config_mode": "enforcing", "type": "targeted"},
+		parseSELinux([]byte("Permissive\n"), config))
+	assert.Equal(t, map[string]any{"status": "disabled", "mode": "disabled"}, parseSELinux([]byte("Disabled\n"), nil))
+}
+
+func TestParseServiceMgr(t *testing.T) {
+	testcases := []struct {
+		stdout string
+		except string
+	}{
+		{stdout: "systemd\n", except: "systemd"},
+		{stdout: "init\n/sbin/openrc\n", except: "openrc"},
+		{stdout: "init\n/sbin/initctl\n", except: "upstart"},
+		{stdout: "init\n", except: "sysvinit"},
+		{stdout: "tini\n", except: "tini"},
+	}
+	for _, tc := range testcases {
+		assert.Equal(t, tc.except, parseServiceMgr([]byte(tc.stdout)))
+	}
+}
+
+func TestParsePkgMgr(t *testing.T) {
+	assert.Equal(t, "dnf", parsePkgMgr([]byte("/usr/bin/yum\n/usr/bin/dnf\n")))
+	assert.Equal(t, "apt", parsePkgMgr([]byte("/usr/bin/apt-get\n")))
+	assert.Equal(t, "unknown", parsePkgMgr(nil))
+}
+
+func TestParseTimeSync(t *testing.T) {
+	assert.Equal(t, map[string]any{"service": "chronyd", "active": true, "synchronized": true},
+		parseTimeSync([]byte("synchronized=yes\nservice=chronyd\nservice=systemd-timesyncd\n")))
+	assert.Equal(t, map[string]any{"service": "", "active": false, "synchronized": false},
+		parseTimeSync([]byte("synchronized=\n")))
+}
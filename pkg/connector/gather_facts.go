@@ -6,12 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"gopkg.in/yaml.v3"
 	"k8s.io/klog/v2"
 
 	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
 )
 
 const (
@@ -21,37 +23,46 @@ const (
 	gatherFactsCacheYAML = "yamlfile"
 	// gatherFactsCacheMemory indicates that facts should be cached in memory
 	gatherFactsCacheMemory = "memory"
+	// defaultFactCachingTimeout is the default expiration of cached facts, the same as ansible.
+	defaultFactCachingTimeout = 24 * time.Hour
 )
 
 var cache = &memoryCache{
-	cache: make(map[string]map[string]any),
+	cache: make(map[string]factCache),
+}
+
+// factCache is the cached facts of a host, with the time and the connector address when they are gathered.
+type factCache struct {
+	CachedAt time.Time      `json:"cached_at" yaml:"cached_at"`
+	Address  string         `json:"address" yaml:"address"`
+	Facts    map[string]any `json:"facts" yaml:"facts"`
 }
 
 type memoryCache struct {
-	cache      map[string]map[string]any
+	cache      map[string]factCache
 	cacheMutex sync.RWMutex
 }
 
-// Get retrieves cached data for a host (thread-safe).
-func (m *memoryCache) Get(hostname string) (map[string]any, bool) {
+// Get retrieves cached data for a key (thread-safe).
+func (m *memoryCache) Get(key string) (factCache, bool) {
 	m.cacheMutex.RLock()
 	defer m.cacheMutex.RUnlock()
-	data, exists := m.cache[hostname]
+	data, exists := m.cache[key]
 	return data, exists
 }
 
-// Set stores data for a host (thread-safe).
-func (m *memoryCache) Set(hostname string, data map[string]any) {
+// Set stores data for a key (thread-safe).
+func (m *memoryCache) Set(key string, data factCache) {
 	m.cacheMutex.Lock()
 	defer m.cacheMutex.Unlock()
-	m.cache[hostname] = data
+	m.cache[key] = data
 }
 
-// Delete removes the cached data for a specific hostname (thread-safe).
-func (m *memoryCache) Delete(hostname string) {
+// Delete removes the cached data for a specific key (thread-safe).
+func (m *memoryCache) Delete(key string) {
 	m.cacheMutex.Lock()
 	defer m.cacheMutex.Unlock()
-	delete(m.cache, hostname)
+	delete(m.cache, key)
 }
 
 // GatherFacts defines an interface for retrieving host information
@@ -64,49 +75,101 @@ type GatherFacts interface {
 type cacheGatherFact struct {
 	// inventoryName is the name of the host in the inventory
 	inventoryName string
+	// address identifies the connection to the host. The cache is invalid when it changes.
+	address string
 	// cacheType specifies the format to cache facts (json, yaml, or memory)
 	cacheType string
 	// cacheDir is the cache dir in local
 	cacheDir string
+	// timeout is the expiration of cached facts. Zero means never expire.
+	timeout time.Duration
 	// getHostInfoFn is the function that actually gathers host information
 	getHostInfoFn func(context.Context) (map[string]any, error)
 }
 
-// newCacheGatherFact creates a new cacheGatherFact instance
-func newCacheGatherFact(inventoryName, cacheType, workdir string, getHostInfoFn func(context.Context) (map[string]any, error)) *cacheGatherFact {
+// newCacheGatherFact creates a new cacheGatherFact instance. The cache type and timeout are
+// read from "fact_caching" and "fact_caching_timeout" (in seconds) in host variables.
+func newCacheGatherFact(inventoryName, address, workdir string, hostVars map[string]any, getHostInfoFn func(context.Context) (map[string]any, error)) *cacheGatherFact {
+	cacheType, _ := variable.StringVar(nil, hostVars, _const.VariableGatherFactsCache)
+	timeout := defaultFactCachingTimeout
+	if t, err := variable.IntVar(nil, hostVars, _const.VariableGatherFactsCacheTimeout); err == nil {
+		timeout = time.Duration(*t) * time.Second
+	}
+
 	return &cacheGatherFact{
 		inventoryName: inventoryName,
+		address:       address,
 		cacheType:     cacheType,
-		cacheDir:      filepath.Join(workdir, _const.RuntimeDir, _const.RuntimeGatherFactsCacheDir),
+		cacheDir:      factCacheDir(workdir),
+		timeout:       timeout,
 		getHostInfoFn: getHostInfoFn,
 	}
 }
 
+// factCacheDir is the dir of cache files in workdir.
+func factCacheDir(workdir string) string {
+	return filepath.Join(workdir, _const.RuntimeDir, _const.RuntimeGatherFactsCacheDir)
+}
+
+// ClearCachedFacts removes the cached facts of the host in all cache types.
+func ClearCachedFacts(workdir, inventoryName string) error {
+	return clearFactCache(factCacheDir(workdir), inventoryName)
+}
+
+// clearFactCache removes the cache files and memory cache of the host in cacheDir.
+func clearFactCache(cacheDir, inventoryName string) error {
+	cache.Delete(filepath.Join(cacheDir, inventoryName))
+	for _, ext := range []string{".json", ".yaml"} {
+		if err := os.Remove(filepath.Join(cacheDir, inventoryName+ext)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove fact cache of host %q", inventoryName)
+		}
+	}
+
+	return nil
+}
+
 // HostInfo returns host information from cache or fetches it remotely if not cached.
 // The caching behavior depends on the configured cache type (JSON, YAML, or memory).
+// The cache is fetched again when it is expired, the connector address changed, or it does not cover the gather_subset in context.
 func (c *cacheGatherFact) HostInfo(ctx context.Context) (map[string]any, error) {
 	if ctx.Value(_const.CTXSetupForceKey) != nil && ctx.Value(_const.CTXSetupForceKey).(bool) {
-		_ = os.Remove(filepath.Join(c.cacheDir, c.inventoryName+".json"))
-		_ = os.Remove(filepath.Join(c.cacheDir, c.inventoryName+".yaml"))
-		cache.Delete(c.inventoryName)
-		return c.getHostInfoFn(ctx)
+		if err := clearFactCache(c.cacheDir, c.inventoryName); err != nil {
+			klog.V(4).ErrorS(err, "failed to clear fact cache", "host", c.inventoryName)
+		}
+		if c.cacheType == "" {
+			return c.getHostInfoFn(ctx)
+		}
 	}
 	switch c.cacheType {
 	case gatherFactsCacheJSON:
-		return c.handleJSONCache(ctx)
+		return c.handleFileCache(ctx, filepath.Join(c.cacheDir, c.inventoryName+".json"), json.Unmarshal, json.Marshal)
 	case gatherFactsCacheYAML:
-		return c.handleYAMLCache(ctx)
+		return c.handleFileCache(ctx, filepath.Join(c.cacheDir, c.inventoryName+".yaml"), yaml.Unmarshal, yaml.Marshal)
 	case gatherFactsCacheMemory:
 		return c.handleMemoryCache(ctx)
 	default:
 		// fallback: delete possible cache and fetch directly
-		_ = os.Remove(filepath.Join(c.cacheDir, c.inventoryName+".json"))
-		_ = os.Remove(filepath.Join(c.cacheDir, c.inventoryName+".yaml"))
-		cache.Delete(c.inventoryName)
+		_ = clearFactCache(c.cacheDir, c.inventoryName)
 		return c.getHostInfoFn(ctx)
 	}
 }
 
+// valid checks whether the cached facts can be used: not expired, gathered by the same address and covers the gather_subset.
+func (c *cacheGatherFact) valid(ctx context.Context, fc factCache) (bool, string) {
+	switch {
+	case fc.Facts == nil:
+		return false, "cache is empty"
+	case fc.Address != c.address:
+		return false, "connector address changed"
+	case c.timeout > 0 && time.Since(fc.CachedAt) > c.timeout:
+		return false, "cache is expired"
+	case !coversGatherSubset(fc.Facts, gatherSubsetFromContext(ctx)):
+		return false, "cache does not cover gather_subset"
+	default:
+		return true, ""
+	}
+}
+
 // ensureCacheDir ensures the cache directory exists, creating it if necessary
 func (c *cacheGatherFact) ensureCacheDir() error {
 	if _, err := os.Stat(c.cacheDir); err != nil {
@@ -118,36 +181,32 @@ func (c *cacheGatherFact) ensureCacheDir() error {
 	return nil
 }
 
-// handleJSONCache handles caching host information in JSON format.
+// handleFileCache handles caching host information in file.
 // It attempts to read from the cache file first, falling back to remote fetch if needed.
-func (c *cacheGatherFact) handleJSONCache(ctx context.Context) (map[string]any, error) {
+// unmarshalFn and marshalFn specify the format of the file (JSON or YAML).
+func (c *cacheGatherFact) handleFileCache(
+	ctx context.Context,
+	filename string,
+	unmarshalFn func([]byte, any) error,
+	marshalFn func(any) ([]byte, error),
+) (map[string]any, error) {
 	if err := c.ensureCacheDir(); err != nil {
-		return nil, errors.Wrapf(err, "json cache dir error for host %q", c.inventoryName)
+		return nil, errors.Wrapf(err, "cache dir error for host %q", c.inventoryName)
 	}
-	filename := filepath.Join(c.cacheDir, c.inventoryName+".json")
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		klog.V(4).InfoS("json cache miss. fetching remotely.", "filename", filename)
-		return c.fetchAndCache(ctx, filename, json.Marshal)
+		klog.V(4).InfoS("fact cache miss. fetching remotely.", "filename", filename)
+		return c.fetchAndCache(ctx, filename, marshalFn)
 	}
-	var result map[string]any
-	return result, json.Unmarshal(data, &result)
-}
-
-// handleYAMLCache handles caching host information in YAML format.
-// It attempts to read from the cache file first, falling back to remote fetch if needed.
-func (c *cacheGatherFact) handleYAMLCache(ctx context.Context) (map[string]any, error) {
-	if err := c.ensureCacheDir(); err != nil {
-		return nil, errors.Wrapf(err, "yaml cache dir error for host %q", c.inventoryName)
+	var fc factCache
+	if err := unmarshalFn(data, &fc); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal fact cache %q", filename)
 	}
-	filename := filepath.Join(c.cacheDir, c.inventoryName+".yaml")
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		klog.V(4).InfoS("yaml cache miss. fetching remotely.", "filename", filename)
-		return c.fetchAndCache(ctx, filename, yaml.Marshal)
+	if ok, reason := c.valid(ctx, fc); !ok {
+		klog.V(4).InfoS("fact cache is invalid. fetching remotely.", "filename", filename, "reason", reason)
+		return c.fetchAndCache(ctx, filename, marshalFn)
 	}
-	var result map[string]any
-	return result, yaml.Unmarshal(data, &result)
+	return fc.Facts, nil
 }
 
 // fetchAndCache fetches host information remotely and caches it to a file.
@@ -161,7 +220,7 @@ func (c *cacheGatherFact) fetchAndCache(
 	if err != nil {
 		return nil, err
 	}
-	data, err := marshalFn(hostInfo)
+	data, err := marshalFn(factCache{CachedAt: time.Now(), Address: c.address, Facts: hostInfo})
 	if err != nil {
 		return nil, err
 	}
@@ -170,14 +229,20 @@ func (c *cacheGatherFact) fetchAndCache(
 
 // handleMemoryCache handles caching host information in memory.
 // It checks the in-memory cache first, falling back to remote fetch if needed.
+// The memory cache is keyed by the cache dir and host, so the hosts with the same name in different workdir do not conflict.
 func (c *cacheGatherFact) handleMemoryCache(ctx context.Context) (map[string]any, error) {
-	if cached, exists := cache.Get(c.inventoryName); exists {
-		return cached, nil
+	key := filepath.Join(c.cacheDir, c.inventoryName)
+	if cached, exists := cache.Get(key); exists {
+		ok, reason := c.valid(ctx, cached)
+		if ok {
+			return cached.Facts, nil
+		}
+		klog.V(4).InfoS("fact cache is invalid. fetching remotely.", "host", c.inventoryName, "reason", reason)
 	}
 	hostInfo, err := c.getHostInfoFn(ctx)
 	if err != nil {
 		return nil, err
 	}
-	cache.Set(c.inventoryName, hostInfo)
+	cache.Set(key, factCache{CachedAt: time.Now(), Address: c.address, Facts: hostInfo})
 	return hostInfo, nil
 }
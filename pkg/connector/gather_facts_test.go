@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+// countingHostInfo returns the facts with the count of gathering.
+func countingHostInfo(count *int) func(context.Context) (map[string]any, error) {
+	return func(ctx context.Context) (map[string]any, error) {
+		*count++
+
+		return map[string]any{
+			"count":                     *count,
+			_const.VariableGatherSubset: gatherSubsetFromContext(ctx),
+		}, nil
+	}
+}
+
+// writeJSONCache overwrites the json cache file.
+func writeJSONCache(filename string, fc factCache) error {
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, _const.PermFilePublic)
+}
+
+func TestCacheGatherFact(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cacheType string
+	}{
+		{name: "json", cacheType: gatherFactsCacheJSON},
+		{name: "yaml", cacheType: gatherFactsCacheYAML},
+		{name: "memory", cacheType: gatherFactsCacheMemory},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			workdir := t.TempDir()
+			var count int
+			hostVars := map[string]any{_const.VariableGatherFactsCache: tc.cacheType}
+			gf := newCacheGatherFact("node1", "root@10.0.0.1:22", workdir, hostVars, countingHostInfo(&count))
+			assert.Equal(t, defaultFactCachingTimeout, gf.timeout)
+
+			// cache miss
+			_, err := gf.HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 1, count)
+			// cache hit
+			_, err = gf.HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 1, count)
+			// the host with the same name in other workdir does not share the cache.
+			_, err = newCacheGatherFact("node1", "root@10.0.0.1:22", t.TempDir(), hostVars, countingHostInfo(&count)).HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 2, count)
+			// address changed
+			_, err = newCacheGatherFact("node1", "root@10.0.0.2:22", workdir, hostVars, countingHostInfo(&count)).HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+			// cached by the new address
+			_, err = newCacheGatherFact("node1", "root@10.0.0.2:22", workdir, hostVars, countingHostInfo(&count)).HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+			// force
+			_, err = newCacheGatherFact("node1", "root@10.0.0.2:22", workdir, hostVars, countingHostInfo(&count)).
+				HostInfo(context.WithValue(context.TODO(), _const.CTXSetupForceKey, true))
+			assert.NoError(t, err)
+			assert.Equal(t, 4, count)
+			// cleared
+			assert.NoError(t, ClearCachedFacts(workdir, "node1"))
+			facts, err := newCacheGatherFact("node1", "root@10.0.0.2:22", workdir, hostVars, countingHostInfo(&count)).HostInfo(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, 5, count)
+			assert.EqualValues(t, 5, facts["count"])
+		})
+	}
+}
+
+func TestCacheGatherFactTimeout(t *testing.T) {
+	workdir := t.TempDir()
+	var count int
+	gf := newCacheGatherFact("node1", "local", workdir, map[string]any{
+		_const.VariableGatherFactsCache:        gatherFactsCacheJSON,
+		_const.VariableGatherFactsCacheTimeout: 60,
+	}, countingHostInfo(&count))
+	assert.Equal(t, time.Minute, gf.timeout)
+	_, err := gf.HostInfo(context.TODO())
+	assert.NoError(t, err)
+
+	// expire the cache
+	filename := filepath.Join(factCacheDir(workdir), "node1.json")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc factCache
+	assert.NoError(t, json.Unmarshal(data, &fc))
+	assert.Equal(t, "local", fc.Address)
+	fc.CachedAt = time.Now().Add(-2 * time.Minute)
+	assert.NoError(t, writeJSONCache(filename, fc))
+	_, err = gf.HostInfo(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// never expire
+	gf.timeout = 0
+	fc.CachedAt = time.Now().Add(-365 * 24 * time.Hour)
+	assert.NoError(t, writeJSONCache(filename, fc))
+	_, err = gf.HostInfo(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCacheGatherFactLegacyCache(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.MkdirAll(factCacheDir(workdir), _const.PermDirPublic); err != nil {
+		t.Fatal(err)
+	}
+	// the facts cached without metadata by old version.
+	if err := os.WriteFile(filepath.Join(factCacheDir(workdir), "node1.json"), []byte(`{"os":{}}`), _const.PermFilePublic); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	gf := newCacheGatherFact("node1", "local", workdir, map[string]any{_const.VariableGatherFactsCache: gatherFactsCacheJSON}, countingHostInfo(&count))
+	_, err := gf.HostInfo(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -49,7 +49,7 @@ func gpuInfoFromLspci(ctx context.Context, workdir string, conn Connector) (GPUI
 		return gpu, err
 	}
 
-	stdout, stderr, err := conn.ExecuteCommand(ctx, "lspci -mm -nn")
+	stdout, stderr, err := conn.ExecuteCommand(ctx, factCmdLspci)
 	if err != nil {
 		return gpu, err
 	}
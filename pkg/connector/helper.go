@@ -29,7 +29,7 @@ import (
 
 // blockDevicesFromLsblk runs lsblk on the target host and returns parsed block device trees.
 func blockDevicesFromLsblk(ctx context.Context, conn Connector) (any, error) {
-	stdout, stderr, err := conn.ExecuteCommand(ctx, "lsblk -J -b -o NAME,SIZE,TYPE,MOUNTPOINT,FSTYPE,MODEL")
+	stdout, stderr, err := conn.ExecuteCommand(ctx, factCmdLsblk)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to run lsblk: stderr: %q", string(stderr))
 	}
@@ -38,7 +38,7 @@ func blockDevicesFromLsblk(ctx context.Context, conn Connector) (any, error) {
 		return nil, err
 	}
 
-	lvsStdout, _, err := conn.ExecuteCommand(ctx, "lvs --reportformat json -o lv_name,vg_name,lv_path,lv_dm_path 2>/dev/null")
+	lvsStdout, _, err := conn.ExecuteCommand(ctx, factCmdLvs)
 	if err != nil {
 		return devices, nil
 	}
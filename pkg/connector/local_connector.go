@@ -45,7 +45,7 @@ func getLocalUser() string {
 	return user.Username
 }
 
-func newLocalConnector(workdir string, hostVars map[string]any) *localConnector {
+func newLocalConnector(workdir, host string, hostVars map[string]any) *localConnector {
 	user, err := variable.StringVar(nil, hostVars, _const.VariableConnector, _const.VariableConnectorUser)
 	if err != nil {
 		klog.V(4).Info("Warning: Failed to obtain local connector user when executing command with sudo. Please ensure the 'kk' process is run by a root-privileged user.")
@@ -55,7 +55,6 @@ func newLocalConnector(workdir string, hostVars map[string]any) *localConnector
 	if err != nil { // password is not necessary when execute with root user.
 		klog.V(4).Info("Warning: Failed to obtain local connector password when executing command with sudo. Please ensure the 'kk' process is run by a root-privileged user.")
 	}
-	cacheType, _ := variable.StringVar(nil, hostVars, _const.VariableGatherFactsCache)
 	connector := &localConnector{
 		workdir:  workdir,
 		User:     user,
@@ -63,7 +62,7 @@ func newLocalConnector(workdir string, hostVars map[string]any) *localConnector
 		Cmd:      exec.New(),
 	}
 	// Initialize the cacheGatherFact with a function that will call getHostInfoFromRemote
-	connector.gatherFacts = newCacheGatherFact(_const.VariableLocalHost, cacheType, workdir, connector.getHostInfo)
+	connector.gatherFacts = newCacheGatherFact(host, connectedLocal, workdir, hostVars, connector.getHostInfo)
 
 	return connector
 }
@@ -157,63 +156,35 @@ func (c *localConnector) HostInfo(ctx context.Context) (map[string]any, error) {
 func (c *localConnector) getHostInfo(ctx context.Context) (map[string]any, error) {
 	switch runtime.GOOS {
 	case "linux":
+		// gather all facts by the facts script in one round trip, fallback to execute each command.
+		conn := newFactScriptConnector(ctx, c, gatherSubsetFromContext(ctx))
 		// os information
 		osVars := make(map[string]any)
 		osVars[_const.VariableOSType] = "Linux"
 		var osRelease bytes.Buffer
-		if err := c.FetchFile(ctx, "/etc/os-release", &osRelease); err != nil {
+		if err := conn.FetchFile(ctx, factFileOSRelease, &osRelease); err != nil {
 			return nil, err
 		}
 		osVars[_const.VariableOSRelease] = convertBytesToMap(osRelease.Bytes(), "=")
-		kernel, stderr, err := c.ExecuteCommand(ctx, "uname -r")
+		kernel, stderr, err := conn.ExecuteCommand(ctx, factCmdKernelVersion)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get kernel: %v, stderr: %q", err, string(stderr))
 		}
 		osVars[_const.VariableOSKernelVersion] = string(bytes.TrimSpace(kernel))
 
-		hn, hnStderr, err := c.ExecuteCommand(ctx, "hostname")
+		hn, hnStderr, err := conn.ExecuteCommand(ctx, factCmdHostname)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get hostname: %v, stderr: %q", err, string(hnStderr))
 		}
 		osVars[_const.VariableOSHostName] = string(bytes.TrimSpace(hn))
 
-		arch, archStderr, err := c.ExecuteCommand(ctx, "arch")
+		arch, archStderr, err := conn.ExecuteCommand(ctx, factCmdArch)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get arch: %v, stderr: %q", err, string(archStderr))
 		}
 		osVars[_const.VariableOSArchitecture] = string(bytes.TrimSpace(arch))
 
-		// process information
-		procVars := make(map[string]any)
-		var cpu bytes.Buffer
-		if err := c.FetchFile(ctx, "/proc/cpuinfo", &cpu); err != nil {
-			return nil, err
-		}
-		procVars[_const.VariableProcessCPU] = convertBytesToSlice(cpu.Bytes(), ":")
-		var mem bytes.Buffer
-		if err := c.FetchFile(ctx, "/proc/meminfo", &mem); err != nil {
-			return nil, err
-		}
-		procVars[_const.VariableProcessMemory] = convertBytesToMap(mem.Bytes(), ":")
-
-		// block devices
-		blockdevicesVars, blockErr := blockDevicesFromLsblk(ctx, c)
-		if blockErr != nil {
-			klog.V(4).ErrorS(blockErr, "skip block device gathering")
-		}
-
-		// gpu
-		gpuVars, gpuErr := gpuInfoFromLspci(ctx, c.workdir, c)
-		if gpuErr != nil {
-			klog.V(4).ErrorS(gpuErr, "skip gpu gathering")
-		}
-
-		return map[string]any{
-			_const.VariableOS:           osVars,
-			_const.VariableProcess:      procVars,
-			_const.VariableBlockDevices: blockdevicesVars,
-			_const.VariableGPU:          gpuVars,
-		}, nil
+		return collectFacts(ctx, conn, c.workdir, osVars)
 	default:
 		klog.V(4).ErrorS(nil, "Unsupported platform", "platform", runtime.GOOS)
 		// os information
@@ -23,8 +23,10 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -81,7 +83,6 @@ func newSSHConnector(workdir, host string, hostVars map[string]any) *sshConnecto
 		klog.V(4).InfoS("ssh private key content is empty")
 		// Leave keycontentParam as empty string - no default needed
 	}
-	cacheType, _ := variable.StringVar(nil, hostVars, _const.VariableGatherFactsCache)
 	connector := &sshConnector{
 		workdir:               workdir,
 		Host:                  hostParam,
@@ -94,7 +95,7 @@ func newSSHConnector(workdir, host string, hostVars map[string]any) *sshConnecto
 	}
 
 	// Initialize the cacheGatherFact with a function that will call getHostInfoFromRemote
-	connector.gatherFacts = newCacheGatherFact(host, cacheType, workdir, connector.getHostInfo)
+	connector.gatherFacts = newCacheGatherFact(host, connector.address(), workdir, hostVars, connector.getHostInfo)
 
 	return connector
 }
@@ -118,6 +119,11 @@ type sshConnector struct {
 	mu sync.Mutex
 }
 
+// address identifies the ssh connection, such as "root@192.168.1.1:22".
+func (c *sshConnector) address() string {
+	return fmt.Sprintf("%s@%s", c.User, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)))
+}
+
 // Init establishes SSH connection with the following authentication priority:
 // - Password: Always included if set (independent)
 // - Key auth (exclusive priority):
@@ -389,65 +395,37 @@ func (c *sshConnector) HostInfo(ctx context.Context) (map[string]any, error) {
 
 // getHostInfo from remote
 func (c *sshConnector) getHostInfo(ctx context.Context) (map[string]any, error) {
+	// gather all facts by the facts script in one round trip, fallback to execute each command.
+	conn := newFactScriptConnector(ctx, c, gatherSubsetFromContext(ctx))
 	// os information
 	osVars := make(map[string]any)
-	osType, osTypeStderr, err := c.ExecuteCommand(ctx, "uname -s")
+	osType, osTypeStderr, err := conn.ExecuteCommand(ctx, factCmdOSType)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get os type: %v, stderr: %q", err, string(osTypeStderr))
 	}
 	osVars[_const.VariableOSType] = string(bytes.TrimSpace(osType))
 	var osRelease bytes.Buffer
-	if err := c.FetchFile(ctx, "/etc/os-release", &osRelease); err != nil {
+	if err := conn.FetchFile(ctx, factFileOSRelease, &osRelease); err != nil {
 		return nil, err
 	}
 	osVars[_const.VariableOSRelease] = convertBytesToMap(osRelease.Bytes(), "=")
-	kernel, kernelStderr, err := c.ExecuteCommand(ctx, "uname -r")
+	kernel, kernelStderr, err := conn.ExecuteCommand(ctx, factCmdKernelVersion)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get kernel: %v, stderr: %q", err, string(kernelStderr))
 	}
 	osVars[_const.VariableOSKernelVersion] = string(bytes.TrimSpace(kernel))
 
-	hn, hnStderr, err := c.ExecuteCommand(ctx, "hostname")
+	hn, hnStderr, err := conn.ExecuteCommand(ctx, factCmdHostname)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get hostname: %v, stderr: %q", err, string(hnStderr))
 	}
 	osVars[_const.VariableOSHostName] = string(bytes.TrimSpace(hn))
 
-	arch, archStderr, err := c.ExecuteCommand(ctx, "arch")
+	arch, archStderr, err := conn.ExecuteCommand(ctx, factCmdArch)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get arch: %v, stderr: %q", err, string(archStderr))
 	}
 	osVars[_const.VariableOSArchitecture] = string(bytes.TrimSpace(arch))
 
-	// process information
-	procVars := make(map[string]any)
-	var cpu bytes.Buffer
-	if err := c.FetchFile(ctx, "/proc/cpuinfo", &cpu); err != nil {
-		return nil, err
-	}
-	procVars[_const.VariableProcessCPU] = convertBytesToSlice(cpu.Bytes(), ":")
-	var mem bytes.Buffer
-	if err := c.FetchFile(ctx, "/proc/meminfo", &mem); err != nil {
-		return nil, err
-	}
-	procVars[_const.VariableProcessMemory] = convertBytesToMap(mem.Bytes(), ":")
-
-	// block devices
-	blockdevicesVars, blockErr := blockDevicesFromLsblk(ctx, c)
-	if blockErr != nil {
-		klog.V(4).ErrorS(blockErr, "skip block device gathering")
-	}
-
-	// gpu
-	gpuVars, gpuErr := gpuInfoFromLspci(ctx, c.workdir, c)
-	if gpuErr != nil {
-		klog.V(4).ErrorS(gpuErr, "skip gpu gathering")
-	}
-
-	return map[string]any{
-		_const.VariableOS:           osVars,
-		_const.VariableProcess:      procVars,
-		_const.VariableBlockDevices: blockdevicesVars,
-		_const.VariableGPU:          gpuVars,
-	}, nil
+	return collectFacts(ctx, conn, c.workdir, osVars)
 }
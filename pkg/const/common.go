@@ -54,6 +54,8 @@ const ( // === From Global Parameter ===
 	VariableConnectorToken = "token"
 	// VariableGatherFactsCache type in runtimedir. support jsonfile, yamlfile, memory.
 	VariableGatherFactsCache = "fact_caching"
+	// VariableGatherFactsCacheTimeout is the expiration in seconds of cached facts. default 86400, 0 means never expire.
+	VariableGatherFactsCacheTimeout = "fact_caching_timeout"
 )
 
 const ( // === From system generate ===
@@ -93,6 +95,26 @@ const ( // === From GatherFact ===
 	VariableBlockDevices = "blockdevices"
 	// VariableGPU the value is GPU info gathered via lspci.
 	VariableGPU = "gpu"
+	// VariableNetwork the value is network interfaces and default routes.
+	VariableNetwork = "network"
+	// VariableMounts the value is mounted filesystems.
+	VariableMounts = "mounts"
+	// VariableSwap the value is swap devices.
+	VariableSwap = "swap"
+	// VariableCgroup the value is cgroup version and controllers.
+	VariableCgroup = "cgroup"
+	// VariableSELinux the value is SELinux status.
+	VariableSELinux = "selinux"
+	// VariableAppArmor the value is AppArmor status.
+	VariableAppArmor = "apparmor"
+	// VariableServiceMgr the value is the init system. such as systemd, openrc.
+	VariableServiceMgr = "service_mgr"
+	// VariablePkgMgr the value is the package manager. such as yum, dnf, apt, zypper.
+	VariablePkgMgr = "pkg_mgr"
+	// VariableTimeSync the value is time synchronization status.
+	VariableTimeSync = "time_sync"
+	// VariableGatherSubset the value is the fact subsets which have been gathered.
+	VariableGatherSubset = "gather_subset"
 )
 
 const ( // === From runtime ===
@@ -145,4 +167,6 @@ type contextKey string
 const (
 	// CTXSetupForceKey is the context key for force flag in setup module
 	CTXSetupForceKey contextKey = "force"
+	// CTXGatherSubsetKey is the context key for gather_subset in setup module
+	CTXGatherSubsetKey contextKey = "gather_subset"
 )
@@ -0,0 +1,456 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/kubesphere/kubekey/v4/cmd/controller-manager/app/options"
+	"github.com/kubesphere/kubekey/v4/pkg/controllers/util"
+)
+
+const (
+	// certsCheckPlaybook checks the expiration of certificates, and records the earliest expiry of each host
+	// in the playbook result.
+	certsCheckPlaybook = "playbooks/certs_check_expiration.yaml"
+	// certsRenewPlaybook renews the certificates of the hosts in limit.
+	certsRenewPlaybook = "playbooks/certs_renew.yaml"
+	// certsExpirationResult is the key of playbook result which stores the earliest expiry of each host.
+	certsExpirationResult = "certs_expiration"
+
+	defaultCertsRenewBefore   = 30 * 24 * time.Hour
+	defaultCertsCheckInterval = 24 * time.Hour
+)
+
+// CertRotationReconciler rotates the certificates of inventory hosts by the policy in inventory.spec.certRotation.
+// It runs the expiry-check playbook every check interval. When the certificates of some hosts expire within the
+// renew-before window, it renews them by the renewal playbook in the maintenance window, one batch of hosts after
+// another, and runs the expiry-check playbook again after each batch.
+type CertRotationReconciler struct {
+	ctrlclient.Client
+	events.EventRecorder
+
+	clock clock.PassiveClock
+}
+
+var _ options.Controller = &CertRotationReconciler{}
+var _ reconcile.Reconciler = &CertRotationReconciler{}
+
+// Name implements controllers.controller.
+func (r *CertRotationReconciler) Name() string {
+	return "certrotation-reconciler"
+}
+
+// SetupWithManager implements controllers.controller.
+func (r *CertRotationReconciler) SetupWithManager(mgr manager.Manager, o options.ControllerManagerServerOptions) error {
+	r.Client = mgr.GetClient()
+	r.EventRecorder = mgr.GetEventRecorder(r.Name())
+	r.clock = clock.RealClock{}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(r.Name()).
+		WithOptions(ctrlcontroller.Options{
+			MaxConcurrentReconciles: o.MaxConcurrentReconciles,
+		}).
+		// only inventories with rotation policy.
+		For(&kkcorev1.Inventory{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj ctrlclient.Object) bool {
+			inventory, ok := obj.(*kkcorev1.Inventory)
+
+			return ok && inventory.Spec.CertRotation != nil
+		}))).
+		// Watches playbook to sync the rotation progress.
+		Watches(&kkcorev1.Playbook{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+			inventory := &kkcorev1.Inventory{}
+			if err := util.GetOwnerFromObject(ctx, r.Client, obj, inventory); err == nil {
+				return []ctrl.Request{{NamespacedName: ctrlclient.ObjectKeyFromObject(inventory)}}
+			}
+
+			return nil
+		})).
+		Complete(r)
+}
+
+// Reconcile implements controllers.controller.
+func (r *CertRotationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, retErr error) {
+	inventory := &kkcorev1.Inventory{}
+	if err := r.Get(ctx, req.NamespacedName, inventory); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get inventory %q", req.String())
+		}
+
+		return ctrl.Result{}, nil
+	}
+	if inventory.Spec.CertRotation == nil || !inventory.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	helper, err := patch.NewHelper(inventory, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.WithStack(err)
+	}
+	defer func() {
+		if err := helper.Patch(ctx, inventory); err != nil {
+			retErr = errors.Join(retErr, errors.WithStack(err))
+		}
+	}()
+
+	return r.reconcileNormal(ctx, inventory)
+}
+
+func (r *CertRotationReconciler) reconcileNormal(ctx context.Context, inventory *kkcorev1.Inventory) (ctrl.Result, error) {
+	policy := inventory.Spec.CertRotation
+	now := r.clock.Now()
+
+	// sync the renewal playbook of the current batch.
+	renew, err := r.getAnnotatedPlaybook(ctx, inventory, kkcorev1.CertsRenewPlaybookAnnotation)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if renew != nil {
+		switch renew.Status.Phase {
+		case kkcorev1.PlaybookPhaseSucceeded:
+			// check again to find the hosts which are still expiring.
+			if err := r.Delete(ctx, renew); err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, errors.Wrapf(err, "failed to delete playbook %q", ctrlclient.ObjectKeyFromObject(renew))
+			}
+			delete(inventory.Annotations, kkcorev1.CertsRenewPlaybookAnnotation)
+
+			return ctrl.Result{}, r.createPlaybook(ctx, inventory, kkcorev1.CertsCheckPlaybookAnnotation, certsCheckPlaybook, nil)
+		case kkcorev1.PlaybookPhaseFailed:
+			meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+				Type:    kkcorev1.InventoryCertsRotatedCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  kkcorev1.InventoryCertsRotateFailedReason,
+				Message: fmt.Sprintf("renewal playbook %q failed: %s", renew.Name, renew.Status.FailureMessage),
+			})
+		default:
+			// waiting for the renewal playbook.
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// sync the expiry-check playbook.
+	check, err := r.getAnnotatedPlaybook(ctx, inventory, kkcorev1.CertsCheckPlaybookAnnotation)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if check == nil {
+		return ctrl.Result{}, r.createPlaybook(ctx, inventory, kkcorev1.CertsCheckPlaybookAnnotation, certsCheckPlaybook, nil)
+	}
+	nextCheck := check.CreationTimestamp.Add(durationOrDefault(policy.CheckInterval, defaultCertsCheckInterval))
+	switch check.Status.Phase {
+	case kkcorev1.PlaybookPhaseSucceeded:
+		renewing, waitWindow, err := r.syncExpiration(ctx, inventory, check, renew, now)
+		if err != nil || renewing {
+			return ctrl.Result{}, err
+		}
+		if waitWindow > 0 && now.Add(waitWindow).Before(nextCheck) {
+			return ctrl.Result{RequeueAfter: waitWindow}, nil
+		}
+	case kkcorev1.PlaybookPhaseFailed:
+		meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+			Type:    kkcorev1.InventoryCertsCheckedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  kkcorev1.InventoryCertsCheckFailedReason,
+			Message: fmt.Sprintf("expiry-check playbook %q failed: %s", check.Name, check.Status.FailureMessage),
+		})
+	default:
+		// waiting for the expiry-check playbook.
+		return ctrl.Result{}, nil
+	}
+	if !now.Before(nextCheck) {
+		return ctrl.Result{}, r.createPlaybook(ctx, inventory, kkcorev1.CertsCheckPlaybookAnnotation, certsCheckPlaybook, nil)
+	}
+
+	return ctrl.Result{RequeueAfter: nextCheck.Sub(now)}, nil
+}
+
+// syncExpiration sets the conditions by the result of the succeeded expiry-check playbook, and creates the renewal
+// playbook of the next batch when it is in the maintenance window. It reports whether the renewal playbook is created,
+// and returns the duration to the next maintenance window when the renewal is waiting for it.
+func (r *CertRotationReconciler) syncExpiration(ctx context.Context, inventory *kkcorev1.Inventory, check, renew *kkcorev1.Playbook, now time.Time) (bool, time.Duration, error) {
+	policy := inventory.Spec.CertRotation
+	renewBefore := durationOrDefault(policy.RenewBefore, defaultCertsRenewBefore)
+	expirations, err := certsExpirations(check)
+	if err != nil {
+		return false, 0, err
+	}
+	var expiring []string
+	for _, e := range expirations {
+		if e.notAfter.Before(now.Add(renewBefore)) {
+			expiring = append(expiring, e.host)
+		}
+	}
+	if len(expiring) == 0 {
+		message := "no certificate is found"
+		if len(expirations) > 0 {
+			message = fmt.Sprintf("the earliest certificate %s on host %q expires at %s", expirations[0].path, expirations[0].host, expirations[0].notAfter.UTC().Format(time.RFC3339))
+		}
+		meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+			Type:    kkcorev1.InventoryCertsCheckedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  kkcorev1.InventoryCertsValidReason,
+			Message: message,
+		})
+		if cond := meta.FindStatusCondition(inventory.Status.Conditions, kkcorev1.InventoryCertsRotatedCondition); cond != nil && cond.Reason == kkcorev1.InventoryCertsRotatingReason {
+			meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+				Type:    kkcorev1.InventoryCertsRotatedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  kkcorev1.InventoryCertsRotatedReason,
+				Message: "the certificates of all hosts are renewed",
+			})
+			r.Eventf(inventory, check, corev1.EventTypeNormal, kkcorev1.InventoryCertsRotatedReason, "RotateCertificates", "the certificates of all hosts are renewed")
+		}
+
+		return false, 0, nil
+	}
+	meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+		Type:    kkcorev1.InventoryCertsCheckedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  kkcorev1.InventoryCertsExpiringReason,
+		Message: fmt.Sprintf("the certificates of hosts %v expire within %s", expiring, renewBefore),
+	})
+	// the renewal failed after this check. retry after the next check.
+	if renew != nil && renew.Status.Phase == kkcorev1.PlaybookPhaseFailed && !renew.CreationTimestamp.Before(&check.CreationTimestamp) {
+		return false, 0, nil
+	}
+	inWindow, next, err := maintenanceWindow(policy.MaintenanceWindow, now)
+	if err != nil {
+		return false, 0, err
+	}
+	if !inWindow {
+		meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+			Type:    kkcorev1.InventoryCertsRotatedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  kkcorev1.InventoryCertsWaitingReason,
+			Message: fmt.Sprintf("waiting for the maintenance window at %s to renew hosts %v", next.UTC().Format(time.RFC3339), expiring),
+		})
+
+		return false, next.Sub(now), nil
+	}
+	batch := expiring[:min(max(policy.BatchSize, 1), len(expiring))]
+	if renew != nil {
+		if err := r.Delete(ctx, renew); err != nil && !apierrors.IsNotFound(err) {
+			return false, 0, errors.Wrapf(err, "failed to delete playbook %q", ctrlclient.ObjectKeyFromObject(renew))
+		}
+	}
+	if err := r.createPlaybook(ctx, inventory, kkcorev1.CertsRenewPlaybookAnnotation, certsRenewPlaybook, batch); err != nil {
+		return false, 0, err
+	}
+	meta.SetStatusCondition(&inventory.Status.Conditions, metav1.Condition{
+		Type:    kkcorev1.InventoryCertsRotatedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  kkcorev1.InventoryCertsRotatingReason,
+		Message: fmt.Sprintf("renewing hosts %v by playbook %q, %d hosts remaining", batch, inventory.Annotations[kkcorev1.CertsRenewPlaybookAnnotation], len(expiring)-len(batch)),
+	})
+	r.Eventf(inventory, nil, corev1.EventTypeNormal, kkcorev1.InventoryCertsRotatingReason, "RotateCertificates", "renewing hosts %v", batch)
+
+	return true, 0, nil
+}
+
+// getAnnotatedPlaybook gets the playbook whose name is stored in the annotation of inventory.
+// It returns nil when the annotation is empty or the playbook is not found.
+func (r *CertRotationReconciler) getAnnotatedPlaybook(ctx context.Context, inventory *kkcorev1.Inventory, annotation string) (*kkcorev1.Playbook, error) {
+	name := inventory.Annotations[annotation]
+	if name == "" {
+		return nil, nil
+	}
+	playbook := &kkcorev1.Playbook{}
+	if err := r.Get(ctx, ctrlclient.ObjectKey{Namespace: inventory.Namespace, Name: name}, playbook); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get playbook with inventory %q annotation %q", ctrlclient.ObjectKeyFromObject(inventory), annotation)
+		}
+
+		return nil, nil
+	}
+
+	return playbook, nil
+}
+
+// createPlaybook creates the playbook by the rotation policy, and stores its name in the annotation of inventory.
+// The previous playbook in the annotation is deleted.
+func (r *CertRotationReconciler) createPlaybook(ctx context.Context, inventory *kkcorev1.Inventory, annotation, playbookPath string, limit []string) error {
+	if name := inventory.Annotations[annotation]; name != "" {
+		if err := r.Delete(ctx, &kkcorev1.Playbook{ObjectMeta: metav1.ObjectMeta{Namespace: inventory.Namespace, Name: name}}); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete playbook %q", name)
+		}
+	}
+	policy := inventory.Spec.CertRotation
+	playbook := &kkcorev1.Playbook{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: inventory.Name + "-" + strings.TrimSuffix(strings.ReplaceAll(strings.TrimPrefix(playbookPath, "playbooks/"), "_", "-"), ".yaml") + "-",
+			Namespace:    inventory.Namespace,
+		},
+		Spec: kkcorev1.PlaybookSpec{
+			Project:            policy.Project,
+			Playbook:           playbookPath,
+			InventoryRef:       util.ObjectRef(r.Client, inventory),
+			Config:             kkcorev1.Config{Spec: *policy.Config.DeepCopy()},
+			Limit:              limit,
+			Volumes:            policy.Volumes,
+			VolumeMounts:       policy.VolumeMounts,
+			ServiceAccountName: policy.ServiceAccountName,
+		},
+	}
+	if playbookPath == certsRenewPlaybook {
+		playbook.Spec.Tags = []string{"certs"}
+	}
+	if policy.Project.Addr == "" {
+		playbook.Annotations = map[string]string{kkcorev1.BuiltinsProjectAnnotation: ""}
+	}
+	if err := ctrl.SetControllerReference(inventory, playbook, r.Scheme()); err != nil {
+		return errors.Wrapf(err, "failed to set ownerReference of inventory %q to playbook", ctrlclient.ObjectKeyFromObject(inventory))
+	}
+	if err := r.Create(ctx, playbook); err != nil {
+		return errors.Wrapf(err, "failed to create playbook use inventory %q", ctrlclient.ObjectKeyFromObject(inventory))
+	}
+	if inventory.Annotations == nil {
+		inventory.Annotations = make(map[string]string)
+	}
+	inventory.Annotations[annotation] = playbook.Name
+
+	return nil
+}
+
+// hostCertsExpiration is the earliest expiry of the certificates on a host.
+type hostCertsExpiration struct {
+	host     string
+	path     string
+	notAfter time.Time
+}
+
+// certsExpirations returns the earliest expiry of each host in the result of the expiry-check playbook,
+// sorted by the expiry. Hosts without certificates are ignored.
+func certsExpirations(playbook *kkcorev1.Playbook) ([]hostCertsExpiration, error) {
+	if len(playbook.Status.Result.Raw) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]map[string]json.RawMessage)
+	if err := json.Unmarshal(playbook.Status.Result.Raw, &result); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal result of playbook %q", ctrlclient.ObjectKeyFromObject(playbook))
+	}
+	expirations := make([]hostCertsExpiration, 0, len(result[certsExpirationResult]))
+	for host, raw := range result[certsExpirationResult] {
+		var expiry struct {
+			NotAfter int64  `json:"notAfter"`
+			Path     string `json:"path"`
+		}
+		// the host has no certificate when it is not a json object.
+		if err := json.Unmarshal(raw, &expiry); err != nil || expiry.NotAfter == 0 {
+			continue
+		}
+		expirations = append(expirations, hostCertsExpiration{host: host, path: expiry.Path, notAfter: time.Unix(expiry.NotAfter, 0)})
+	}
+	slices.SortFunc(expirations, func(a, b hostCertsExpiration) int {
+		if c := a.notAfter.Compare(b.notAfter); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.host, b.host)
+	})
+
+	return expirations, nil
+}
+
+// maintenanceWindow reports whether now is in the maintenance window. When it is not, it returns the start of
+// the next window. A nil window means any time.
+func maintenanceWindow(window *kkcorev1.InventoryMaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	if window == nil {
+		return true, now, nil
+	}
+	loc := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(window.TimeZone); err != nil {
+			return false, now, errors.Wrapf(err, "invalid maintenance window timeZone %q", window.TimeZone)
+		}
+	}
+	start, err := time.ParseInLocation("15:04", window.Start, loc)
+	if err != nil {
+		return false, now, errors.Wrapf(err, "invalid maintenance window start %q", window.Start)
+	}
+	if window.Duration.Duration <= 0 {
+		return false, now, errors.Errorf("maintenance window duration should be positive, got %s", window.Duration.Duration)
+	}
+	days := make(map[time.Weekday]bool)
+	for _, d := range window.Days {
+		wd, ok := parseWeekday(d)
+		if !ok {
+			return false, now, errors.Errorf("invalid maintenance window day %q", d)
+		}
+		days[wd] = true
+	}
+	local := now.In(loc)
+	// windows may start in the past week and last until now.
+	for i := -7; i <= 7; i++ {
+		begin := time.Date(local.Year(), local.Month(), local.Day()+i, start.Hour(), start.Minute(), 0, 0, loc)
+		if len(days) > 0 && !days[begin.Weekday()] {
+			continue
+		}
+		if !now.Before(begin) && now.Before(begin.Add(window.Duration.Duration)) {
+			return true, now, nil
+		}
+		if begin.After(now) {
+			return false, begin, nil
+		}
+	}
+
+	return false, now, errors.New("no maintenance window is found")
+}
+
+// parseWeekday parses the short or full name of weekday, such as "Mon" or "monday".
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+// durationOrDefault returns the duration, or def when it is not set.
+func durationOrDefault(d *metav1.Duration, def time.Duration) time.Duration {
+	if d == nil || d.Duration <= 0 {
+		return def
+	}
+
+	return d.Duration
+}
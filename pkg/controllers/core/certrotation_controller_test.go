@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	// 2024-01-01 is Monday.
+	testcases := []struct {
+		name       string
+		window     *kkcorev1.InventoryMaintenanceWindow
+		now        time.Time
+		exceptIn   bool
+		exceptNext time.Time
+		exceptErr  bool
+	}{
+		{
+			name:     "any time",
+			window:   nil,
+			now:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			exceptIn: true,
+		},
+		{
+			name:     "in daily window",
+			window:   &kkcorev1.InventoryMaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			now:      time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+			exceptIn: true,
+		},
+		{
+			name:       "before daily window",
+			window:     &kkcorev1.InventoryMaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+			now:        time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			exceptNext: time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "window across midnight",
+			window:   &kkcorev1.InventoryMaintenanceWindow{Start: "22:00", Duration: metav1.Duration{Duration: 4 * time.Hour}, Days: []string{"Sun"}},
+			now:      time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+			exceptIn: true,
+		},
+		{
+			name:       "weekend window",
+			window:     &kkcorev1.InventoryMaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: time.Hour}, Days: []string{"saturday", "Sun"}},
+			now:        time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+			exceptNext: time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "time zone",
+			window:     &kkcorev1.InventoryMaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Asia/Shanghai"},
+			now:        time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			exceptNext: time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "invalid day",
+			window:    &kkcorev1.InventoryMaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: time.Hour}, Days: []string{"someday"}},
+			now:       time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			exceptErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			in, next, err := maintenanceWindow(tc.window, tc.now)
+			if tc.exceptErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exceptIn, in)
+			if !tc.exceptIn {
+				assert.True(t, tc.exceptNext.Equal(next), "next window %s", next)
+			}
+		})
+	}
+}
+
+func TestCertsExpirations(t *testing.T) {
+	playbook := &kkcorev1.Playbook{Status: kkcorev1.PlaybookStatus{Result: runtime.RawExtension{
+		Raw: []byte(`{"certs_expiration":{"node1":{"notAfter":200,"path":"/etc/kubernetes/pki/apiserver.crt"},"node2":{"notAfter":100,"path":"/etc/kubernetes/admin.conf"},"node3":{},"node4":"failed"}}`),
+	}}}
+
+	expirations, err := certsExpirations(playbook)
+	require.NoError(t, err)
+	assert.Equal(t, []hostCertsExpiration{
+		{host: "node2", path: "/etc/kubernetes/admin.conf", notAfter: time.Unix(100, 0)},
+		{host: "node1", path: "/etc/kubernetes/pki/apiserver.crt", notAfter: time.Unix(200, 0)},
+	}, expirations)
+}
+
+func TestCertRotationReconcile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inventory := &kkcorev1.Inventory{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: kkcorev1.InventorySpec{
+			CertRotation: &kkcorev1.InventoryCertRotation{BatchSize: 1},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(_const.Scheme).
+		WithObjects(inventory).
+		WithStatusSubresource(&kkcorev1.Inventory{}, &kkcorev1.Playbook{}).
+		Build()
+	r := &CertRotationReconciler{Client: client, EventRecorder: events.NewFakeRecorder(10), clock: clocktesting.NewFakePassiveClock(now)}
+
+	reconcile := func() *kkcorev1.Inventory {
+		t.Helper()
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(inventory)})
+		require.NoError(t, err)
+		got := &kkcorev1.Inventory{}
+		require.NoError(t, client.Get(ctx, ctrlclient.ObjectKeyFromObject(inventory), got))
+
+		return got
+	}
+	// complete the playbook in the annotation of inventory.
+	complete := func(inv *kkcorev1.Inventory, annotation string, phase kkcorev1.PlaybookPhase, result string) *kkcorev1.Playbook {
+		t.Helper()
+		playbook := &kkcorev1.Playbook{}
+		require.NoError(t, client.Get(ctx, ctrlclient.ObjectKey{Namespace: inv.Namespace, Name: inv.Annotations[annotation]}, playbook))
+		playbook.Status.Phase = phase
+		playbook.Status.Result = runtime.RawExtension{Raw: []byte(result)}
+		require.NoError(t, client.Status().Update(ctx, playbook))
+
+		return playbook
+	}
+	expiration := func(days map[string]int) string {
+		result := `{"certs_expiration":{`
+		i := 0
+		for _, host := range []string{"node1", "node2", "node3"} {
+			if d, ok := days[host]; ok {
+				if i > 0 {
+					result += ","
+				}
+				result += fmt.Sprintf(`%q:{"notAfter":%d,"path":"/etc/kubernetes/pki/apiserver.crt"}`, host, now.Add(time.Duration(d)*24*time.Hour).Unix())
+				i++
+			}
+		}
+
+		return result + "}}"
+	}
+
+	// create the expiry-check playbook.
+	got := reconcile()
+	require.NotEmpty(t, got.Annotations[kkcorev1.CertsCheckPlaybookAnnotation])
+	check := complete(got, kkcorev1.CertsCheckPlaybookAnnotation, kkcorev1.PlaybookPhaseSucceeded, expiration(map[string]int{"node1": 10, "node2": 5, "node3": 365}))
+	assert.Equal(t, certsCheckPlaybook, check.Spec.Playbook)
+	assert.Contains(t, check.Annotations, kkcorev1.BuiltinsProjectAnnotation)
+
+	// renew the first batch of expiring hosts.
+	got = reconcile()
+	renew := complete(got, kkcorev1.CertsRenewPlaybookAnnotation, kkcorev1.PlaybookPhaseSucceeded, "{}")
+	assert.Equal(t, certsRenewPlaybook, renew.Spec.Playbook)
+	assert.Equal(t, []string{"node2"}, renew.Spec.Limit)
+	cond := meta.FindStatusCondition(got.Status.Conditions, kkcorev1.InventoryCertsRotatedCondition)
+	require.NotNil(t, cond)
+	assert.Equal(t, kkcorev1.InventoryCertsRotatingReason, cond.Reason)
+	assert.Equal(t, kkcorev1.InventoryCertsExpiringReason, meta.FindStatusCondition(got.Status.Conditions, kkcorev1.InventoryCertsCheckedCondition).Reason)
+
+	// check again after the batch is renewed.
+	got = reconcile()
+	assert.Empty(t, got.Annotations[kkcorev1.CertsRenewPlaybookAnnotation])
+	assert.NotEqual(t, check.Name, got.Annotations[kkcorev1.CertsCheckPlaybookAnnotation])
+	complete(got, kkcorev1.CertsCheckPlaybookAnnotation, kkcorev1.PlaybookPhaseSucceeded, expiration(map[string]int{"node1": 10, "node2": 365, "node3": 365}))
+
+	// renew the next batch.
+	got = reconcile()
+	renew = complete(got, kkcorev1.CertsRenewPlaybookAnnotation, kkcorev1.PlaybookPhaseSucceeded, "{}")
+	assert.Equal(t, []string{"node1"}, renew.Spec.Limit)
+
+	// all hosts are renewed.
+	got = reconcile()
+	complete(got, kkcorev1.CertsCheckPlaybookAnnotation, kkcorev1.PlaybookPhaseSucceeded, expiration(map[string]int{"node1": 365, "node2": 365, "node3": 365}))
+	got = reconcile()
+	assert.Empty(t, got.Annotations[kkcorev1.CertsRenewPlaybookAnnotation])
+	assert.Equal(t, kkcorev1.InventoryCertsValidReason, meta.FindStatusCondition(got.Status.Conditions, kkcorev1.InventoryCertsCheckedCondition).Reason)
+	cond = meta.FindStatusCondition(got.Status.Conditions, kkcorev1.InventoryCertsRotatedCondition)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, kkcorev1.InventoryCertsRotatedReason, cond.Reason)
+}
+
+func TestCertRotationReconcileMaintenanceWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	inventory := &kkcorev1.Inventory{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: kkcorev1.InventorySpec{
+			CertRotation: &kkcorev1.InventoryCertRotation{
+				MaintenanceWindow: &kkcorev1.InventoryMaintenanceWindow{Start: "14:00", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(_const.Scheme).
+		WithObjects(inventory).
+		WithStatusSubresource(&kkcorev1.Inventory{}, &kkcorev1.Playbook{}).
+		Build()
+	r := &CertRotationReconciler{Client: client, EventRecorder: events.NewFakeRecorder(10), clock: clocktesting.NewFakePassiveClock(now)}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(inventory)})
+	require.NoError(t, err)
+	got := &kkcorev1.Inventory{}
+	require.NoError(t, client.Get(ctx, ctrlclient.ObjectKeyFromObject(inventory), got))
+	check := &kkcorev1.Playbook{}
+	require.NoError(t, client.Get(ctx, ctrlclient.ObjectKey{Namespace: got.Namespace, Name: got.Annotations[kkcorev1.CertsCheckPlaybookAnnotation]}, check))
+	check.CreationTimestamp = metav1.NewTime(now)
+	require.NoError(t, client.Update(ctx, check))
+	check.Status.Phase = kkcorev1.PlaybookPhaseSucceeded
+	check.Status.Result = runtime.RawExtension{Raw: fmt.Appendf(nil, `{"certs_expiration":{"node1":{"notAfter":%d}}}`, now.Add(24*time.Hour).Unix())}
+	require.NoError(t, client.Status().Update(ctx, check))
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(inventory)})
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, result.RequeueAfter)
+	require.NoError(t, client.Get(ctx, ctrlclient.ObjectKeyFromObject(inventory), got))
+	assert.Empty(t, got.Annotations[kkcorev1.CertsRenewPlaybookAnnotation])
+	cond := meta.FindStatusCondition(got.Status.Conditions, kkcorev1.InventoryCertsRotatedCondition)
+	require.NotNil(t, cond)
+	assert.Equal(t, kkcorev1.InventoryCertsWaitingReason, cond.Reason)
+}
@@ -12,4 +12,5 @@ import (
 func init() {
 	utilruntime.Must(options.Register(&PlaybookReconciler{}))
 	utilruntime.Must(options.Register(&PlaybookWebhook{}))
+	utilruntime.Must(options.Register(&CertRotationReconciler{}))
 }
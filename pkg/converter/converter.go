@@ -52,6 +52,7 @@ func MarshalBlock(hosts []string, when []string, block kkprojectv1.Block) *kkcor
 			DelegateTo:   block.DelegateTo,
 			IgnoreError:  block.IgnoreErrors,
 			Retries:      block.Retries,
+			Debugger:     block.Debugger,
 			When:         when,
 			FailedWhen:   block.FailedWhen.Data,
 			Register:     block.Register,
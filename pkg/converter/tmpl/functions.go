@@ -1,6 +1,7 @@
 package tmpl
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"net"
@@ -43,6 +44,7 @@ func funcMap() template.FuncMap {
 	f["mapToNamedStringArgs"] = mapToNamedStringArgs
 	f["toToml"] = toTOML
 	f["toBool"] = toBool
+	f["imageRewrite"] = imageRewrite
 
 	return f
 }
@@ -240,6 +242,31 @@ func isIP(addr string) bool {
 	return net.ParseIP(host) != nil
 }
 
+// imageRewrite rewrites the image by the first matching rule of rules, the same way the image module
+// does when pushing, and returns the image unchanged when no rule matches. rules is a list of
+// {prefix|regex, target} maps, or its JSON string.
+// e.g. {{ "docker.io/calico/node:v3.28.0" | imageRewrite .image_registry.rewrites }}
+func imageRewrite(rules any, image string) (string, error) {
+	var data []byte
+	switch v := rules.(type) {
+	case nil:
+		return image, nil
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return "", errors.Wrap(err, "failed to marshal image rewrite rules")
+		}
+	}
+	var imageRewrites []utils.ImageRewrite
+	if err := json.Unmarshal(data, &imageRewrites); err != nil {
+		return "", errors.Wrap(err, "image rewrite rules should be a list of {prefix|regex, target}")
+	}
+
+	return utils.RewriteImage(imageRewrites, image)
+}
+
 // pow Get the "pow" power of "base". (base ** pow)
 func pow(base, pow float64) (float64, error) {
 	return math.Pow(base, pow), nil
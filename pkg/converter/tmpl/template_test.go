@@ -918,6 +918,24 @@ a2:
 			variable: make(map[string]any),
 			excepted: "IPv4",
 		},
+		// ======= imageRewrite =======
+		{
+			name:  "imageRewrite with prefix rule",
+			input: `{{ "docker.io/calico/node:v3.28.0" | imageRewrite .rewrites }}`,
+			variable: map[string]any{
+				"rewrites": []any{
+					map[string]any{"regex": `registry\.k8s\.io/(.*)`, "target": "harbor.local/kubernetes/$1"},
+					map[string]any{"prefix": "docker.io/calico/*", "target": "harbor.local/mirror/calico/*"},
+				},
+			},
+			excepted: "harbor.local/mirror/calico/node:v3.28.0",
+		},
+		{
+			name:     "imageRewrite without rules",
+			input:    `{{ "registry.k8s.io/pause:3.9" | imageRewrite .rewrites }}`,
+			variable: map[string]any{"rewrites": "[]"},
+			excepted: "registry.k8s.io/pause:3.9",
+		},
 		// ======= pow =======
 		{
 			name:     "pow true-1",
@@ -188,5 +188,15 @@ func (e blockExecutor) dealTask(ctx context.Context, hosts []string, when []stri
 		return errors.Wrapf(err, "failed to set playbook %q ownerReferences to %q", ctrlclient.ObjectKeyFromObject(e.playbook), block.Name)
 	}
 
-	return (&taskExecutor{option: e.option, task: task}).Exec(ctx)
+	te := &taskExecutor{option: e.option, task: task}
+	// skip tasks before "start-at-task"
+	if !te.dealStartAtTask() {
+		return nil
+	}
+	// confirm task by "step"
+	if run, err := te.dealStep(); err != nil || !run {
+		return err
+	}
+
+	return te.Exec(ctx)
 }
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"k8s.io/klog/v2"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventType is the type of event which emitted by executor.
+type EventType string
+
+const (
+	// EventPlaybookStart is emitted before the first play runs.
+	EventPlaybookStart EventType = "playbook_start"
+	// EventPlaybookEnd is emitted after the playbook finished, with statistics.
+	EventPlaybookEnd EventType = "playbook_end"
+	// EventPlayStart is emitted when a play starts, with the hosts of the play.
+	EventPlayStart EventType = "play_start"
+	// EventTaskStart is emitted each time a task starts to run.
+	EventTaskStart EventType = "task_start"
+	// EventTaskEnd is emitted when a task finished, with the task status.
+	EventTaskEnd EventType = "task_end"
+	// EventHostResult is emitted when a task finished on a host, with the result of the host.
+	EventHostResult EventType = "host_result"
+
+	// There is no handler event: the handlers of play are not run by executor yet.
+)
+
+// Event status of task and host result.
+const (
+	EventStatusSuccess = "success"
+	EventStatusFailed  = "failed"
+	EventStatusIgnored = "ignored"
+	EventStatusSkipped = "skipped"
+)
+
+// Event is a structured record of playbook execution.
+type Event struct {
+	// Type of event.
+	Type EventType `json:"type"`
+	// Time when the event is emitted.
+	Time time.Time `json:"time"`
+	// Playbook is the namespaced name of playbook.
+	Playbook string `json:"playbook"`
+	// Play is the name of play. only set in play_start.
+	Play string `json:"play,omitempty"`
+	// Task is the name of task.
+	Task string `json:"task,omitempty"`
+	// TaskID is the namespaced name of task resource.
+	TaskID string `json:"task_id,omitempty"`
+	// Role is the relative path of the role which task belongs to.
+	Role string `json:"role,omitempty"`
+	// Module is the module name of task.
+	Module string `json:"module,omitempty"`
+	// Hosts of play or task.
+	Hosts []string `json:"hosts,omitempty"`
+	// Host of host_result.
+	Host string `json:"host,omitempty"`
+	// Status is one of success, failed, ignored and skipped for task and host result,
+	// and the playbook phase for playbook_end.
+	Status string `json:"status,omitempty"`
+	// Stdout of module. each loop item is separated by newline.
+	Stdout string `json:"stdout,omitempty"`
+	// Stderr of module. each loop item is separated by newline.
+	Stderr string `json:"stderr,omitempty"`
+	// Error message of failure.
+	Error string `json:"error,omitempty"`
+	// Duration from the start of playbook, task or host execution to the event, in milliseconds.
+	Duration int64 `json:"duration_ms,omitempty"`
+	// Statistics of tasks. only set in playbook_end.
+	Statistics *kkcorev1.PlaybookStatistics `json:"statistics,omitempty"`
+}
+
+// Callback receives events from executor, like ansible callback plugins.
+// OnEvent may be called concurrently for host results, and should not block the executor for long.
+type Callback interface {
+	OnEvent(ctx context.Context, event Event)
+}
+
+// CallbackFunc is an adapter to allow the use of ordinary functions as Callback.
+type CallbackFunc func(ctx context.Context, event Event)
+
+// OnEvent calls f(ctx, event).
+func (f CallbackFunc) OnEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+var (
+	// callbacks registered by name. they can be enabled by name from command line (--callback).
+	callbacks = make(map[string]Callback)
+	// callbacksMu guards callbacks.
+	callbacksMu sync.RWMutex
+)
+
+// RegisterCallback registers a callback by name. it returns an error if the name already registered.
+func RegisterCallback(name string, callback Callback) error {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+	if _, ok := callbacks[name]; ok {
+		return errors.Errorf("callback %s already registered", name)
+	}
+	callbacks[name] = callback
+
+	return nil
+}
+
+// FindCallback returns the registered callback by name. it returns nil if not found.
+func FindCallback(name string) Callback {
+	callbacksMu.RLock()
+	defer callbacksMu.RUnlock()
+
+	return callbacks[name]
+}
+
+// NewJSONCallback returns a Callback which writes each event as a line of json to w.
+func NewJSONCallback(w io.Writer) Callback {
+	return &jsonCallback{encoder: json.NewEncoder(w)}
+}
+
+// jsonCallback writes events as json lines.
+type jsonCallback struct {
+	sync.Mutex
+	encoder *json.Encoder
+}
+
+// OnEvent writes event as a line of json.
+func (c *jsonCallback) OnEvent(_ context.Context, event Event) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.encoder.Encode(event); err != nil {
+		klog.ErrorS(err, "failed to write event", "type", event.Type)
+	}
+}
+
+// WithCallbacks adds callbacks which receive the events of executor.
+func WithCallbacks(cbs ...Callback) Option {
+	return func(o *option) {
+		o.callbacks = append(o.callbacks, cbs...)
+	}
+}
+
+// emit fills the common fields of event and sends it to all callbacks.
+func (o *option) emit(ctx context.Context, event Event) {
+	if len(o.callbacks) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Playbook = ctrlclient.ObjectKeyFromObject(o.playbook).String()
+	for _, cb := range o.callbacks {
+		cb.OnEvent(ctx, event)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kkcorev1alpha1 "github.com/kubesphere/kubekey/api/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestTaskExecutor_Events(t *testing.T) {
+	testcases := []struct {
+		name        string
+		hosts       []string
+		when        []string
+		ignoreError *bool
+		args        string
+		expected    []Event
+	}{
+		{
+			name:  "success",
+			hosts: []string{"node1", "node2"},
+			args:  `{"msg":"hello"}`,
+			expected: []Event{
+				{Type: EventTaskStart},
+				{Type: EventHostResult, Host: "node1", Status: EventStatusSuccess},
+				{Type: EventHostResult, Host: "node2", Status: EventStatusSuccess},
+				{Type: EventTaskEnd, Status: EventStatusSuccess},
+			},
+		},
+		{
+			name:  "skipped",
+			hosts: []string{"node1"},
+			when:  []string{"{{ false }}"},
+			args:  `{"msg":"hello"}`,
+			expected: []Event{
+				{Type: EventTaskStart},
+				{Type: EventHostResult, Host: "node1", Status: EventStatusSkipped},
+				{Type: EventTaskEnd, Status: EventStatusSkipped},
+			},
+		},
+		{
+			name:        "ignored",
+			hosts:       []string{"node1"},
+			ignoreError: ptr.To(true),
+			args:        `{}`,
+			expected: []Event{
+				{Type: EventTaskStart},
+				{Type: EventHostResult, Host: "node1", Status: EventStatusIgnored},
+				{Type: EventTaskEnd, Status: EventStatusIgnored},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+			o, err := newTestOption(tc.hosts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var mu sync.Mutex
+			var events []Event
+			WithCallbacks(CallbackFunc(func(_ context.Context, event Event) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}))(o)
+
+			if err := (&taskExecutor{
+				option: o,
+				task: &kkcorev1alpha1.Task{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: corev1.NamespaceDefault,
+					},
+					Spec: kkcorev1alpha1.TaskSpec{
+						Name:        "test",
+						Hosts:       tc.hosts,
+						When:        tc.when,
+						IgnoreError: tc.ignoreError,
+						Module: kkcorev1alpha1.Module{
+							Name: "debug",
+							Args: runtime.RawExtension{Raw: []byte(tc.args)},
+						},
+					},
+				},
+			}).Exec(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			// host results are emitted concurrently, compare them regardless of order.
+			var actual []Event
+			for _, event := range events {
+				assert.Equal(t, "default/test", event.TaskID)
+				assert.Equal(t, "debug", event.Module)
+				assert.NotEmpty(t, event.Playbook)
+				assert.False(t, event.Time.IsZero())
+				actual = append(actual, Event{Type: event.Type, Host: event.Host, Status: event.Status})
+			}
+			assert.Equal(t, tc.expected[0], actual[0])
+			assert.Equal(t, tc.expected[len(tc.expected)-1], actual[len(actual)-1])
+			assert.ElementsMatch(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestJSONCallback(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cb := NewJSONCallback(buf)
+	cb.OnEvent(context.TODO(), Event{Type: EventTaskStart, Playbook: "default/test", Task: "task1", Hosts: []string{"node1"}})
+	cb.OnEvent(context.TODO(), Event{Type: EventHostResult, Playbook: "default/test", Task: "task1", Host: "node1", Status: EventStatusFailed, Error: "failed"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+		assert.Equal(t, "host_result", event["type"])
+		assert.Equal(t, "node1", event["host"])
+		assert.Equal(t, "failed", event["status"])
+		assert.Equal(t, "failed", event["error"])
+		assert.NotContains(t, event, "statistics")
+	}
+}
+
+func TestRegisterCallback(t *testing.T) {
+	cb := CallbackFunc(func(context.Context, Event) {})
+	assert.NoError(t, RegisterCallback("test-register", cb))
+	assert.Error(t, RegisterCallback("test-register", cb))
+	assert.NotNil(t, FindCallback("test-register"))
+	assert.Nil(t, FindCallback("not-registered"))
+}
+
+func TestRegisterCallbackConcurrently(t *testing.T) {
+	cb := CallbackFunc(func(context.Context, Event) {})
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, RegisterCallback(fmt.Sprintf("test-concurrent-%d", i), cb))
+		}()
+		go func() {
+			defer wg.Done()
+			FindCallback(fmt.Sprintf("test-concurrent-%d", i))
+		}()
+	}
+	wg.Wait()
+	for i := range 10 {
+		assert.NotNil(t, FindCallback(fmt.Sprintf("test-concurrent-%d", i)))
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	kkcorev1alpha1 "github.com/kubesphere/kubekey/api/core/v1alpha1"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+// debuggerHelp is the usage of the interactive debugger.
+const debuggerHelp = `Available commands:
+  p task                      print the task name, module and args
+  p task.args                 print the module args
+  p result                    print the result of each host
+  p vars <host> [key.path]    print the variables of host
+  task.args[<key>] = <value>  set a module arg, the value is parsed as yaml
+  task.args = <value>         replace all module args, the value is parsed as yaml
+  r, redo                     run the task again
+  s, skip                     ignore the task result and continue
+  c, continue                 keep the task result and continue
+  q, quit                     abort the playbook
+  h, help                     print this help
+`
+
+var (
+	// regexForSetArg matches `task.args[key] = value`.
+	regexForSetArg = regexp.MustCompile(`^task\.args\[\s*['"]?([^'"\]]+)['"]?\s*]\s*=\s*(.*)$`)
+	// regexForSetArgs matches `task.args = value`.
+	regexForSetArgs = regexp.MustCompile(`^task\.args\s*=\s*(.*)$`)
+)
+
+// readLine prints the prompt and reads an answer from input.
+// It returns io.EOF when there is no more input.
+func (e *taskExecutor) readLine(prompt string) (string, error) {
+	fmt.Fprint(e.logOutput, prompt)
+	line, err := e.input.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// dealStartAtTask "start-at-task" option. skip all tasks until the task with the given name.
+// Once the task is reached, the option is cleared and the following tasks run as usual.
+func (e *taskExecutor) dealStartAtTask() bool {
+	if e.startAtTask == "" {
+		return true
+	}
+	if e.task.Spec.Name != e.startAtTask {
+		return false
+	}
+	e.startAtTask = ""
+
+	return true
+}
+
+// dealStep "step" option. confirm the task before running it.
+// Answer "y" to run the task, "n" to skip it and "c" to run all the following tasks without confirmation.
+func (e *taskExecutor) dealStep() (bool, error) {
+	if !e.step || e.input == nil {
+		return true, nil
+	}
+	for {
+		answer, err := e.readLine(fmt.Sprintf("Perform task: %s (N)o/(y)es/(c)ontinue: ", e.task.Spec.Name))
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to read step answer of task %q", e.task.Spec.Name)
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		case "c", "continue":
+			e.step = false
+
+			return true, nil
+		}
+	}
+}
+
+// shouldDebug checks whether the debugger should be invoked for the task result.
+// The "debugger" defined in task takes precedence over the debugger option of executor.
+func (e *taskExecutor) shouldDebug() bool {
+	if e.input == nil {
+		return false
+	}
+	mode := e.task.Spec.Debugger
+	if mode == "" {
+		mode = e.debugger
+	}
+	switch mode {
+	case kkcorev1alpha1.TaskDebuggerAlways:
+		return true
+	case kkcorev1alpha1.TaskDebuggerOnFailed:
+		return e.task.IsFailed()
+	case kkcorev1alpha1.TaskDebuggerOnSkipped:
+		for _, result := range e.task.Status.HostResults {
+			for _, r := range result.LoopResults {
+				if r.Stdout != modules.StdoutSkip {
+					return false
+				}
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+// dealDebugger runs the interactive debugger when shouldDebug.
+// It returns true if the task should run again.
+func (e *taskExecutor) dealDebugger(ctx context.Context) (bool, error) {
+	if !e.shouldDebug() {
+		return false, nil
+	}
+	fmt.Fprintf(e.logOutput, "[%s] task %s, enter debugger. type \"h\" for help.\n", e.task.Spec.Name, e.task.Status.Phase)
+	origin := e.task.DeepCopy()
+	for {
+		line, err := e.readLine(fmt.Sprintf("[%s] (debug)> ", e.task.Spec.Name))
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// no more input, keep the task result.
+				return false, nil
+			}
+
+			return false, errors.Wrapf(err, "failed to read debugger command of task %q", e.task.Spec.Name)
+		}
+		switch line {
+		case "":
+			continue
+		case "h", "help":
+			fmt.Fprint(e.logOutput, debuggerHelp)
+		case "r", "redo":
+			// store the changed module args before run again.
+			if err := e.client.Patch(ctx, e.task, ctrlclient.MergeFrom(origin)); err != nil {
+				return false, errors.Wrapf(err, "failed to patch task %s", ctrlclient.ObjectKeyFromObject(origin))
+			}
+			e.task.Status.Phase = kkcorev1alpha1.TaskPhasePending
+			e.task.Status.RestartCount = 0
+
+			return true, nil
+		case "s", "skip":
+			task := e.task.DeepCopy()
+			e.task.Status.Phase = kkcorev1alpha1.TaskPhaseIgnored
+			if err := e.client.Status().Patch(ctx, e.task, ctrlclient.MergeFrom(task)); err != nil {
+				return false, errors.Wrapf(err, "failed to patch task status of %s", ctrlclient.ObjectKeyFromObject(task))
+			}
+
+			return false, nil
+		case "c", "continue":
+			return false, nil
+		case "q", "quit":
+			return false, errors.Errorf("task [%s](%s) aborted by debugger", e.task.Spec.Name, ctrlclient.ObjectKeyFromObject(e.task))
+		default:
+			if err := e.debuggerCommand(line); err != nil {
+				fmt.Fprintf(e.logOutput, "ERROR: %v\n", err)
+			}
+		}
+	}
+}
+
+// debuggerCommand executes the debugger commands which print or change the task.
+func (e *taskExecutor) debuggerCommand(line string) error {
+	if match := regexForSetArg.FindStringSubmatch(line); match != nil {
+		args := make(map[string]any)
+		if len(e.task.Spec.Module.Args.Raw) > 0 {
+			if err := json.Unmarshal(e.task.Spec.Module.Args.Raw, &args); err != nil {
+				return errors.Wrap(err, "module args is not a map, use \"task.args = <value>\" instead")
+			}
+		}
+		args[strings.TrimSpace(match[1])] = parseDebuggerValue(match[2])
+
+		return e.setModuleArgs(args)
+	}
+	if match := regexForSetArgs.FindStringSubmatch(line); match != nil {
+		return e.setModuleArgs(parseDebuggerValue(match[1]))
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || (fields[0] != "p" && fields[0] != "print") {
+		return errors.Errorf("unknown command %q. type \"h\" for help", line)
+	}
+	switch fields[1] {
+	case "task":
+		return e.printDebuggerValue(map[string]any{
+			"name":   e.task.Spec.Name,
+			"hosts":  e.task.Spec.Hosts,
+			"module": e.task.Spec.Module.Name,
+			"args":   string(e.task.Spec.Module.Args.Raw),
+		})
+	case "task.args":
+		var args any
+		if len(e.task.Spec.Module.Args.Raw) > 0 {
+			if err := json.Unmarshal(e.task.Spec.Module.Args.Raw, &args); err != nil {
+				return errors.Wrap(err, "failed to unmarshal module args")
+			}
+		}
+
+		return e.printDebuggerValue(args)
+	case "result":
+		return e.printDebuggerValue(e.task.Status.HostResults)
+	case "vars":
+		if len(fields) < 3 {
+			return errors.New("host is required. usage: p vars <host> [key.path]")
+		}
+		ha, err := e.variable.Get(variable.GetAllVariable(fields[2]))
+		if err != nil {
+			return err
+		}
+		if len(fields) == 3 {
+			return e.printDebuggerValue(ha)
+		}
+		had, ok := ha.(map[string]any)
+		if !ok {
+			return errors.Errorf("host: %s variable is not a map", fields[2])
+		}
+		val, err := variable.PrintVar(had, strings.Split(fields[3], ".")...)
+		if err != nil {
+			return err
+		}
+
+		return e.printDebuggerValue(val)
+	default:
+		return errors.Errorf("unknown print target %q. type \"h\" for help", fields[1])
+	}
+}
+
+// setModuleArgs replaces the module args of task. It takes effect when the task runs again.
+func (e *taskExecutor) setModuleArgs(args any) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal module args")
+	}
+	e.task.Spec.Module.Args = runtime.RawExtension{Raw: data}
+	fmt.Fprintf(e.logOutput, "module args: %s\n", data)
+
+	return nil
+}
+
+// printDebuggerValue prints value as indented json.
+func (e *taskExecutor) printDebuggerValue(val any) error {
+	data, err := json.MarshalIndent(val, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal value")
+	}
+	fmt.Fprintf(e.logOutput, "%s\n", data)
+
+	return nil
+}
+
+// parseDebuggerValue parses the value as yaml. If failed, use it as string.
+func parseDebuggerValue(s string) any {
+	var val any
+	if err := yaml.Unmarshal([]byte(s), &val); err != nil {
+		return s
+	}
+
+	return val
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kkcorev1alpha1 "github.com/kubesphere/kubekey/api/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newDebuggerTestTask(name string, args string) *kkcorev1alpha1.Task {
+	return &kkcorev1alpha1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: corev1.NamespaceDefault,
+		},
+		Spec: kkcorev1alpha1.TaskSpec{
+			Name:  name,
+			Hosts: []string{"node1"},
+			Module: kkcorev1alpha1.Module{
+				Name: "debug",
+				Args: runtime.RawExtension{Raw: []byte(args)},
+			},
+		},
+	}
+}
+
+func TestTaskExecutor_DealStep(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		except   bool
+		stepLeft bool
+	}{
+		{
+			name:     "yes",
+			input:    "y\n",
+			except:   true,
+			stepLeft: true,
+		},
+		{
+			name:     "no",
+			input:    "n\n",
+			except:   false,
+			stepLeft: true,
+		},
+		{
+			name:     "continue",
+			input:    "c\n",
+			except:   true,
+			stepLeft: false,
+		},
+		{
+			name:     "invalid answer then yes",
+			input:    "x\ny\n",
+			except:   true,
+			stepLeft: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newTestOption([]string{"node1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			WithStep(true)(o)
+			WithInput(strings.NewReader(tc.input))(o)
+
+			run, err := (&taskExecutor{option: o, task: newDebuggerTestTask("test", `{"msg":"hello"}`)}).dealStep()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.except, run)
+			assert.Equal(t, tc.stepLeft, o.step)
+		})
+	}
+}
+
+func TestTaskExecutor_DealStartAtTask(t *testing.T) {
+	o, err := newTestOption([]string{"node1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	WithStartAtTask("task2")(o)
+
+	assert.False(t, (&taskExecutor{option: o, task: newDebuggerTestTask("task1", `{}`)}).dealStartAtTask())
+	assert.True(t, (&taskExecutor{option: o, task: newDebuggerTestTask("task2", `{}`)}).dealStartAtTask())
+	assert.True(t, (&taskExecutor{option: o, task: newDebuggerTestTask("task1", `{}`)}).dealStartAtTask())
+}
+
+func TestTaskExecutor_Debugger(t *testing.T) {
+	testcases := []struct {
+		name      string
+		debugger  string
+		args      string
+		input     string
+		exceptErr bool
+		phase     kkcorev1alpha1.TaskPhase
+	}{
+		{
+			name:     "edit args and redo",
+			debugger: kkcorev1alpha1.TaskDebuggerOnFailed,
+			args:     `{}`,
+			input:    "p task\np vars node1 inventory_hostname\ntask.args[msg] = hello\nr\n",
+			phase:    kkcorev1alpha1.TaskPhaseSuccess,
+		},
+		{
+			name:     "skip failed task",
+			debugger: kkcorev1alpha1.TaskDebuggerOnFailed,
+			args:     `{}`,
+			input:    "s\n",
+			phase:    kkcorev1alpha1.TaskPhaseIgnored,
+		},
+		{
+			name:      "abort failed task",
+			debugger:  kkcorev1alpha1.TaskDebuggerOnFailed,
+			args:      `{}`,
+			input:     "q\n",
+			exceptErr: true,
+			phase:     kkcorev1alpha1.TaskPhaseFailed,
+		},
+		{
+			name:      "continue failed task",
+			debugger:  kkcorev1alpha1.TaskDebuggerOnFailed,
+			args:      `{}`,
+			input:     "c\n",
+			exceptErr: true,
+			phase:     kkcorev1alpha1.TaskPhaseFailed,
+		},
+		{
+			name:     "always debug success task",
+			debugger: kkcorev1alpha1.TaskDebuggerAlways,
+			args:     `{"msg":"hello"}`,
+			input:    "p result\nc\n",
+			phase:    kkcorev1alpha1.TaskPhaseSuccess,
+		},
+		{
+			name:     "no debug for success task",
+			debugger: kkcorev1alpha1.TaskDebuggerOnFailed,
+			args:     `{"msg":"hello"}`,
+			phase:    kkcorev1alpha1.TaskPhaseSuccess,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+			o, err := newTestOption([]string{"node1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			WithDebugger(tc.debugger)(o)
+			WithInput(strings.NewReader(tc.input))(o)
+
+			task := newDebuggerTestTask(strings.ReplaceAll(tc.name, " ", "-"), tc.args)
+			err = (&taskExecutor{option: o, task: task, taskRunTimeout: 10 * time.Second}).Exec(ctx)
+			if tc.exceptErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.phase, task.Status.Phase)
+		})
+	}
+}
+
+func TestTaskExecutor_NoInput(t *testing.T) {
+	o, err := newTestOption([]string{"node1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	WithStep(true)(o)
+	WithDebugger("always")(o)
+	e := &taskExecutor{option: o, task: newDebuggerTestTask("test", `{}`)}
+
+	// without input, such as in controller-manager mode, step and debugger are skipped.
+	run, err := e.dealStep()
+	assert.NoError(t, err)
+	assert.True(t, run)
+	assert.False(t, e.shouldDebug())
+}
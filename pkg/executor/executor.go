@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"bufio"
 	"context"
 	"io"
 
@@ -23,4 +24,47 @@ type option struct {
 	variable variable.Variable
 	// commandLine log output. default os.stdout
 	logOutput io.Writer
+
+	// step confirms each task before running it.
+	step bool
+	// startAtTask skips all tasks until the task with this name.
+	startAtTask string
+	// debugger is the default debugger mode for tasks. see kkcorev1alpha1.TaskSpec.Debugger.
+	debugger string
+	// input is where the interactive answers of step and debugger are read from.
+	// It is nil by default, then step and debugger are skipped, such as in controller-manager mode.
+	input *bufio.Reader
+	// callbacks receive the events of execution.
+	callbacks []Callback
+}
+
+// Option for playbookExecutor.
+type Option func(*option)
+
+// WithStep confirms each task before running it.
+func WithStep(step bool) Option {
+	return func(o *option) {
+		o.step = step
+	}
+}
+
+// WithStartAtTask skips all tasks until the task with the given name.
+func WithStartAtTask(name string) Option {
+	return func(o *option) {
+		o.startAtTask = name
+	}
+}
+
+// WithDebugger sets the default debugger mode for tasks which not define "debugger".
+func WithDebugger(debugger string) Option {
+	return func(o *option) {
+		o.debugger = debugger
+	}
+}
+
+// WithInput sets where the interactive answers are read from. The command line uses os.Stdin.
+func WithInput(input io.Reader) Option {
+	return func(o *option) {
+		o.input = bufio.NewReader(input)
+	}
 }
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/cockroachdb/errors"
@@ -28,9 +29,12 @@ import (
 	kkcorev1alpha1 "github.com/kubesphere/kubekey/api/core/v1alpha1"
 	kkprojectv1 "github.com/kubesphere/kubekey/api/project/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/klog/v2"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
 	"github.com/kubesphere/kubekey/v4/pkg/converter"
 	"github.com/kubesphere/kubekey/v4/pkg/project"
 	"github.com/kubesphere/kubekey/v4/pkg/variable"
@@ -38,7 +42,7 @@ import (
 )
 
 // NewPlaybookExecutor return a new playbookExecutor
-func NewPlaybookExecutor(ctx context.Context, client ctrlclient.Client, playbook *kkcorev1.Playbook, logOutput io.Writer) Executor {
+func NewPlaybookExecutor(ctx context.Context, client ctrlclient.Client, playbook *kkcorev1.Playbook, logOutput io.Writer, opts ...Option) Executor {
 	// get variable
 	v, err := variable.New(ctx, client, *playbook, source.FileSource)
 	if err != nil {
@@ -47,14 +51,17 @@ func NewPlaybookExecutor(ctx context.Context, client ctrlclient.Client, playbook
 		return nil
 	}
 
-	return &playbookExecutor{
-		option: &option{
-			client:    client,
-			playbook:  playbook,
-			variable:  v,
-			logOutput: logOutput,
-		},
+	o := &option{
+		client:    client,
+		playbook:  playbook,
+		variable:  v,
+		logOutput: logOutput,
+	}
+	for _, opt := range opts {
+		opt(o)
 	}
+
+	return &playbookExecutor{option: o}
 }
 
 // executor for playbook
@@ -65,8 +72,10 @@ type playbookExecutor struct {
 // Exec playbook. covert playbook to block and executor it.
 func (e playbookExecutor) Exec(ctx context.Context) (retErr error) {
 	old := e.playbook.DeepCopy()
+	start := time.Now()
 	defer func() {
 		e.syncStatus(ctx, old, retErr)
+		e.emitPlaybookEnd(ctx, start, retErr)
 	}()
 	fmt.Fprint(e.logOutput, `
 
@@ -81,6 +90,11 @@ func (e playbookExecutor) Exec(ctx context.Context) (retErr error) {
 
 `)
 	fmt.Fprintf(e.logOutput, "%s [Playbook %s] start\n", time.Now().Format(time.TimeOnly+" MST"), ctrlclient.ObjectKeyFromObject(e.playbook))
+	e.emit(ctx, Event{Type: EventPlaybookStart, Time: start})
+	// fail before any play is skipped silently, the same as ansible.
+	if err := e.checkLimit(); err != nil {
+		return err
+	}
 	klog.V(5).InfoS("deal project", "playbook", ctrlclient.ObjectKeyFromObject(e.playbook))
 	pj, err := project.New(ctx, *e.playbook, true)
 	if err != nil {
@@ -99,8 +113,12 @@ func (e playbookExecutor) Exec(ctx context.Context) (retErr error) {
 
 			continue
 		}
+		if err := e.dealOrder(play.Order, hosts); err != nil {
+			return err
+		}
+		e.emit(ctx, Event{Type: EventPlayStart, Play: play.Name, Hosts: hosts})
 		// check tags
-		if err := e.dealGatherFacts(ctx, play.GatherFacts, hosts); err != nil {
+		if err := e.dealGatherFacts(ctx, play, hosts); err != nil {
 			return err
 		}
 
@@ -147,6 +165,20 @@ func (e playbookExecutor) syncStatus(ctx context.Context, old *kkcorev1.Playbook
 	}
 }
 
+// emitPlaybookEnd emits the playbook_end event with the phase and statistics of playbook.
+func (e playbookExecutor) emitPlaybookEnd(ctx context.Context, start time.Time, err error) {
+	event := Event{
+		Type:       EventPlaybookEnd,
+		Status:     string(e.playbook.Status.Phase),
+		Duration:   time.Since(start).Milliseconds(),
+		Statistics: e.playbook.Status.Statistics.DeepCopy(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	e.emit(ctx, event)
+}
+
 // execBatchHosts executor block in play order by: "pre_tasks" > "roles" > "tasks" > "post_tasks"
 func (e playbookExecutor) execBatchHosts(ctx context.Context, play kkprojectv1.Play, batchHosts [][]string) error {
 	// generate and execute task.
@@ -215,6 +247,7 @@ func (e playbookExecutor) execBatchHosts(ctx context.Context, play kkprojectv1.P
 }
 
 // dealHosts "hosts" argument in playbook. get hostname from kkprojectv1.PlayHost
+// and restrict them by the "limit" of playbook.
 func (e playbookExecutor) dealHosts(host kkprojectv1.PlayHost, i *[]string) error {
 	ahn, err := e.variable.Get(variable.GetHostnames(host.Hosts))
 	if err != nil {
@@ -224,6 +257,9 @@ func (e playbookExecutor) dealHosts(host kkprojectv1.PlayHost, i *[]string) erro
 	if h, ok := ahn.([]string); ok {
 		*i = h
 	}
+	if err := e.dealLimit(i); err != nil {
+		return err
+	}
 	if len(*i) == 0 { // if hosts is empty skip this playbook
 		return errors.New("hosts is empty")
 	}
@@ -231,12 +267,82 @@ func (e playbookExecutor) dealHosts(host kkprojectv1.PlayHost, i *[]string) erro
 	return nil
 }
 
-// dealGatherFacts "gather_facts" argument in playbook. get host remote info and merge to variable
-func (e playbookExecutor) dealGatherFacts(ctx context.Context, gatherFacts bool, hosts []string) error {
-	if !gatherFacts {
+// checkLimit returns an error when the "limit" of playbook matches no host in inventory.
+func (e playbookExecutor) checkLimit() error {
+	if len(e.playbook.Spec.Limit) == 0 {
+		return nil
+	}
+	lhn, err := e.variable.Get(variable.GetHostnames(e.playbook.Spec.Limit))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get limit hosts %q", e.playbook.Spec.Limit)
+	}
+	if limitHosts, _ := lhn.([]string); len(limitHosts) == 0 {
+		return errors.Errorf("limit %q matches no host in inventory", e.playbook.Spec.Limit)
+	}
+
+	return nil
+}
+
+// dealLimit "limit" argument in playbook. only keep the hosts which match the limit patterns.
+// The localhost is always kept, plays which run on localhost usually need all hostvars.
+func (e playbookExecutor) dealLimit(hosts *[]string) error {
+	if len(e.playbook.Spec.Limit) == 0 {
+		return nil
+	}
+	lhn, err := e.variable.Get(variable.GetHostnames(e.playbook.Spec.Limit))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get limit hosts %q", e.playbook.Spec.Limit)
+	}
+	limitHosts, _ := lhn.([]string)
+	*hosts = slices.DeleteFunc(*hosts, func(h string) bool {
+		return h != _const.VariableLocalHost && !slices.Contains(limitHosts, h)
+	})
+
+	return nil
+}
+
+// dealOrder "order" argument in playbook. sort hosts in place by the given order.
+func (e playbookExecutor) dealOrder(order string, hosts []string) error {
+	if !kkprojectv1.IsSupportedPlayOrder(order) {
+		return errors.Errorf("unsupported order %q in play", order)
+	}
+	switch order {
+	case kkprojectv1.PlayOrderReverseInventory:
+		slices.Reverse(hosts)
+	case kkprojectv1.PlayOrderSorted:
+		slices.Sort(hosts)
+	case kkprojectv1.PlayOrderReverseSorted:
+		slices.Sort(hosts)
+		slices.Reverse(hosts)
+	case kkprojectv1.PlayOrderShuffle:
+		shuffled := make([]string, len(hosts))
+		for i, j := range rand.Perm(len(hosts)) {
+			shuffled[i] = hosts[j]
+		}
+		copy(hosts, shuffled)
+	}
+
+	return nil
+}
+
+// dealGatherFacts "gather_facts" argument in playbook. get host remote info and merge to variable.
+// "gather_subset" and "gather_timeout" are passed to the setup module.
+func (e playbookExecutor) dealGatherFacts(ctx context.Context, play kkprojectv1.Play, hosts []string) error {
+	if !play.GatherFacts {
 		// skip
 		return nil
 	}
+	args := make(map[string]any)
+	if len(play.GatherSubset.Subsets) > 0 {
+		args["gather_subset"] = play.GatherSubset.Subsets
+	}
+	if play.GatherTimeout > 0 {
+		args["gather_timeout"] = play.GatherTimeout
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal setup args")
+	}
 	// run setup task
 	return (&taskExecutor{option: e.option, task: &kkcorev1alpha1.Task{
 		ObjectMeta: metav1.ObjectMeta{
@@ -248,6 +354,7 @@ func (e playbookExecutor) dealGatherFacts(ctx context.Context, gatherFacts bool,
 			Hosts: hosts,
 			Module: kkcorev1alpha1.Module{
 				Name: "setup",
+				Args: runtime.RawExtension{Raw: data},
 			},
 		},
 	}}).Exec(ctx)
@@ -3,6 +3,7 @@ package executor
 import (
 	"testing"
 
+	kkprojectv1 "github.com/kubesphere/kubekey/api/project/v1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,3 +37,155 @@ func TestPlaybookExecutor_DealRunOnce(t *testing.T) {
 		})
 	}
 }
+
+func TestPlaybookExecutor_DealOrder(t *testing.T) {
+	testcases := []struct {
+		name   string
+		order  string
+		hosts  []string
+		except []string
+	}{
+		{
+			name:   "default order",
+			order:  "",
+			hosts:  []string{"node2", "node1", "node3"},
+			except: []string{"node2", "node1", "node3"},
+		},
+		{
+			name:   "inventory",
+			order:  "inventory",
+			hosts:  []string{"node2", "node1", "node3"},
+			except: []string{"node2", "node1", "node3"},
+		},
+		{
+			name:   "reverse_inventory",
+			order:  "reverse_inventory",
+			hosts:  []string{"node2", "node1", "node3"},
+			except: []string{"node3", "node1", "node2"},
+		},
+		{
+			name:   "sorted",
+			order:  "sorted",
+			hosts:  []string{"node2", "node1", "node3"},
+			except: []string{"node1", "node2", "node3"},
+		},
+		{
+			name:   "reverse_sorted",
+			order:  "reverse_sorted",
+			hosts:  []string{"node2", "node1", "node3"},
+			except: []string{"node3", "node2", "node1"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, playbookExecutor{}.dealOrder(tc.order, tc.hosts))
+			assert.Equal(t, tc.except, tc.hosts)
+		})
+	}
+
+	t.Run("shuffle", func(t *testing.T) {
+		hosts := []string{"node1", "node2", "node3"}
+		assert.NoError(t, playbookExecutor{}.dealOrder("shuffle", hosts))
+		assert.ElementsMatch(t, []string{"node1", "node2", "node3"}, hosts)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		assert.Error(t, playbookExecutor{}.dealOrder("unknown", []string{"node1"}))
+	})
+}
+
+func TestPlaybookExecutor_DealHostsWithLimit(t *testing.T) {
+	testcases := []struct {
+		name   string
+		hosts  []string
+		limit  []string
+		except []string
+	}{
+		{
+			name:   "no limit",
+			hosts:  []string{"node1", "node2"},
+			except: []string{"node1", "node2"},
+		},
+		{
+			name:   "limit by hostname",
+			hosts:  []string{"node1", "node2"},
+			limit:  []string{"node2"},
+			except: []string{"node2"},
+		},
+		{
+			name:   "limit by exclusion",
+			hosts:  []string{"node1", "node2", "node3"},
+			limit:  []string{"!node1"},
+			except: []string{"node2", "node3"},
+		},
+		{
+			name:   "localhost is always kept",
+			hosts:  []string{"localhost"},
+			limit:  []string{"node1"},
+			except: []string{"localhost"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newTestOption([]string{"node1", "node2", "node3"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.playbook.Spec.Limit = tc.limit
+
+			var hosts []string
+			assert.NoError(t, playbookExecutor{option: o}.dealHosts(kkprojectv1.PlayHost{Hosts: tc.hosts}, &hosts))
+			assert.Equal(t, tc.except, hosts)
+		})
+	}
+
+	t.Run("no host match limit", func(t *testing.T) {
+		o, err := newTestOption([]string{"node1", "node2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.playbook.Spec.Limit = []string{"node3"}
+
+		var hosts []string
+		assert.Error(t, playbookExecutor{option: o}.dealHosts(kkprojectv1.PlayHost{Hosts: []string{"node1"}}, &hosts))
+	})
+}
+
+func TestPlaybookExecutor_CheckLimit(t *testing.T) {
+	testcases := []struct {
+		name    string
+		limit   []string
+		wantErr bool
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name:  "limit matches host",
+			limit: []string{"node1", "node3"},
+		},
+		{
+			name:    "limit matches no host",
+			limit:   []string{"node3"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, err := newTestOption([]string{"node1", "node2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.playbook.Spec.Limit = tc.limit
+			err = playbookExecutor{option: o}.checkLimit()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
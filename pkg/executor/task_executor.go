@@ -43,7 +43,9 @@ func (e *taskExecutor) Exec(ctx context.Context) error {
 	if err := e.client.Create(ctx, e.task); err != nil {
 		return errors.Wrapf(err, "failed to create task %v", e.task)
 	}
+	start := time.Now()
 	defer func() {
+		e.emitTaskEnd(ctx, start)
 		e.playbook.Status.Statistics.Total++
 		switch e.task.Status.Phase {
 		case kkcorev1alpha1.TaskPhaseSuccess:
@@ -54,9 +56,18 @@ func (e *taskExecutor) Exec(ctx context.Context) error {
 			e.playbook.Status.Statistics.Failed++
 		}
 	}()
-	// run task
-	if err := e.runTaskLoop(ctx); err != nil {
-		return err
+	// run task. run again when the debugger asks to redo.
+	for {
+		if err := e.runTaskLoop(ctx); err != nil {
+			return err
+		}
+		redo, err := e.dealDebugger(ctx)
+		if err != nil {
+			return err
+		}
+		if !redo {
+			break
+		}
 	}
 	// exit when task run failed
 	if e.task.IsFailed() {
@@ -120,6 +131,14 @@ func (e *taskExecutor) runTaskLoop(ctx context.Context) error {
 		roleLog = "[" + e.task.Annotations[kkcorev1alpha1.TaskAnnotationRelativePath] + "] "
 	}
 	fmt.Fprintf(e.logOutput, "%s %s%s\n", time.Now().Format(time.TimeOnly+" MST"), roleLog, e.task.Spec.Name)
+	e.emit(ctx, Event{
+		Type:   EventTaskStart,
+		Task:   e.task.Spec.Name,
+		TaskID: ctrlclient.ObjectKeyFromObject(e.task).String(),
+		Role:   e.task.Annotations[kkcorev1alpha1.TaskAnnotationRelativePath],
+		Module: e.task.Spec.Module.Name,
+		Hosts:  e.task.Spec.Hosts,
+	})
 
 	for !e.task.IsComplete() {
 		task := e.task.DeepCopy()
@@ -169,6 +188,7 @@ func (e *taskExecutor) execTaskHost(i int, h string) func(ctx context.Context) {
 	return func(ctx context.Context) {
 		var resErr error
 		var loopResults []kkcorev1alpha1.LoopResult
+		start := time.Now()
 
 		// task log
 		deferFunc := e.execTaskHostLogs(ctx, h, &loopResults)
@@ -187,6 +207,7 @@ func (e *taskExecutor) execTaskHost(i int, h string) func(ctx context.Context) {
 				Error:       errMsg,
 				LoopResults: loopResults,
 			}
+			e.emitHostResult(ctx, start, e.task.Status.HostResults[i])
 		}()
 
 		ha, err := e.variable.Get(variable.GetAllVariable(h))
@@ -279,40 +300,20 @@ func (e *taskExecutor) execTaskHostLogs(ctx context.Context, h string, loopResul
 	}()
 
 	return func() {
-		var failed bool
-		var skipped bool
-
-		// determine overall status by scanning all register results
-		// A module's failure is determined solely by its returned error.
-		// StdoutFailed is only a human-readable marker and must not be used as a failure signal.
-		skipped = true // assume skip until we find a non-skip stdout
-		for _, r := range *loopResults {
-			if r.Error != "" {
-				failed = true
-				break
-			}
-			if r.Stdout != modules.StdoutSkip {
-				skipped = false
+		switch e.hostResultStatus(*loopResults, "") {
+		case EventStatusIgnored:
+			// ignore
+			bar.Describe(fmt.Sprintf("[\033[36m%s\033[0m]%s \033[34mignore \033[0m", h, placeholder))
+			if e.logOutput != os.Stdout {
+				fmt.Fprintf(e.logOutput, "[%s]%s ignore \n", h, placeholder)
 			}
-		}
-
-		switch {
-		case failed:
-			// failed or ignore
-			if e.task.Spec.IgnoreError != nil && *e.task.Spec.IgnoreError {
-				// ignore
-				bar.Describe(fmt.Sprintf("[\033[36m%s\033[0m]%s \033[34mignore \033[0m", h, placeholder))
-				if e.logOutput != os.Stdout {
-					fmt.Fprintf(e.logOutput, "[%s]%s ignore \n", h, placeholder)
-				}
-			} else {
-				// failed
-				bar.Describe(fmt.Sprintf("[\033[36m%s\033[0m]%s \033[31mfailed \033[0m", h, placeholder))
-				if e.logOutput != os.Stdout {
-					fmt.Fprintf(e.logOutput, "[%s]%s failed \n", h, placeholder)
-				}
+		case EventStatusFailed:
+			// failed
+			bar.Describe(fmt.Sprintf("[\033[36m%s\033[0m]%s \033[31mfailed \033[0m", h, placeholder))
+			if e.logOutput != os.Stdout {
+				fmt.Fprintf(e.logOutput, "[%s]%s failed \n", h, placeholder)
 			}
-		case skipped:
+		case EventStatusSkipped:
 			// skip
 			bar.Describe(fmt.Sprintf("[\033[36m%s\033[0m]%s \033[34mskip   \033[0m", h, placeholder))
 			if e.logOutput != os.Stdout {
@@ -330,6 +331,93 @@ func (e *taskExecutor) execTaskHostLogs(ctx context.Context, h string, loopResul
 	}
 }
 
+// hostResultStatus determines the status of a host by scanning all loop results.
+// A module's failure is determined solely by its returned error.
+// StdoutFailed is only a human-readable marker and must not be used as a failure signal.
+func (e *taskExecutor) hostResultStatus(loopResults []kkcorev1alpha1.LoopResult, hostErr string) string {
+	failed := hostErr != ""
+	skipped := true // assume skip until we find a non-skip stdout
+	for _, r := range loopResults {
+		if r.Error != "" {
+			failed = true
+
+			break
+		}
+		if r.Stdout != modules.StdoutSkip {
+			skipped = false
+		}
+	}
+
+	switch {
+	case failed:
+		if e.task.Spec.IgnoreError != nil && *e.task.Spec.IgnoreError {
+			return EventStatusIgnored
+		}
+
+		return EventStatusFailed
+	case skipped:
+		return EventStatusSkipped
+	default:
+		return EventStatusSuccess
+	}
+}
+
+// emitHostResult emits the host_result event of a host. stdout and stderr of each loop item are joined by newline.
+func (e *taskExecutor) emitHostResult(ctx context.Context, start time.Time, result kkcorev1alpha1.TaskHostResult) {
+	stdout := make([]string, 0, len(result.LoopResults))
+	stderr := make([]string, 0, len(result.LoopResults))
+	for _, r := range result.LoopResults {
+		stdout = append(stdout, r.Stdout)
+		if r.Stderr != "" {
+			stderr = append(stderr, r.Stderr)
+		}
+	}
+	e.emit(ctx, Event{
+		Type:     EventHostResult,
+		Task:     e.task.Spec.Name,
+		TaskID:   ctrlclient.ObjectKeyFromObject(e.task).String(),
+		Role:     e.task.Annotations[kkcorev1alpha1.TaskAnnotationRelativePath],
+		Module:   e.task.Spec.Module.Name,
+		Host:     result.Host,
+		Status:   e.hostResultStatus(result.LoopResults, result.Error),
+		Stdout:   strings.Join(stdout, "\n"),
+		Stderr:   strings.Join(stderr, "\n"),
+		Error:    result.Error,
+		Duration: time.Since(start).Milliseconds(),
+	})
+}
+
+// emitTaskEnd emits the task_end event. the task is skipped when it's skipped in all hosts.
+func (e *taskExecutor) emitTaskEnd(ctx context.Context, start time.Time) {
+	var status string
+	switch e.task.Status.Phase {
+	case kkcorev1alpha1.TaskPhaseSuccess:
+		status = EventStatusSkipped
+		for _, result := range e.task.Status.HostResults {
+			if e.hostResultStatus(result.LoopResults, result.Error) != EventStatusSkipped {
+				status = EventStatusSuccess
+
+				break
+			}
+		}
+	case kkcorev1alpha1.TaskPhaseIgnored:
+		status = EventStatusIgnored
+	default:
+		// failed, or not completed because of an error.
+		status = EventStatusFailed
+	}
+	e.emit(ctx, Event{
+		Type:     EventTaskEnd,
+		Task:     e.task.Spec.Name,
+		TaskID:   ctrlclient.ObjectKeyFromObject(e.task).String(),
+		Role:     e.task.Annotations[kkcorev1alpha1.TaskAnnotationRelativePath],
+		Module:   e.task.Spec.Module.Name,
+		Hosts:    e.task.Spec.Hosts,
+		Status:   status,
+		Duration: time.Since(start).Milliseconds(),
+	})
+}
+
 // executeModule executes a single module task on a specific host.
 func (e *taskExecutor) executeModule(ctx context.Context, task *kkcorev1alpha1.Task, item any, host string) (stdout string, stderr string, rendered any, resErr error) {
 	// Set loop item variable if one was provided
@@ -33,9 +33,11 @@ type commandManager struct {
 	ctrlclient.Client
 
 	logOutput io.Writer
+
+	executorOptions []executor.Option
 }
 
 // Run command Manager. print log and run playbook executor.
 func (m *commandManager) Run(ctx context.Context) error {
-	return executor.NewPlaybookExecutor(ctx, m.Client, m.Playbook, m.logOutput).Exec(ctx)
+	return executor.NewPlaybookExecutor(ctx, m.Client, m.Playbook, m.logOutput, m.executorOptions...).Exec(ctx)
 }
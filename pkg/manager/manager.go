@@ -18,6 +18,7 @@ package manager
 
 import (
 	"context"
+	"io"
 	"os"
 
 	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
@@ -25,6 +26,7 @@ import (
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kubesphere/kubekey/v4/cmd/controller-manager/app/options"
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
 )
 
 // Manager defines the interface for different types of managers that can run operations
@@ -40,15 +42,25 @@ type CommandManagerOptions struct {
 	*kkcorev1.Inventory
 
 	ctrlclient.Client
+	// ExecutorOptions for the playbook executor. such as step and debugger.
+	ExecutorOptions []executor.Option
+	// LogOutput is where the human-readable progress is written. default os.Stdout
+	LogOutput io.Writer
 }
 
 // NewCommandManager creates and returns a new command manager instance with the provided options
 func NewCommandManager(o CommandManagerOptions) Manager {
+	logOutput := o.LogOutput
+	if logOutput == nil {
+		logOutput = os.Stdout
+	}
+
 	return &commandManager{
-		Playbook:  o.Playbook,
-		Inventory: o.Inventory,
-		Client:    o.Client,
-		logOutput: os.Stdout,
+		Playbook:        o.Playbook,
+		Inventory:       o.Inventory,
+		Client:          o.Client,
+		logOutput:       logOutput,
+		executorOptions: o.ExecutorOptions,
 	}
 }
 
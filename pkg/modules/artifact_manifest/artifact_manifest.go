@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact_manifest
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Artifact Manifest module writes the manifest of an unpacked offline artifact in the local machine.
+The manifest ("artifact.json" at the root of the artifact) lists the digest of every binary and image blob,
+which is used by "kk artifact diff" and "kk artifact apply".
+
+Configuration:
+
+artifact_manifest:
+  path: string    # required: the unpacked artifact directory
+  base: string    # optional: the base artifact (archive, unpacked directory or manifest file).
+                  # When set, the files whose content is in the base artifact are removed, so path becomes a delta of base.
+  arch: []string  # optional: the CPU architectures which the artifact is exported for, recorded in the manifest
+
+Usage Examples in Playbook Tasks:
+1. Write the manifest before packaging:
+   ```yaml
+   - name: Generate artifact manifest
+     artifact_manifest:
+       path: "{{ .artifact_dir }}/kubekey"
+       arch: ["amd64", "arm64"]
+   ```
+
+2. Keep only the files which are not in the base artifact:
+   ```yaml
+   - name: Generate delta artifact
+     artifact_manifest:
+       path: "{{ .artifact_dir }}/kubekey"
+       base: /opt/kubekey-artifact-v1.json
+   ```
+
+Return Values:
+- On success: Returns "Success" in stdout
+- On failure: Returns error message in stderr
+*/
+
+// artifactManifestArgs holds the arguments for the artifact_manifest module.
+type artifactManifestArgs struct {
+	path string   // the unpacked artifact directory
+	base string   // the base artifact of delta
+	arch []string // the CPU architectures of the artifact
+}
+
+func newArtifactManifestArgs(raw runtime.RawExtension, vars map[string]any) (*artifactManifestArgs, error) {
+	args := variable.Extension2Variables(raw)
+	path, err := variable.StringVar(vars, args, "path")
+	if err != nil {
+		return nil, errors.New("\"path\" should be string")
+	}
+	if path == "" {
+		return nil, errors.New("\"path\" should not be empty")
+	}
+	base, _ := variable.StringVar(vars, args, "base")
+	arch, _ := variable.StringSliceVar(vars, args, "arch")
+
+	return &artifactManifestArgs{path: path, base: base, arch: arch}, nil
+}
+
+// ModuleArtifactManifest handles the "artifact_manifest" module, which writes the manifest of an artifact
+// and optionally prunes it to a delta of the base artifact.
+func ModuleArtifactManifest(_ context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	args, err := newArtifactManifestArgs(opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	m, err := artifact.NewManifest(args.path)
+	if err != nil {
+		return internal.StdoutFailed, "failed to generate artifact manifest", err
+	}
+	m.SetArches(args.arch)
+	if args.base == "" {
+		if err := artifact.WriteManifest(args.path, m); err != nil {
+			return internal.StdoutFailed, "failed to write artifact manifest", err
+		}
+
+		return internal.StdoutSuccess, "", nil
+	}
+
+	base, err := artifact.ReadManifest(args.base)
+	if err != nil {
+		return internal.StdoutFailed, "failed to read base artifact manifest", err
+	}
+	if err := artifact.Prune(args.path, m, base); err != nil {
+		return internal.StdoutFailed, "failed to generate delta artifact", err
+	}
+
+	return internal.StdoutSuccess, "", nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifact_manifest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/kubesphere/kubekey/v4/pkg/artifact"
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+	"github.com/kubesphere/kubekey/v4/pkg/variable/source"
+)
+
+// NewTestVariable creates a new variable.Variable for testing purposes.
+func NewTestVariable(hosts []string, vars map[string]any) variable.Variable {
+	client, playbook, err := _const.NewTestPlaybook(hosts)
+	if err != nil {
+		klog.ErrorS(err, "failed to create test playbook")
+	}
+	v, err := variable.New(context.TODO(), client, *playbook, source.MemorySource)
+	if err != nil {
+		klog.ErrorS(err, "failed to create variable")
+	}
+	if err := v.Merge(variable.MergeRemoteVariable(vars, hosts...)); err != nil {
+		klog.ErrorS(err, "failed to merge variable")
+	}
+	return v
+}
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestModuleArtifactManifest(t *testing.T) {
+	baseDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "kubelet"), []byte("v1.33.0"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "helm"), []byte("v3.18.0"), 0644))
+	base, err := artifact.NewManifest(baseDir)
+	require.NoError(t, err)
+	require.NoError(t, artifact.WriteManifest(baseDir, base))
+
+	testcases := []struct {
+		name         string
+		args         func(dir string) map[string]any
+		expectStdout string
+		expectFiles  []string
+		expectArches []string
+	}{
+		{
+			name:         "empty path",
+			args:         func(string) map[string]any { return map[string]any{} },
+			expectStdout: internal.StdoutFailed,
+		},
+		{
+			name:         "manifest",
+			args:         func(dir string) map[string]any { return map[string]any{"path": dir} },
+			expectStdout: internal.StdoutSuccess,
+			expectFiles:  []string{artifact.ManifestFile, "helm", "kubelet"},
+		},
+		{
+			name: "manifest with arches",
+			args: func(dir string) map[string]any {
+				return map[string]any{"path": dir, "arch": []string{"arm64", "amd64", "arm64"}}
+			},
+			expectStdout: internal.StdoutSuccess,
+			expectFiles:  []string{artifact.ManifestFile, "helm", "kubelet"},
+			expectArches: []string{"amd64", "arm64"},
+		},
+		{
+			name: "delta of base",
+			args: func(dir string) map[string]any {
+				return map[string]any{"path": dir, "base": filepath.Join(baseDir, artifact.ManifestFile)}
+			},
+			expectStdout: internal.StdoutSuccess,
+			expectFiles:  []string{artifact.ManifestFile, "kubelet"},
+		},
+		{
+			name: "base not found",
+			args: func(dir string) map[string]any {
+				return map[string]any{"path": dir, "base": filepath.Join(dir, "not-found.tgz")}
+			},
+			expectStdout: internal.StdoutFailed,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "kubelet"), []byte("v1.33.1"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "helm"), []byte("v3.18.0"), 0644))
+			stdout, _, err := ModuleArtifactManifest(context.Background(), internal.ExecOptions{
+				Host:     "node1",
+				Variable: NewTestVariable([]string{"node1"}, nil),
+				Args:     createRawArgs(tc.args(dir)),
+			})
+			require.Equal(t, tc.expectStdout, stdout)
+			if tc.expectStdout != internal.StdoutSuccess {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			files := make([]string, 0, len(entries))
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			require.Equal(t, tc.expectFiles, files)
+			m, err := artifact.ReadManifest(dir)
+			require.NoError(t, err)
+			require.Len(t, m.Files, 2)
+			require.Equal(t, tc.expectArches, m.Arches)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockinfile
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Blockinfile module ensures a block of lines surrounded by marker lines is in a file, or removes it, on remote hosts.
+The file is read by the connector, edited in memory and only written back when its content changes.
+
+Configuration:
+Users can specify the block and where to put it:
+
+blockinfile:
+  path: /etc/security/limits.conf # required: the file in remote host
+  block: |                  # optional: the lines of the block. An empty block removes the block
+    * soft nofile 65535
+    * hard nofile 65535
+  marker: "# {mark} KUBEKEY MANAGED BLOCK" # optional: the marker line template, "{mark}" is replaced by marker_begin or marker_end
+  marker_begin: BEGIN       # optional: (default: BEGIN)
+  marker_end: END           # optional: (default: END)
+  insertafter: EOF          # optional: insert a new block after the last matched line, or "EOF" (default)
+  insertbefore: BOF         # optional: insert a new block before the last matched line, or "BOF"
+  state: present            # optional: present or absent (default: present)
+  create: false             # optional: create the file when it does not exist (default: false)
+  backup: false             # optional: backup the file before changing it (default: false)
+
+Usage Examples in Playbook Tasks:
+1. Ensure limits:
+   ```yaml
+   - name: Set nofile limits
+     blockinfile:
+       path: /etc/security/limits.conf
+       block: |
+         * soft nofile 65535
+         * hard nofile 65535
+   ```
+
+2. Multiple blocks in one file:
+   ```yaml
+   - name: Add kubernetes hosts
+     blockinfile:
+       path: /etc/hosts
+       marker: "# {mark} kubernetes hosts"
+       block: |
+         {{- range .groups.k8s_cluster }}
+         {{ index $.hostvars . "internal_ipv4" }} {{ . }}
+         {{- end }}
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "msg" and the "backup" file
+- On failure: Returns error message in stderr
+*/
+
+const (
+	statePresent = "present"
+	stateAbsent  = "absent"
+	// defaultMarker is the default marker line template.
+	defaultMarker = "# {mark} KUBEKEY MANAGED BLOCK"
+)
+
+// blockinfileArgs holds the arguments for the blockinfile module.
+type blockinfileArgs struct {
+	path        string                  // Path of the file in remote host
+	block       string                  // The lines of the block
+	markerBegin string                  // The begin marker line
+	markerEnd   string                  // The end marker line
+	insertPos   internal.InsertPosition // Where to insert when it does not exist
+	state       string                  // present or absent
+	create      bool                    // Create the file when it does not exist
+	backup      bool                    // Backup the file before changing it
+}
+
+// blockinfileResult is the output of the blockinfile module.
+type blockinfileResult struct {
+	Changed bool   `json:"changed"`
+	Msg     string `json:"msg"`
+	Backup  string `json:"backup,omitempty"`
+}
+
+// newBlockinfileArgs parses and validates the arguments for the blockinfile module.
+func newBlockinfileArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*blockinfileArgs, error) {
+	var err error
+	ba := &blockinfileArgs{}
+	args := variable.Extension2Variables(raw)
+	ba.path, err = variable.StringVar(vars, args, "path")
+	if err != nil || ba.path == "" {
+		return nil, errors.New("\"path\" in args should be string")
+	}
+	ba.state, _ = variable.StringVar(vars, args, "state")
+	if ba.state == "" {
+		ba.state = statePresent
+	}
+	if ba.state != statePresent && ba.state != stateAbsent {
+		return nil, errors.Errorf("\"state\" should be %q or %q, got %q", statePresent, stateAbsent, ba.state)
+	}
+	ba.block, _ = variable.StringVar(vars, args, "block")
+	marker, _ := variable.StringVar(vars, args, "marker")
+	if marker == "" {
+		marker = defaultMarker
+	}
+	if !strings.Contains(marker, "{mark}") {
+		return nil, errors.Errorf("\"marker\" %q should contain \"{mark}\"", marker)
+	}
+	begin, _ := variable.StringVar(vars, args, "marker_begin")
+	if begin == "" {
+		begin = "BEGIN"
+	}
+	end, _ := variable.StringVar(vars, args, "marker_end")
+	if end == "" {
+		end = "END"
+	}
+	if begin == end {
+		return nil, errors.New("\"marker_begin\" and \"marker_end\" should be different")
+	}
+	ba.markerBegin = strings.ReplaceAll(marker, "{mark}", begin)
+	ba.markerEnd = strings.ReplaceAll(marker, "{mark}", end)
+	if ba.insertPos, err = internal.InsertPositionArg(vars, args); err != nil {
+		return nil, err
+	}
+	if ba.create, err = internal.BoolArg(vars, args, "create", false); err != nil {
+		return nil, err
+	}
+	if ba.backup, err = internal.BoolArg(vars, args, "backup", false); err != nil {
+		return nil, err
+	}
+
+	return ba, nil
+}
+
+// ModuleBlockinfile handles the "blockinfile" module, ensuring a marked block in a file on remote hosts.
+func ModuleBlockinfile(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	ba, err := newBlockinfileArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	rf, err := internal.ReadRemoteFile(ctx, conn, ba.path)
+	if err != nil {
+		return internal.StdoutFailed, "failed to read file", err
+	}
+	result := &blockinfileResult{}
+	if !rf.Exists {
+		if ba.state == stateAbsent {
+			result.Msg = "file not present"
+
+			return internal.MarshalResult(result)
+		}
+		if !ba.create {
+			return internal.StdoutFailed, "failed to read file", errors.Errorf("file %q does not exist, set \"create\" to create it", ba.path)
+		}
+	}
+
+	content, msg := ba.apply(string(rf.Content))
+	result.Msg = msg
+	if rf.Exists && content == string(rf.Content) {
+		return internal.MarshalResult(result)
+	}
+	result.Changed = true
+	if ba.backup {
+		if result.Backup, err = rf.Backup(ctx, conn); err != nil {
+			return internal.StdoutFailed, "failed to backup file", err
+		}
+	}
+	if err := rf.Write(ctx, conn, []byte(content)); err != nil {
+		return internal.StdoutFailed, "failed to write file", err
+	}
+
+	return internal.MarshalResult(result)
+}
+
+// apply returns the content with the block ensured or removed, and the message of what is done.
+// An existing block is replaced in place. A new block is inserted at insertafter or insertbefore.
+func (ba blockinfileArgs) apply(content string) (string, string) {
+	lines := internal.SplitLines(content)
+	begin, end := internal.LastMatch(lines, func(l string) bool { return l == ba.markerBegin }), -1
+	if begin >= 0 {
+		for i := begin + 1; i < len(lines); i++ {
+			if lines[i] == ba.markerEnd {
+				end = i
+
+				break
+			}
+		}
+	}
+	found := end > begin
+
+	var block []string
+	if ba.state == statePresent && ba.block != "" {
+		block = append([]string{ba.markerBegin}, internal.SplitLines(ba.block)...)
+		block = append(block, ba.markerEnd)
+	}
+
+	var result []string
+	var msg string
+	switch {
+	case found && len(block) == 0:
+		result = append(append(result, lines[:begin]...), lines[end+1:]...)
+		msg = "block removed"
+	case found:
+		result = append(append(append(result, lines[:begin]...), block...), lines[end+1:]...)
+		msg = "block replaced"
+	case len(block) == 0:
+		return content, ""
+	default:
+		i := internal.InsertIndex(lines, ba.insertPos)
+		result = append(append(append(result, lines[:i]...), block...), lines[i:]...)
+		msg = "block inserted"
+	}
+	newContent := internal.JoinLines(result)
+	if newContent == content {
+		return content, ""
+	}
+
+	return newContent, msg
+}
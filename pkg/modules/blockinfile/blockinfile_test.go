@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockinfile
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestBlockinfileArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *blockinfileArgs
+		wantErr bool
+	}{
+		{
+			name: "default marker",
+			args: map[string]any{"path": "/etc/hosts", "block": "a\n"},
+			except: &blockinfileArgs{path: "/etc/hosts", block: "a\n", state: statePresent,
+				markerBegin: "# BEGIN KUBEKEY MANAGED BLOCK", markerEnd: "# END KUBEKEY MANAGED BLOCK"},
+		},
+		{
+			name: "custom marker",
+			args: map[string]any{"path": "/etc/hosts", "marker": "## {mark} hosts", "marker_begin": "start", "marker_end": "stop", "create": "true"},
+			except: &blockinfileArgs{path: "/etc/hosts", state: statePresent, create: true,
+				markerBegin: "## start hosts", markerEnd: "## stop hosts"},
+		},
+		{
+			name:    "missing path",
+			args:    map[string]any{"block": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "marker without mark",
+			args:    map[string]any{"path": "/etc/hosts", "marker": "# hosts"},
+			wantErr: true,
+		},
+		{
+			name:    "same begin and end",
+			args:    map[string]any{"path": "/etc/hosts", "marker_begin": "X", "marker_end": "X"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid insertafter",
+			args:    map[string]any{"path": "/etc/hosts", "insertafter": "("},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ba, err := newBlockinfileArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, ba)
+		})
+	}
+}
+
+func TestModuleBlockinfile(t *testing.T) {
+	limits := "# limits\n* soft core 0\n"
+	managed := "# limits\n# BEGIN KUBEKEY MANAGED BLOCK\n* soft nofile 1024\n# END KUBEKEY MANAGED BLOCK\n* soft core 0\n"
+	testcases := []struct {
+		name          string
+		args          map[string]any
+		files         map[string]string
+		exceptContent string
+		exceptChanged bool
+		exceptMsg     string
+		exceptErr     string
+	}{
+		{
+			name:          "insert block at end of file",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 1024\n"},
+			files:         map[string]string{"/etc/limits.conf": limits},
+			exceptContent: limits + "# BEGIN KUBEKEY MANAGED BLOCK\n* soft nofile 1024\n# END KUBEKEY MANAGED BLOCK\n",
+			exceptChanged: true,
+			exceptMsg:     "block inserted",
+		},
+		{
+			name:          "insert block after matched line",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 1024", "insertafter": "^# limits"},
+			files:         map[string]string{"/etc/limits.conf": limits},
+			exceptContent: managed,
+			exceptChanged: true,
+			exceptMsg:     "block inserted",
+		},
+		{
+			name:          "block already present",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 1024\n"},
+			files:         map[string]string{"/etc/limits.conf": managed},
+			exceptContent: managed,
+		},
+		{
+			name:          "replace block in place",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 65535\n* hard nofile 65535\n"},
+			files:         map[string]string{"/etc/limits.conf": managed},
+			exceptContent: "# limits\n# BEGIN KUBEKEY MANAGED BLOCK\n* soft nofile 65535\n* hard nofile 65535\n# END KUBEKEY MANAGED BLOCK\n* soft core 0\n",
+			exceptChanged: true,
+			exceptMsg:     "block replaced",
+		},
+		{
+			name:          "remove block",
+			args:          map[string]any{"path": "/etc/limits.conf", "state": "absent"},
+			files:         map[string]string{"/etc/limits.conf": managed},
+			exceptContent: limits,
+			exceptChanged: true,
+			exceptMsg:     "block removed",
+		},
+		{
+			name:          "empty block removes block",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": ""},
+			files:         map[string]string{"/etc/limits.conf": managed},
+			exceptContent: limits,
+			exceptChanged: true,
+			exceptMsg:     "block removed",
+		},
+		{
+			name:          "block of other marker is kept",
+			args:          map[string]any{"path": "/etc/limits.conf", "marker": "# {mark} other", "state": "absent"},
+			files:         map[string]string{"/etc/limits.conf": managed},
+			exceptContent: managed,
+		},
+		{
+			name:          "create file",
+			args:          map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 1024", "create": true},
+			exceptContent: "# BEGIN KUBEKEY MANAGED BLOCK\n* soft nofile 1024\n# END KUBEKEY MANAGED BLOCK\n",
+			exceptChanged: true,
+			exceptMsg:     "block inserted",
+		},
+		{
+			name:      "file not exist",
+			args:      map[string]any{"path": "/etc/limits.conf", "block": "* soft nofile 1024"},
+			exceptErr: "does not exist",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := internal.NewTestFileConnector(tc.files)
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			stdout, _, err := ModuleBlockinfile(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err)
+			var result blockinfileResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, tc.exceptChanged, result.Changed)
+			assert.Equal(t, tc.exceptMsg, result.Msg)
+			assert.Equal(t, tc.exceptContent, conn.Files["/etc/limits.conf"])
+		})
+	}
+}
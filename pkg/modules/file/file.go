@@ -0,0 +1,347 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The File module manages paths on remote hosts: directories, empty files, symbolic links and their owner, group and mode.
+An action only runs when the path is not in the desired state, and the result reports whether anything changed.
+
+Configuration:
+Users can specify the state and attributes of the path:
+
+file:
+  path: /etc/kubernetes/pki # required: the path in remote host
+  state: directory          # optional: file, directory, link, absent or touch (default: file)
+  src: /usr/local/bin/kubectl # required when state is link: the target of the symbolic link
+  force: false              # optional: replace an existing path which is not a link when state is link (default: false)
+  owner: root               # optional: owner name or uid
+  group: root               # optional: group name or gid
+  mode: 0755                # optional: permission in octal, such as 0755 or "0755"
+  recurse: false            # optional: apply owner, group and mode to all files in the directory (default: false)
+
+States:
+- file: the file must exist, only the attributes are changed
+- directory: create the directory and its parents when it does not exist
+- link: create or update the symbolic link to src
+- absent: remove the path recursively
+- touch: create an empty file, or update the times of an existing path
+
+Usage Examples in Playbook Tasks:
+1. Create a directory:
+   ```yaml
+   - name: Create pki dir
+     file:
+       path: /etc/kubernetes/pki
+       state: directory
+       mode: 0755
+   ```
+
+2. Create a symbolic link:
+   ```yaml
+   - name: Link kubectl
+     file:
+       path: /usr/bin/kubectl
+       src: /usr/local/bin/kubectl
+       state: link
+   ```
+
+3. Remove a path:
+   ```yaml
+   - name: Remove etcd data
+     file:
+       path: /var/lib/etcd
+       state: absent
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "path", "state" and the "actions" which ran
+- On failure: Returns error message in stderr
+*/
+
+const (
+	stateFile      = "file"
+	stateDirectory = "directory"
+	stateLink      = "link"
+	stateAbsent    = "absent"
+	stateTouch     = "touch"
+)
+
+// fileArgs holds the arguments for the file module.
+type fileArgs struct {
+	path    string       // Path in remote host
+	state   string       // file, directory, link, absent or touch
+	src     string       // The target of the symbolic link
+	force   bool         // Replace an existing path which is not a link
+	owner   string       // Owner name or uid
+	group   string       // Group name or gid
+	mode    *fs.FileMode // Permission bits
+	recurse bool         // Apply the attributes to all files in the directory
+}
+
+// fileResult is the output of the file module.
+type fileResult struct {
+	Changed bool     `json:"changed"`
+	Path    string   `json:"path"`
+	State   string   `json:"state"`
+	Actions []string `json:"actions"`
+}
+
+// newFileArgs parses and validates the arguments for the file module.
+func newFileArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*fileArgs, error) {
+	var err error
+	fa := &fileArgs{}
+	args := variable.Extension2Variables(raw)
+	fa.path, err = variable.StringVar(vars, args, "path")
+	if err != nil || fa.path == "" {
+		return nil, errors.New("\"path\" in args should be string")
+	}
+	fa.path = filepath.Clean(fa.path)
+	fa.state, _ = variable.StringVar(vars, args, "state")
+	switch fa.state {
+	case "":
+		fa.state = stateFile
+	case stateFile, stateDirectory, stateLink, stateAbsent, stateTouch:
+	default:
+		return nil, errors.Errorf("unsupported state %q, should be one of file, directory, link, absent or touch", fa.state)
+	}
+	fa.src, _ = variable.StringVar(vars, args, "src")
+	if fa.state == stateLink && fa.src == "" {
+		return nil, errors.New("\"src\" in args should be string when state is link")
+	}
+	fa.owner, _ = variable.StringVar(vars, args, "owner")
+	fa.group, _ = variable.StringVar(vars, args, "group")
+	if fa.mode, err = internal.ModeArg(vars, args, "mode"); err != nil {
+		return nil, err
+	}
+	if fa.force, err = internal.BoolArg(vars, args, "force", false); err != nil {
+		return nil, err
+	}
+	if fa.recurse, err = internal.BoolArg(vars, args, "recurse", false); err != nil {
+		return nil, err
+	}
+	if fa.recurse && fa.state != stateDirectory {
+		return nil, errors.New("\"recurse\" is only supported when state is directory")
+	}
+
+	return fa, nil
+}
+
+// ModuleFile handles the "file" module, managing a path and its attributes on remote hosts.
+func ModuleFile(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	fa, err := newFileArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	r := &runner{conn: conn, path: fa.path}
+	if err := fa.apply(ctx, r); err != nil {
+		return internal.StdoutFailed, "failed to manage path", err
+	}
+	data, err := json.Marshal(fileResult{Changed: len(r.actions) > 0, Path: fa.path, State: fa.state, Actions: r.actions})
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal file result", err
+	}
+
+	return string(data), "", nil
+}
+
+// runner runs the commands of actions on the remote host and records the actions which ran.
+type runner struct {
+	conn    connector.Connector
+	path    string
+	actions []string
+}
+
+// run executes the command and records the action.
+func (r *runner) run(ctx context.Context, action, cmd string) error {
+	if _, stderr, err := r.conn.ExecuteCommand(ctx, cmd); err != nil {
+		return errors.Wrapf(err, "failed to %s %q, stderr: %s", action, r.path, strings.TrimSpace(string(stderr)))
+	}
+	r.actions = append(r.actions, action)
+
+	return nil
+}
+
+// apply brings the path to the desired state.
+func (fa fileArgs) apply(ctx context.Context, r *runner) error {
+	st, err := internal.StatRemotePath(ctx, r.conn, fa.path, false)
+	if err != nil {
+		return err
+	}
+	path := internal.ShellQuote(fa.path)
+	switch fa.state {
+	case stateAbsent:
+		if st.Exists {
+			return r.run(ctx, "remove", "rm -rf "+path)
+		}
+
+		return nil
+	case stateFile:
+		if !st.Exists {
+			return errors.Errorf("file %q does not exist, use state touch to create it", fa.path)
+		}
+		if st.Type == stateLink {
+			// manage the attributes of the link target, as chown and chmod do.
+			if st, err = internal.StatRemotePath(ctx, r.conn, fa.path, true); err != nil {
+				return err
+			}
+		}
+		if st.Type != stateFile {
+			return errors.Errorf("%q is a %s, not a file", fa.path, st.Type)
+		}
+	case stateDirectory:
+		if st.Exists && st.Type == stateLink {
+			if st, err = internal.StatRemotePath(ctx, r.conn, fa.path, true); err != nil {
+				return err
+			}
+		}
+		switch {
+		case !st.Exists:
+			if err := r.run(ctx, "create", "mkdir -p "+path); err != nil {
+				return err
+			}
+			// the attributes of a new directory are unknown, set them all.
+			st = &internal.PathStat{Exists: true, Type: stateDirectory, UID: -1, GID: -1}
+		case st.Type != stateDirectory:
+			return errors.Errorf("%q is a %s, not a directory", fa.path, st.Type)
+		}
+	case stateTouch:
+		if st.Exists {
+			if err := r.run(ctx, "touch", "touch "+path); err != nil {
+				return err
+			}
+		} else {
+			if err := r.run(ctx, "create", fmt.Sprintf("mkdir -p %s && touch %s", internal.ShellQuote(filepath.Dir(fa.path)), path)); err != nil {
+				return err
+			}
+			st = &internal.PathStat{Exists: true, Type: stateFile, UID: -1, GID: -1}
+		}
+	case stateLink:
+		return fa.applyLink(ctx, r, st)
+	}
+
+	return fa.applyAttributes(ctx, r, st)
+}
+
+// applyLink creates or updates the symbolic link, and changes its owner and group.
+func (fa fileArgs) applyLink(ctx context.Context, r *runner, st *internal.PathStat) error {
+	path := internal.ShellQuote(fa.path)
+	switch {
+	case st.Exists && st.Type == stateLink && st.LinkTarget == fa.src:
+	case st.Exists && st.Type != stateLink && !fa.force:
+		return errors.Errorf("%q is a %s, set \"force\" to replace it with a link", fa.path, st.Type)
+	case st.Exists:
+		if err := r.run(ctx, "link", fmt.Sprintf("rm -rf %s && ln -s %s %s", path, internal.ShellQuote(fa.src), path)); err != nil {
+			return err
+		}
+		st = &internal.PathStat{Exists: true, Type: stateLink, UID: -1, GID: -1}
+	default:
+		if err := r.run(ctx, "link", fmt.Sprintf("mkdir -p %s && ln -s %s %s", internal.ShellQuote(filepath.Dir(fa.path)), internal.ShellQuote(fa.src), path)); err != nil {
+			return err
+		}
+		st = &internal.PathStat{Exists: true, Type: stateLink, UID: -1, GID: -1}
+	}
+	// the mode of a link cannot be changed, only the owner.
+	if fa.owner != "" && !ownerMatched(fa.owner, st.Owner, st.UID) {
+		if err := r.run(ctx, "chown", fmt.Sprintf("chown -h %s %s", internal.ShellQuote(fa.owner), path)); err != nil {
+			return err
+		}
+	}
+	if fa.group != "" && !ownerMatched(fa.group, st.Group, st.GID) {
+		if err := r.run(ctx, "chgrp", fmt.Sprintf("chgrp -h %s %s", internal.ShellQuote(fa.group), path)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyAttributes changes the owner, group and mode of the path when they differ from the stat.
+// With recurse, the files in the directory are checked by find and changed recursively.
+func (fa fileArgs) applyAttributes(ctx context.Context, r *runner, st *internal.PathStat) error {
+	type attribute struct {
+		action, value, find string
+		matched             bool
+	}
+	var attrs []attribute
+	if fa.owner != "" {
+		attrs = append(attrs, attribute{action: "chown", value: internal.ShellQuote(fa.owner), find: "! -user " + internal.ShellQuote(fa.owner),
+			matched: ownerMatched(fa.owner, st.Owner, st.UID)})
+	}
+	if fa.group != "" {
+		attrs = append(attrs, attribute{action: "chgrp", value: internal.ShellQuote(fa.group), find: "! -group " + internal.ShellQuote(fa.group),
+			matched: ownerMatched(fa.group, st.Group, st.GID)})
+	}
+	if fa.mode != nil {
+		attrs = append(attrs, attribute{action: "chmod", value: fmt.Sprintf("%04o", *fa.mode), find: fmt.Sprintf("! -perm %04o", *fa.mode),
+			matched: st.Mode == *fa.mode})
+	}
+	recurse := ""
+	if fa.recurse {
+		recurse = "-R "
+	}
+	for _, attr := range attrs {
+		if attr.matched && fa.recurse {
+			// the directory is matched, check the files in it.
+			stdout, _, err := r.conn.ExecuteCommand(ctx, fmt.Sprintf("find %s %s -print -quit", internal.ShellQuote(fa.path), attr.find))
+			attr.matched = err == nil && strings.TrimSpace(string(stdout)) == ""
+		}
+		if attr.matched {
+			continue
+		}
+		if err := r.run(ctx, attr.action, fmt.Sprintf("%s %s%s %s", attr.action, recurse, attr.value, internal.ShellQuote(fa.path))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ownerMatched returns true when the owner in args, which is a name or an id, is the same as the name or id of the path.
+func ownerMatched(want, name string, id int) bool {
+	return want == name || want == strconv.Itoa(id)
+}
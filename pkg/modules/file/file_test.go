@@ -0,0 +1,247 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakeStat answers stat, readlink and find by the preset output, and records the other commands.
+type fakeStat struct {
+	// stat is the output of stat for the path, empty when the path does not exist.
+	stat string
+	// followStat is the output of stat -L for the path.
+	followStat string
+	link       string
+	find       string
+	executed   []string
+}
+
+func (f *fakeStat) Init(context.Context) error { return nil }
+
+func (f *fakeStat) Close(context.Context) error { return nil }
+
+func (f *fakeStat) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (f *fakeStat) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakeStat) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	switch {
+	case strings.Contains(cmd, "then stat -L "):
+		return []byte(f.followStat), nil, nil
+	case strings.Contains(cmd, "then stat "):
+		return []byte(f.stat), nil, nil
+	case strings.HasPrefix(cmd, "readlink "):
+		return []byte(f.link + "\n"), nil, nil
+	case strings.HasPrefix(cmd, "find "):
+		return []byte(f.find), nil, nil
+	}
+	f.executed = append(f.executed, cmd)
+
+	return nil, nil, nil
+}
+
+func TestFileArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *fileArgs
+		wantErr bool
+	}{
+		{
+			name:   "directory with numeric mode",
+			args:   map[string]any{"path": "/etc/kubernetes/", "state": "directory", "mode": 0o755, "recurse": true},
+			except: &fileArgs{path: "/etc/kubernetes", state: stateDirectory, mode: ptr.To(fs.FileMode(0o755)), recurse: true},
+		},
+		{
+			name:   "file with string mode and owner",
+			args:   map[string]any{"path": "/etc/hosts", "mode": "0644", "owner": "root", "group": "0"},
+			except: &fileArgs{path: "/etc/hosts", state: stateFile, mode: ptr.To(fs.FileMode(0o644)), owner: "root", group: "0"},
+		},
+		{
+			name:   "link",
+			args:   map[string]any{"path": "/usr/bin/kubectl", "state": "link", "src": "/usr/local/bin/kubectl", "force": true},
+			except: &fileArgs{path: "/usr/bin/kubectl", state: stateLink, src: "/usr/local/bin/kubectl", force: true},
+		},
+		{
+			name:    "link without src",
+			args:    map[string]any{"path": "/usr/bin/kubectl", "state": "link"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			args:    map[string]any{"path": "/etc/hosts", "mode": "0789"},
+			wantErr: true,
+		},
+		{
+			name:    "recurse for file",
+			args:    map[string]any{"path": "/etc/hosts", "recurse": true},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported state",
+			args:    map[string]any{"path": "/etc/hosts", "state": "hard"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fa, err := newFileArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, fa)
+		})
+	}
+}
+
+func TestModuleFile(t *testing.T) {
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		conn           *fakeStat
+		exceptExecuted []string
+		exceptActions  []string
+		exceptErr      string
+	}{
+		{
+			name:           "create directory with mode",
+			args:           map[string]any{"path": "/etc/kubernetes/pki", "state": "directory", "mode": "0700"},
+			conn:           &fakeStat{},
+			exceptExecuted: []string{"mkdir -p '/etc/kubernetes/pki'", "chmod 0700 '/etc/kubernetes/pki'"},
+			exceptActions:  []string{"create", "chmod"},
+		},
+		{
+			name: "directory already in state",
+			args: map[string]any{"path": "/etc/kubernetes/pki", "state": "directory", "mode": "0755", "owner": "root"},
+			conn: &fakeStat{stat: "755 0 0 root root 4096 0 directory"},
+		},
+		{
+			name:           "recurse into directory",
+			args:           map[string]any{"path": "/var/lib/etcd", "state": "directory", "owner": "etcd", "recurse": true},
+			conn:           &fakeStat{stat: "700 1000 1000 etcd etcd 4096 0 directory", find: "/var/lib/etcd/member\n"},
+			exceptExecuted: []string{"chown -R 'etcd' '/var/lib/etcd'"},
+			exceptActions:  []string{"chown"},
+		},
+		{
+			name:      "directory is a file",
+			args:      map[string]any{"path": "/etc/hosts", "state": "directory"},
+			conn:      &fakeStat{stat: "644 0 0 root root 10 0 regular file"},
+			exceptErr: "not a directory",
+		},
+		{
+			name:           "change owner of file by uid",
+			args:           map[string]any{"path": "/etc/kubernetes/admin.conf", "owner": "1000", "group": "0"},
+			conn:           &fakeStat{stat: "600 0 0 root root 10 0 regular file"},
+			exceptExecuted: []string{"chown '1000' '/etc/kubernetes/admin.conf'"},
+			exceptActions:  []string{"chown"},
+		},
+		{
+			name:      "file not exist",
+			args:      map[string]any{"path": "/etc/kubernetes/admin.conf"},
+			conn:      &fakeStat{},
+			exceptErr: "does not exist",
+		},
+		{
+			name:           "remove path",
+			args:           map[string]any{"path": "/var/lib/etcd", "state": "absent"},
+			conn:           &fakeStat{stat: "700 0 0 root root 4096 0 directory"},
+			exceptExecuted: []string{"rm -rf '/var/lib/etcd'"},
+			exceptActions:  []string{"remove"},
+		},
+		{
+			name: "remove path which is not exist",
+			args: map[string]any{"path": "/var/lib/etcd", "state": "absent"},
+			conn: &fakeStat{},
+		},
+		{
+			name:           "touch new file",
+			args:           map[string]any{"path": "/etc/kubekey/flag", "state": "touch", "mode": 0o600},
+			conn:           &fakeStat{},
+			exceptExecuted: []string{"mkdir -p '/etc/kubekey' && touch '/etc/kubekey/flag'", "chmod 0600 '/etc/kubekey/flag'"},
+			exceptActions:  []string{"create", "chmod"},
+		},
+		{
+			name:           "create link",
+			args:           map[string]any{"path": "/usr/bin/kubectl", "state": "link", "src": "/usr/local/bin/kubectl"},
+			conn:           &fakeStat{},
+			exceptExecuted: []string{"mkdir -p '/usr/bin' && ln -s '/usr/local/bin/kubectl' '/usr/bin/kubectl'"},
+			exceptActions:  []string{"link"},
+		},
+		{
+			name: "link already in state",
+			args: map[string]any{"path": "/usr/bin/kubectl", "state": "link", "src": "/usr/local/bin/kubectl"},
+			conn: &fakeStat{stat: "777 0 0 root root 22 0 symbolic link", link: "/usr/local/bin/kubectl"},
+		},
+		{
+			name:           "update link",
+			args:           map[string]any{"path": "/usr/bin/kubectl", "state": "link", "src": "/usr/local/bin/kubectl"},
+			conn:           &fakeStat{stat: "777 0 0 root root 22 0 symbolic link", link: "/opt/kubectl"},
+			exceptExecuted: []string{"rm -rf '/usr/bin/kubectl' && ln -s '/usr/local/bin/kubectl' '/usr/bin/kubectl'"},
+			exceptActions:  []string{"link"},
+		},
+		{
+			name:      "replace file with link without force",
+			args:      map[string]any{"path": "/usr/bin/kubectl", "state": "link", "src": "/usr/local/bin/kubectl"},
+			conn:      &fakeStat{stat: "755 0 0 root root 100 0 regular file"},
+			exceptErr: "set \"force\"",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), internal.ConnKey, tc.conn)
+			stdout, _, err := ModuleFile(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err)
+			var result fileResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, len(tc.exceptActions) > 0, result.Changed)
+			assert.Equal(t, tc.exceptActions, result.Actions)
+			assert.Equal(t, tc.exceptExecuted, tc.conn.executed)
+		})
+	}
+}
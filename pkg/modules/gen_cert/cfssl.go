@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen_cert
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// cfsslCA signs certificates by a cfssl compatible signing API.
+// Certificates are signed by "/api/v1/cfssl/sign", or "/api/v1/cfssl/authsign" when the auth key is set.
+// The key usages and the validity period are decided by the signing profile.
+type cfsslCA struct {
+	client    *http.Client
+	address   string
+	profile   string
+	caProfile string
+	label     string
+	authKey   string
+}
+
+// cfsslName is a subject name of cfssl.
+type cfsslName struct {
+	C  string `json:"C,omitempty"`
+	ST string `json:"ST,omitempty"`
+	L  string `json:"L,omitempty"`
+	O  string `json:"O,omitempty"`
+	OU string `json:"OU,omitempty"`
+}
+
+// cfsslSubject is the subject of cfssl.
+type cfsslSubject struct {
+	CN    string      `json:"CN"`
+	Names []cfsslName `json:"names,omitempty"`
+}
+
+// cfsslSignRequest is the request body of the cfssl sign endpoint.
+type cfsslSignRequest struct {
+	CertificateRequest string        `json:"certificate_request"`
+	Hosts              []string      `json:"hosts,omitempty"`
+	Subject            *cfsslSubject `json:"subject,omitempty"`
+	Profile            string        `json:"profile,omitempty"`
+	Label              string        `json:"label,omitempty"`
+}
+
+// cfsslAuthRequest is the request body of the cfssl authsign endpoint.
+type cfsslAuthRequest struct {
+	Token   []byte `json:"token"`
+	Request []byte `json:"request"`
+}
+
+// cfsslInfoRequest is the request body of the cfssl info endpoint.
+type cfsslInfoRequest struct {
+	Label   string `json:"label,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// cfsslResponse is the response body of the cfssl endpoints.
+type cfsslResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		Certificate string `json:"certificate"`
+	} `json:"result"`
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// err returns the errors in the response.
+func (r cfsslResponse) err() error {
+	if r.Success {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		msgs = append(msgs, e.Message)
+	}
+
+	return errors.Errorf("cfssl request failed: %s", strings.Join(msgs, "; "))
+}
+
+// CACert returns the CA certificate of the signer by the info endpoint.
+func (c *cfsslCA) CACert(ctx context.Context) (*x509.Certificate, error) {
+	resp := &cfsslResponse{}
+	if err := postJSON(ctx, c.client, c.address+"/api/v1/cfssl/info", nil, cfsslInfoRequest{Label: c.label, Profile: c.profile}, resp); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	return parseCertPEM(resp.Result.Certificate)
+}
+
+// Sign signs the CSR by the signing profile.
+func (c *cfsslCA) Sign(ctx context.Context, req SignRequest) (*x509.Certificate, error) {
+	body := cfsslSignRequest{CertificateRequest: req.csrPEM(), Profile: c.profile, Label: c.label}
+	if req.IsCA {
+		body.Profile = c.caProfile
+	}
+	body.Hosts = append(body.Hosts, req.Config.AltNames.DNSNames...)
+	for _, ip := range req.Config.AltNames.IPs {
+		body.Hosts = append(body.Hosts, ip.String())
+	}
+	subject := req.Options.subject(req.Config)
+	name := cfsslName{C: first(subject.Country), ST: first(subject.Province), L: first(subject.Locality),
+		O: first(subject.Organization), OU: first(subject.OrganizationalUnit)}
+	body.Subject = &cfsslSubject{CN: subject.CommonName}
+	if name != (cfsslName{}) {
+		body.Subject.Names = []cfsslName{name}
+	}
+
+	url, reqBody := c.address+"/api/v1/cfssl/sign", any(body)
+	if c.authKey != "" {
+		key, err := hex.DecodeString(c.authKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "\"ca.auth_key\" should be hex encoded")
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal request")
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		url, reqBody = c.address+"/api/v1/cfssl/authsign", cfsslAuthRequest{Token: mac.Sum(nil), Request: data}
+	}
+	resp := &cfsslResponse{}
+	if err := postJSON(ctx, c.client, url, nil, reqBody, resp); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	return parseCertPEM(resp.Result.Certificate)
+}
+
+// first returns the first value, or empty string.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
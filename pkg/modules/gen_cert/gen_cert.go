@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	cryptorand "crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -13,6 +15,8 @@ import (
 	"math"
 	"math/big"
 	"net"
+	"os"
+	"slices"
 	"time"
 
 	"github.com/cockroachdb/errors"
@@ -32,21 +36,45 @@ import (
 /*
 The GenCert module is designed to generate SSL/TLS certificates for secure communications.
 It supports both self-signed certificates and certificates signed by a root Certificate Authority (CA).
+It can also emit a certificate signing request (CSR) instead of a certificate, or sign an existing CSR with the root CA.
+The root CA is the key and certificate files on the controller by default, or a remote CAProvider selected by "ca".
 
 Configuration:
 You can customize certificate generation with the following parameters:
 
 gen_cert:
-  cn: example.com             # required: Common Name for the certificate
-  out_key: /path/to/key       # required: Output path for the private key
-  out_cert: /path/to/cert     # required: Output path for the certificate
+  cn: example.com             # required: Common Name for the certificate. Optional when signing a CSR
+  out_key: /path/to/key       # required: Output path for the private key. Not used when signing a CSR
+  out_cert: /path/to/cert     # required: Output path for the certificate. Not used when emitting a CSR
+  out_csr: /path/to/csr       # optional: Emit a CSR to this path with out_key, instead of a certificate
+  csr: /path/to/csr           # optional: Sign the existing CSR with the root CA and write out_cert
   root_key: /path/to/ca.key   # optional: Path to the root CA private key
   root_cert: /path/to/ca.crt  # optional: Path to the root CA certificate
   sans:                       # optional: Subject Alternative Names (SANs)
     - example.com
     - www.example.com
+  key_type: rsa               # optional: rsa, ecdsa or ed25519 (default: rsa)
+  key_size: 2048              # optional: 2048 or more for rsa (default: 2048), 256, 384 or 521 for ecdsa (default: 256)
+  key_usage:                  # optional: key usages, such as digital_signature, key_encipherment, cert_sign
+    - digital_signature
+  ext_key_usage:              # optional: extended key usages, such as server_auth, client_auth
+    - server_auth
+  organization: [kubekey]     # optional: Organization of the subject (default: [kubekey])
+  organizational_unit: []     # optional: Organizational units of the subject
+  country: []                 # optional: Countries of the subject
+  province: []                # optional: Provinces of the subject
+  locality: []                # optional: Localities of the subject
   policy: IfNotPresent        # optional: Certificate generation policy
   date: 8760h                 # optional: Certificate validity period
+  ca:                         # optional: CA provider which signs certificates instead of root_key
+    type: vault               # file (default), vault or cfssl
+    address: https://vault:8200 # URL of the vault or cfssl server
+    mount: pki                # vault: path of the PKI secrets engine (default: pki)
+    role: kubernetes          # vault: role to sign leaf certificates
+    token_file: /path/to/token # vault: token, or "token" / the VAULT_TOKEN environment variable
+    profile: server           # cfssl: signing profile, and "ca_profile" for CA certificates
+    auth_key: "0123abcd"      # cfssl: hex auth key to use the authsign endpoint
+    ca_path: /path/to/ca.crt  # CA to verify the server, or insecure_skip_verify: true
 
 Usage Examples in Playbook Tasks:
 1. Generate a self-signed certificate:
@@ -71,9 +99,36 @@ Usage Examples in Playbook Tasks:
        root_cert: /etc/ssl/certs/ca.crt
        out_key: /etc/ssl/private/example.key
        out_cert: /etc/ssl/certs/example.crt
+       key_type: ecdsa
+       ext_key_usage: [server_auth]
      register: signed_cert
    ```
 
+3. Sign a CSR which is fetched from the node:
+   ```yaml
+   - name: Sign kubelet serving certificate
+     gen_cert:
+       csr: /tmp/pki/kubelet-node1.csr
+       root_key: /tmp/pki/kubernetes.key
+       root_cert: /tmp/pki/kubernetes.crt
+       ext_key_usage: [server_auth]
+       out_cert: /tmp/pki/kubelet-node1.crt
+   ```
+
+4. Sign by HashiCorp Vault without a CA key on the controller. The CA certificate of vault is written to root_cert:
+   ```yaml
+   - name: Generate etcd certificate by vault
+     gen_cert:
+       cn: etcd
+       root_cert: /tmp/pki/root.crt
+       out_key: /tmp/pki/etcd.key
+       out_cert: /tmp/pki/etcd.crt
+       ca:
+         type: vault
+         address: https://vault.example.com:8200
+         role: etcd
+   ```
+
 Return Values:
 - On success: "Success" is returned in stdout.
 - On failure: An error message is returned in stderr.
@@ -84,7 +139,16 @@ const (
 	defaultSignCertAfter = time.Hour * 24 * 365 * 10
 	// certificateBlockType is the PEM block type for certificates.
 	certificateBlockType = "CERTIFICATE"
-	rsaKeySize           = 2048
+	// certificateRequestBlockType is the PEM block type for certificate signing requests.
+	certificateRequestBlockType = "CERTIFICATE REQUEST"
+	// privateKeyBlockType is the PEM block type for PKCS#8 private keys.
+	privateKeyBlockType = "PRIVATE KEY"
+	rsaKeySize          = 2048
+
+	// Key types:
+	keyTypeRSA     = "rsa"
+	keyTypeECDSA   = "ecdsa"
+	keyTypeEd25519 = "ed25519"
 
 	// Certificate generation policies:
 	// policyAlways: Always generate a new certificate, overwriting any existing one.
@@ -101,6 +165,30 @@ var defaultAltName = &cgutilcert.AltNames{
 	IPs:      []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
 }
 
+// keyUsages maps the key_usage names to x509 key usages.
+var keyUsages = map[string]x509.KeyUsage{
+	"digital_signature":  x509.KeyUsageDigitalSignature,
+	"content_commitment": x509.KeyUsageContentCommitment,
+	"key_encipherment":   x509.KeyUsageKeyEncipherment,
+	"data_encipherment":  x509.KeyUsageDataEncipherment,
+	"key_agreement":      x509.KeyUsageKeyAgreement,
+	"cert_sign":          x509.KeyUsageCertSign,
+	"crl_sign":           x509.KeyUsageCRLSign,
+	"encipher_only":      x509.KeyUsageEncipherOnly,
+	"decipher_only":      x509.KeyUsageDecipherOnly,
+}
+
+// extKeyUsages maps the ext_key_usage names to x509 extended key usages.
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":              x509.ExtKeyUsageAny,
+	"server_auth":      x509.ExtKeyUsageServerAuth,
+	"client_auth":      x509.ExtKeyUsageClientAuth,
+	"code_signing":     x509.ExtKeyUsageCodeSigning,
+	"email_protection": x509.ExtKeyUsageEmailProtection,
+	"time_stamping":    x509.ExtKeyUsageTimeStamping,
+	"ocsp_signing":     x509.ExtKeyUsageOCSPSigning,
+}
+
 // genCertArgs holds the arguments for certificate generation.
 type genCertArgs struct {
 	rootKey  string
@@ -112,28 +200,133 @@ type genCertArgs struct {
 	outKey   string
 	outCert  string
 	isCA     *bool
+	// outCSR emits a CSR instead of a certificate.
+	outCSR string
+	// csr is the CSR to sign with the root CA.
+	csr string
+	// keyType and keySize of the generated key. Empty keyType and zero keySize are not checked for existing keys.
+	keyType string
+	keySize int
+	// extKeyUsage of the certificate, which is set to cgutilcert.Config.Usages.
+	extKeyUsage []x509.ExtKeyUsage
+	// options are the subject and usages of the certificate.
+	options CertOptions
+	// ca selects the CAProvider which signs the certificate.
+	ca caConfig
 }
 
-// signedCertificate generates a certificate signed by the specified root CA.
-func (gca genCertArgs) signedCertificate(cfg cgutilcert.Config) (string, string, error) {
-	// Load the CA private key.
-	caKey, err := TryLoadKeyFromDisk(gca.rootKey)
-	if err != nil {
-		return internal.StdoutFailed, "Failed to load root key", err
+// CertOptions holds the fields of a certificate which are not in cgutilcert.Config.
+type CertOptions struct {
+	// Subject holds the subject fields besides CommonName and Organization, which are set by cgutilcert.Config.
+	Subject pkix.Name
+	// KeyUsage of the certificate. Zero means the default usage of the key.
+	KeyUsage x509.KeyUsage
+}
+
+// subject returns the subject of the certificate.
+func (o CertOptions) subject(cfg cgutilcert.Config) pkix.Name {
+	subject := o.Subject
+	subject.CommonName, subject.Organization = cfg.CommonName, cfg.Organization
+
+	return subject
+}
+
+// mergeSubject returns the subject of base, with the fields which are set in override.
+func mergeSubject(base, override pkix.Name) pkix.Name {
+	for _, field := range []struct{ base, override *[]string }{
+		{&base.Organization, &override.Organization},
+		{&base.OrganizationalUnit, &override.OrganizationalUnit},
+		{&base.Country, &override.Country},
+		{&base.Province, &override.Province},
+		{&base.Locality, &override.Locality},
+	} {
+		if *field.override != nil {
+			*field.base = *field.override
+		}
+	}
+
+	return base
+}
+
+// keyUsage returns the key usage of the certificate. By default, key encipherment is only set for RSA keys.
+// Cert sign is always set for CA certificates.
+func (o CertOptions) keyUsage(pub crypto.PublicKey, isCA bool) x509.KeyUsage {
+	keyUsage := o.KeyUsage
+	if keyUsage == 0 {
+		keyUsage = x509.KeyUsageDigitalSignature
+		if _, ok := pub.(*rsa.PublicKey); ok {
+			keyUsage |= x509.KeyUsageKeyEncipherment
+		}
+	}
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+
+	return keyUsage
+}
+
+// generateKey generates a private key with the key type and size in the arguments.
+func (gca genCertArgs) generateKey() (crypto.Signer, error) {
+	switch gca.keyType {
+	case keyTypeECDSA:
+		curve := elliptic.P256()
+		switch gca.keySize {
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		}
+
+		return ecdsa.GenerateKey(curve, cryptorand.Reader)
+	case keyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(cryptorand.Reader)
+
+		return key, err
+	default:
+		return rsa.GenerateKey(cryptorand.Reader, max(gca.keySize, rsaKeySize))
+	}
+}
+
+// verifyKeyType checks the existing key matches key_type and key_size when they are set.
+func (gca genCertArgs) verifyKeyType(key crypto.Signer) error {
+	var keyType string
+	var keySize int
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		keyType, keySize = keyTypeRSA, k.N.BitLen()
+	case *ecdsa.PrivateKey:
+		keyType, keySize = keyTypeECDSA, k.Curve.Params().BitSize
+	case ed25519.PrivateKey:
+		keyType = keyTypeEd25519
 	}
-	// Load the CA certificate chain.
-	caCert, err := TryLoadCertChainFromDisk(gca.rootCert)
+	if gca.keyType != "" && gca.keyType != keyType {
+		return errors.Errorf("the key type is %s, expected %s", keyType, gca.keyType)
+	}
+	if gca.keySize != 0 && gca.keySize != keySize {
+		return errors.Errorf("the key size is %d, expected %d", keySize, gca.keySize)
+	}
+
+	return nil
+}
+
+// signedCertificate generates a certificate signed by the CA provider.
+func (gca genCertArgs) signedCertificate(ctx context.Context, cfg cgutilcert.Config) (string, string, error) {
+	provider, caCert, err := gca.loadCA(ctx)
 	if err != nil {
-		return internal.StdoutFailed, "Failed to load root certificate", err
+		return internal.StdoutFailed, "Failed to load root CA", err
 	}
 
 	// Helper function to generate and write a new certificate and key.
 	generateAndWrite := func() (string, string, error) {
-		newKey, err := rsa.GenerateKey(cryptorand.Reader, rsaKeySize)
+		newKey, err := gca.generateKey()
 		if err != nil {
-			return internal.StdoutFailed, "Failed to generate RSA key", err
+			return internal.StdoutFailed, "Failed to generate private key", err
 		}
-		newCert, err := NewSignedCert(cfg, gca.date, newKey, caCert[0], caKey, ptr.Deref(gca.isCA, false))
+		csr, _, err := newCSR(newKey, cfg, gca.options)
+		if err != nil {
+			return internal.StdoutFailed, "Failed to generate CSR", err
+		}
+		newCert, err := provider.Sign(ctx, SignRequest{CSR: csr, Config: cfg, Options: gca.options, Duration: gca.date, IsCA: ptr.Deref(gca.isCA, false)})
 		if err != nil {
 			return internal.StdoutFailed, "Failed to generate signed certificate", err
 		}
@@ -149,7 +342,11 @@ func (gca genCertArgs) signedCertificate(cfg cgutilcert.Config) (string, string,
 	// Helper function to verify the existing certificate and key.
 	verify := func() error {
 		// Check if the private key exists and is valid.
-		if _, err := TryLoadKeyFromDisk(gca.outKey); err != nil {
+		existKey, err := TryLoadKeyFromDisk(gca.outKey)
+		if err != nil {
+			return err
+		}
+		if err := gca.verifyKeyType(existKey); err != nil {
 			return err
 		}
 		// Check if the certificate exists and is valid.
@@ -157,37 +354,168 @@ func (gca genCertArgs) signedCertificate(cfg cgutilcert.Config) (string, string,
 		if err != nil {
 			return err
 		}
+		if !publicKeyEqual(existCert[0].PublicKey, existKey.Public()) {
+			return errors.Errorf("the public key of certificate %s does not match key %s", gca.outCert, gca.outKey)
+		}
 		// Validate the certificate's validity period.
 		if err := ValidateCertPeriod(existCert[0], 0); err != nil {
 			return err
 		}
 		// Validate the certificate chain.
-		if err := VerifyCertChain(existCert[0], existCert[:1], caCert[0]); err != nil {
+		if err := VerifyCertChain(existCert[0], existCert[:1], caCert); err != nil {
 			return err
 		}
 		// Validate the certificate's SANs and other configuration.
 		return validateCertificateWithConfig(existCert[0], gca.outCert, cfg)
 	}
 
-	switch gca.policy {
-	case policyAlways:
-		// For all other cases (including policyAlways), always generate a new certificate and key.
-		return generateAndWrite()
-	case policyIfNotPresent:
-		if err := verify(); err != nil {
-			klog.V(4).ErrorS(err, "Certificate or key verification failed, will regenerate", "outKey", gca.outKey, "outCert", gca.outCert)
-			return generateAndWrite()
+	return gca.applyPolicy(generateAndWrite, verify)
+}
+
+// loadCA creates the CA provider and returns it with the CA certificate.
+// The CA certificate of a remote provider is written to root_cert when it is set.
+func (gca genCertArgs) loadCA(ctx context.Context) (CAProvider, *x509.Certificate, error) {
+	provider, err := newCAProvider(gca.ca, gca.rootKey, gca.rootCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCert, err := provider.CACert(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gca.ca.remote() && gca.rootCert != "" {
+		if err := writeCACert(gca.rootCert, caCert); err != nil {
+			return nil, nil, err
 		}
-		// Existing certificate and key are valid; skip generation.
-		return internal.StdoutSkip, "", nil
-	case policyNone:
-		if err := verify(); err != nil {
-			return internal.StdoutFailed, "Certificate validation failed", err
+	}
+
+	return provider, caCert, nil
+}
+
+// newCSR creates a CSR of the key with the subject and SANs, and returns it with its PEM encoding.
+func newCSR(key crypto.Signer, cfg cgutilcert.Config, options CertOptions) (*x509.CertificateRequest, []byte, error) {
+	RemoveDuplicateAltNames(&cfg.AltNames)
+	encoded, err := cgutilcert.MakeCSRFromTemplate(key, &x509.CertificateRequest{
+		Subject:     options.subject(cfg),
+		DNSNames:    cfg.AltNames.DNSNames,
+		IPAddresses: cfg.AltNames.IPs,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create CSR")
+	}
+	block, _ := pem.Decode(encoded)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse CSR")
+	}
+
+	return csr, encoded, nil
+}
+
+// signCSR signs the CSR by the CA provider. The subject and SANs of the CSR are kept,
+// cn and organization in the arguments override the subject, and sans are appended to the SANs.
+func (gca genCertArgs) signCSR(ctx context.Context) (string, string, error) {
+	csr, err := TryLoadCSRFromDisk(gca.csr)
+	if err != nil {
+		return internal.StdoutFailed, "Failed to load CSR", err
+	}
+	provider, caCert, err := gca.loadCA(ctx)
+	if err != nil {
+		return internal.StdoutFailed, "Failed to load root CA", err
+	}
+
+	cfg := cgutilcert.Config{
+		CommonName:   csr.Subject.CommonName,
+		Organization: csr.Subject.Organization,
+		AltNames:     appendSANsToAltNames(&cgutilcert.AltNames{DNSNames: csr.DNSNames, IPs: csr.IPAddresses}, gca.sans),
+		Usages:       gca.extKeyUsage,
+	}
+	if gca.cn != "" {
+		cfg.CommonName = gca.cn
+	}
+	if organization := gca.options.Subject.Organization; len(organization) > 0 {
+		cfg.Organization = organization
+	}
+	options := gca.options
+	options.Subject = mergeSubject(csr.Subject, options.Subject)
+
+	generateAndWrite := func() (string, string, error) {
+		newCert, err := provider.Sign(ctx, SignRequest{CSR: csr, Config: cfg, Options: options, Duration: gca.date, IsCA: ptr.Deref(gca.isCA, false)})
+		if err != nil {
+			return internal.StdoutFailed, "Failed to sign CSR", err
 		}
-		return internal.StdoutSkip, "", nil
-	default:
-		return internal.StdoutFailed, "unsupport policy", errors.New("unsupport policy")
+		if err := WriteCert(gca.outCert, newCert, gca.policy); err != nil {
+			return internal.StdoutFailed, "Failed to write certificate", err
+		}
+
+		return internal.StdoutSuccess, "", nil
+	}
+
+	verify := func() error {
+		existCert, err := TryLoadCertChainFromDisk(gca.outCert)
+		if err != nil {
+			return err
+		}
+		if !publicKeyEqual(existCert[0].PublicKey, csr.PublicKey) {
+			return errors.Errorf("the public key of certificate %s does not match CSR %s", gca.outCert, gca.csr)
+		}
+		if err := ValidateCertPeriod(existCert[0], 0); err != nil {
+			return err
+		}
+		if err := VerifyCertChain(existCert[0], existCert[:1], caCert); err != nil {
+			return err
+		}
+
+		return validateCertificateWithConfig(existCert[0], gca.outCert, cfg)
+	}
+
+	return gca.applyPolicy(generateAndWrite, verify)
+}
+
+// generateCSR generates a private key and a CSR with the subject and SANs, and writes them to out_key and out_csr.
+func (gca genCertArgs) generateCSR(cfg cgutilcert.Config) (string, string, error) {
+	generateAndWrite := func() (string, string, error) {
+		newKey, err := gca.generateKey()
+		if err != nil {
+			return internal.StdoutFailed, "Failed to generate private key", err
+		}
+		_, csr, err := newCSR(newKey, cfg, gca.options)
+		if err != nil {
+			return internal.StdoutFailed, "Failed to generate CSR", err
+		}
+		if err := WriteKey(gca.outKey, newKey, gca.policy); err != nil {
+			return internal.StdoutFailed, "Failed to write private key", err
+		}
+		if err := keyutil.WriteKey(gca.outCSR, csr); err != nil {
+			return internal.StdoutFailed, "Failed to write CSR", errors.Wrapf(err, "failed to write CSR to file %s", gca.outCSR)
+		}
+
+		return internal.StdoutSuccess, "", nil
+	}
+
+	verify := func() error {
+		existKey, err := TryLoadKeyFromDisk(gca.outKey)
+		if err != nil {
+			return err
+		}
+		if err := gca.verifyKeyType(existKey); err != nil {
+			return err
+		}
+		csr, err := TryLoadCSRFromDisk(gca.outCSR)
+		if err != nil {
+			return err
+		}
+		if !publicKeyEqual(csr.PublicKey, existKey.Public()) {
+			return errors.Errorf("the public key of CSR %s does not match key %s", gca.outCSR, gca.outKey)
+		}
+		if csr.Subject.CommonName != cfg.CommonName {
+			return errors.Errorf("the common name of CSR %s is %q, expected %q", gca.outCSR, csr.Subject.CommonName, cfg.CommonName)
+		}
+
+		return nil
 	}
+
+	return gca.applyPolicy(generateAndWrite, verify)
 }
 
 // selfSignedCertificate creates a self-signed certificate and writes it to disk according to the specified policy.
@@ -195,12 +523,12 @@ func (gca genCertArgs) signedCertificate(cfg cgutilcert.Config) (string, string,
 func (gca genCertArgs) selfSignedCertificate(cfg cgutilcert.Config) (string, string, error) {
 	// Generates a new self-signed certificate and writes both the key and certificate to their respective files.
 	generateAndWrite := func() (string, string, error) {
-		newKey, err := rsa.GenerateKey(cryptorand.Reader, rsaKeySize)
+		newKey, err := gca.generateKey()
 		if err != nil {
-			return internal.StdoutFailed, "Unable to generate RSA private key", err
+			return internal.StdoutFailed, "Unable to generate private key", err
 		}
 
-		newCert, err := NewSelfSignedCACert(cfg, gca.date, newKey)
+		newCert, err := NewSelfSignedCACert(cfg, gca.options, gca.date, newKey)
 		if err != nil {
 			return internal.StdoutFailed, "Unable to generate self-signed certificate", err
 		}
@@ -218,7 +546,11 @@ func (gca genCertArgs) selfSignedCertificate(cfg cgutilcert.Config) (string, str
 
 	// Verifies that both the private key and certificate exist and are valid.
 	verify := func() error {
-		if _, err := TryLoadKeyFromDisk(gca.outKey); err != nil {
+		existKey, err := TryLoadKeyFromDisk(gca.outKey)
+		if err != nil {
+			return err
+		}
+		if err := gca.verifyKeyType(existKey); err != nil {
 			return err
 		}
 		if _, err := TryLoadCertChainFromDisk(gca.outCert); err != nil {
@@ -227,21 +559,26 @@ func (gca genCertArgs) selfSignedCertificate(cfg cgutilcert.Config) (string, str
 		return nil
 	}
 
+	return gca.applyPolicy(generateAndWrite, verify)
+}
+
+// applyPolicy generates or verifies the output files according to the policy.
+func (gca genCertArgs) applyPolicy(generateAndWrite func() (string, string, error), verify func() error) (string, string, error) {
 	switch gca.policy {
 	case policyAlways:
-		// Always generate a new certificate and key, regardless of existing files.
+		// Always generate new files, regardless of existing files.
 		return generateAndWrite()
 	case policyIfNotPresent:
 		// If verification fails, log and regenerate; otherwise, skip generation.
 		if err := verify(); err != nil {
-			klog.V(4).ErrorS(err, "Existing self-signed certificate or key is invalid or missing, regenerating", "outKey", gca.outKey, "outCert", gca.outCert)
+			klog.V(4).ErrorS(err, "Existing files are invalid or missing, regenerating", "outKey", gca.outKey, "outCert", gca.outCert, "outCSR", gca.outCSR)
 			return generateAndWrite()
 		}
 		return internal.StdoutSkip, "", nil
 	case policyNone:
-		// Only verify the presence and validity of the certificate and key.
+		// Only verify the presence and validity of the existing files.
 		if err := verify(); err != nil {
-			return internal.StdoutFailed, "Self-signed certificate or key validation failed", err
+			return internal.StdoutFailed, "Certificate validation failed", err
 		}
 		return internal.StdoutSkip, "", nil
 	default:
@@ -262,12 +599,51 @@ func newGenCertArgs(_ context.Context, raw runtime.RawExtension, vars map[string
 	gca.cn, _ = variable.StringVar(vars, args, "cn")
 	gca.outKey, _ = variable.StringVar(vars, args, "out_key")
 	gca.outCert, _ = variable.StringVar(vars, args, "out_cert")
+	gca.outCSR, _ = variable.StringVar(vars, args, "out_csr")
+	gca.csr, _ = variable.StringVar(vars, args, "csr")
 	gca.isCA, _ = variable.BoolVar(vars, args, "is_ca")
+	gca.keyType, _ = variable.StringVar(vars, args, "key_type")
+	if _, ok := args["key_size"]; ok {
+		keySize, err := variable.IntVar(vars, args, "key_size")
+		if err != nil {
+			return nil, errors.New("\"key_size\" should be int")
+		}
+		gca.keySize = *keySize
+	}
+	if err := gca.parseOptions(vars, args); err != nil {
+		return nil, err
+	}
+	if _, ok := args["ca"]; ok {
+		if err := variable.AnyVar(vars, args, &gca.ca, "ca"); err != nil {
+			return nil, errors.Wrap(err, "\"ca\" should be a map")
+		}
+		if err := gca.ca.validate(); err != nil {
+			return nil, err
+		}
+	}
 	// Validate arguments.
 	if gca.policy != policyAlways && gca.policy != policyIfNotPresent && gca.policy != policyNone {
 		return nil, errors.New("\"policy\" must be one of [Always, IfNotPresent, None]")
 	}
-	if gca.outKey == "" || gca.outCert == "" {
+	if err := gca.validateKey(); err != nil {
+		return nil, err
+	}
+	switch {
+	case gca.csr != "" && gca.outCSR != "":
+		return nil, errors.New("\"csr\" and \"out_csr\" are mutually exclusive")
+	case gca.csr != "":
+		if !gca.ca.remote() && (gca.rootKey == "" || gca.rootCert == "") {
+			return nil, errors.New("\"root_key\" and \"root_cert\" must be specified to sign \"csr\"")
+		}
+		if gca.outCert == "" {
+			return nil, errors.New("\"out_cert\" must be specified as a string")
+		}
+		return gca, nil
+	case gca.outCSR != "":
+		if gca.outKey == "" {
+			return nil, errors.New("\"out_key\" must be specified as a string")
+		}
+	case gca.outKey == "" || gca.outCert == "":
 		return nil, errors.New("\"out_key\" and \"out_cert\" must be specified as strings")
 	}
 	if gca.cn == "" {
@@ -277,6 +653,77 @@ func newGenCertArgs(_ context.Context, raw runtime.RawExtension, vars map[string
 	return gca, nil
 }
 
+// validateKey checks key_type and key_size.
+func (gca genCertArgs) validateKey() error {
+	switch gca.keyType {
+	case "", keyTypeRSA:
+		if gca.keySize != 0 && gca.keySize < rsaKeySize {
+			return errors.Errorf("\"key_size\" of rsa should be at least %d", rsaKeySize)
+		}
+	case keyTypeECDSA:
+		if gca.keySize != 0 && !slices.Contains([]int{256, 384, 521}, gca.keySize) {
+			return errors.New("\"key_size\" of ecdsa should be one of [256, 384, 521]")
+		}
+	case keyTypeEd25519:
+		if gca.keySize != 0 {
+			return errors.New("\"key_size\" is not supported for ed25519")
+		}
+	default:
+		return errors.Errorf("\"key_type\" must be one of [%s, %s, %s]", keyTypeRSA, keyTypeECDSA, keyTypeEd25519)
+	}
+
+	return nil
+}
+
+// parseOptions parses the subject fields, key_usage and ext_key_usage.
+func (gca *genCertArgs) parseOptions(vars, args map[string]any) error {
+	subject := &gca.options.Subject
+	for key, field := range map[string]*[]string{
+		"organization":        &subject.Organization,
+		"organizational_unit": &subject.OrganizationalUnit,
+		"country":             &subject.Country,
+		"province":            &subject.Province,
+		"locality":            &subject.Locality,
+	} {
+		if _, ok := args[key]; !ok {
+			continue
+		}
+		val, err := variable.StringSliceVar(vars, args, key)
+		if err != nil {
+			return errors.Errorf("%q should be string slice", key)
+		}
+		*field = val
+	}
+	if _, ok := args["key_usage"]; ok {
+		names, err := variable.StringSliceVar(vars, args, "key_usage")
+		if err != nil {
+			return errors.New("\"key_usage\" should be string slice")
+		}
+		for _, name := range names {
+			usage, ok := keyUsages[name]
+			if !ok {
+				return errors.Errorf("unsupported key usage %q", name)
+			}
+			gca.options.KeyUsage |= usage
+		}
+	}
+	if _, ok := args["ext_key_usage"]; ok {
+		names, err := variable.StringSliceVar(vars, args, "ext_key_usage")
+		if err != nil {
+			return errors.New("\"ext_key_usage\" should be string slice")
+		}
+		for _, name := range names {
+			usage, ok := extKeyUsages[name]
+			if !ok {
+				return errors.Errorf("unsupported extended key usage %q", name)
+			}
+			gca.extKeyUsage = append(gca.extKeyUsage, usage)
+		}
+	}
+
+	return nil
+}
+
 // ModuleGenCert is the entry point for the "gen_cert" module, responsible for generating SSL/TLS certificates.
 func ModuleGenCert(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
 	// Retrieve all host variables.
@@ -289,18 +736,28 @@ func ModuleGenCert(ctx context.Context, opts internal.ExecOptions) (string, stri
 	if err != nil {
 		return internal.StdoutFailed, internal.StderrParseArgument, err
 	}
+	if gca.csr != "" {
+		return gca.signCSR(ctx)
+	}
 
+	organization := gca.options.Subject.Organization
+	if organization == nil {
+		organization = []string{"kubekey"}
+	}
 	cfg := &cgutilcert.Config{
 		CommonName:   gca.cn,
-		Organization: []string{"kubekey"},
+		Organization: organization,
 		AltNames:     appendSANsToAltNames(defaultAltName, gca.sans),
+		Usages:       gca.extKeyUsage,
 	}
 
 	switch {
-	case gca.rootKey == "" || gca.rootCert == "":
+	case gca.outCSR != "":
+		return gca.generateCSR(*cfg)
+	case !gca.ca.remote() && (gca.rootKey == "" || gca.rootCert == ""):
 		return gca.selfSignedCertificate(*cfg)
 	default:
-		return gca.signedCertificate(*cfg)
+		return gca.signedCertificate(ctx, *cfg)
 	}
 }
 
@@ -310,9 +767,19 @@ func WriteKey(outKey string, key crypto.Signer, policy string) error {
 		return errors.New("private key cannot be nil when writing to file")
 	}
 
-	encoded, err := keyutil.MarshalPrivateKeyToPEM(key)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal private key to PEM")
+	var encoded []byte
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		// keyutil only marshals RSA and ECDSA keys, ed25519 keys are in PKCS#8 format.
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal private key to PKCS#8")
+		}
+		encoded = pem.EncodeToMemory(&pem.Block{Type: privateKeyBlockType, Bytes: der})
+	} else {
+		var err error
+		if encoded, err = keyutil.MarshalPrivateKeyToPEM(key); err != nil {
+			return errors.Wrap(err, "failed to marshal private key to PEM")
+		}
 	}
 	if err := keyutil.WriteKey(outKey, encoded); err != nil {
 		return errors.Wrapf(err, "failed to write private key to file %s", outKey)
@@ -352,20 +819,50 @@ func TryLoadKeyFromDisk(rootKey string) (crypto.Signer, error) {
 		return nil, errors.Wrapf(err, "failed to load the private key file %s", rootKey)
 	}
 
-	// Only RSA and ECDSA private keys are supported.
+	// Only RSA, ECDSA and Ed25519 private keys are supported.
 	var key crypto.Signer
 	switch k := privKey.(type) {
 	case *rsa.PrivateKey:
 		key = k
 	case *ecdsa.PrivateKey:
 		key = k
+	case ed25519.PrivateKey:
+		key = k
 	default:
-		return nil, errors.Errorf("the private key file %s is neither in RSA nor ECDSA format", rootKey)
+		return nil, errors.Errorf("the private key file %s is not in RSA, ECDSA or Ed25519 format", rootKey)
 	}
 
 	return key, nil
 }
 
+// TryLoadCSRFromDisk loads a certificate signing request from the specified file and checks its signature.
+func TryLoadCSRFromDisk(csrFile string) (*x509.CertificateRequest, error) {
+	data, err := os.ReadFile(csrFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the CSR file %s", csrFile)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certificateRequestBlockType {
+		return nil, errors.Errorf("the CSR file %s does not contain a PEM encoded %s", csrFile, certificateRequestBlockType)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the CSR file %s", csrFile)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrapf(err, "failed to check the signature of the CSR file %s", csrFile)
+	}
+
+	return csr, nil
+}
+
+// publicKeyEqual reports whether the public keys are the same.
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+
+	return ok && k.Equal(b)
+}
+
 // TryLoadCertChainFromDisk loads a certificate chain from the specified file.
 func TryLoadCertChainFromDisk(rootCert string) ([]*x509.Certificate, error) {
 	return cgutilcert.CertsFromFile(rootCert)
@@ -394,7 +891,7 @@ func appendSANsToAltNames(altNames *cgutilcert.AltNames, sans []string) cgutilce
 }
 
 // NewSelfSignedCACert creates a new self-signed CA certificate.
-func NewSelfSignedCACert(cfg cgutilcert.Config, after time.Duration, key crypto.Signer) (*x509.Certificate, error) {
+func NewSelfSignedCACert(cfg cgutilcert.Config, opts CertOptions, after time.Duration, key crypto.Signer) (*x509.Certificate, error) {
 	now := time.Now()
 	// Generate a random serial number in the range [1, MaxInt64).
 	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64-1))
@@ -412,15 +909,13 @@ func NewSelfSignedCACert(cfg cgutilcert.Config, after time.Duration, key crypto.
 	}
 
 	tmpl := x509.Certificate{
-		SerialNumber: serial,
-		Subject: pkix.Name{
-			CommonName:   cfg.CommonName,
-			Organization: cfg.Organization,
-		},
+		SerialNumber:          serial,
+		Subject:               opts.subject(cfg),
 		DNSNames:              []string{cfg.CommonName},
 		NotBefore:             notBefore,
 		NotAfter:              now.Add(after).UTC(),
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              opts.keyUsage(key.Public(), true),
+		ExtKeyUsage:           cfg.Usages,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
 	}
@@ -433,8 +928,8 @@ func NewSelfSignedCACert(cfg cgutilcert.Config, after time.Duration, key crypto.
 	return x509.ParseCertificate(certDERBytes)
 }
 
-// NewSignedCert creates a certificate signed by the given CA certificate and key.
-func NewSignedCert(cfg cgutilcert.Config, after time.Duration, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer, isCA bool) (*x509.Certificate, error) {
+// NewSignedCert creates a certificate of the public key signed by the given CA certificate and key.
+func NewSignedCert(cfg cgutilcert.Config, opts CertOptions, after time.Duration, pub crypto.PublicKey, caCert *x509.Certificate, caKey crypto.Signer, isCA bool) (*x509.Certificate, error) {
 	// Generate a random serial number in the range [1, MaxInt64).
 	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64-1))
 	if err != nil {
@@ -446,11 +941,6 @@ func NewSignedCert(cfg cgutilcert.Config, after time.Duration, key crypto.Signer
 		return nil, errors.New("commonName must be specified")
 	}
 
-	keyUsage := x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
-	if isCA {
-		keyUsage |= x509.KeyUsageCertSign
-	}
-
 	RemoveDuplicateAltNames(&cfg.AltNames)
 
 	if after == 0 {
@@ -458,22 +948,19 @@ func NewSignedCert(cfg cgutilcert.Config, after time.Duration, key crypto.Signer
 	}
 
 	certTmpl := x509.Certificate{
-		Subject: pkix.Name{
-			CommonName:   cfg.CommonName,
-			Organization: cfg.Organization,
-		},
+		Subject:               opts.subject(cfg),
 		DNSNames:              cfg.AltNames.DNSNames,
 		IPAddresses:           cfg.AltNames.IPs,
 		SerialNumber:          serial,
 		NotBefore:             caCert.NotBefore,
 		NotAfter:              time.Now().Add(after).UTC(),
-		KeyUsage:              keyUsage,
+		KeyUsage:              opts.keyUsage(pub, isCA),
 		ExtKeyUsage:           cfg.Usages,
 		BasicConstraintsValid: true,
 		IsCA:                  isCA,
 	}
 
-	certDERBytes, err := x509.CreateCertificate(cryptorand.Reader, &certTmpl, caCert, key.Public(), caKey)
+	certDERBytes, err := x509.CreateCertificate(cryptorand.Reader, &certTmpl, caCert, pub, caKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create certificate")
 	}
@@ -539,8 +1026,14 @@ func VerifyCertChain(cert *x509.Certificate, intermediates []*x509.Certificate,
 	return nil
 }
 
-// validateCertificateWithConfig ensures that the certificate is valid for all SANs specified in the configuration.
+// validateCertificateWithConfig ensures that the certificate is valid for all SANs and extended key usages specified in the configuration.
 func validateCertificateWithConfig(cert *x509.Certificate, baseName string, cfg cgutilcert.Config) error {
+	for _, usage := range cfg.Usages {
+		if !slices.Contains(cert.ExtKeyUsage, usage) {
+			return errors.Errorf("certificate %s is invalid: missing extended key usage %v", baseName, usage)
+		}
+	}
+
 	for _, dnsName := range cfg.AltNames.DNSNames {
 		if err := cert.VerifyHostname(dnsName); err != nil {
 			return errors.Wrapf(err, "certificate %s is invalid", baseName)
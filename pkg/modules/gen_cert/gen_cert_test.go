@@ -18,11 +18,19 @@ package gen_cert
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
 )
 
 // createRawArgs creates a runtime.RawExtension from a map
@@ -94,6 +102,72 @@ func TestGenCertArgsParse(t *testing.T) {
 			expectParseError: false,
 			description:      "When policy is IfNotPresent, should parse successfully",
 		},
+		{
+			name:             "valid ecdsa key",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_type": "ecdsa", "key_size": 384},
+			expectParseError: false,
+			description:      "When key_type is ecdsa with a supported size, should parse successfully",
+		},
+		{
+			name:             "invalid ecdsa key size",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_type": "ecdsa", "key_size": 2048},
+			expectParseError: true,
+			description:      "When key_size is not a supported curve, should return error",
+		},
+		{
+			name:             "small rsa key size",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_size": 1024},
+			expectParseError: true,
+			description:      "When key_size of rsa is less than 2048, should return error",
+		},
+		{
+			name:             "ed25519 with key size",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_type": "ed25519", "key_size": 256},
+			expectParseError: true,
+			description:      "When key_size is set for ed25519, should return error",
+		},
+		{
+			name:             "invalid key type",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_type": "dsa"},
+			expectParseError: true,
+			description:      "When key_type is not supported, should return error",
+		},
+		{
+			name:             "invalid key usage",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "key_usage": []string{"sign_everything"}},
+			expectParseError: true,
+			description:      "When key_usage is not supported, should return error",
+		},
+		{
+			name:             "invalid ext key usage",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always", "ext_key_usage": []string{"web"}},
+			expectParseError: true,
+			description:      "When ext_key_usage is not supported, should return error",
+		},
+		{
+			name:             "emit csr without out_cert",
+			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_csr": "/tmp/req.csr", "policy": "Always"},
+			expectParseError: false,
+			description:      "When out_csr is set, out_cert is not required",
+		},
+		{
+			name:             "sign csr without cn",
+			args:             map[string]any{"csr": "/tmp/req.csr", "root_key": "/tmp/root.key", "root_cert": "/tmp/root.crt", "out_cert": "/tmp/cert.pem", "policy": "Always"},
+			expectParseError: false,
+			description:      "When csr is set, cn and out_key are not required",
+		},
+		{
+			name:             "sign csr without root",
+			args:             map[string]any{"csr": "/tmp/req.csr", "out_cert": "/tmp/cert.pem", "policy": "Always"},
+			expectParseError: true,
+			description:      "When csr is set without root_key and root_cert, should return error",
+		},
+		{
+			name:             "csr and out_csr",
+			args:             map[string]any{"csr": "/tmp/req.csr", "out_csr": "/tmp/out.csr", "root_key": "/tmp/root.key", "root_cert": "/tmp/root.crt", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "Always"},
+			expectParseError: true,
+			description:      "When both csr and out_csr are set, should return error",
+		},
 		{
 			name:             "valid policy None",
 			args:             map[string]any{"cn": "test.example.com", "out_key": "/tmp/key.pem", "out_cert": "/tmp/cert.pem", "policy": "None"},
@@ -162,9 +236,91 @@ func TestGenCertArgsTemplate(t *testing.T) {
 	}
 }
 
+// runGenCert executes the gen_cert module with the arguments.
+func runGenCert(t *testing.T, args map[string]any) string {
+	t.Helper()
+	stdout, stderr, err := ModuleGenCert(context.Background(), internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(args),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.NoError(t, err, stderr)
+
+	return stdout
+}
+
+// loadCert loads the first certificate in the file.
+func loadCert(t *testing.T, path string) *x509.Certificate {
+	t.Helper()
+	certs, err := TryLoadCertChainFromDisk(path)
+	require.NoError(t, err)
+
+	return certs[0]
+}
+
 // TestGenCertModule tests the actual functionality of the gen_cert module.
 func TestGenCertModule(t *testing.T) {
-	t.Run("module exists", func(t *testing.T) {
-		require.NotNil(t, ModuleGenCert)
+	dir := t.TempDir()
+	rootKey, rootCert := filepath.Join(dir, "root.key"), filepath.Join(dir, "root.crt")
+
+	t.Run("self-signed ecdsa ca", func(t *testing.T) {
+		stdout := runGenCert(t, map[string]any{"cn": "root", "policy": "IfNotPresent", "key_type": "ecdsa", "key_size": 384,
+			"organization": []string{"example"}, "country": []string{"CN"}, "out_key": rootKey, "out_cert": rootCert})
+		assert.Equal(t, internal.StdoutSuccess, stdout)
+		cert := loadCert(t, rootCert)
+		assert.True(t, cert.IsCA)
+		assert.Equal(t, []string{"example"}, cert.Subject.Organization)
+		assert.Equal(t, []string{"CN"}, cert.Subject.Country)
+		assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign, cert.KeyUsage)
+		pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		require.True(t, ok)
+		assert.Equal(t, 384, pub.Curve.Params().BitSize)
+		// the existing key matches, so it is skipped.
+		assert.Equal(t, internal.StdoutSkip, runGenCert(t, map[string]any{"cn": "root", "policy": "IfNotPresent", "key_type": "ecdsa", "key_size": 384,
+			"out_key": rootKey, "out_cert": rootCert}))
+	})
+
+	t.Run("signed ed25519 cert with usages", func(t *testing.T) {
+		key, cert := filepath.Join(dir, "server.key"), filepath.Join(dir, "server.crt")
+		args := map[string]any{"cn": "server", "policy": "IfNotPresent", "root_key": rootKey, "root_cert": rootCert, "key_type": "ed25519",
+			"key_usage": []string{"digital_signature"}, "ext_key_usage": []string{"server_auth", "client_auth"}, "sans": []string{"10.0.0.1"},
+			"out_key": key, "out_cert": cert}
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		c := loadCert(t, cert)
+		assert.Equal(t, x509.KeyUsageDigitalSignature, c.KeyUsage)
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, c.ExtKeyUsage)
+		assert.Equal(t, []string{"kubekey"}, c.Subject.Organization)
+		assert.IsType(t, ed25519.PublicKey{}, c.PublicKey)
+		require.NoError(t, c.VerifyHostname("10.0.0.1"))
+		assert.Equal(t, internal.StdoutSkip, runGenCert(t, args))
+		// the key type is changed, so it is regenerated.
+		args["key_type"] = "rsa"
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		assert.IsType(t, &rsa.PublicKey{}, loadCert(t, cert).PublicKey)
+	})
+
+	t.Run("emit and sign csr", func(t *testing.T) {
+		key, csr, cert := filepath.Join(dir, "kubelet.key"), filepath.Join(dir, "kubelet.csr"), filepath.Join(dir, "kubelet.crt")
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, map[string]any{"cn": "system:node:node1", "organization": []string{"system:nodes"},
+			"key_type": "ecdsa", "sans": []string{"node1"}, "policy": "IfNotPresent", "out_key": key, "out_csr": csr}))
+		req, err := TryLoadCSRFromDisk(csr)
+		require.NoError(t, err)
+		assert.Equal(t, "system:node:node1", req.Subject.CommonName)
+		assert.Contains(t, req.DNSNames, "node1")
+
+		args := map[string]any{"csr": csr, "root_key": rootKey, "root_cert": rootCert, "ext_key_usage": []string{"server_auth"},
+			"sans": []string{"192.168.0.1"}, "policy": "IfNotPresent", "out_cert": cert}
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		c := loadCert(t, cert)
+		assert.Equal(t, "system:node:node1", c.Subject.CommonName)
+		assert.Equal(t, []string{"system:nodes"}, c.Subject.Organization)
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, c.ExtKeyUsage)
+		require.NoError(t, c.VerifyHostname("node1"))
+		require.NoError(t, c.VerifyHostname("192.168.0.1"))
+		k, err := TryLoadKeyFromDisk(key)
+		require.NoError(t, err)
+		assert.True(t, publicKeyEqual(c.PublicKey, k.Public()))
+		require.NoError(t, VerifyCertChain(c, nil, loadCert(t, rootCert)))
+		assert.Equal(t, internal.StdoutSkip, runGenCert(t, args))
 	})
 }
@@ -0,0 +1,306 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen_cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	cgutilcert "k8s.io/client-go/util/cert"
+)
+
+const (
+	// caTypeFile signs certificates by the CA key and certificate files on the controller.
+	caTypeFile = "file"
+	// caTypeVault signs certificates by the PKI secrets engine of HashiCorp Vault.
+	caTypeVault = "vault"
+	// caTypeCFSSL signs certificates by a cfssl compatible signing API.
+	caTypeCFSSL = "cfssl"
+
+	// defaultCATimeout is the default timeout of requests to remote CA providers.
+	defaultCATimeout = 30 * time.Second
+)
+
+// CAProvider is a certificate authority which signs certificates for gen_cert.
+type CAProvider interface {
+	// CACert returns the CA certificate which verifies the signed certificates.
+	CACert(ctx context.Context) (*x509.Certificate, error)
+	// Sign signs a certificate for the public key in the request.
+	Sign(ctx context.Context, req SignRequest) (*x509.Certificate, error)
+}
+
+// SignRequest is a request to sign a certificate by a CAProvider.
+type SignRequest struct {
+	// CSR holds the public key to sign. Its PEM encoding is sent to remote providers.
+	CSR *x509.CertificateRequest
+	// Config holds the common name, organization, SANs and extended key usages of the certificate.
+	Config cgutilcert.Config
+	// Options holds the other subject fields and the key usage of the certificate.
+	Options CertOptions
+	// Duration is the validity period of the certificate. Zero means the default of the provider.
+	Duration time.Duration
+	// IsCA signs an intermediate CA certificate.
+	IsCA bool
+}
+
+// csrPEM returns the PEM encoding of the CSR in the request.
+func (r SignRequest) csrPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: certificateRequestBlockType, Bytes: r.CSR.Raw}))
+}
+
+// caConfig is the "ca" argument of gen_cert, which selects and configures the CAProvider.
+type caConfig struct {
+	// Type is one of file, vault or cfssl.
+	Type string `json:"type"`
+	// Address is the URL of the vault or cfssl server.
+	Address string `json:"address"`
+	// Mount is the path of the vault PKI secrets engine.
+	Mount string `json:"mount"`
+	// Role is the vault PKI role to sign certificates.
+	Role string `json:"role"`
+	// Token is the vault token. TokenFile or the VAULT_TOKEN environment variable is used when it is empty.
+	Token string `json:"token"`
+	// TokenFile is the file which holds the vault token.
+	TokenFile string `json:"token_file"`
+	// Namespace is the vault enterprise namespace.
+	Namespace string `json:"namespace"`
+	// Profile is the cfssl signing profile.
+	Profile string `json:"profile"`
+	// CAProfile is the cfssl signing profile for intermediate CA certificates. It defaults to Profile.
+	CAProfile string `json:"ca_profile"`
+	// Label is the cfssl signer label.
+	Label string `json:"label"`
+	// AuthKey is the hex encoded cfssl auth key. The authsign endpoint is used when it is set.
+	AuthKey string `json:"auth_key"`
+	// CAPath is the CA certificate file to verify the server certificate.
+	CAPath string `json:"ca_path"`
+	// InsecureSkipVerify skips the verification of the server certificate.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	// Timeout of each request, such as "30s".
+	Timeout string `json:"timeout"`
+}
+
+// remote reports whether the provider signs certificates by a remote server.
+func (c caConfig) remote() bool {
+	return c.Type == caTypeVault || c.Type == caTypeCFSSL
+}
+
+// validate checks the configuration of the provider type.
+func (c caConfig) validate() error {
+	switch c.Type {
+	case "", caTypeFile:
+		return nil
+	case caTypeVault:
+		if c.Role == "" {
+			return errors.New("\"ca.role\" must be specified for vault")
+		}
+	case caTypeCFSSL:
+	default:
+		return errors.Errorf("\"ca.type\" must be one of [%s, %s, %s]", caTypeFile, caTypeVault, caTypeCFSSL)
+	}
+	if !strings.HasPrefix(c.Address, "http://") && !strings.HasPrefix(c.Address, "https://") {
+		return errors.Errorf("\"ca.address\" should be a http or https URL, got %q", c.Address)
+	}
+	if c.Timeout != "" {
+		if _, err := time.ParseDuration(c.Timeout); err != nil {
+			return errors.Wrapf(err, "\"ca.timeout\" %q is invalid", c.Timeout)
+		}
+	}
+
+	return nil
+}
+
+// newCAProvider creates the CAProvider of the configuration. The file provider loads rootKey and rootCert.
+func newCAProvider(cfg caConfig, rootKey, rootCert string) (CAProvider, error) {
+	switch cfg.Type {
+	case caTypeVault:
+		client, err := cfg.httpClient()
+		if err != nil {
+			return nil, err
+		}
+		token, err := cfg.vaultToken()
+		if err != nil {
+			return nil, err
+		}
+		mount := cfg.Mount
+		if mount == "" {
+			mount = "pki"
+		}
+
+		return &vaultCA{client: client, address: strings.TrimSuffix(cfg.Address, "/"), mount: strings.Trim(mount, "/"),
+			role: cfg.Role, token: token, namespace: cfg.Namespace}, nil
+	case caTypeCFSSL:
+		client, err := cfg.httpClient()
+		if err != nil {
+			return nil, err
+		}
+		caProfile := cfg.CAProfile
+		if caProfile == "" {
+			caProfile = cfg.Profile
+		}
+
+		return &cfsslCA{client: client, address: strings.TrimSuffix(cfg.Address, "/"), profile: cfg.Profile,
+			caProfile: caProfile, label: cfg.Label, authKey: cfg.AuthKey}, nil
+	default:
+		return newFileCA(rootKey, rootCert)
+	}
+}
+
+// httpClient returns the http client to request the remote provider.
+func (c caConfig) httpClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify} //nolint:gosec // it is set by users
+	if c.CAPath != "" {
+		pool, err := cgutilcert.NewPool(c.CAPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load \"ca.ca_path\" %q", c.CAPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	timeout := defaultCATimeout
+	if c.Timeout != "" {
+		timeout, _ = time.ParseDuration(c.Timeout)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// vaultToken returns the vault token from Token, TokenFile or the VAULT_TOKEN environment variable.
+func (c caConfig) vaultToken() (string, error) {
+	switch {
+	case c.Token != "":
+		return c.Token, nil
+	case c.TokenFile != "":
+		data, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read \"ca.token_file\" %q", c.TokenFile)
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	case os.Getenv("VAULT_TOKEN") != "":
+		return os.Getenv("VAULT_TOKEN"), nil
+	default:
+		return "", errors.New("vault token should be set by \"ca.token\", \"ca.token_file\" or VAULT_TOKEN")
+	}
+}
+
+// fileCA signs certificates by the CA key and certificate files.
+type fileCA struct {
+	key  crypto.Signer
+	cert *x509.Certificate
+}
+
+// newFileCA loads the CA key and certificate files.
+func newFileCA(rootKey, rootCert string) (CAProvider, error) {
+	caKey, err := TryLoadKeyFromDisk(rootKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := TryLoadCertChainFromDisk(rootCert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileCA{key: caKey, cert: caCert[0]}, nil
+}
+
+// CACert returns the CA certificate file.
+func (f *fileCA) CACert(context.Context) (*x509.Certificate, error) {
+	return f.cert, nil
+}
+
+// Sign signs the certificate by the CA key file.
+func (f *fileCA) Sign(_ context.Context, req SignRequest) (*x509.Certificate, error) {
+	return NewSignedCert(req.Config, req.Options, req.Duration, req.CSR.PublicKey, f.cert, f.key, req.IsCA)
+}
+
+// postJSON posts the request as json and decodes the json response. It returns error for non 2xx status codes.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request to %q", url)
+	}
+	httpReq.Header = header.Clone()
+	if httpReq.Header == nil {
+		httpReq.Header = http.Header{}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return doJSON(client, httpReq, resp)
+}
+
+// doJSON sends the request and decodes the json response. It returns error for non 2xx status codes.
+func doJSON(client *http.Client, req *http.Request, resp any) error {
+	httpResp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %q", req.URL.Redacted())
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read response of %q", req.URL.Redacted())
+	}
+	// decode the errors in the body before checking the status code.
+	jsonErr := json.Unmarshal(data, resp)
+	if httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("request %q failed with status %d: %s", req.URL.Redacted(), httpResp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if jsonErr != nil {
+		return errors.Wrapf(jsonErr, "failed to decode response of %q", req.URL.Redacted())
+	}
+
+	return nil
+}
+
+// parseCertPEM parses the first certificate in the PEM data.
+func parseCertPEM(data string) (*x509.Certificate, error) {
+	certs, err := cgutilcert.ParseCertsPEM([]byte(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	return certs[0], nil
+}
+
+// writeCACert writes the CA certificate of a remote provider to the file when its content is different.
+func writeCACert(path string, caCert *x509.Certificate) error {
+	encoded := EncodeCertPEM(caCert)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, encoded) {
+		return nil
+	}
+	if err := cgutilcert.WriteCert(path, encoded); err != nil {
+		return errors.Wrapf(err, "failed to write CA certificate to file %s", path)
+	}
+
+	return nil
+}
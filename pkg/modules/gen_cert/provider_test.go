@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen_cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cgutilcert "k8s.io/client-go/util/cert"
+	netutils "k8s.io/utils/net"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// fakeCA is the CA of the fake servers.
+type fakeCA struct {
+	key  crypto.Signer
+	cert *x509.Certificate
+}
+
+func newFakeCA(t *testing.T) *fakeCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	require.NoError(t, err)
+	cert, err := NewSelfSignedCACert(cgutilcert.Config{CommonName: "fake-ca"}, CertOptions{}, 0, key)
+	require.NoError(t, err)
+
+	return &fakeCA{key: key, cert: cert}
+}
+
+// sign signs the PEM encoded CSR with the common name and SANs.
+func (f *fakeCA) sign(t *testing.T, csrPEM, cn string, hosts []string, isCA bool) string {
+	t.Helper()
+	block, _ := pem.Decode([]byte(csrPEM))
+	require.NotNil(t, block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	require.NoError(t, err)
+	cfg := cgutilcert.Config{CommonName: cn}
+	for _, host := range hosts {
+		if ip := netutils.ParseIPSloppy(host); ip != nil {
+			cfg.AltNames.IPs = append(cfg.AltNames.IPs, ip)
+		} else if host != "" {
+			cfg.AltNames.DNSNames = append(cfg.AltNames.DNSNames, host)
+		}
+	}
+	cert, err := NewSignedCert(cfg, CertOptions{}, 0, csr.PublicKey, f.cert, f.key, isCA)
+	require.NoError(t, err)
+
+	return string(EncodeCertPEM(cert))
+}
+
+// newFakeVault serves the vault PKI endpoints which are used by vaultCA.
+func newFakeVault(t *testing.T, ca *fakeCA, token string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/pki/ca/pem":
+			_, _ = w.Write(EncodeCertPEM(ca.cert))
+		case "/v1/pki/sign/server", "/v1/pki/root/sign-intermediate":
+			req := &vaultSignRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+			assert.Equal(t, "pem", req.Format)
+			hosts := append(strings.Split(req.AltNames, ","), strings.Split(req.IPSANs, ",")...)
+			cert := ca.sign(t, req.CSR, req.CommonName, hosts, r.URL.Path == "/v1/pki/root/sign-intermediate")
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"certificate": cert, "issuing_ca": string(EncodeCertPEM(ca.cert))}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["no handler for route"]}`))
+		}
+	}))
+}
+
+// newFakeCFSSL serves the cfssl endpoints which are used by cfsslCA. authsign is checked by the auth key.
+func newFakeCFSSL(t *testing.T, ca *fakeCA, authKey []byte) *httptest.Server {
+	t.Helper()
+	reply := func(w http.ResponseWriter, cert string) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": map[string]any{"certificate": cert}, "errors": []any{}})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/cfssl/info":
+			reply(w, string(EncodeCertPEM(ca.cert)))
+		case "/api/v1/cfssl/authsign":
+			auth := &cfsslAuthRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(auth))
+			mac := hmac.New(sha256.New, authKey)
+			mac.Write(auth.Request)
+			if !hmac.Equal(mac.Sum(nil), auth.Token) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":2400,"message":"invalid token"}]}`))
+
+				return
+			}
+			req := &cfsslSignRequest{}
+			require.NoError(t, json.Unmarshal(auth.Request, req))
+			assert.Equal(t, "server", req.Profile)
+			reply(w, ca.sign(t, req.CertificateRequest, req.Subject.CN, req.Hosts, false))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCAConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		config  caConfig
+		wantErr bool
+	}{
+		{name: "file", config: caConfig{Type: "file"}},
+		{name: "vault", config: caConfig{Type: "vault", Address: "https://vault:8200", Role: "server", Timeout: "10s"}},
+		{name: "vault without role", config: caConfig{Type: "vault", Address: "https://vault:8200"}, wantErr: true},
+		{name: "cfssl without address", config: caConfig{Type: "cfssl"}, wantErr: true},
+		{name: "invalid timeout", config: caConfig{Type: "cfssl", Address: "http://cfssl:8888", Timeout: "soon"}, wantErr: true},
+		{name: "unsupported type", config: caConfig{Type: "acme"}, wantErr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.validate()
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGenCertVaultProvider(t *testing.T) {
+	ca := newFakeCA(t)
+	server := newFakeVault(t, ca, "s.token")
+	defer server.Close()
+	dir := t.TempDir()
+	rootCert := filepath.Join(dir, "root.crt")
+
+	t.Run("sign intermediate ca", func(t *testing.T) {
+		args := map[string]any{"cn": "kubernetes-ca", "is_ca": true, "policy": "IfNotPresent", "root_cert": rootCert,
+			"out_key": filepath.Join(dir, "kubernetes.key"), "out_cert": filepath.Join(dir, "kubernetes.crt"),
+			"ca": map[string]any{"type": "vault", "address": server.URL, "role": "server", "token": "s.token"}}
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		cert := loadCert(t, filepath.Join(dir, "kubernetes.crt"))
+		assert.True(t, cert.IsCA)
+		// the CA certificate of vault is written to root_cert.
+		assert.True(t, loadCert(t, rootCert).Equal(ca.cert))
+		assert.Equal(t, internal.StdoutSkip, runGenCert(t, args))
+	})
+
+	t.Run("sign leaf by token file", func(t *testing.T) {
+		tokenFile := filepath.Join(dir, "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("s.token\n"), 0o600))
+		args := map[string]any{"cn": "etcd", "sans": []string{"10.0.0.1", "etcd.local"}, "policy": "IfNotPresent",
+			"out_key": filepath.Join(dir, "etcd.key"), "out_cert": filepath.Join(dir, "etcd.crt"),
+			"ca": `{"type": "vault", "address": "` + server.URL + `", "role": "server", "token_file": "` + tokenFile + `"}`}
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		cert := loadCert(t, filepath.Join(dir, "etcd.crt"))
+		require.NoError(t, cert.VerifyHostname("10.0.0.1"))
+		require.NoError(t, cert.VerifyHostname("etcd.local"))
+		require.NoError(t, VerifyCertChain(cert, nil, ca.cert))
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		stdout, stderr, err := ModuleGenCert(t.Context(), internal.ExecOptions{
+			Host: "node1",
+			Args: createRawArgs(map[string]any{"cn": "etcd", "policy": "Always", "out_key": filepath.Join(dir, "denied.key"), "out_cert": filepath.Join(dir, "denied.crt"),
+				"ca": map[string]any{"type": "vault", "address": server.URL, "role": "server", "token": "wrong"}}),
+			Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+		})
+		require.ErrorContains(t, err, "permission denied")
+		assert.Equal(t, internal.StdoutFailed, stdout)
+		assert.Equal(t, "Failed to load root CA", stderr)
+	})
+}
+
+func TestGenCertCFSSLProvider(t *testing.T) {
+	ca := newFakeCA(t)
+	authKey := []byte("0123456789abcdef")
+	server := newFakeCFSSL(t, ca, authKey)
+	defer server.Close()
+	dir := t.TempDir()
+
+	t.Run("authsign csr", func(t *testing.T) {
+		key, csr := filepath.Join(dir, "kubelet.key"), filepath.Join(dir, "kubelet.csr")
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, map[string]any{"cn": "system:node:node1", "sans": []string{"node1"},
+			"policy": "Always", "out_key": key, "out_csr": csr}))
+		args := map[string]any{"csr": csr, "policy": "IfNotPresent", "out_cert": filepath.Join(dir, "kubelet.crt"),
+			"ca": map[string]any{"type": "cfssl", "address": server.URL, "profile": "server", "auth_key": hex.EncodeToString(authKey)}}
+		assert.Equal(t, internal.StdoutSuccess, runGenCert(t, args))
+		cert := loadCert(t, filepath.Join(dir, "kubelet.crt"))
+		assert.Equal(t, "system:node:node1", cert.Subject.CommonName)
+		require.NoError(t, cert.VerifyHostname("node1"))
+		require.NoError(t, VerifyCertChain(cert, nil, ca.cert))
+		assert.Equal(t, internal.StdoutSkip, runGenCert(t, args))
+	})
+
+	t.Run("wrong auth key", func(t *testing.T) {
+		_, _, err := ModuleGenCert(t.Context(), internal.ExecOptions{
+			Host: "node1",
+			Args: createRawArgs(map[string]any{"cn": "registry", "policy": "Always", "out_key": filepath.Join(dir, "registry.key"), "out_cert": filepath.Join(dir, "registry.crt"),
+				"ca": map[string]any{"type": "cfssl", "address": server.URL, "profile": "server", "auth_key": "00"}}),
+			Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+		})
+		require.ErrorContains(t, err, "invalid token")
+	})
+}
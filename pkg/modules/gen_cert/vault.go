@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gen_cert
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// vaultCA signs certificates by the PKI secrets engine of HashiCorp Vault.
+// Leaf certificates are signed by "<mount>/sign/<role>", and intermediate CA certificates by "<mount>/root/sign-intermediate".
+// The key usages are decided by the vault role.
+type vaultCA struct {
+	client    *http.Client
+	address   string
+	mount     string
+	role      string
+	token     string
+	namespace string
+}
+
+// vaultSignRequest is the request body of the vault sign endpoints.
+type vaultSignRequest struct {
+	CSR          string `json:"csr"`
+	CommonName   string `json:"common_name"`
+	AltNames     string `json:"alt_names,omitempty"`
+	IPSANs       string `json:"ip_sans,omitempty"`
+	TTL          string `json:"ttl,omitempty"`
+	Format       string `json:"format"`
+	UseCSRValues bool   `json:"use_csr_values,omitempty"`
+}
+
+// vaultSignResponse is the response body of the vault sign endpoints.
+type vaultSignResponse struct {
+	Data struct {
+		Certificate string `json:"certificate"`
+		IssuingCA   string `json:"issuing_ca"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// header returns the authentication headers of vault.
+func (v *vaultCA) header() http.Header {
+	header := http.Header{}
+	header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	return header
+}
+
+// CACert returns the CA certificate of the PKI secrets engine.
+func (v *vaultCA) CACert(ctx context.Context) (*x509.Certificate, error) {
+	url := v.address + "/v1/" + v.mount + "/ca/pem"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request to %q", url)
+	}
+	req.Header = v.header()
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request %q", url)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response of %q", url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("request %q failed with status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return parseCertPEM(string(data))
+}
+
+// Sign signs the CSR by the vault role, or as an intermediate CA.
+func (v *vaultCA) Sign(ctx context.Context, req SignRequest) (*x509.Certificate, error) {
+	body := vaultSignRequest{CSR: req.csrPEM(), CommonName: req.Config.CommonName, Format: "pem"}
+	body.AltNames = strings.Join(req.Config.AltNames.DNSNames, ",")
+	ips := make([]string, 0, len(req.Config.AltNames.IPs))
+	for _, ip := range req.Config.AltNames.IPs {
+		ips = append(ips, ip.String())
+	}
+	body.IPSANs = strings.Join(ips, ",")
+	if req.Duration != 0 {
+		body.TTL = req.Duration.String()
+	}
+	url := v.address + "/v1/" + v.mount + "/sign/" + v.role
+	if req.IsCA {
+		url, body.UseCSRValues = v.address+"/v1/"+v.mount+"/root/sign-intermediate", true
+	}
+	resp := &vaultSignResponse{}
+	if err := postJSON(ctx, v.client, url, v.header(), body, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, errors.Errorf("vault failed to sign certificate: %s", strings.Join(resp.Errors, "; "))
+	}
+
+	return parseCertPEM(resp.Data.Certificate)
+}
@@ -17,11 +17,23 @@ limitations under the License.
 package http_get_file
 
 import (
+	"bufio"
 	"context"
+	"crypto/md5"  //nolint:gosec // md5 is only used to verify the checksum of downloaded files
+	"crypto/sha1" //nolint:gosec // sha1 is only used to verify the checksum of downloaded files
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -34,19 +46,95 @@ import (
 	"github.com/kubesphere/kubekey/v4/pkg/variable"
 )
 
+/*
+The HttpGetFile module downloads a file from http or https servers to the local (kk) machine.
+The file is downloaded to a part file "<dest>.<random>.part" of the run first, and renamed to dest after the checksum is verified.
+When the download is interrupted, the part file is resumed by HTTP Range in the retries. The Range request carries
+If-Range with the ETag or Last-Modified of the first response, and the download restarts when the file has changed.
+Credentials and headers are only sent to a checksum url on the same host as url.
+
+Configuration:
+Users can specify the file and how to download it:
+
+http_get_file:
+  url: https://example.com/file  # required: the url, or a list of urls tried in order
+  mirrors: [https://mirror/file] # optional: urls tried in order after url fails
+  dest: /path/to/file            # required: the local path of the file
+  checksum: "sha256:xxx"         # optional: "<algorithm>:<hex>" or "<algorithm>:<url of checksum file>", algorithm is md5, sha1, sha256 or sha512
+  username: user                 # optional: username of basic auth
+  password: pass                 # optional: password of basic auth
+  token: xxx                     # optional: bearer token
+  headers: {X-Key: value}        # optional: additional request headers
+  timeout: 10s                   # optional: timeout of connecting and waiting for the response headers (default: 10s)
+  retries: 3                     # optional: retries of each url (default: 3)
+  retry_delay: 3s                # optional: delay between retries (default: 3s)
+  proxy: http://proxy:3128       # optional: proxy of the download (default: the proxy environment variables)
+  ca_path: /path/to/ca.crt       # optional: CA bundle to verify the https servers
+  validate_certs: true           # optional: verify the certificates of https servers (default: true)
+
+Usage Examples in Playbook Tasks:
+1. Download with checksum:
+   ```yaml
+   - name: Download kubeadm
+     http_get_file:
+       url: https://dl.k8s.io/release/v1.33.1/bin/linux/amd64/kubeadm
+       checksum: sha256:https://dl.k8s.io/release/v1.33.1/bin/linux/amd64/kubeadm.sha256
+       dest: /tmp/kubeadm
+   ```
+
+2. Download from mirrors through a proxy:
+   ```yaml
+   - name: Download helm
+     http_get_file:
+       url:
+         - https://get.helm.sh/helm-v3.18.5-linux-amd64.tar.gz
+         - https://mirror.example.com/helm/helm-v3.18.5-linux-amd64.tar.gz
+       proxy: http://proxy.example.com:3128
+       dest: /tmp/helm.tar.gz
+   ```
+
+Return Values:
+- On success: Returns "success" in stdout
+- When dest already matches the checksum: Returns "skip" in stdout
+- On failure: Returns error message in stderr
+*/
+
 const (
 	// Default timeout for http API
 	defaultHttpTimeout = 10 * time.Second
+	// Default retries of each url
+	defaultRetries = 3
+	// Default delay between retries
+	defaultRetryDelay = 3 * time.Second
+	// partPattern is the pattern of the file which is downloading, "*" is replaced by a random string of each run.
+	partPattern = ".*.part"
 )
 
+// checksumHashes are the supported checksum algorithms.
+var checksumHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
 type httpArgs struct {
 	url      string
+	mirrors  []string
 	username string
 	password string
 	token    string
 	headers  map[string]string
 	timeout  time.Duration
 	client   *http.Client
+
+	checksumAlgorithm string // The algorithm of checksum
+	checksum          string // The checksum in hex, or the url of checksum file
+	retries           int
+	retryDelay        time.Duration
+	proxy             string
+	caPath            string
+	validateCerts     bool
 }
 
 func (hc *httpArgs) Init(ctx context.Context) error {
@@ -55,60 +143,308 @@ func (hc *httpArgs) Init(ctx context.Context) error {
 		return errors.New("http URL is required")
 	}
 
-	// Parse and normalize the URL
-	parsedURL, err := url.Parse(hc.url)
+	// Parse and normalize the URLs
+	var err error
+	if hc.url, err = normalizeURL(hc.url); err != nil {
+		return err
+	}
+	for i, mirror := range hc.mirrors {
+		if hc.mirrors[i], err = normalizeURL(mirror); err != nil {
+			return err
+		}
+	}
+	klog.V(4).InfoS("Initializing http connector", "url", hc.url, "mirrors", hc.mirrors)
+
+	// Create HTTP client. The timeout applies to connecting and waiting for the response headers,
+	// and not to reading the body, so that large files are not cut off.
+	tlsConfig := &tls.Config{InsecureSkipVerify: !hc.validateCerts} //nolint:gosec // disabled by validate_certs
+	if hc.caPath != "" {
+		ca, err := os.ReadFile(hc.caPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read ca %q", hc.caPath)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return errors.Errorf("no certificate found in ca %q", hc.caPath)
+		}
+	}
+	proxy := http.ProxyFromEnvironment
+	if hc.proxy != "" {
+		proxyURL, err := url.Parse(hc.proxy)
+		if err != nil || proxyURL.Host == "" {
+			return errors.Errorf("invalid proxy %q", hc.proxy)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	hc.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 proxy,
+			DialContext:           (&net.Dialer{Timeout: hc.timeout, KeepAlive: 30 * time.Second}).DialContext,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   hc.timeout,
+			ResponseHeaderTimeout: hc.timeout,
+		},
+	}
+
+	return nil
+}
+
+// normalizeURL defaults the scheme of rawURL to http, and checks that it is http or https.
+func normalizeURL(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return errors.Wrapf(err, "invalid http URL: %s", hc.url)
+		return "", errors.Wrapf(err, "invalid http URL: %s", rawURL)
 	}
 
 	// Default to http if scheme is missing
 	if parsedURL.Scheme == "" {
-		klog.V(4).InfoS("No scheme specified in http URL, defaulting to HTTP", "url", hc.url)
+		klog.V(4).InfoS("No scheme specified in http URL, defaulting to HTTP", "url", rawURL)
 		parsedURL.Scheme = "http"
 	} else if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return errors.Errorf("unsupported URL scheme: %s, only http and https are supported", parsedURL.Scheme)
+		return "", errors.Errorf("unsupported URL scheme: %s, only http and https are supported", parsedURL.Scheme)
 	}
 
-	// Update the URL with normalized version
-	hc.url = parsedURL.String()
-	klog.V(4).InfoS("Initializing http connector", "url", hc.url)
-
-	// Create HTTP client with timeout
-	hc.client = &http.Client{
-		Timeout: hc.timeout,
-	}
-	return nil
+	return parsedURL.String(), nil
 }
 
 // FetchFile from http file server.  dst is the local writer.
 func (hc *httpArgs) FetchFile(ctx context.Context, dst io.Writer) error {
+	resp, err := hc.get(ctx, hc.url, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	if dst != nil {
+		_, err = io.Copy(dst, resp.Body)
+	}
+
+	return err
+}
+
+// get sends the GET request to rawURL. When offset is positive, the content is requested from offset by HTTP Range,
+// and only if the file still matches the validator by If-Range.
+func (hc *httpArgs) get(ctx context.Context, rawURL string, offset int64, validator string) (*http.Response, error) {
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hc.url, http.NoBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
 	if err != nil {
-		return errors.Wrap(err, "failed to create request for server info")
+		return nil, errors.Wrap(err, "failed to create request for server info")
 	}
 
 	// Add authentication headers
 	hc.addAuthHeaders(req)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
 
 	// Execute request
 	resp, err := hc.client.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "failed to get http server info")
+		return nil, errors.Wrapf(err, "failed to get %q", rawURL)
+	}
+
+	return resp, nil
+}
+
+// statusError reads the response body and returns it as the error of unexpected status.
+func statusError(resp *http.Response) error {
+	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	klog.V(4).InfoS("http server info request failed",
+		"statusCode", resp.StatusCode,
+		"response", string(bodyBytes))
+
+	return errors.Errorf("http request %q failed with status %d", resp.Request.URL, resp.StatusCode)
+}
+
+// download downloads rawURL to the part file. The existing content of the part file is resumed by HTTP Range
+// when the validator of the file is known, and it is truncated when the server responds with the whole file.
+// The validator is updated from the response of the whole file.
+func (hc *httpArgs) download(ctx context.Context, rawURL, part string, validator *string) error {
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %q", part)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q", part)
+	}
+	offset := info.Size()
+	if *validator == "" {
+		// the file may have changed since the part file was written.
+		offset = 0
+	}
+
+	resp, err := hc.get(ctx, rawURL, offset, *validator)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		if dst != nil {
-			_, err = io.Copy(dst, resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the server does not support Range, or the file has changed.
+		offset = 0
+		*validator = responseValidator(resp)
+	case http.StatusPartialContent:
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+			_ = f.Truncate(0)
+			return errors.Errorf("unexpected Content-Range %q of %q", resp.Header.Get("Content-Range"), rawURL)
 		}
-		return err
+		klog.V(4).InfoS("Resume downloading", "url", rawURL, "offset", offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the part file is already complete when its size equals the size of the file.
+		var size int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes */%d", &size); err == nil && size == offset {
+			return nil
+		}
+		_ = f.Truncate(0)
+		return errors.Errorf("failed to resume %q from %d", rawURL, offset)
+	default:
+		return statusError(resp)
 	}
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	klog.ErrorS(err, "http server info request failed",
-		"statusCode", resp.StatusCode,
-		"response", string(bodyBytes))
-	return errors.Errorf("http server info request failed with status %d", resp.StatusCode)
+
+	if err := f.Truncate(offset); err != nil {
+		return errors.Wrapf(err, "failed to truncate %q", part)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "failed to seek %q", part)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return errors.Wrapf(err, "failed to download %q", rawURL)
+	}
+
+	return nil
+}
+
+// responseValidator returns the strong ETag of the response, or the Last-Modified when there is no strong ETag.
+// It returns empty when the response has neither, and the download cannot be resumed.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return resp.Header.Get("Last-Modified")
+}
+
+// expectedChecksum returns the expected checksum in hex. When the checksum is an url, the checksum file is downloaded,
+// and the checksum is the line of the file name, or the only line in it, such as "<hex>  <file name>".
+// The credentials and headers are only sent when the checksum file is on the same host as url.
+func (hc *httpArgs) expectedChecksum(ctx context.Context) (string, error) {
+	if !strings.HasPrefix(hc.checksum, "http://") && !strings.HasPrefix(hc.checksum, "https://") {
+		return hc.checksum, nil
+	}
+	var content strings.Builder
+	fetch := &httpArgs{url: hc.checksum, client: hc.client}
+	if sameHost(hc.url, hc.checksum) {
+		fetch.username, fetch.password, fetch.token, fetch.headers = hc.username, hc.password, hc.token, hc.headers
+	}
+	if err := fetch.FetchFile(ctx, &content); err != nil {
+		return "", errors.Wrap(err, "failed to get checksum file")
+	}
+
+	var sums []string
+	name := path.Base(hc.url)
+	scanner := bufio.NewScanner(strings.NewReader(content.String()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 1 && path.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return strings.ToLower(fields[0]), nil
+		}
+		sums = append(sums, fields[0])
+	}
+	if len(sums) != 1 {
+		return "", errors.Errorf("cannot find the checksum of %q in %q", name, hc.checksum)
+	}
+
+	return strings.ToLower(sums[0]), nil
+}
+
+// sameHost reports whether the urls have the same scheme and host.
+func sameHost(url1, url2 string) bool {
+	u1, err := url.Parse(url1)
+	if err != nil {
+		return false
+	}
+	u2, err := url.Parse(url2)
+	if err != nil {
+		return false
+	}
+
+	return u1.Scheme == u2.Scheme && strings.EqualFold(u1.Host, u2.Host)
+}
+
+// fileChecksum returns the checksum of file in hex.
+func (hc *httpArgs) fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %q", file)
+	}
+	defer f.Close()
+	h := checksumHashes[hc.checksumAlgorithm]()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "failed to read %q", file)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fetchTo downloads the file to dest. The urls are tried in order and each url is retried,
+// the part file of the run is resumed in the retries of the same url and truncated when it turns to the next url.
+func (hc *httpArgs) fetchTo(ctx context.Context, dest, expected string) error {
+	f, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+partPattern)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create part file of %q", dest)
+	}
+	part := f.Name()
+	// the part file is removed when the download fails, and it is already renamed when succeeded.
+	defer os.Remove(part)
+	if err := errors.Join(f.Chmod(0o644), f.Close()); err != nil {
+		return errors.Wrapf(err, "failed to create part file of %q", dest)
+	}
+
+	var lastErr error
+	for _, rawURL := range append([]string{hc.url}, hc.mirrors...) {
+		var validator string
+		for attempt := 0; attempt <= hc.retries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return errors.Wrap(ctx.Err(), "failed to get http file")
+				case <-time.After(hc.retryDelay):
+				}
+			}
+			if lastErr = hc.download(ctx, rawURL, part, &validator); lastErr != nil {
+				klog.V(4).InfoS("Failed to download", "url", rawURL, "attempt", attempt, "error", lastErr)
+				continue
+			}
+			if expected != "" {
+				sum, err := hc.fileChecksum(part)
+				if err != nil {
+					return err
+				}
+				if sum != expected {
+					// the part file is corrupted, download it again.
+					validator = ""
+					lastErr = errors.Errorf("%s checksum of %q is %s, expected %s", hc.checksumAlgorithm, rawURL, sum, expected)
+					klog.V(4).InfoS("Checksum mismatch", "url", rawURL, "attempt", attempt, "error", lastErr)
+					continue
+				}
+			}
+
+			return errors.Wrap(os.Rename(part, dest), "failed to rename file")
+		}
+	}
+
+	return lastErr
 }
 
 // addAuthHeaders adds authentication headers to the request
@@ -135,17 +471,27 @@ func (hc *httpArgs) addAuthHeaders(req *http.Request) {
 func newHttpArgs(ctx context.Context, args map[string]any, vars map[string]any) (httpArg *httpArgs, err error) {
 
 	httpArg = &httpArgs{
-		headers: make(map[string]string),
-		timeout: defaultHttpTimeout,
+		headers:       make(map[string]string),
+		timeout:       defaultHttpTimeout,
+		retries:       defaultRetries,
+		retryDelay:    defaultRetryDelay,
+		validateCerts: true,
 	}
 
-	// Retrieve http URL
-	httpURL, err := variable.StringVar(vars, args, "url")
-	if err != nil {
+	// Retrieve http URL, which may be a list of urls tried in order
+	if urls, err := variable.StringSliceVar(vars, args, "url"); err == nil && len(urls) > 0 {
+		httpArg.url, httpArg.mirrors = urls[0], urls[1:]
+	} else if httpArg.url, err = variable.StringVar(vars, args, "url"); err != nil {
 		klog.V(4).InfoS("Failed to get http url, using current url", "error", err)
 	}
-	httpArg.url = httpURL
 	klog.V(4).InfoS("http url", "url", httpArg.url)
+	if _, ok := args["mirrors"]; ok {
+		mirrors, err := variable.StringSliceVar(vars, args, "mirrors")
+		if err != nil {
+			return nil, errors.New("\"mirrors\" in args should be string or string slice")
+		}
+		httpArg.mirrors = append(httpArg.mirrors, mirrors...)
+	}
 
 	// Retrieve username
 	username, err := variable.StringVar(vars, args, _const.VariableConnectorUserName)
@@ -186,9 +532,41 @@ func newHttpArgs(ctx context.Context, args map[string]any, vars map[string]any)
 		}
 	}
 
+	// Retrieve checksum, which is "<algorithm>:<hex>" or "<algorithm>:<url>"
+	if checksum, _ := variable.StringVar(vars, args, "checksum"); checksum != "" {
+		httpArg.checksumAlgorithm, httpArg.checksum = "sha256", checksum
+		if algorithm, sum, ok := strings.Cut(checksum, ":"); ok && !strings.Contains(algorithm, "/") {
+			httpArg.checksumAlgorithm, httpArg.checksum = algorithm, sum
+		}
+		if _, ok := checksumHashes[httpArg.checksumAlgorithm]; !ok {
+			return nil, errors.Errorf("unsupported checksum algorithm %q, should be one of md5, sha1, sha256 or sha512", httpArg.checksumAlgorithm)
+		}
+		if !strings.HasPrefix(httpArg.checksum, "http://") && !strings.HasPrefix(httpArg.checksum, "https://") {
+			httpArg.checksum = strings.ToLower(httpArg.checksum)
+		}
+	}
+	if _, ok := args["retries"]; ok {
+		retries, err := variable.IntVar(vars, args, "retries")
+		if err != nil || *retries < 0 {
+			return nil, errors.New("\"retries\" in args should be a non-negative int")
+		}
+		httpArg.retries = *retries
+	}
+	if _, ok := args["retry_delay"]; ok {
+		if httpArg.retryDelay, err = variable.DurationVar(vars, args, "retry_delay"); err != nil {
+			return nil, errors.New("\"retry_delay\" in args should be duration")
+		}
+	}
+	if httpArg.validateCerts, err = internal.BoolArg(vars, args, "validate_certs", true); err != nil {
+		return nil, err
+	}
+	httpArg.proxy, _ = variable.StringVar(vars, args, "proxy")
+	httpArg.caPath, _ = variable.StringVar(vars, args, "ca_path")
+
 	return httpArg, httpArg.Init(ctx)
 }
 
+// ModuleHttpGetFile handles the "http_get_file" module, downloading a file to the local machine.
 func ModuleHttpGetFile(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
 	// get host variable
 	ha, err := opts.GetAllVariables()
@@ -208,38 +586,32 @@ func ModuleHttpGetFile(ctx context.Context, opts internal.ExecOptions) (string,
 	if err != nil {
 		return internal.StdoutFailed, "\"dest\" in args should be string", err
 	}
+	destParam = strings.TrimSpace(destParam)
 
 	// fetch file
-	parentDir := filepath.Dir(strings.TrimSpace(destParam))
+	parentDir := filepath.Dir(destParam)
 	if _, err := os.Stat(parentDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(parentDir, os.ModePerm); err != nil {
 			return internal.StdoutFailed, "failed to create dest dir", err
 		}
 	}
 
-	tmpFile, err := os.CreateTemp(parentDir, "*.tmp")
-	if err != nil {
-		return internal.StdoutFailed, "failed to create dest file", err
+	var expected string
+	if httpArg.checksum != "" {
+		if expected, err = httpArg.expectedChecksum(ctx); err != nil {
+			return internal.StdoutFailed, "failed to get checksum", err
+		}
+		// skip when dest is already downloaded.
+		if _, err := os.Stat(destParam); err == nil {
+			if sum, err := httpArg.fileChecksum(destParam); err == nil && sum == expected {
+				return internal.StdoutSkip, "", nil
+			}
+		}
 	}
 
-	tmpFilePath := tmpFile.Name()
-	defer func() {
-		_ = os.Remove(tmpFilePath)
-	}()
-
-	err = httpArg.FetchFile(ctx, tmpFile)
-	errC := tmpFile.Close()
-	if err != nil {
+	if err := httpArg.fetchTo(ctx, destParam, expected); err != nil {
 		return internal.StdoutFailed, "failed to get http file", err
 	}
-	if errC != nil {
-		return internal.StdoutFailed, "failed to close tmp file", errC
-	}
-
-	err = os.Rename(tmpFilePath, destParam)
-	if err != nil {
-		return internal.StdoutFailed, "failed to rename file", err
-	}
 
 	return internal.StdoutSuccess, "", nil
 }
@@ -17,8 +17,17 @@ limitations under the License.
 package http_get_file
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -144,6 +153,48 @@ func TestHttpGetFileArgsParse(t *testing.T) {
 			expectParseError: true,
 			description:      "When scheme is not http/https, should return error",
 		},
+		{
+			name:             "valid args with url list and mirrors",
+			args:             map[string]any{"url": []string{"http://a.com/file.txt", "http://b.com/file.txt"}, "mirrors": []string{"http://c.com/file.txt"}, "dest": "/tmp/file.txt"},
+			expectParseError: false,
+			description:      "When url is a list, should parse successfully",
+		},
+		{
+			name:             "valid args with checksum url",
+			args:             map[string]any{"url": "http://example.com/file.txt", "dest": "/tmp/file.txt", "checksum": "sha512:http://example.com/file.txt.sha512"},
+			expectParseError: false,
+			description:      "When checksum is an url, should parse successfully",
+		},
+		{
+			name:             "unsupported checksum algorithm",
+			args:             map[string]any{"url": "http://example.com/file.txt", "dest": "/tmp/file.txt", "checksum": "crc32:abc"},
+			expectParseError: true,
+			description:      "When checksum algorithm is unsupported, should return error",
+		},
+		{
+			name:             "invalid mirror scheme",
+			args:             map[string]any{"url": "http://example.com/file.txt", "mirrors": []string{"ftp://example.com/file.txt"}, "dest": "/tmp/file.txt"},
+			expectParseError: true,
+			description:      "When scheme of mirror is not http/https, should return error",
+		},
+		{
+			name:             "invalid proxy",
+			args:             map[string]any{"url": "http://example.com/file.txt", "dest": "/tmp/file.txt", "proxy": "proxy"},
+			expectParseError: true,
+			description:      "When proxy has no host, should return error",
+		},
+		{
+			name:             "missing ca",
+			args:             map[string]any{"url": "https://example.com/file.txt", "dest": "/tmp/file.txt", "ca_path": "/not/exist/ca.crt"},
+			expectParseError: true,
+			description:      "When ca_path does not exist, should return error",
+		},
+		{
+			name:             "invalid retries",
+			args:             map[string]any{"url": "http://example.com/file.txt", "dest": "/tmp/file.txt", "retries": -1},
+			expectParseError: true,
+			description:      "When retries is negative, should return error",
+		},
 		{
 			name:             "invalid timeout format (ignored, uses default)",
 			args:             map[string]any{"url": "http://example.com/file.txt", "dest": "/tmp/file.txt", "timeout": "invalid"},
@@ -214,8 +265,209 @@ func TestHttpGetFileArgsTemplate(t *testing.T) {
 
 // TestHttpGetFileModule tests the actual functionality of the http_get_file module.
 func TestHttpGetFileModule(t *testing.T) {
-	// HTTP get file requires actual HTTP server
-	t.Run("module exists", func(t *testing.T) {
-		require.NotNil(t, ModuleHttpGetFile)
-	})
+	content := []byte(strings.Repeat("kubekey", 1024))
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	var failed int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file":
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+		case "/file.sha256sum":
+			_, _ = w.Write([]byte("0000  other\n" + checksum + "  file\n"))
+		case "/flaky":
+			// fails once, and then succeeds.
+			if failed++; failed == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write(content)
+		case "/corrupted":
+			_, _ = w.Write(content[1:])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name         string
+		args         map[string]any
+		dest         []byte
+		expectStdout string
+		expectError  bool
+	}{
+		{
+			name:         "download",
+			args:         map[string]any{"url": server.URL + "/file"},
+			expectStdout: internal.StdoutSuccess,
+		},
+		{
+			name:         "checksum",
+			args:         map[string]any{"url": server.URL + "/file", "checksum": "sha256:" + checksum},
+			expectStdout: internal.StdoutSuccess,
+		},
+		{
+			name:         "checksum url",
+			args:         map[string]any{"url": server.URL + "/file", "checksum": "sha256:" + server.URL + "/file.sha256sum"},
+			expectStdout: internal.StdoutSuccess,
+		},
+		{
+			name:         "checksum mismatch",
+			args:         map[string]any{"url": server.URL + "/corrupted", "checksum": "sha256:" + checksum},
+			expectStdout: internal.StdoutFailed,
+			expectError:  true,
+		},
+		{
+			name:         "mirrors",
+			args:         map[string]any{"url": []string{server.URL + "/not-found", server.URL + "/corrupted"}, "mirrors": []string{server.URL + "/file"}, "checksum": "sha256:" + checksum},
+			expectStdout: internal.StdoutSuccess,
+		},
+		{
+			name:         "retries",
+			args:         map[string]any{"url": server.URL + "/flaky", "retries": 1},
+			expectStdout: internal.StdoutSuccess,
+		},
+		{
+			name:         "skip downloaded",
+			args:         map[string]any{"url": server.URL + "/not-found", "checksum": "sha256:" + checksum},
+			dest:         content,
+			expectStdout: internal.StdoutSkip,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "file")
+			if tc.dest != nil {
+				require.NoError(t, os.WriteFile(dest, tc.dest, 0o644))
+			}
+			tc.args["dest"] = dest
+			tc.args["retry_delay"] = "1ms"
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			stdout, _, err := ModuleHttpGetFile(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Variable: NewTestVariable([]string{"node1"}, nil),
+				Args:     createRawArgs(tc.args),
+			})
+			require.Equal(t, tc.expectStdout, stdout)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			data, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			require.Equal(t, content, data)
+			assertNoPartFile(t, dest)
+		})
+	}
+}
+
+func TestHttpGetFileResume(t *testing.T) {
+	content := []byte(strings.Repeat("kubekey", 1024))
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	testcases := []struct {
+		name        string
+		etags       []string
+		exceptRange []string
+	}{
+		{
+			name:        "resume unchanged file",
+			etags:       []string{`"v1"`, `"v1"`},
+			exceptRange: []string{"", `bytes=100- "v1"`},
+		},
+		{
+			name:        "restart changed file",
+			etags:       []string{`"v1"`, `"v2"`},
+			exceptRange: []string{"", `bytes=100- "v1"`},
+		},
+		{
+			name:        "restart without validator",
+			etags:       []string{`W/"v1"`, `W/"v1"`},
+			exceptRange: []string{"", ""},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, strings.TrimSpace(r.Header.Get("Range")+" "+r.Header.Get("If-Range")))
+				w.Header().Set("ETag", tc.etags[len(ranges)-1])
+				if len(ranges) == 1 {
+					// interrupts the first download after 100 bytes.
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					_, _ = w.Write(content[:100])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+				http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+			}))
+			defer server.Close()
+
+			dest := filepath.Join(t.TempDir(), "file")
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			stdout, _, err := ModuleHttpGetFile(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Variable: NewTestVariable([]string{"node1"}, nil),
+				Args: createRawArgs(map[string]any{
+					"url": server.URL + "/file", "dest": dest, "checksum": "sha256:" + checksum, "retries": 1, "retry_delay": "1ms",
+				}),
+			})
+			require.NoError(t, err)
+			require.Equal(t, internal.StdoutSuccess, stdout)
+			require.Equal(t, tc.exceptRange, ranges)
+			data, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			require.Equal(t, content, data)
+			assertNoPartFile(t, dest)
+		})
+	}
+}
+
+func TestHttpGetFileChecksumCredentials(t *testing.T) {
+	content := []byte("kubekey")
+	sum := sha256.Sum256(content)
+	newServer := func(auth *string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/file.sha256sum" {
+				*auth = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(hex.EncodeToString(sum[:]) + "  file\n"))
+
+				return
+			}
+			_, _ = w.Write(content)
+		}))
+	}
+	var sameAuth, otherAuth string
+	server := newServer(&sameAuth)
+	defer server.Close()
+	other := newServer(&otherAuth)
+	defer other.Close()
+
+	for _, checksumURL := range []string{server.URL + "/file.sha256sum", other.URL + "/file.sha256sum"} {
+		_, _, err := ModuleHttpGetFile(context.Background(), internal.ExecOptions{
+			Host:     "node1",
+			Variable: NewTestVariable([]string{"node1"}, nil),
+			Args: createRawArgs(map[string]any{
+				"url": server.URL + "/file", "dest": filepath.Join(t.TempDir(), "file"), "checksum": "sha256:" + checksumURL, "token": "secret",
+			}),
+		})
+		require.NoError(t, err)
+	}
+	require.Equal(t, "Bearer secret", sameAuth)
+	require.Empty(t, otherAuth)
+}
+
+// assertNoPartFile asserts that the part files of dest are removed.
+func assertNoPartFile(t *testing.T, dest string) {
+	t.Helper()
+	parts, err := filepath.Glob(dest + partPattern)
+	require.NoError(t, err)
+	require.Empty(t, parts)
 }
@@ -41,6 +41,7 @@ import (
 
 	"github.com/kubesphere/kubekey/v4/pkg/converter/tmpl"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/utils"
 	"github.com/kubesphere/kubekey/v4/pkg/variable"
 )
 
@@ -71,12 +72,27 @@ image:
   dest: string                # optional: destination image reference
                                #   - "local://{{ .module.image.localPath }}/{{ .module.image.reference.repository }}:{{ .module.image.reference.reference }}" means save to local directory
                                #   - "oci://{{ .image_registry.auth.registry }}{{ .module.image.reference.repository }}:{{ .module.image.reference.reference }}" means push to remote registry
+  verify:                     # optional: verify the signatures of images in src before copying
+    type: string              # optional: "cosign" or "notation" (default: cosign)
+    key: string               # optional: cosign public key
+    ca_file: string           # optional: roots of cosign keyless certificates, or the notation trust store
+    identity: string          # optional: regex of certificate identity, SAN for cosign keyless (required) and subject for notation
+    issuer: string            # optional: OIDC issuer of cosign keyless certificates (required for keyless)
+    rekor_key: string         # optional: rekor public key to verify the bundle of cosign keyless signatures
+  signatures: bool            # optional: copy cosign signatures, attestations, SBOMs and OCI referrers with images (default: false)
+  rewrites:                   # optional: ordered rules to rewrite the repository of images pushed to a remote registry, the first match wins
+    - prefix: string          # optional: repository prefix matched by whole path components, e.g. "docker.io/calico/*"
+      regex: string           # optional: regex matched against the whole repository, e.g. "registry\\.k8s\\.io/(.*)"
+      target: string          # required: replacement registry and namespace, e.g. "harbor.local/mirror/calico/*" or "harbor.local/k8s/$1"
 
 Operation Types (determined by src and dest):
 - src=oci://, dest=local://  -> pull image from remote registry to local directory
 - src=local://, dest=oci:// -> push image from local directory to remote registry
 - src=local://, dest=local://-> copy image from one local directory to another
 
+When dest is a remote registry (oci://) and the image matches a rewrite rule, the image is pushed to the
+rewritten reference (keeping its tag) instead of dest. The template function "imageRewrite" applies the same rules.
+
 Usage Examples in Playbook Tasks:
 1. Pull images from registry:
    ```yaml
@@ -111,7 +127,33 @@ Usage Examples in Playbook Tasks:
      register: push_result
    ```
 
-3. Copy image from local to local
+3. Verify cosign signatures and keep them in local directory:
+   ```yaml
+   - name: Pull signed images
+     image:
+       manifests:
+         - registry.example.com/app:v1.0.0
+       src: "oci://{{ .module.image.reference.registry }}/{{ .module.image.reference.repository }}:{{ .module.image.reference.reference }}"
+       dest: "local:///var/lib/kubekey/images"
+       verify:
+         key: /etc/kubekey/cosign.pub
+       signatures: true
+   ```
+
+4. Push images to a mirror with rewrite rules:
+   ```yaml
+   - name: Push images to harbor
+     image:
+       src: "local:///var/lib/kubekey/images"
+       dest: "oci://harbor.local/{{ .module.image.reference.repository }}:{{ .module.image.reference.reference }}"
+       rewrites:
+         - prefix: docker.io/calico/*
+           target: harbor.local/mirror/calico/*
+         - regex: registry\.k8s\.io/(.*)
+           target: harbor.local/kubernetes/$1
+   ```
+
+5. Copy image from local to local
    ```yaml
    - name: local to local copy
      image:
@@ -161,6 +203,11 @@ type imageArgs struct {
 	dest      string         // optional: destination image reference (local or remote)
 	policy    string         // optional: policy for image copy, default is strict
 	logOutput io.Writer      // optional: output writer for module logs
+
+	verify     *imageVerify // optional: verify the signatures of images in src
+	signatures bool         // optional: copy the signatures of images to dest
+
+	rewrites []utils.ImageRewrite // optional: rewrite rules of images pushed to remote registry
 }
 
 // newImageArgs creates a new imageArgs instance from raw configuration.
@@ -221,6 +268,41 @@ func newImageArgs(_ context.Context, raw runtime.RawExtension, vars map[string]a
 	_ = variable.AnyVar(vars, args, &auths, "auths")
 	ia.auths = append(ia.auths, auths...)
 
+	// Parse verify, an empty verify disables the verification
+	if _, ok := args["verify"]; ok {
+		verify := &imageVerify{}
+		if err := variable.AnyVar(vars, args, verify, "verify"); err != nil {
+			return nil, errors.Wrap(err, "\"verify\" should be a map")
+		}
+		if verify.Type != "" || verify.Key != "" || verify.CaFile != "" {
+			if err := verify.init(); err != nil {
+				return nil, err
+			}
+			ia.verify = verify
+		}
+	}
+
+	// Parse rewrites
+	if _, ok := args["rewrites"]; ok {
+		if err := variable.AnyVar(vars, args, &ia.rewrites, "rewrites"); err != nil {
+			return nil, errors.Wrap(err, "\"rewrites\" should be a list of {prefix|regex, target}")
+		}
+		for _, rule := range ia.rewrites {
+			if err := rule.Validate(); err != nil {
+				return nil, errors.Wrap(err, "invalid \"rewrites\"")
+			}
+		}
+	}
+
+	// Parse signatures
+	if _, ok := args["signatures"]; ok {
+		signatures, err := variable.BoolVar(vars, args, "signatures")
+		if err != nil {
+			return nil, errors.New("\"signatures\" should be bool")
+		}
+		ia.signatures = *signatures
+	}
+
 	// Parse src
 	src, _ := variable.PrintVar(args, "src")
 	if src, ok := src.(string); !ok {
@@ -282,6 +364,9 @@ func (i *imageArgs) copy(ctx context.Context, hostVars map[string]any) error {
 		if err != nil {
 			return errors.Wrapf(err, "failed to parse dest %q", i.dest)
 		}
+		if dest, err = i.rewrite(dest, img); err != nil {
+			return errors.Wrapf(err, "failed to rewrite image %q", img)
+		}
 		klog.V(4).InfoS("copy image", "src", src, "dst", dest)
 		// Create source repository
 		srcRepo, err := newRepository(src, img, i.auths)
@@ -292,23 +377,56 @@ func (i *imageArgs) copy(ctx context.Context, hostVars map[string]any) error {
 		if err != nil {
 			return err
 		}
+		var desc ocispec.Descriptor
+		srcRef := srcRepo.Reference.Reference
+		if i.verify != nil || i.signatures {
+			if desc, err = srcRepo.Resolve(ctx, srcRepo.Reference.Reference); err != nil {
+				return errors.Wrapf(err, "failed to resolve image %q", img)
+			}
+			// copy the resolved digest, so that the tag moved after the verification is not copied.
+			srcRef = desc.Digest.String()
+		}
+		if i.verify != nil {
+			if err := i.verify.verify(ctx, srcRepo, desc); err != nil {
+				return errors.Wrapf(err, "failed to verify signature of image %q", img)
+			}
+		}
 		// Handle multi-platform copy with filtering
 		if len(i.platform) > 0 && !slices.Contains(i.platform, "all") {
-			if err := i.copyWithPlatformFilter(ctx, srcRepo, dstRepo, img, i.platform, i.logOutput); err != nil {
+			if err := i.copyWithPlatformFilter(ctx, srcRepo, dstRepo, img, srcRef, i.platform, i.logOutput); err != nil {
 				return err
 			}
 		} else {
 			// Original copy (all platforms)
-			if _, err := oras.Copy(ctx, srcRepo, srcRepo.Reference.Reference, dstRepo, dstRepo.Reference.Reference, oras.DefaultCopyOptions); err != nil {
+			if _, err := oras.Copy(ctx, srcRepo, srcRef, dstRepo, dstRepo.Reference.Reference, oras.DefaultCopyOptions); err != nil {
 				return errors.Wrapf(err, "failed to copy image %q", img)
 			}
 		}
+		if i.signatures {
+			if err := copySignatures(ctx, srcRepo, dstRepo, desc); err != nil {
+				return errors.Wrapf(err, "failed to copy signatures of image %q", img)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (i *imageArgs) copyWithPlatformFilter(ctx context.Context, src, dst *remote.Repository, img string, platform []string, logOutput io.Writer) error {
+// rewrite returns the reference rewritten by rewrites for the image, when dest is a remote registry
+// and a rule matches. Otherwise dest is returned unchanged.
+func (i *imageArgs) rewrite(dest, img string) (string, error) {
+	if len(i.rewrites) == 0 || !strings.HasPrefix(dest, "oci://") {
+		return dest, nil
+	}
+	rewritten, err := utils.RewriteImage(i.rewrites, img)
+	if err != nil || rewritten == img {
+		return dest, err
+	}
+
+	return "oci://" + rewritten, nil
+}
+
+func (i *imageArgs) copyWithPlatformFilter(ctx context.Context, src, dst *remote.Repository, img, srcRef string, platform []string, logOutput io.Writer) error {
 	// Build a set of requested platforms
 	requestedPlatforms := make(map[string]bool)
 	for _, p := range platform {
@@ -319,7 +437,7 @@ func (i *imageArgs) copyWithPlatformFilter(ctx context.Context, src, dst *remote
 	manifests := src.Manifests()
 
 	// Resolve the reference to get the descriptor
-	desc, err := manifests.Resolve(ctx, src.Reference.Reference)
+	desc, err := manifests.Resolve(ctx, srcRef)
 	if err != nil {
 		return errors.Wrapf(err, "failed to resolve manifest for %s", img)
 	}
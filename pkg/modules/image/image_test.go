@@ -504,3 +504,68 @@ func TestImageArgs(t *testing.T) {
 func ptrTo(b bool) *bool {
 	return &b
 }
+
+func TestImageArgsRewrite(t *testing.T) {
+	testcases := []struct {
+		name        string
+		rewrites    any
+		dest        string
+		img         string
+		expectError bool
+		expectDest  string
+	}{
+		{
+			name:       "push with prefix rule",
+			rewrites:   []map[string]any{{"prefix": "docker.io/calico/*", "target": "harbor.local/mirror/calico/*"}},
+			dest:       "oci://harbor.local/calico/node:v3.28.0",
+			img:        "docker.io/calico/node:v3.28.0",
+			expectDest: "oci://harbor.local/mirror/calico/node:v3.28.0",
+		},
+		{
+			name:       "push with json rules",
+			rewrites:   `[{"regex": "registry\\.k8s\\.io/(.*)", "target": "harbor.local/kubernetes/$1"}]`,
+			dest:       "oci://harbor.local/pause:3.9",
+			img:        "registry.k8s.io/pause:3.9",
+			expectDest: "oci://harbor.local/kubernetes/pause:3.9",
+		},
+		{
+			name:       "push without matching rule",
+			rewrites:   []map[string]any{{"prefix": "docker.io/calico", "target": "harbor.local/mirror/calico"}},
+			dest:       "oci://harbor.local/library/nginx:latest",
+			img:        "docker.io/library/nginx:latest",
+			expectDest: "oci://harbor.local/library/nginx:latest",
+		},
+		{
+			name:       "pull to local directory",
+			rewrites:   []map[string]any{{"prefix": "docker.io/calico", "target": "harbor.local/mirror/calico"}},
+			dest:       "local:///var/lib/kubekey/images",
+			img:        "docker.io/calico/node:v3.28.0",
+			expectDest: "local:///var/lib/kubekey/images",
+		},
+		{
+			name:        "invalid rule",
+			rewrites:    []map[string]any{{"prefix": "docker.io/calico"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := createRawArgs(map[string]any{
+				"manifests": []string{tc.img},
+				"src":       "local:///var/lib/kubekey/images",
+				"dest":      "oci://harbor.local/image",
+				"rewrites":  tc.rewrites,
+			})
+			ia, err := newImageArgs(context.Background(), raw, map[string]any{}, io.Discard)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			dest, err := ia.rewrite(tc.dest, tc.img)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectDest, dest)
+		})
+	}
+}
@@ -155,6 +155,11 @@ func newLocalRepository(reference, localDir string) (*remote.Repository, error)
 	repo := &remote.Repository{
 		Reference: ref,
 		Client:    &http.Client{Transport: &imageTransport{baseDir: localDir}},
+		// referrers are indexed by the referrers tag in local directory, which are not deleted.
+		SkipReferrersGC: true,
+	}
+	if err := repo.SetReferrersCapability(false); err != nil {
+		return nil, errors.Wrap(err, "failed to set referrers capability")
 	}
 
 	// Store in cache
@@ -474,6 +479,9 @@ func (i imageTransport) get(request *http.Request) *http.Response {
 				}
 			}
 			actualDigest = tagDigest[requestDigest]
+			if actualDigest == "" {
+				return responseNotFound
+			}
 		}
 
 		actualFilename = filepath.Join(filepath.Dir(filename), actualDigest)
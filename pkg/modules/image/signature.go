@@ -0,0 +1,652 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+const (
+	// VerifyCosign verifies the cosign signatures, which are stored in the tag "sha256-<hex>.sig" of the image.
+	VerifyCosign = "cosign"
+	// VerifyNotation verifies the notation signatures, which are stored as the OCI referrers of the image.
+	VerifyNotation = "notation"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+
+	notationArtifactType = "application/vnd.cncf.notary.signature"
+	notationJWSMediaType = "application/jose+json"
+
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// cosignTagSuffixes are the tag suffixes of the cosign signatures, attestations and SBOMs of an image.
+var cosignTagSuffixes = []string{".sig", ".att", ".sbom"}
+
+// The OIDC issuer extensions of the certificates issued by fulcio.
+var (
+	oidFulcioIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// jwsAlgorithms are the hashes of the JWS signature algorithms supported by notation.
+var jwsAlgorithms = map[string]crypto.Hash{
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// errNoSignature is returned when the image has no signature to verify.
+var errNoSignature = errors.New("no signature found")
+
+// imageVerify holds the configuration to verify the signatures of images before they are copied.
+type imageVerify struct {
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+	CaFile   string `json:"ca_file"`
+	Identity string `json:"identity"`
+	Issuer   string `json:"issuer"`
+	RekorKey string `json:"rekor_key"`
+
+	publicKey crypto.PublicKey
+	rekorKey  crypto.PublicKey
+	roots     *x509.CertPool
+	identity  *regexp.Regexp
+}
+
+// init validates the configuration, and loads the keys and certificates.
+func (v *imageVerify) init() error {
+	if v.Type == "" {
+		v.Type = VerifyCosign
+	}
+	switch v.Type {
+	case VerifyCosign:
+		if (v.Key == "") == (v.CaFile == "") {
+			return errors.New("one of \"key\" and \"ca_file\" in verify should be set for cosign")
+		}
+		if v.CaFile != "" && (v.Identity == "" || v.Issuer == "") {
+			return errors.New("\"identity\" and \"issuer\" in verify should be set for cosign keyless signatures")
+		}
+		if v.CaFile != "" && v.RekorKey == "" {
+			return errors.New("\"rekor_key\" in verify should be set for cosign keyless signatures")
+		}
+	case VerifyNotation:
+		if v.CaFile == "" {
+			return errors.New("\"ca_file\" in verify should be set for notation")
+		}
+	default:
+		return errors.Errorf("unsupported verify type %q, should be %q or %q", v.Type, VerifyCosign, VerifyNotation)
+	}
+
+	var err error
+	if v.Key != "" {
+		if v.publicKey, err = loadPublicKey(v.Key); err != nil {
+			return err
+		}
+	}
+	if v.RekorKey != "" {
+		if v.rekorKey, err = loadPublicKey(v.RekorKey); err != nil {
+			return err
+		}
+	}
+	if v.CaFile != "" {
+		ca, err := os.ReadFile(v.CaFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read CA file %q", v.CaFile)
+		}
+		v.roots = x509.NewCertPool()
+		if !v.roots.AppendCertsFromPEM(ca) {
+			return errors.Errorf("failed to append CA certificate from %q", v.CaFile)
+		}
+	}
+	if v.Identity != "" {
+		if v.identity, err = regexp.Compile("^(?:" + v.Identity + ")$"); err != nil {
+			return errors.Wrap(err, "\"identity\" in verify should be a valid regular expression")
+		}
+	}
+
+	return nil
+}
+
+// loadPublicKey reads the PEM encoded public key from file.
+func loadPublicKey(file string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read public key %q", file)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in public key %q", file)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse public key %q", file)
+	}
+
+	return key, nil
+}
+
+// verify checks that the image has a valid signature. When the image is an index without signature,
+// such as the index filtered by platform, every manifest of it should have a valid signature,
+// which requires the images to be signed recursively.
+func (v *imageVerify) verify(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) error {
+	err := v.verifyManifest(ctx, repo, desc)
+	if !errors.Is(err, errNoSignature) || !isIndex(desc.MediaType) {
+		return err
+	}
+	manifests, err := indexManifests(ctx, repo, desc)
+	if err != nil {
+		return err
+	}
+	for _, m := range manifests {
+		if err := v.verifyManifest(ctx, repo, m); err != nil {
+			return errors.Wrapf(err, "failed to verify manifest %s of index %s", m.Digest, desc.Digest)
+		}
+	}
+
+	return nil
+}
+
+// verifyManifest checks that the manifest has a valid signature.
+func (v *imageVerify) verifyManifest(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) error {
+	if v.Type == VerifyNotation {
+		return v.verifyNotation(ctx, repo, desc)
+	}
+
+	return v.verifyCosign(ctx, repo, desc)
+}
+
+// verifyCosign checks the cosign signatures of the manifest, one of which should be valid.
+func (v *imageVerify) verifyCosign(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) error {
+	tag := cosignTag(desc.Digest, ".sig")
+	_, data, err := oras.FetchBytes(ctx, repo, tag, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return errors.Wrapf(errNoSignature, "cosign signature %q", tag)
+		}
+		return errors.Wrapf(err, "failed to fetch cosign signature %q", tag)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return errors.Wrapf(err, "failed to parse cosign signature %q", tag)
+	}
+
+	err = errors.Wrapf(errNoSignature, "cosign signature %q", tag)
+	for _, layer := range manifest.Layers {
+		if err = v.verifyCosignLayer(ctx, repo, layer, desc); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// verifyCosignLayer checks a cosign signature, whose payload is the simple signing json of the manifest digest.
+func (v *imageVerify) verifyCosignLayer(ctx context.Context, repo *remote.Repository, layer, desc ocispec.Descriptor) error {
+	sig, err := base64.StdEncoding.DecodeString(layer.Annotations[cosignSignatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return errors.New("invalid cosign signature annotation")
+	}
+	payload, err := content.FetchAll(ctx, repo.Blobs(), layer)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch cosign signature payload")
+	}
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return errors.Wrap(err, "failed to parse cosign signature payload")
+	}
+	if simpleSigning.Critical.Image.DockerManifestDigest != desc.Digest.String() {
+		return errors.Errorf("cosign signature is for %q, expected %q", simpleSigning.Critical.Image.DockerManifestDigest, desc.Digest)
+	}
+
+	publicKey := v.publicKey
+	if publicKey == nil {
+		cert, err := v.verifyCosignCertificate(layer.Annotations, payload, sig)
+		if err != nil {
+			return err
+		}
+		publicKey = cert.PublicKey
+	}
+
+	return verifySignature(publicKey, payload, sig)
+}
+
+// verifyCosignCertificate checks the keyless signing certificate by the CA, identity and issuer.
+// Signing certificates are short-lived, so they are verified at the integrated time of the rekor bundle,
+// which should record the payload, the signature and the certificate.
+func (v *imageVerify) verifyCosignCertificate(annotations map[string]string, payload, sig []byte) (*x509.Certificate, error) {
+	certs, err := parseCertificates(annotations[cosignCertificateAnnotation])
+	if err != nil || len(certs) == 0 {
+		return nil, errors.New("no certificate found in cosign keyless signature")
+	}
+	chain, err := parseCertificates(annotations[cosignChainAnnotation])
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+
+	bundle := annotations[cosignBundleAnnotation]
+	if bundle == "" {
+		return nil, errors.New("no rekor bundle found in cosign keyless signature")
+	}
+	cert := certs[0]
+	verifyTime, err := v.verifyRekorBundle(bundle, payload, sig, cert)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   verifyTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to verify cosign signing certificate")
+	}
+
+	identities := slices.Clone(cert.EmailAddresses)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	if !slices.ContainsFunc(identities, v.identity.MatchString) {
+		return nil, errors.Errorf("identities %v of cosign signing certificate do not match %q", identities, v.Identity)
+	}
+	if issuer := certificateIssuer(cert); issuer != v.Issuer {
+		return nil, errors.Errorf("issuer %q of cosign signing certificate does not match %q", issuer, v.Issuer)
+	}
+
+	return cert, nil
+}
+
+// verifyRekorBundle returns the integrated time of the rekor bundle. The signed entry timestamp is verified
+// by the rekor public key, and the entry in the body should record the payload hash, the signature and the certificate.
+func (v *imageVerify) verifyRekorBundle(data string, payload, sig []byte, cert *x509.Certificate) (time.Time, error) {
+	var bundle struct {
+		SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+		// the fields are in the order of canonical json, which is signed by rekor.
+		Payload struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogID          string `json:"logID"`
+			LogIndex       int64  `json:"logIndex"`
+		} `json:"Payload"`
+	}
+	if err := json.Unmarshal([]byte(data), &bundle); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to parse rekor bundle")
+	}
+	bundlePayload, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to marshal rekor bundle payload")
+	}
+	if err := verifySignature(v.rekorKey, bundlePayload, bundle.SignedEntryTimestamp); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to verify rekor bundle")
+	}
+	if err := verifyRekorEntry(bundle.Payload.Body, payload, sig, cert); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// verifyRekorEntry checks that the hashedrekord or rekord entry in the body of the rekor bundle records
+// the sha256 of the payload, the signature and the signing certificate.
+func verifyRekorEntry(body string, payload, sig []byte, cert *x509.Certificate) error {
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode rekor bundle body")
+	}
+	var entry struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return errors.Wrap(err, "failed to parse rekor bundle body")
+	}
+	if entry.Kind != "hashedrekord" && entry.Kind != "rekord" {
+		return errors.Errorf("unsupported rekor entry kind %q", entry.Kind)
+	}
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != digest.SHA256.FromBytes(payload).Encoded() {
+		return errors.New("rekor entry does not match the hash of cosign signature payload")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, sig) {
+		return errors.New("rekor entry does not match the cosign signature")
+	}
+	certs, err := parseCertificates(string(entry.Spec.Signature.PublicKey.Content))
+	if err != nil || len(certs) == 0 || !certs[0].Equal(cert) {
+		return errors.New("rekor entry does not match the cosign signing certificate")
+	}
+
+	return nil
+}
+
+// verifyNotation checks the notation signatures of the manifest, one of which should be valid.
+func (v *imageVerify) verifyNotation(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) error {
+	var referrers []ocispec.Descriptor
+	if err := repo.Referrers(ctx, desc, notationArtifactType, func(r []ocispec.Descriptor) error {
+		referrers = append(referrers, r...)
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed to list notation signatures of %s", desc.Digest)
+	}
+
+	err := errors.Wrapf(errNoSignature, "notation signature of %s", desc.Digest)
+	for _, referrer := range referrers {
+		if err = v.verifyNotationSignature(ctx, repo, referrer, desc); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// verifyNotationSignature checks a notation signature in JWS envelope, whose payload is the descriptor of the manifest.
+func (v *imageVerify) verifyNotationSignature(ctx context.Context, repo *remote.Repository, referrer, desc ocispec.Descriptor) error {
+	data, err := content.FetchAll(ctx, repo, referrer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch notation signature %s", referrer.Digest)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil || len(manifest.Layers) == 0 {
+		return errors.Errorf("invalid notation signature %s", referrer.Digest)
+	}
+	if manifest.Layers[0].MediaType != notationJWSMediaType {
+		return errors.Errorf("unsupported notation signature envelope %q, only %q is supported", manifest.Layers[0].MediaType, notationJWSMediaType)
+	}
+	data, err = content.FetchAll(ctx, repo, manifest.Layers[0])
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch notation signature envelope %s", manifest.Layers[0].Digest)
+	}
+
+	var envelope struct {
+		Payload   string `json:"payload"`
+		Protected string `json:"protected"`
+		Header    struct {
+			CertChain [][]byte `json:"x5c"`
+		} `json:"header"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return errors.Wrap(err, "failed to parse notation signature envelope")
+	}
+	protectedData, err := base64.RawURLEncoding.DecodeString(envelope.Protected)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode notation protected header")
+	}
+	var protected struct {
+		Alg    string     `json:"alg"`
+		Expiry *time.Time `json:"io.cncf.notary.expiry"`
+	}
+	if err := json.Unmarshal(protectedData, &protected); err != nil {
+		return errors.Wrap(err, "failed to parse notation protected header")
+	}
+	if protected.Expiry != nil && time.Now().After(*protected.Expiry) {
+		return errors.Errorf("notation signature expired at %s", protected.Expiry)
+	}
+
+	// verify the certificate chain, the signature and the payload.
+	if len(envelope.Header.CertChain) == 0 {
+		return errors.New("no certificate found in notation signature")
+	}
+	certs := make([]*x509.Certificate, 0, len(envelope.Header.CertChain))
+	for _, der := range envelope.Header.CertChain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse notation certificate")
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return errors.Wrap(err, "failed to verify notation signing certificate")
+	}
+	if v.identity != nil && !v.identity.MatchString(certs[0].Subject.String()) {
+		return errors.Errorf("subject %q of notation signing certificate does not match %q", certs[0].Subject, v.Identity)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode notation signature")
+	}
+	if err := verifyJWSSignature(certs[0].PublicKey, protected.Alg, []byte(envelope.Protected+"."+envelope.Payload), sig); err != nil {
+		return err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode notation payload")
+	}
+	var target struct {
+		TargetArtifact ocispec.Descriptor `json:"targetArtifact"`
+	}
+	if err := json.Unmarshal(payload, &target); err != nil {
+		return errors.Wrap(err, "failed to parse notation payload")
+	}
+	if target.TargetArtifact.Digest != desc.Digest {
+		return errors.Errorf("notation signature is for %q, expected %q", target.TargetArtifact.Digest, desc.Digest)
+	}
+
+	return nil
+}
+
+// verifySignature verifies the signature of message, which is signed with SHA256 by ecdsa or rsa (PKCS #1 v1.5), or by ed25519.
+func verifySignature(publicKey crypto.PublicKey, message, sig []byte) error {
+	hash := crypto.SHA256.New()
+	hash.Write(message)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, hash.Sum(nil), sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash.Sum(nil), sig) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, message, sig) {
+			return nil
+		}
+	default:
+		return errors.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return errors.New("invalid signature")
+}
+
+// verifyJWSSignature verifies the JWS signature, which is RSASSA-PSS or ECDSA with SHA-2 as required by notation.
+func verifyJWSSignature(publicKey crypto.PublicKey, alg string, message, sig []byte) error {
+	hash, ok := jwsAlgorithms[alg]
+	if !ok {
+		return errors.Errorf("unsupported notation signature algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(message)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "PS") && rsa.VerifyPSS(key, hash, h.Sum(nil), sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if strings.HasPrefix(alg, "ES") && len(sig) == 2*size &&
+			ecdsa.Verify(key, h.Sum(nil), new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])) {
+			return nil
+		}
+	default:
+		return errors.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return errors.New("invalid notation signature")
+}
+
+// parseCertificates parses the PEM encoded certificates.
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// certificateIssuer returns the OIDC issuer in the fulcio extension of the certificate.
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidFulcioIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidFulcioIssuer):
+			return string(ext.Value)
+		}
+	}
+
+	return ""
+}
+
+// copySignatures copies the cosign signatures, attestations and SBOMs, and the OCI referrers such as notation signatures,
+// of the image and the manifests of it which exist in dst. Local directories have no referrers API, and the referrers
+// are listed and pushed by the referrers tag "sha256-<hex>", see newLocalRepository.
+func copySignatures(ctx context.Context, src, dst *remote.Repository, desc ocispec.Descriptor) error {
+	descs := []ocispec.Descriptor{desc}
+	if isIndex(desc.MediaType) {
+		manifests, err := indexManifests(ctx, src, desc)
+		if err != nil {
+			return err
+		}
+		descs = append(descs, manifests...)
+	}
+
+	for _, d := range descs {
+		// the manifest may be filtered out by platform.
+		if exists, err := dst.Exists(ctx, d); err != nil || !exists {
+			continue
+		}
+		for _, suffix := range cosignTagSuffixes {
+			tag := cosignTag(d.Digest, suffix)
+			if _, err := src.Resolve(ctx, tag); err != nil {
+				if errors.Is(err, errdef.ErrNotFound) {
+					continue
+				}
+				return errors.Wrapf(err, "failed to resolve %q", tag)
+			}
+			if _, err := oras.Copy(ctx, src, tag, dst, tag, oras.DefaultCopyOptions); err != nil {
+				return errors.Wrapf(err, "failed to copy %q", tag)
+			}
+		}
+		var referrers []ocispec.Descriptor
+		if err := src.Referrers(ctx, d, "", func(r []ocispec.Descriptor) error {
+			referrers = append(referrers, r...)
+			return nil
+		}); err != nil {
+			return errors.Wrapf(err, "failed to list referrers of %s", d.Digest)
+		}
+		for _, referrer := range referrers {
+			if err := oras.CopyGraph(ctx, src, dst, referrer, oras.DefaultCopyGraphOptions); err != nil {
+				return errors.Wrapf(err, "failed to copy referrer %s", referrer.Digest)
+			}
+		}
+	}
+
+	return nil
+}
+
+// cosignTag returns the tag of the cosign signature, attestation or SBOM of the manifest, such as "sha256-<hex>.sig".
+func cosignTag(dgst digest.Digest, suffix string) string {
+	return dgst.Algorithm().String() + "-" + dgst.Encoded() + suffix
+}
+
+// isIndex reports whether the media type is an image index or a docker manifest list.
+func isIndex(mediaType string) bool {
+	return mediaType == ocispec.MediaTypeImageIndex || mediaType == mediaTypeDockerManifestList
+}
+
+// indexManifests returns the manifests of the image index.
+func indexManifests(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	data, err := content.FetchAll(ctx, repo, desc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch index %s", desc.Digest)
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse index %s", desc.Digest)
+	}
+
+	return index.Manifests, nil
+}
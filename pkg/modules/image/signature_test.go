@@ -0,0 +1,408 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+const testSignedImage = "registry.example.com/app:v1"
+
+// signer signs the test image in the repository.
+type signer func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor)
+
+// pushTestBlob pushes the content to the repository and returns its descriptor.
+func pushTestBlob(t *testing.T, repo *remote.Repository, mediaType string, data []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := content.NewDescriptorFromBytes(mediaType, data)
+	require.NoError(t, repo.Push(context.TODO(), desc, bytes.NewReader(data)))
+
+	return desc
+}
+
+// pushTestManifest pushes the manifest to the repository, and tags it when tag is not empty.
+func pushTestManifest(t *testing.T, repo *remote.Repository, manifest any, mediaType, tag string) ocispec.Descriptor {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	desc := content.NewDescriptorFromBytes(mediaType, data)
+	if tag == "" {
+		require.NoError(t, repo.Push(context.TODO(), desc, bytes.NewReader(data)))
+	} else {
+		require.NoError(t, repo.PushReference(context.TODO(), desc, bytes.NewReader(data), tag))
+	}
+
+	return desc
+}
+
+// pushTestImage pushes a single platform image, and an index of it when index is true.
+func pushTestImage(t *testing.T, repo *remote.Repository, index bool) ocispec.Descriptor {
+	t.Helper()
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    pushTestBlob(t, repo, ocispec.MediaTypeImageConfig, []byte(`{"architecture":"amd64","os":"linux"}`)),
+		Layers:    []ocispec.Descriptor{pushTestBlob(t, repo, ocispec.MediaTypeImageLayer, []byte("layer"))},
+	}
+	manifest.SchemaVersion = 2
+	if !index {
+		return pushTestManifest(t, repo, manifest, ocispec.MediaTypeImageManifest, "v1")
+	}
+	desc := pushTestManifest(t, repo, manifest, ocispec.MediaTypeImageManifest, "")
+	desc.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	idx := ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: []ocispec.Descriptor{desc}}
+	idx.SchemaVersion = 2
+
+	return pushTestManifest(t, repo, idx, ocispec.MediaTypeImageIndex, "v1")
+}
+
+// pushCosignSignature pushes the cosign signature of desc, which is signed by key.
+// annotations returns the additional annotations of the signature layer from the payload and signature.
+func pushCosignSignature(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor, key *ecdsa.PrivateKey, annotations func(payload, sig []byte) map[string]string) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.example.com/app"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, desc.Digest))
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	require.NoError(t, err)
+	layer := pushTestBlob(t, repo, "application/vnd.dev.cosign.simplesigning.v1+json", payload)
+	layer.Annotations = map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	if annotations != nil {
+		for k, v := range annotations(payload, sig) {
+			layer.Annotations[k] = v
+		}
+	}
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    pushTestBlob(t, repo, "application/vnd.oci.image.config.v1+json", []byte("{}")),
+		Layers:    []ocispec.Descriptor{layer},
+	}
+	manifest.SchemaVersion = 2
+	pushTestManifest(t, repo, manifest, ocispec.MediaTypeImageManifest, cosignTag(desc.Digest, ".sig"))
+}
+
+// pushNotationSignature pushes the notation JWS signature of desc, which is signed by key with the certificate chain.
+func pushNotationSignature(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor, key *rsa.PrivateKey, chain ...*x509.Certificate) {
+	t.Helper()
+	target, err := json.Marshal(map[string]any{"targetArtifact": ocispec.Descriptor{MediaType: desc.MediaType, Digest: desc.Digest, Size: desc.Size}})
+	require.NoError(t, err)
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"PS256","cty":"application/vnd.cncf.notary.payload.v1+json","io.cncf.notary.signingScheme":"notary.x509"}`))
+	payload := base64.RawURLEncoding.EncodeToString(target)
+	sum := sha256.Sum256([]byte(protected + "." + payload))
+	sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, sum[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	require.NoError(t, err)
+	x5c := make([][]byte, 0, len(chain))
+	for _, c := range chain {
+		x5c = append(x5c, c.Raw)
+	}
+	envelope, err := json.Marshal(map[string]any{
+		"payload": payload, "protected": protected, "signature": base64.RawURLEncoding.EncodeToString(sig),
+		"header": map[string]any{"x5c": x5c},
+	})
+	require.NoError(t, err)
+	manifest := ocispec.Manifest{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: notationArtifactType,
+		Config:       pushTestBlob(t, repo, ocispec.MediaTypeEmptyJSON, []byte("{}")),
+		Layers:       []ocispec.Descriptor{pushTestBlob(t, repo, notationJWSMediaType, envelope)},
+		Subject:      &ocispec.Descriptor{MediaType: desc.MediaType, Digest: desc.Digest, Size: desc.Size},
+	}
+	manifest.SchemaVersion = 2
+	pushTestManifest(t, repo, manifest, ocispec.MediaTypeImageManifest, "")
+}
+
+// newTestCertificate creates a certificate signed by parent, or a self-signed CA when parent is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serial
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// writeTestPEM writes the PEM block to a file in dir and returns the file path.
+func writeTestPEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+
+	return file
+}
+
+func TestImageVerifyCopy(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	cosignKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cosignPub, err := x509.MarshalPKIXPublicKey(cosignKey.Public())
+	require.NoError(t, err)
+	keyFile := writeTestPEM(t, dir, "cosign.pub", "PUBLIC KEY", cosignPub)
+
+	// a short-lived keyless certificate, which is only valid at the integrated time of the rekor bundle.
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "test-ca"}, NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)}, caKey, nil, nil)
+	caFile := writeTestPEM(t, dir, "ca.crt", "CERTIFICATE", ca.Raw)
+	issuer, err := asn1.Marshal("https://issuer.example.com")
+	require.NoError(t, err)
+	leaf := newTestCertificate(t, &x509.Certificate{
+		NotBefore:       now.Add(-time.Hour),
+		NotAfter:        now.Add(-50 * time.Minute),
+		EmailAddresses:  []string{"dev@example.com"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidFulcioIssuerV2, Value: issuer}},
+	}, cosignKey, ca, caKey)
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rekorPub, err := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	require.NoError(t, err)
+	rekorFile := writeTestPEM(t, dir, "rekor.pub", "PUBLIC KEY", rekorPub)
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+	// newKeyless returns the annotations of the keyless signature, whose rekor entry records the payload and entrySig.
+	newKeyless := func(t *testing.T, payload, entrySig []byte) map[string]string {
+		t.Helper()
+		sum := sha256.Sum256(payload)
+		body, err := json.Marshal(map[string]any{
+			"apiVersion": "0.0.1",
+			"kind":       "hashedrekord",
+			"spec": map[string]any{
+				"data":      map[string]any{"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(sum[:])}},
+				"signature": map[string]any{"content": entrySig, "publicKey": map[string]any{"content": leafPEM}},
+			},
+		})
+		require.NoError(t, err)
+		bundlePayload := []byte(fmt.Sprintf(`{"body":%q,"integratedTime":%d,"logID":"abc","logIndex":1}`, base64.StdEncoding.EncodeToString(body), now.Add(-55*time.Minute).Unix()))
+		sum = sha256.Sum256(bundlePayload)
+		set, err := ecdsa.SignASN1(rand.Reader, rekorKey, sum[:])
+		require.NoError(t, err)
+
+		return map[string]string{
+			cosignCertificateAnnotation: string(leafPEM),
+			cosignBundleAnnotation:      fmt.Sprintf(`{"SignedEntryTimestamp":%q,"Payload":%s}`, base64.StdEncoding.EncodeToString(set), bundlePayload),
+		}
+	}
+
+	// notation signing certificate.
+	notationKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	notationCert := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kubekey", Organization: []string{"kubesphere"}},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, notationKey, ca, caKey)
+	otherCAKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherCA := newTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "other-ca"}, NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)}, otherCAKey, nil, nil)
+	otherCAFile := writeTestPEM(t, dir, "other-ca.crt", "CERTIFICATE", otherCA.Raw)
+
+	testcases := []struct {
+		name        string
+		index       bool
+		sign        signer
+		verify      imageVerify
+		expectError bool
+	}{
+		{
+			name: "cosign key",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, cosignKey, nil)
+			},
+			verify: imageVerify{Key: keyFile},
+		},
+		{
+			name: "cosign signed by other key",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, otherKey, nil)
+			},
+			verify:      imageVerify{Key: keyFile},
+			expectError: true,
+		},
+		{
+			name:        "unsigned",
+			sign:        func(*testing.T, *remote.Repository, ocispec.Descriptor) {},
+			verify:      imageVerify{Key: keyFile},
+			expectError: true,
+		},
+		{
+			name:  "cosign key signed recursively",
+			index: true,
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				manifests, err := indexManifests(context.TODO(), repo, desc)
+				require.NoError(t, err)
+				pushCosignSignature(t, repo, manifests[0], cosignKey, nil)
+			},
+			verify: imageVerify{Key: keyFile},
+		},
+		{
+			name: "cosign keyless",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, cosignKey, func(payload, sig []byte) map[string]string {
+					return newKeyless(t, payload, sig)
+				})
+			},
+			verify: imageVerify{CaFile: caFile, Identity: ".*@example.com", Issuer: "https://issuer.example.com", RekorKey: rekorFile},
+		},
+		{
+			name: "cosign keyless identity mismatch",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, cosignKey, func(payload, sig []byte) map[string]string {
+					return newKeyless(t, payload, sig)
+				})
+			},
+			verify:      imageVerify{CaFile: caFile, Identity: "admin@example.com", Issuer: "https://issuer.example.com", RekorKey: rekorFile},
+			expectError: true,
+		},
+		{
+			name: "cosign keyless bundle signed by other rekor",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, cosignKey, func(payload, sig []byte) map[string]string {
+					return newKeyless(t, payload, sig)
+				})
+			},
+			verify:      imageVerify{CaFile: caFile, Identity: ".*@example.com", Issuer: "https://issuer.example.com", RekorKey: keyFile},
+			expectError: true,
+		},
+		{
+			name: "cosign keyless bundle of other signature",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushCosignSignature(t, repo, desc, cosignKey, func(payload, _ []byte) map[string]string {
+					return newKeyless(t, payload, []byte("other signature"))
+				})
+			},
+			verify:      imageVerify{CaFile: caFile, Identity: ".*@example.com", Issuer: "https://issuer.example.com", RekorKey: rekorFile},
+			expectError: true,
+		},
+		{
+			name: "notation",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushNotationSignature(t, repo, desc, notationKey, notationCert)
+			},
+			verify: imageVerify{Type: VerifyNotation, CaFile: caFile, Identity: "CN=kubekey,O=kubesphere"},
+		},
+		{
+			name: "notation untrusted",
+			sign: func(t *testing.T, repo *remote.Repository, desc ocispec.Descriptor) {
+				pushNotationSignature(t, repo, desc, notationKey, notationCert)
+			},
+			verify:      imageVerify{Type: VerifyNotation, CaFile: otherCAFile},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			src, dest := t.TempDir(), t.TempDir()
+			srcRepo, err := newLocalRepository(testSignedImage, src)
+			require.NoError(t, err)
+			desc := pushTestImage(t, srcRepo, tc.index)
+			tc.sign(t, srcRepo, desc)
+			require.NoError(t, tc.verify.init())
+
+			ia := &imageArgs{
+				manifests:  []string{testSignedImage},
+				src:        "local://" + src,
+				dest:       "local://" + dest,
+				policy:     PolicyStrict,
+				verify:     &tc.verify,
+				signatures: true,
+			}
+			err = ia.copy(ctx, make(map[string]any))
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			// the signatures are carried to dest.
+			destRepo, err := newLocalRepository(testSignedImage, dest)
+			require.NoError(t, err)
+			require.NoError(t, tc.verify.verify(ctx, destRepo, desc))
+		})
+	}
+}
+
+func TestImageVerifyInit(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	keyFile := writeTestPEM(t, dir, "cosign.pub", "PUBLIC KEY", pub)
+
+	testcases := []struct {
+		name        string
+		verify      imageVerify
+		expectError bool
+	}{
+		{name: "cosign key", verify: imageVerify{Key: keyFile}},
+		{name: "cosign without key", verify: imageVerify{Type: VerifyCosign}, expectError: true},
+		{name: "cosign keyless without identity", verify: imageVerify{CaFile: keyFile, Issuer: "https://issuer.example.com"}, expectError: true},
+		{name: "cosign keyless without rekor key", verify: imageVerify{CaFile: keyFile, Identity: ".*", Issuer: "https://issuer.example.com"}, expectError: true},
+		{name: "notation without ca", verify: imageVerify{Type: VerifyNotation}, expectError: true},
+		{name: "unsupported type", verify: imageVerify{Type: "gpg", Key: keyFile}, expectError: true},
+		{name: "missing key", verify: imageVerify{Key: filepath.Join(dir, "not-exist.pub")}, expectError: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.verify.init()
+			if tc.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
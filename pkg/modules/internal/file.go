@@ -0,0 +1,348 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+const (
+	// PositionBOF is the insert position at the beginning of the file.
+	PositionBOF = "BOF"
+	// PositionEOF is the insert position at the end of the file.
+	PositionEOF = "EOF"
+)
+
+// RemoteFile is a regular file in the remote host, which is read by ReadRemoteFile and edited in place by modules.
+type RemoteFile struct {
+	// Path of the file in the remote host.
+	Path string
+	// Exists is false when the file does not exist. The other fields are zero value.
+	Exists bool
+	// Mode is the permission bits of the file.
+	Mode fs.FileMode
+	// UID and GID are the owner of the file.
+	UID, GID int
+	// Content of the file.
+	Content []byte
+}
+
+// PathStat is the status of a path in the remote host, which is returned by StatRemotePath.
+type PathStat struct {
+	// Exists is false when the path does not exist. The other fields are zero value.
+	Exists bool
+	// Type is one of "file", "directory", "link" or the file type by stat, such as "socket".
+	Type string
+	// Mode is the permission bits of the path, including setuid, setgid and sticky bits.
+	Mode fs.FileMode
+	// UID and GID are the owner of the path.
+	UID, GID int
+	// Owner and Group are the names of the owner, or "UNKNOWN" when the id has no name.
+	Owner, Group string
+	// Size is the size in bytes.
+	Size int64
+	// MTime is the last modification time in unix seconds.
+	MTime int64
+	// LinkTarget is the target of a symbolic link.
+	LinkTarget string
+}
+
+// StatRemotePath stats the path by command. A symbolic link is followed when follow is true.
+func StatRemotePath(ctx context.Context, conn connector.Connector, path string, follow bool) (*PathStat, error) {
+	test, flag := fmt.Sprintf("[ -e %[1]s ] || [ -L %[1]s ]", ShellQuote(path)), ""
+	if follow {
+		test, flag = fmt.Sprintf("[ -e %s ]", ShellQuote(path)), "-L "
+	}
+	cmd := fmt.Sprintf("if %s; then stat %s-c '%%a %%u %%g %%U %%G %%s %%Y %%F' %s; fi", test, flag, ShellQuote(path))
+	stdout, stderr, err := conn.ExecuteCommand(ctx, cmd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %q, stderr: %s", path, strings.TrimSpace(string(stderr)))
+	}
+	fields := strings.SplitN(strings.TrimSpace(string(stdout)), " ", 8)
+	if len(fields) < 8 {
+		// the path does not exist.
+		return &PathStat{}, nil
+	}
+	st := &PathStat{Exists: true, Owner: fields[3], Group: fields[4]}
+	switch fields[7] {
+	case "regular file", "regular empty file":
+		st.Type = "file"
+	case "directory":
+		st.Type = "directory"
+	case "symbolic link":
+		st.Type = "link"
+	default:
+		st.Type = fields[7]
+	}
+	mode, err := strconv.ParseUint(fields[0], 8, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse mode of %q", path)
+	}
+	st.Mode = fs.FileMode(mode)
+	if st.UID, err = strconv.Atoi(fields[1]); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse owner of %q", path)
+	}
+	if st.GID, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse group of %q", path)
+	}
+	if st.Size, err = strconv.ParseInt(fields[5], 10, 64); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse size of %q", path)
+	}
+	if st.MTime, err = strconv.ParseInt(fields[6], 10, 64); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse mtime of %q", path)
+	}
+	if st.Type == "link" {
+		stdout, stderr, err := conn.ExecuteCommand(ctx, "readlink "+ShellQuote(path))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read link %q, stderr: %s", path, strings.TrimSpace(string(stderr)))
+		}
+		st.LinkTarget = strings.TrimSpace(string(stdout))
+	}
+
+	return st, nil
+}
+
+// ReadRemoteFile stats the file by StatRemotePath and reads its content by Connector.FetchFile.
+// It returns error when the path exists but is not a regular file.
+func ReadRemoteFile(ctx context.Context, conn connector.Connector, path string) (*RemoteFile, error) {
+	st, err := StatRemotePath(ctx, conn, path, true)
+	if err != nil {
+		return nil, err
+	}
+	rf := &RemoteFile{Path: path}
+	if !st.Exists {
+		return rf, nil
+	}
+	if st.Type != "file" {
+		return nil, errors.Errorf("%q is a %s, not a regular file", path, st.Type)
+	}
+	rf.Exists, rf.Mode, rf.UID, rf.GID = true, st.Mode, st.UID, st.GID
+	var content bytes.Buffer
+	if err := conn.FetchFile(ctx, path, &content); err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %q", path)
+	}
+	rf.Content = content.Bytes()
+
+	return rf, nil
+}
+
+// Backup copies the existing file to "<path>.<timestamp>~" with its mode and owner, and returns the backup path.
+func (rf *RemoteFile) Backup(ctx context.Context, conn connector.Connector) (string, error) {
+	if !rf.Exists {
+		return "", nil
+	}
+	backup := rf.Path + "." + time.Now().Format("2006-01-02@15:04:05") + "~"
+	if _, stderr, err := conn.ExecuteCommand(ctx, "cp -p "+ShellQuote(rf.Path)+" "+ShellQuote(backup)); err != nil {
+		return "", errors.Wrapf(err, "failed to backup %q, stderr: %s", rf.Path, strings.TrimSpace(string(stderr)))
+	}
+
+	return backup, nil
+}
+
+// Write replaces the content of the file by connector.PutData. The mode and owner of the existing file are kept.
+// A new file is created with _const.PermFilePublic and the owner of the connector.
+func (rf *RemoteFile) Write(ctx context.Context, conn connector.Connector, content []byte) error {
+	mode := _const.PermFilePublic
+	if rf.Exists {
+		mode = rf.Mode
+	}
+	if err := connector.PutData(ctx, content, rf.Path, mode, conn); err != nil {
+		return errors.Wrapf(err, "failed to write %q", rf.Path)
+	}
+	if rf.Exists {
+		// the uploaded file is owned by the connector user.
+		cmd := fmt.Sprintf("chown %d:%d %s && chmod %o %s", rf.UID, rf.GID, ShellQuote(rf.Path), rf.Mode, ShellQuote(rf.Path))
+		if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+			return errors.Wrapf(err, "failed to restore owner of %q, stderr: %s", rf.Path, strings.TrimSpace(string(stderr)))
+		}
+	}
+	rf.Content = content
+
+	return nil
+}
+
+// ModeArg returns the file mode argument, or nil when it is not set.
+// A string is parsed as octal, such as "0755". A number is the mode itself, such as 0755 in yaml.
+func ModeArg(vars, args map[string]any, key string) (*fs.FileMode, error) {
+	val, ok := args[key]
+	if !ok {
+		return nil, nil
+	}
+	var mode uint64
+	if _, isString := val.(string); isString {
+		s, err := variable.StringVar(vars, args, key)
+		if err != nil {
+			return nil, err
+		}
+		if mode, err = strconv.ParseUint(s, 8, 32); err != nil {
+			return nil, errors.Errorf("%q in args should be octal, got %q", key, s)
+		}
+	} else {
+		i, err := variable.IntVar(vars, args, key)
+		if err != nil || *i < 0 {
+			return nil, errors.Errorf("%q in args should be octal", key)
+		}
+		mode = uint64(*i)
+	}
+	if mode > 0o7777 {
+		return nil, errors.Errorf("%q in args should not be greater than 07777, got %o", key, mode)
+	}
+	m := fs.FileMode(mode)
+
+	return &m, nil
+}
+
+// BoolArg returns the bool argument, or def when it is not set.
+func BoolArg(vars, args map[string]any, key string, def bool) (bool, error) {
+	if _, ok := args[key]; !ok {
+		return def, nil
+	}
+	b, err := variable.BoolVar(vars, args, key)
+	if err != nil {
+		return false, errors.Errorf("%q in args should be bool", key)
+	}
+
+	return *b, nil
+}
+
+// DurationArg returns the duration argument, or the default value when it is not set.
+// A number, or a string of number, is in seconds. Otherwise it is parsed as duration, such as "5m".
+func DurationArg(vars, args map[string]any, key string, def time.Duration) (time.Duration, error) {
+	val, ok := args[key]
+	if !ok {
+		return def, nil
+	}
+	if _, isString := val.(string); !isString {
+		seconds, err := variable.IntVar(vars, args, key)
+		if err != nil || *seconds < 0 {
+			return 0, errors.Errorf("%q in args should be seconds or duration", key)
+		}
+
+		return time.Duration(*seconds) * time.Second, nil
+	}
+	s, err := variable.StringVar(vars, args, key)
+	if err != nil {
+		return 0, err
+	}
+	if seconds, err := strconv.Atoi(s); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.Errorf("%q in args should be seconds or duration, got %q", key, s)
+	}
+
+	return d, nil
+}
+
+// InsertPosition is where to insert the lines which do not exist in a file.
+type InsertPosition struct {
+	bof    bool           // insert at the beginning of the file
+	after  *regexp.Regexp // insert after the last matched line
+	before *regexp.Regexp // insert before the last matched line
+}
+
+// InsertPositionArg returns the insert position from the "insertafter" and "insertbefore" arguments.
+// "insertafter" is a regexp or EOF, "insertbefore" is a regexp or BOF, and they are mutually exclusive.
+func InsertPositionArg(vars, args map[string]any) (InsertPosition, error) {
+	var pos InsertPosition
+	insertAfter, _ := variable.StringVar(vars, args, "insertafter")
+	insertBefore, _ := variable.StringVar(vars, args, "insertbefore")
+	if insertAfter != "" && insertBefore != "" {
+		return pos, errors.New("\"insertafter\" and \"insertbefore\" are mutually exclusive")
+	}
+	var err error
+	switch {
+	case insertBefore == PositionBOF:
+		pos.bof = true
+	case insertBefore != "":
+		if pos.before, err = regexp.Compile(insertBefore); err != nil {
+			return pos, errors.Wrapf(err, "insert position %q is invalid", insertBefore)
+		}
+	case insertAfter != "" && insertAfter != PositionEOF:
+		if pos.after, err = regexp.Compile(insertAfter); err != nil {
+			return pos, errors.Wrapf(err, "insert position %q is invalid", insertAfter)
+		}
+	}
+
+	return pos, nil
+}
+
+// InsertIndex returns the index to insert the line. It falls back to the end of the file when nothing is matched.
+func InsertIndex(lines []string, pos InsertPosition) int {
+	switch {
+	case pos.bof:
+		return 0
+	case pos.before != nil:
+		if i := LastMatch(lines, pos.before.MatchString); i >= 0 {
+			return i
+		}
+	case pos.after != nil:
+		if i := LastMatch(lines, pos.after.MatchString); i >= 0 {
+			return i + 1
+		}
+	}
+
+	return len(lines)
+}
+
+// LastMatch returns the index of the last matched line, or -1.
+func LastMatch(lines []string, match func(string) bool) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if match(lines[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// SplitLines splits the content into lines without the line endings.
+func SplitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// JoinLines joins the lines with a newline at the end of the file.
+func JoinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ShellQuote quotes the string by single quotes for shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
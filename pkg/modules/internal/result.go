@@ -1,5 +1,7 @@
 package internal
 
+import "encoding/json"
+
 // Standard output results for module execution.
 // These constants are used to standardize the messages returned by modules for their basic result states.
 const (
@@ -40,3 +42,13 @@ const (
 	// could not be loaded, parsed, or found. Module execution often depends on an existing playbook context.
 	StderrGetPlaybook = "failed to get playbook"
 )
+
+// MarshalResult returns the module result as json in stdout.
+func MarshalResult(result any) (string, string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return StdoutFailed, "failed to marshal result", err
+	}
+
+	return string(data), "", nil
+}
@@ -18,8 +18,10 @@ package internal
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/fs"
+	"strings"
 
 	"k8s.io/klog/v2"
 
@@ -102,3 +104,110 @@ func NewTestVariable(hosts []string, vars map[string]any) variable.Variable {
 
 	return v
 }
+
+// TestFileConnector is a mock implementation of connector.Connector with files in memory.
+// It supports the commands used by StatRemotePath (only for regular files), ReadRemoteFile, RemoteFile.Backup, RemoteFile.Write and connector.PutData,
+// and records the other commands in Executed.
+type TestFileConnector struct {
+	// Files is the content of files by path.
+	Files map[string]string
+	// Modes is the mode of files by path. The default mode is 0644.
+	Modes map[string]fs.FileMode
+	// Executed is the commands which are not about files.
+	Executed []string
+}
+
+// NewTestFileConnector creates a TestFileConnector with the files.
+func NewTestFileConnector(files map[string]string) *TestFileConnector {
+	if files == nil {
+		files = make(map[string]string)
+	}
+
+	return &TestFileConnector{Files: files, Modes: make(map[string]fs.FileMode)}
+}
+
+// Init does nothing.
+func (t *TestFileConnector) Init(context.Context) error { return nil }
+
+// Close does nothing.
+func (t *TestFileConnector) Close(context.Context) error { return nil }
+
+// PutFile writes the file in memory.
+func (t *TestFileConnector) PutFile(_ context.Context, src []byte, dst string, mode fs.FileMode) error {
+	t.Files[dst] = string(src)
+	t.Modes[dst] = mode
+
+	return nil
+}
+
+// FetchFile reads the file in memory.
+func (t *TestFileConnector) FetchFile(_ context.Context, src string, dst io.Writer) error {
+	content, ok := t.Files[src]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	_, err := io.WriteString(dst, content)
+
+	return err
+}
+
+// ExecuteCommand simulates the file commands by the files in memory.
+func (t *TestFileConnector) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	fields := shellFields(cmd)
+	switch {
+	case strings.HasPrefix(cmd, "if [ -e "):
+		content, ok := t.Files[fields[3]]
+		if !ok {
+			return nil, nil, nil
+		}
+		mode, ok := t.Modes[fields[3]]
+		if !ok {
+			mode = _const.PermFilePublic
+		}
+		kind := "regular file"
+		if content == "" {
+			kind = "regular empty file"
+		}
+
+		return []byte(fmt.Sprintf("%o 0 0 root root %d 0 %s\n", mode, len(content), kind)), nil, nil
+	case strings.HasPrefix(cmd, "mkdir -p ") && strings.Contains(cmd, " && mv "):
+		// connector.PutData
+		t.Files[fields[6]], t.Modes[fields[6]] = t.Files[fields[5]], t.Modes[fields[5]]
+		delete(t.Files, fields[5])
+		delete(t.Modes, fields[5])
+	case strings.HasPrefix(cmd, "cp -p "):
+		t.Files[fields[3]], t.Modes[fields[3]] = t.Files[fields[2]], t.Modes[fields[2]]
+	case strings.HasPrefix(cmd, "chown "):
+	default:
+		t.Executed = append(t.Executed, cmd)
+	}
+
+	return nil, nil, nil
+}
+
+// shellFields splits the command into fields by space, and unquotes the single quoted fields.
+func shellFields(cmd string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, inField := false, false
+	for _, r := range cmd {
+		switch {
+		case r == '\'':
+			quoted, inField = !quoted, true
+		case r == ' ' && !quoted:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
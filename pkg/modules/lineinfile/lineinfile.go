@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lineinfile
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Lineinfile module ensures a line is in a file, or removes it, on remote hosts.
+The file is read by the connector, edited in memory and only written back when its content changes.
+
+Configuration:
+Users can specify the line and where to put it:
+
+lineinfile:
+  path: /etc/hosts          # required: the file in remote host
+  line: "10.0.0.1 lb.kubesphere.local" # required when state is present: the line to ensure
+  regexp: "lb\\.kubesphere\\.local$"  # optional: the last matched line is replaced by "line". When state is absent, all matched lines are removed
+  insertafter: "^127\\.0\\.0\\.1"      # optional: insert the line after the last matched line, or "EOF" (default)
+  insertbefore: "BOF"       # optional: insert the line before the last matched line, or "BOF"
+  state: present            # optional: present or absent (default: present)
+  create: false             # optional: create the file when it does not exist (default: false)
+  backup: false             # optional: backup the file before changing it (default: false)
+
+Usage Examples in Playbook Tasks:
+1. Ensure a host entry:
+   ```yaml
+   - name: Add hosts entry
+     lineinfile:
+       path: /etc/hosts
+       regexp: "\\slb\\.kubesphere\\.local$"
+       line: "{{ .kubernetes.control_plane_endpoint.address }} lb.kubesphere.local"
+   ```
+
+2. Remove a line:
+   ```yaml
+   - name: Remove swap from fstab
+     lineinfile:
+       path: /etc/fstab
+       regexp: "\\sswap\\s"
+       state: absent
+       backup: true
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "msg" and the "backup" file
+- On failure: Returns error message in stderr
+*/
+
+const (
+	statePresent = "present"
+	stateAbsent  = "absent"
+)
+
+// lineinfileArgs holds the arguments for the lineinfile module.
+type lineinfileArgs struct {
+	path      string                  // Path of the file in remote host
+	line      string                  // The line to ensure
+	regexp    *regexp.Regexp          // The line to replace or remove
+	insertPos internal.InsertPosition // Where to insert when it does not exist
+	state     string                  // present or absent
+	create    bool                    // Create the file when it does not exist
+	backup    bool                    // Backup the file before changing it
+}
+
+// lineinfileResult is the output of the lineinfile module.
+type lineinfileResult struct {
+	Changed bool   `json:"changed"`
+	Msg     string `json:"msg"`
+	Backup  string `json:"backup,omitempty"`
+}
+
+// newLineinfileArgs parses and validates the arguments for the lineinfile module.
+func newLineinfileArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*lineinfileArgs, error) {
+	var err error
+	la := &lineinfileArgs{}
+	args := variable.Extension2Variables(raw)
+	la.path, err = variable.StringVar(vars, args, "path")
+	if err != nil || la.path == "" {
+		return nil, errors.New("\"path\" in args should be string")
+	}
+	la.state, _ = variable.StringVar(vars, args, "state")
+	if la.state == "" {
+		la.state = statePresent
+	}
+	if la.state != statePresent && la.state != stateAbsent {
+		return nil, errors.Errorf("\"state\" should be %q or %q, got %q", statePresent, stateAbsent, la.state)
+	}
+	line, lineErr := variable.StringVar(vars, args, "line")
+	la.line = line
+	if exp, _ := variable.StringVar(vars, args, "regexp"); exp != "" {
+		if la.regexp, err = regexp.Compile(exp); err != nil {
+			return nil, errors.Wrapf(err, "\"regexp\" %q is invalid", exp)
+		}
+	}
+	switch {
+	case la.state == statePresent && lineErr != nil:
+		return nil, errors.New("\"line\" in args should be string when state is present")
+	case la.state == stateAbsent && lineErr != nil && la.regexp == nil:
+		return nil, errors.New("one of \"line\" or \"regexp\" is required when state is absent")
+	}
+	if la.insertPos, err = internal.InsertPositionArg(vars, args); err != nil {
+		return nil, err
+	}
+	if la.create, err = internal.BoolArg(vars, args, "create", false); err != nil {
+		return nil, err
+	}
+	if la.backup, err = internal.BoolArg(vars, args, "backup", false); err != nil {
+		return nil, err
+	}
+
+	return la, nil
+}
+
+// ModuleLineinfile handles the "lineinfile" module, ensuring a line in a file on remote hosts.
+func ModuleLineinfile(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	la, err := newLineinfileArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	rf, err := internal.ReadRemoteFile(ctx, conn, la.path)
+	if err != nil {
+		return internal.StdoutFailed, "failed to read file", err
+	}
+	result := &lineinfileResult{}
+	if !rf.Exists {
+		if la.state == stateAbsent {
+			result.Msg = "file not present"
+
+			return internal.MarshalResult(result)
+		}
+		if !la.create {
+			return internal.StdoutFailed, "failed to read file", errors.Errorf("file %q does not exist, set \"create\" to create it", la.path)
+		}
+	}
+
+	var content string
+	if la.state == statePresent {
+		content, result.Msg = la.present(string(rf.Content))
+	} else {
+		content, result.Msg = la.absent(string(rf.Content))
+	}
+	if rf.Exists && content == string(rf.Content) {
+		return internal.MarshalResult(result)
+	}
+	result.Changed = true
+	if la.backup {
+		if result.Backup, err = rf.Backup(ctx, conn); err != nil {
+			return internal.StdoutFailed, "failed to backup file", err
+		}
+	}
+	if err := rf.Write(ctx, conn, []byte(content)); err != nil {
+		return internal.StdoutFailed, "failed to write file", err
+	}
+
+	return internal.MarshalResult(result)
+}
+
+// present returns the content with the line ensured, and the message of what is done.
+func (la lineinfileArgs) present(content string) (string, string) {
+	lines := internal.SplitLines(content)
+	if la.regexp != nil {
+		if i := internal.LastMatch(lines, la.regexp.MatchString); i >= 0 {
+			if lines[i] == la.line {
+				return content, ""
+			}
+			lines[i] = la.line
+
+			return internal.JoinLines(lines), "line replaced"
+		}
+	}
+	if internal.LastMatch(lines, func(l string) bool { return l == la.line }) >= 0 {
+		return content, ""
+	}
+
+	i := internal.InsertIndex(lines, la.insertPos)
+	lines = append(lines[:i], append([]string{la.line}, lines[i:]...)...)
+
+	return internal.JoinLines(lines), "line added"
+}
+
+// absent returns the content without the lines which match the regexp or equal to the line, and the message of what is done.
+func (la lineinfileArgs) absent(content string) (string, string) {
+	lines := internal.SplitLines(content)
+	kept := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if (la.regexp != nil && la.regexp.MatchString(l)) || (la.regexp == nil && l == la.line) {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	if removed := len(lines) - len(kept); removed > 0 {
+		return internal.JoinLines(kept), fmt.Sprintf("%d line(s) removed", removed)
+	}
+
+	return content, ""
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lineinfile
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestLineinfileArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *lineinfileArgs
+		wantErr bool
+	}{
+		{
+			name:   "line with regexp",
+			args:   map[string]any{"path": "/etc/hosts", "line": "10.0.0.1 lb", "regexp": "\\slb$", "backup": true},
+			except: &lineinfileArgs{path: "/etc/hosts", line: "10.0.0.1 lb", regexp: regexp.MustCompile("\\slb$"), state: statePresent, backup: true},
+		},
+		{
+			name:   "absent by regexp",
+			args:   map[string]any{"path": "/etc/fstab", "regexp": "\\sswap\\s", "state": "absent"},
+			except: &lineinfileArgs{path: "/etc/fstab", regexp: regexp.MustCompile("\\sswap\\s"), state: stateAbsent},
+		},
+		{
+			name:    "missing path",
+			args:    map[string]any{"line": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing line when present",
+			args:    map[string]any{"path": "/etc/hosts", "regexp": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing line and regexp when absent",
+			args:    map[string]any{"path": "/etc/hosts", "state": "absent"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regexp",
+			args:    map[string]any{"path": "/etc/hosts", "line": "a", "regexp": "("},
+			wantErr: true,
+		},
+		{
+			name:    "insertafter and insertbefore",
+			args:    map[string]any{"path": "/etc/hosts", "line": "a", "insertafter": "EOF", "insertbefore": "BOF"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported state",
+			args:    map[string]any{"path": "/etc/hosts", "line": "a", "state": "latest"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			la, err := newLineinfileArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, la)
+		})
+	}
+}
+
+func TestModuleLineinfile(t *testing.T) {
+	hosts := "127.0.0.1 localhost\n10.0.0.2 lb\n::1 localhost\n"
+	testcases := []struct {
+		name          string
+		args          map[string]any
+		files         map[string]string
+		exceptContent string
+		exceptChanged bool
+		exceptMsg     string
+		exceptErr     string
+	}{
+		{
+			name:          "replace matched line",
+			args:          map[string]any{"path": "/etc/hosts", "line": "10.0.0.1 lb", "regexp": "\\slb$"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: "127.0.0.1 localhost\n10.0.0.1 lb\n::1 localhost\n",
+			exceptChanged: true,
+			exceptMsg:     "line replaced",
+		},
+		{
+			name:          "line already present",
+			args:          map[string]any{"path": "/etc/hosts", "line": "10.0.0.2 lb", "regexp": "\\slb$"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: hosts,
+		},
+		{
+			name:          "add line at end of file",
+			args:          map[string]any{"path": "/etc/hosts", "line": "10.0.0.3 registry"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: hosts + "10.0.0.3 registry\n",
+			exceptChanged: true,
+			exceptMsg:     "line added",
+		},
+		{
+			name:          "add line after matched line",
+			args:          map[string]any{"path": "/etc/hosts", "line": "10.0.0.3 registry", "insertafter": "^127\\."},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: "127.0.0.1 localhost\n10.0.0.3 registry\n10.0.0.2 lb\n::1 localhost\n",
+			exceptChanged: true,
+			exceptMsg:     "line added",
+		},
+		{
+			name:          "add line at beginning of file",
+			args:          map[string]any{"path": "/etc/hosts", "line": "# managed", "insertbefore": "BOF"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: "# managed\n" + hosts,
+			exceptChanged: true,
+			exceptMsg:     "line added",
+		},
+		{
+			name:          "remove matched lines",
+			args:          map[string]any{"path": "/etc/hosts", "regexp": "localhost$", "state": "absent"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: "10.0.0.2 lb\n",
+			exceptChanged: true,
+			exceptMsg:     "2 line(s) removed",
+		},
+		{
+			name:          "remove line which is not present",
+			args:          map[string]any{"path": "/etc/hosts", "line": "10.0.0.3 registry", "state": "absent"},
+			files:         map[string]string{"/etc/hosts": hosts},
+			exceptContent: hosts,
+		},
+		{
+			name:          "create file",
+			args:          map[string]any{"path": "/etc/kubekey/flag", "line": "done", "create": true},
+			exceptContent: "done\n",
+			exceptChanged: true,
+			exceptMsg:     "line added",
+		},
+		{
+			name:      "file not exist",
+			args:      map[string]any{"path": "/etc/kubekey/flag", "line": "done"},
+			exceptErr: "does not exist",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := internal.NewTestFileConnector(tc.files)
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			stdout, _, err := ModuleLineinfile(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err)
+			var result lineinfileResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, tc.exceptChanged, result.Changed)
+			assert.Equal(t, tc.exceptMsg, result.Msg)
+			assert.Equal(t, tc.exceptContent, conn.Files[tc.args["path"].(string)])
+		})
+	}
+}
+
+func TestModuleLineinfileBackup(t *testing.T) {
+	conn := internal.NewTestFileConnector(map[string]string{"/etc/fstab": "/dev/sda1 / ext4 defaults 0 1\n/dev/sda2 none swap sw 0 0\n"})
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	stdout, _, err := ModuleLineinfile(ctx, internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(map[string]any{"path": "/etc/fstab", "regexp": "\\sswap\\s", "state": "absent", "backup": true}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.NoError(t, err)
+	var result lineinfileResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.True(t, result.Changed)
+	require.True(t, strings.HasPrefix(result.Backup, "/etc/fstab."))
+	assert.Equal(t, "/dev/sda1 / ext4 defaults 0 1\n/dev/sda2 none swap sw 0 0\n", conn.Files[result.Backup])
+	assert.Equal(t, "/dev/sda1 / ext4 defaults 0 1\n", conn.Files["/etc/fstab"])
+}
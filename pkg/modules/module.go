@@ -20,21 +20,31 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
 	"github.com/kubesphere/kubekey/v4/pkg/modules/add_hostvars"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/artifact_manifest"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/assert"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/blockinfile"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/command"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/copy"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/debug"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/fetch"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/file"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/gen_cert"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/http_get_file"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/image"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/include_vars"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/lineinfile"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/packages"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/prometheus"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/result"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/service"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/set_fact"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/setup"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/stat"
 	"github.com/kubesphere/kubekey/v4/pkg/modules/template"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/unarchive"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/uri"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/wait_for"
 )
 
 // Re-export types and constants from options package
@@ -54,18 +64,28 @@ var FindModule = internal.FindModule
 func init() {
 	// Register all built-in modules
 	utilruntime.Must(internal.RegisterModule(add_hostvars.ModuleAddHostvars, "add_hostvars"))
+	utilruntime.Must(internal.RegisterModule(artifact_manifest.ModuleArtifactManifest, "artifact_manifest"))
 	utilruntime.Must(internal.RegisterModule(assert.ModuleAssert, "assert"))
+	utilruntime.Must(internal.RegisterModule(blockinfile.ModuleBlockinfile, "blockinfile"))
 	utilruntime.Must(internal.RegisterModule(command.ModuleCommand, "command", "shell"))
 	utilruntime.Must(internal.RegisterModule(copy.ModuleCopy, "copy"))
 	utilruntime.Must(internal.RegisterModule(debug.ModuleDebug, "debug"))
 	utilruntime.Must(internal.RegisterModule(fetch.ModuleFetch, "fetch"))
+	utilruntime.Must(internal.RegisterModule(file.ModuleFile, "file"))
 	utilruntime.Must(internal.RegisterModule(gen_cert.ModuleGenCert, "gen_cert"))
 	utilruntime.Must(internal.RegisterModule(http_get_file.ModuleHttpGetFile, "http_get_file"))
 	utilruntime.Must(internal.RegisterModule(image.ModuleImage, "image"))
 	utilruntime.Must(internal.RegisterModule(include_vars.ModuleIncludeVars, "include_vars"))
+	utilruntime.Must(internal.RegisterModule(lineinfile.ModuleLineinfile, "lineinfile"))
+	utilruntime.Must(internal.RegisterModule(packages.ModulePackage, "package"))
 	utilruntime.Must(internal.RegisterModule(prometheus.ModulePrometheus, "prometheus"))
 	utilruntime.Must(internal.RegisterModule(result.ModuleResult, "result"))
+	utilruntime.Must(internal.RegisterModule(service.ModuleService, "service"))
 	utilruntime.Must(internal.RegisterModule(set_fact.ModuleSetFact, "set_fact"))
 	utilruntime.Must(internal.RegisterModule(setup.ModuleSetup, "setup"))
+	utilruntime.Must(internal.RegisterModule(stat.ModuleStat, "stat"))
 	utilruntime.Must(internal.RegisterModule(template.ModuleTemplate, "template"))
+	utilruntime.Must(internal.RegisterModule(unarchive.ModuleUnarchive, "unarchive"))
+	utilruntime.Must(internal.RegisterModule(uri.ModuleURI, "uri"))
+	utilruntime.Must(internal.RegisterModule(wait_for.ModuleWaitFor, "wait_for"))
 }
@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+const (
+	pkgMgrYum    = "yum"
+	pkgMgrDnf    = "dnf"
+	pkgMgrApt    = "apt"
+	pkgMgrZypper = "zypper"
+)
+
+// localRepo is a local repository, which is used instead of the system repositories.
+// The zero value means the system repositories are used.
+type localRepo struct {
+	// path is the dir of the repository in host, such as the mount point of the repository ISO.
+	path string
+	// configDir is the temporary dir to write the repository configuration.
+	configDir string
+}
+
+// pkgManager builds the commands of a package manager.
+type pkgManager interface {
+	// query prints a line of "<name> <version>" for each installed package, or "<name>" when it is not installed.
+	query(names []string) string
+	// configureRepo writes the configuration of the local repository. It returns empty when nothing to write.
+	configureRepo(repo localRepo) string
+	// refresh updates the package index.
+	refresh(repo localRepo) string
+	// install the packages. The version of a package is pinned when it is set.
+	install(repo localRepo, pkgs []pkgSpec) string
+	// remove the packages.
+	remove(names []string) string
+}
+
+// newPkgManager returns the pkgManager by name.
+func newPkgManager(name string) (pkgManager, error) {
+	switch name {
+	case pkgMgrYum, pkgMgrDnf:
+		return rpmManager{cmd: name}, nil
+	case pkgMgrApt:
+		return aptManager{}, nil
+	case pkgMgrZypper:
+		return zypperManager{}, nil
+	default:
+		return nil, errors.Errorf("unsupported package manager %q, should be one of %q, %q, %q or %q", name, pkgMgrYum, pkgMgrDnf, pkgMgrApt, pkgMgrZypper)
+	}
+}
+
+// detectPkgMgr returns the package manager from the "pkg_mgr" fact, or from the "os.release" fact when it is not gathered.
+func detectPkgMgr(vars map[string]any) (string, error) {
+	if pm, _, _ := unstructured.NestedString(vars, _const.VariablePkgMgr); pm != "" && pm != "unknown" {
+		return pm, nil
+	}
+	// ID_LIKE is checked first. e.g. the desktop edition of UOS is like debian.
+	var ids []string
+	for _, key := range []string{"ID_LIKE", "ID"} {
+		val, _, _ := unstructured.NestedFieldNoCopy(vars, _const.VariableOS, _const.VariableOSRelease, key)
+		if s, ok := val.(string); ok {
+			ids = append(ids, strings.Fields(strings.Trim(s, `"'`))...)
+		}
+	}
+	for _, id := range ids {
+		// os-release ID is lower case by spec, but some distributions use mixed case such as "openEuler".
+		switch strings.ToLower(id) {
+		case "debian", "ubuntu":
+			return pkgMgrApt, nil
+		case "suse", "sles", "opensuse":
+			return pkgMgrZypper, nil
+		case "rhel", "fedora", "centos", "kylin", "uos", "openeuler", "anolis":
+			return pkgMgrYum, nil
+		}
+	}
+
+	return "", errors.Errorf("cannot detect package manager from os %v, set \"pkg_mgr\" in args", ids)
+}
+
+// rpmQuery prints the version of the package which provides the name by rpm.
+const rpmQuery = `for p in %s; do
+  if v=$(rpm -q --whatprovides --qf '%%{VERSION}-%%{RELEASE}\n' "$p" 2>/dev/null); then echo "$p $(echo "$v" | head -n1)"; else echo "$p"; fi
+done`
+
+// rpmManager is the package manager of yum and dnf.
+type rpmManager struct {
+	cmd string
+}
+
+func (m rpmManager) query(names []string) string {
+	return fmt.Sprintf(rpmQuery, quoteAll(names))
+}
+
+func (m rpmManager) configureRepo(repo localRepo) string {
+	return fmt.Sprintf("mkdir -p %s && printf '[kubekey]\\nname=kubekey\\nbaseurl=file://%%s\\nenabled=1\\ngpgcheck=0\\n' %s > %s",
+		quote(filepath.Join(repo.configDir, "repos")), quote(repo.path), quote(filepath.Join(repo.configDir, "repos", "kubekey.repo")))
+}
+
+// options to only use the local repository.
+func (m rpmManager) options(repo localRepo) string {
+	if repo.path == "" {
+		return ""
+	}
+
+	return " --nogpgcheck --setopt=reposdir=" + quote(filepath.Join(repo.configDir, "repos"))
+}
+
+func (m rpmManager) refresh(repo localRepo) string {
+	return m.cmd + m.options(repo) + " makecache"
+}
+
+func (m rpmManager) install(repo localRepo, pkgs []pkgSpec) string {
+	specs := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		if p.version != "" {
+			specs = append(specs, p.name+"-"+p.version)
+		} else {
+			specs = append(specs, p.name)
+		}
+	}
+
+	return m.cmd + " -y" + m.options(repo) + " install " + quoteAll(specs)
+}
+
+func (m rpmManager) remove(names []string) string {
+	return m.cmd + " -y remove " + quoteAll(names)
+}
+
+// dpkgQuery prints the version of the installed package by dpkg-query.
+const dpkgQuery = `for p in %s; do
+  if v=$(dpkg-query -W -f='${db:Status-Status} ${Version}' "$p" 2>/dev/null) && [ "${v%%%% *}" = installed ]; then echo "$p ${v#* }"; else echo "$p"; fi
+done`
+
+// aptManager is the package manager of apt.
+type aptManager struct{}
+
+func (m aptManager) query(names []string) string {
+	return fmt.Sprintf(dpkgQuery, quoteAll(names))
+}
+
+func (m aptManager) configureRepo(repo localRepo) string {
+	return fmt.Sprintf("echo 'deb [trusted=yes] file://'%s' /' > %s", quote(repo.path), quote(filepath.Join(repo.configDir, "kubekey.list")))
+}
+
+// options to only use the local repository. The lists of system repositories are kept.
+func (m aptManager) options(repo localRepo) string {
+	if repo.path == "" {
+		return ""
+	}
+
+	return " -o Dir::Etc::SourceList=" + quote(filepath.Join(repo.configDir, "kubekey.list")) + " -o Dir::Etc::SourceParts=- -o APT::Get::List-Cleanup=0"
+}
+
+func (m aptManager) refresh(repo localRepo) string {
+	return "apt-get" + m.options(repo) + " update"
+}
+
+func (m aptManager) install(repo localRepo, pkgs []pkgSpec) string {
+	specs := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		if p.version != "" {
+			specs = append(specs, p.name+"="+p.version)
+		} else {
+			specs = append(specs, p.name)
+		}
+	}
+
+	return "DEBIAN_FRONTEND=noninteractive apt-get -y" + m.options(repo) + " install --allow-downgrades " + quoteAll(specs)
+}
+
+func (m aptManager) remove(names []string) string {
+	return "DEBIAN_FRONTEND=noninteractive apt-get -y remove " + quoteAll(names)
+}
+
+// zypperManager is the package manager of zypper.
+type zypperManager struct{}
+
+func (m zypperManager) query(names []string) string {
+	return fmt.Sprintf(rpmQuery, quoteAll(names))
+}
+
+// configureRepo does nothing, the local repository is added by the "--plus-repo" option.
+func (m zypperManager) configureRepo(localRepo) string {
+	return ""
+}
+
+// options to add the local repository.
+func (m zypperManager) options(repo localRepo) string {
+	if repo.path == "" {
+		return " --non-interactive"
+	}
+
+	return " --non-interactive --no-gpg-checks --plus-repo " + quote("dir://"+repo.path)
+}
+
+func (m zypperManager) refresh(repo localRepo) string {
+	return "zypper" + m.options(repo) + " refresh"
+}
+
+func (m zypperManager) install(repo localRepo, pkgs []pkgSpec) string {
+	specs := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		if p.version != "" {
+			specs = append(specs, p.name+"="+p.version)
+		} else {
+			specs = append(specs, p.name)
+		}
+	}
+
+	return "zypper" + m.options(repo) + " install --oldpackage " + quoteAll(specs)
+}
+
+func (m zypperManager) remove(names []string) string {
+	return "zypper --non-interactive remove " + quoteAll(names)
+}
+
+// quote the string for shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteAll quotes the strings for shell and joins them by space.
+func quoteAll(ss []string) string {
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, quote(s))
+	}
+
+	return strings.Join(quoted, " ")
+}
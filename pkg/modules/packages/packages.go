@@ -0,0 +1,366 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Package module installs or removes system packages on remote hosts by yum, dnf, apt or zypper.
+The package manager is detected from the gathered "pkg_mgr" and "os" facts, so the same task works on
+CentOS, Kylin, UOS, Ubuntu and other distributions.
+
+Configuration:
+Users can specify the packages and their desired state:
+
+package:
+  name:                    # required: package names. "<name>=<version>" pins the version
+    - socat
+    - conntrack=1:1.4.6-2
+  state: present           # optional: present or absent (default: present)
+  repo: /tmp/repository.iso # optional: a repository ISO file or dir in remote host, used instead of the system repositories
+  update_cache: false      # optional: update the package index before install (default: false, always true when repo is set)
+  pkg_mgr: apt             # optional: yum, dnf, apt or zypper. detected from facts by default
+
+Usage Examples in Playbook Tasks:
+1. Install packages from system repositories:
+   ```yaml
+   - name: Install packages
+     package:
+       name: [socat, ipset, ipvsadm]
+   ```
+
+2. Install packages from a repository ISO:
+   ```yaml
+   - name: Install packages offline
+     package:
+       name: [socat, ipset, ipvsadm]
+       repo: "{{ .tmp_dir }}/repository.iso"
+     register: package_result
+     register_type: json
+   ```
+
+3. Remove packages:
+   ```yaml
+   - name: Remove docker
+     package:
+       name: docker-ce
+       state: absent
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "pkg_mgr", "installed", "removed" and the installed "versions" of packages
+- On failure: Returns error message in stderr
+*/
+
+const (
+	statePresent = "present"
+	stateAbsent  = "absent"
+)
+
+// pkgSpec is a package with an optional pinned version.
+type pkgSpec struct {
+	name    string
+	version string
+}
+
+// packageArgs holds the arguments for the package module.
+type packageArgs struct {
+	pkgs        []pkgSpec // Packages to install or remove
+	state       string    // Desired state of the packages
+	repo        string    // Repository ISO file or dir in remote host
+	updateCache bool      // Whether to update the package index before install
+	pkgMgr      string    // Package manager
+}
+
+// packageResult is the output of the package module.
+type packageResult struct {
+	Changed   bool              `json:"changed"`
+	PkgMgr    string            `json:"pkg_mgr"`
+	Installed []string          `json:"installed"`
+	Removed   []string          `json:"removed"`
+	Versions  map[string]string `json:"versions"`
+}
+
+// newPackageArgs parses and validates the arguments for the package module.
+func newPackageArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*packageArgs, error) {
+	var err error
+	pa := &packageArgs{}
+	args := variable.Extension2Variables(raw)
+	names, err := variable.StringSliceVar(vars, args, "name")
+	if err != nil {
+		return nil, errors.New("\"name\" in args should be string or string slice")
+	}
+	for _, name := range names {
+		// skip the empty name rendered by template.
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		n, v, _ := strings.Cut(name, "=")
+		pa.pkgs = append(pa.pkgs, pkgSpec{name: strings.TrimSpace(n), version: strings.TrimSpace(v)})
+	}
+	pa.state, _ = variable.StringVar(vars, args, "state")
+	if pa.state == "" {
+		pa.state = statePresent
+	}
+	if pa.state != statePresent && pa.state != stateAbsent {
+		return nil, errors.Errorf("\"state\" should be %q or %q, got %q", statePresent, stateAbsent, pa.state)
+	}
+	pa.repo, _ = variable.StringVar(vars, args, "repo")
+	if pa.repo != "" && !filepath.IsAbs(pa.repo) {
+		return nil, errors.Errorf("\"repo\" should be an absolute path, got %q", pa.repo)
+	}
+	if pa.updateCache, err = internal.BoolArg(vars, args, "update_cache", false); err != nil {
+		return nil, err
+	}
+	pa.pkgMgr, _ = variable.StringVar(vars, args, "pkg_mgr")
+	if pa.pkgMgr == "" {
+		if pa.pkgMgr, err = detectPkgMgr(vars); err != nil {
+			return nil, err
+		}
+	}
+
+	return pa, nil
+}
+
+// ModulePackage handles the "package" module, installing or removing system packages on remote hosts.
+func ModulePackage(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	pa, err := newPackageArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+	pm, err := newPkgManager(pa.pkgMgr)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrUnsupportArgs, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	result, err := pa.apply(ctx, conn, pm)
+	if err != nil {
+		return internal.StdoutFailed, "failed to manage packages", err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal package result", err
+	}
+
+	return string(data), "", nil
+}
+
+// apply installs the packages which are missing or not in the pinned version, or removes the installed packages.
+func (pa packageArgs) apply(ctx context.Context, conn connector.Connector, pm pkgManager) (*packageResult, error) {
+	result := &packageResult{PkgMgr: pa.pkgMgr, Installed: make([]string, 0), Removed: make([]string, 0)}
+	names := make([]string, 0, len(pa.pkgs))
+	for _, p := range pa.pkgs {
+		names = append(names, p.name)
+	}
+	if len(names) == 0 {
+		result.Versions = make(map[string]string)
+
+		return result, nil
+	}
+	versions, err := queryVersions(ctx, conn, pm, names)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pa.state {
+	case statePresent:
+		var pkgs []pkgSpec
+		for _, p := range pa.pkgs {
+			if !versionMatched(versions[p.name], p.version) {
+				pkgs = append(pkgs, p)
+				result.Installed = append(result.Installed, p.name)
+			}
+		}
+		if len(pkgs) > 0 {
+			if err := pa.install(ctx, conn, pm, pkgs); err != nil {
+				return nil, err
+			}
+		}
+	case stateAbsent:
+		for _, p := range pa.pkgs {
+			if versions[p.name] != "" {
+				result.Removed = append(result.Removed, p.name)
+			}
+		}
+		if len(result.Removed) > 0 {
+			if err := run(ctx, conn, pm.remove(result.Removed)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.Changed = len(result.Installed) > 0 || len(result.Removed) > 0
+	if !result.Changed {
+		result.Versions = versions
+
+		return result, nil
+	}
+	if result.Versions, err = queryVersions(ctx, conn, pm, names); err != nil {
+		return nil, err
+	}
+	// the package manager may succeed without installing the pinned version, e.g. yum does not downgrade by install.
+	for _, p := range pa.pkgs {
+		if pa.state == statePresent && p.version != "" && !versionMatched(result.Versions[p.name], p.version) {
+			return nil, errors.Errorf("package %q is not installed in version %q after install, got %q", p.name, p.version, result.Versions[p.name])
+		}
+	}
+
+	return result, nil
+}
+
+// install the packages from the local repository or the system repositories.
+func (pa packageArgs) install(ctx context.Context, conn connector.Connector, pm pkgManager, pkgs []pkgSpec) error {
+	if pa.repo == "" {
+		if pa.updateCache {
+			if err := run(ctx, conn, pm.refresh(localRepo{})); err != nil {
+				return err
+			}
+		}
+
+		return run(ctx, conn, pm.install(localRepo{}, pkgs))
+	}
+
+	repo, cleanup, err := prepareRepo(ctx, conn, pa.repo)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if cmd := pm.configureRepo(repo); cmd != "" {
+		if err := run(ctx, conn, cmd); err != nil {
+			return err
+		}
+	}
+	if err := run(ctx, conn, pm.refresh(repo)); err != nil {
+		return err
+	}
+
+	return run(ctx, conn, pm.install(repo, pkgs))
+}
+
+// prepareRepo creates the temporary dir for the repository configuration, and mounts the repository ISO when repo is a file.
+// The returned cleanup unmounts the ISO and removes the temporary dir.
+func prepareRepo(ctx context.Context, conn connector.Connector, path string) (localRepo, func(), error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, "if [ -d "+quote(path)+" ]; then echo dir; elif [ -f "+quote(path)+" ]; then echo file; fi")
+	if err != nil {
+		return localRepo{}, nil, errors.Wrapf(err, "failed to check repo %q, stderr: %s", path, strings.TrimSpace(string(stderr)))
+	}
+	kind := strings.TrimSpace(string(stdout))
+	if kind == "" {
+		return localRepo{}, nil, errors.Errorf("repo %q not found", path)
+	}
+	stdout, stderr, err = conn.ExecuteCommand(ctx, "mktemp -d /tmp/kubekey-repo.XXXXXX")
+	if err != nil {
+		return localRepo{}, nil, errors.Wrapf(err, "failed to create temporary dir, stderr: %s", strings.TrimSpace(string(stderr)))
+	}
+	repo := localRepo{path: path, configDir: strings.TrimSpace(string(stdout))}
+	mounted := false
+	cleanup := func() {
+		cmd := "rm -rf " + quote(repo.configDir)
+		if mounted {
+			cmd = "umount " + quote(repo.path) + "; " + cmd
+		}
+		if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+			klog.V(4).ErrorS(err, "failed to clean up repo", "repo", path, "stderr", string(stderr))
+		}
+	}
+	if kind == "file" {
+		repo.path = filepath.Join(repo.configDir, "iso")
+		if err := run(ctx, conn, "mkdir -p "+quote(repo.path)+" && mount -t iso9660 -o loop,ro "+quote(path)+" "+quote(repo.path)); err != nil {
+			cleanup()
+
+			return localRepo{}, nil, err
+		}
+		mounted = true
+	}
+
+	return repo, cleanup, nil
+}
+
+// queryVersions returns the installed version of the packages. The version is empty when the package is not installed.
+func queryVersions(ctx context.Context, conn connector.Connector, pm pkgManager, names []string) (map[string]string, error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, pm.query(names))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query packages, stderr: %s", strings.TrimSpace(string(stderr)))
+	}
+	versions := make(map[string]string, len(names))
+	for _, name := range names {
+		versions[name] = ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		name, version, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if _, ok := versions[name]; ok {
+			versions[name] = strings.TrimSpace(version)
+		}
+	}
+
+	return versions, nil
+}
+
+// versionMatched checks whether the installed version matches the pinned version.
+// The pinned version may omit the release (e.g. "1.4.6" matches "1.4.6-2.el8") and the epoch (e.g. "1.4.6-2" matches "1:1.4.6-2").
+func versionMatched(installed, pinned string) bool {
+	if installed == "" {
+		return false
+	}
+	if pinned == "" {
+		return true
+	}
+	if !strings.Contains(pinned, ":") {
+		if _, v, ok := strings.Cut(installed, ":"); ok {
+			installed = v
+		}
+	}
+
+	return installed == pinned || strings.HasPrefix(installed, pinned+"-")
+}
+
+// run the command and wrap the error with stderr.
+func run(ctx context.Context, conn connector.Connector, cmd string) error {
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		return errors.Wrapf(err, "failed to run %q, stderr: %s", cmd, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakePkgHost simulates the installed packages of a host and records the executed commands except the queries.
+// Installing a package sets its version to "1.0-1" or the pinned version.
+type fakePkgHost struct {
+	versions map[string]string
+	repo     string
+	executed []string
+}
+
+func (f *fakePkgHost) Init(context.Context) error { return nil }
+
+func (f *fakePkgHost) Close(context.Context) error { return nil }
+
+func (f *fakePkgHost) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (f *fakePkgHost) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakePkgHost) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	if names, ok := strings.CutPrefix(cmd, "for p in "); ok {
+		names, _, _ = strings.Cut(names, "; do")
+		var stdout strings.Builder
+		for _, name := range strings.Fields(names) {
+			name = strings.Trim(name, "'")
+			stdout.WriteString(strings.TrimSpace(name+" "+f.versions[name]) + "\n")
+		}
+
+		return []byte(stdout.String()), nil, nil
+	}
+	f.executed = append(f.executed, cmd)
+	switch {
+	case strings.HasPrefix(cmd, "if [ -d "):
+		return []byte(f.repo + "\n"), nil, nil
+	case strings.HasPrefix(cmd, "mktemp -d"):
+		return []byte("/tmp/kubekey-repo.abc\n"), nil, nil
+	case strings.Contains(cmd, " install "):
+		_, specs, _ := strings.Cut(cmd, " install ")
+		for _, spec := range strings.Fields(specs) {
+			if strings.HasPrefix(spec, "--") {
+				continue
+			}
+			name, version, ok := strings.Cut(strings.Trim(spec, "'"), "=")
+			if !ok {
+				version = "1.0-1"
+			}
+			f.versions[name] = version
+		}
+	case strings.Contains(cmd, " remove "):
+		_, names, _ := strings.Cut(cmd, " remove ")
+		for _, name := range strings.Fields(names) {
+			delete(f.versions, strings.Trim(name, "'"))
+		}
+	}
+
+	return nil, nil, nil
+}
+
+func TestPackageArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		vars    map[string]any
+		except  *packageArgs
+		wantErr bool
+	}{
+		{
+			name:   "pkg_mgr from facts",
+			args:   map[string]any{"name": []string{"socat", "conntrack=1:1.4.6-2", ""}},
+			vars:   map[string]any{"pkg_mgr": "dnf"},
+			except: &packageArgs{pkgs: []pkgSpec{{name: "socat"}, {name: "conntrack", version: "1:1.4.6-2"}}, state: statePresent, pkgMgr: "dnf"},
+		},
+		{
+			name:   "pkg_mgr from os release",
+			args:   map[string]any{"name": "socat", "state": "absent"},
+			vars:   map[string]any{"os": map[string]any{"release": map[string]any{"ID": `"kylin"`, "VERSION_ID": `"V10"`}}},
+			except: &packageArgs{pkgs: []pkgSpec{{name: "socat"}}, state: stateAbsent, pkgMgr: "yum"},
+		},
+		{
+			name:   "pkg_mgr from os release like",
+			args:   map[string]any{"name": "socat", "repo": "/tmp/repository.iso", "update_cache": true},
+			vars:   map[string]any{"pkg_mgr": "unknown", "os": map[string]any{"release": map[string]any{"ID": "uos", "ID_LIKE": "debian"}}},
+			except: &packageArgs{pkgs: []pkgSpec{{name: "socat"}}, state: statePresent, repo: "/tmp/repository.iso", updateCache: true, pkgMgr: "apt"},
+		},
+		{
+			name:   "pkg_mgr from mixed case os release",
+			args:   map[string]any{"name": "socat"},
+			vars:   map[string]any{"os": map[string]any{"release": map[string]any{"ID": `"openEuler"`}}},
+			except: &packageArgs{pkgs: []pkgSpec{{name: "socat"}}, state: statePresent, pkgMgr: "yum"},
+		},
+		{
+			name:    "missing name",
+			args:    map[string]any{"pkg_mgr": "apt"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported state",
+			args:    map[string]any{"name": "socat", "state": "latest", "pkg_mgr": "apt"},
+			wantErr: true,
+		},
+		{
+			name:    "relative repo",
+			args:    map[string]any{"name": "socat", "repo": "iso", "pkg_mgr": "apt"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown os",
+			args:    map[string]any{"name": "socat"},
+			vars:    map[string]any{"os": map[string]any{"release": map[string]any{"ID": "alpine"}}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pa, err := newPackageArgs(context.TODO(), createRawArgs(tc.args), tc.vars)
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, pa)
+		})
+	}
+}
+
+func TestModulePackage(t *testing.T) {
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		versions       map[string]string
+		repo           string
+		exceptExecuted []string
+		exceptResult   packageResult
+	}{
+		{
+			name:           "install missing package by yum",
+			args:           map[string]any{"name": []string{"socat", "ipset"}, "pkg_mgr": "yum"},
+			versions:       map[string]string{"ipset": "7.1-1.el7"},
+			exceptExecuted: []string{"yum -y install 'socat'"},
+			exceptResult: packageResult{Changed: true, PkgMgr: "yum", Installed: []string{"socat"}, Removed: []string{},
+				Versions: map[string]string{"socat": "1.0-1", "ipset": "7.1-1.el7"}},
+		},
+		{
+			name:     "packages already installed",
+			args:     map[string]any{"name": []string{"socat", "conntrack=1.4.6"}, "pkg_mgr": "apt"},
+			versions: map[string]string{"socat": "1.7.3.3-2", "conntrack": "1:1.4.6-2"},
+			exceptResult: packageResult{Changed: false, PkgMgr: "apt", Installed: []string{}, Removed: []string{},
+				Versions: map[string]string{"socat": "1.7.3.3-2", "conntrack": "1:1.4.6-2"}},
+		},
+		{
+			name:           "pin version by apt",
+			args:           map[string]any{"name": []string{"conntrack=1:1.4.5-2"}, "pkg_mgr": "apt", "update_cache": true},
+			versions:       map[string]string{"conntrack": "1:1.4.6-2"},
+			exceptExecuted: []string{"apt-get update", "DEBIAN_FRONTEND=noninteractive apt-get -y install --allow-downgrades 'conntrack=1:1.4.5-2'"},
+			exceptResult: packageResult{Changed: true, PkgMgr: "apt", Installed: []string{"conntrack"}, Removed: []string{},
+				Versions: map[string]string{"conntrack": "1:1.4.5-2"}},
+		},
+		{
+			name:     "install from repository ISO by apt",
+			args:     map[string]any{"name": "socat", "pkg_mgr": "apt", "repo": "/tmp/kubekey/repository.iso"},
+			versions: map[string]string{},
+			repo:     "file",
+			exceptExecuted: []string{
+				"if [ -d '/tmp/kubekey/repository.iso' ]; then echo dir; elif [ -f '/tmp/kubekey/repository.iso' ]; then echo file; fi",
+				"mktemp -d /tmp/kubekey-repo.XXXXXX",
+				"mkdir -p '/tmp/kubekey-repo.abc/iso' && mount -t iso9660 -o loop,ro '/tmp/kubekey/repository.iso' '/tmp/kubekey-repo.abc/iso'",
+				"echo 'deb [trusted=yes] file://''/tmp/kubekey-repo.abc/iso'' /' > '/tmp/kubekey-repo.abc/kubekey.list'",
+				"apt-get -o Dir::Etc::SourceList='/tmp/kubekey-repo.abc/kubekey.list' -o Dir::Etc::SourceParts=- -o APT::Get::List-Cleanup=0 update",
+				"DEBIAN_FRONTEND=noninteractive apt-get -y -o Dir::Etc::SourceList='/tmp/kubekey-repo.abc/kubekey.list' -o Dir::Etc::SourceParts=- -o APT::Get::List-Cleanup=0 install --allow-downgrades 'socat'",
+				"umount '/tmp/kubekey-repo.abc/iso'; rm -rf '/tmp/kubekey-repo.abc'",
+			},
+			exceptResult: packageResult{Changed: true, PkgMgr: "apt", Installed: []string{"socat"}, Removed: []string{},
+				Versions: map[string]string{"socat": "1.0-1"}},
+		},
+		{
+			name:     "install from repository dir by dnf",
+			args:     map[string]any{"name": "socat", "pkg_mgr": "dnf", "repo": "/tmp/kubekey/iso"},
+			versions: map[string]string{},
+			repo:     "dir",
+			exceptExecuted: []string{
+				"if [ -d '/tmp/kubekey/iso' ]; then echo dir; elif [ -f '/tmp/kubekey/iso' ]; then echo file; fi",
+				"mktemp -d /tmp/kubekey-repo.XXXXXX",
+				`mkdir -p '/tmp/kubekey-repo.abc/repos' && printf '[kubekey]\nname=kubekey\nbaseurl=file://%s\nenabled=1\ngpgcheck=0\n' '/tmp/kubekey/iso' > '/tmp/kubekey-repo.abc/repos/kubekey.repo'`,
+				"dnf --nogpgcheck --setopt=reposdir='/tmp/kubekey-repo.abc/repos' makecache",
+				"dnf -y --nogpgcheck --setopt=reposdir='/tmp/kubekey-repo.abc/repos' install 'socat'",
+				"rm -rf '/tmp/kubekey-repo.abc'",
+			},
+			exceptResult: packageResult{Changed: true, PkgMgr: "dnf", Installed: []string{"socat"}, Removed: []string{},
+				Versions: map[string]string{"socat": "1.0-1"}},
+		},
+		{
+			name:           "remove installed package by zypper",
+			args:           map[string]any{"name": []string{"docker", "podman"}, "state": "absent", "pkg_mgr": "zypper"},
+			versions:       map[string]string{"docker": "24.0.7-1"},
+			exceptExecuted: []string{"zypper --non-interactive remove 'docker'"},
+			exceptResult: packageResult{Changed: true, PkgMgr: "zypper", Installed: []string{}, Removed: []string{"docker"},
+				Versions: map[string]string{"docker": "", "podman": ""}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &fakePkgHost{versions: tc.versions, repo: tc.repo}
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			stdout, stderr, err := ModulePackage(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			require.NoError(t, err, stderr)
+			assert.Equal(t, tc.exceptExecuted, conn.executed)
+
+			var result packageResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, tc.exceptResult, result)
+		})
+	}
+}
+
+func TestModulePackageRepoNotFound(t *testing.T) {
+	conn := &fakePkgHost{versions: map[string]string{}}
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	_, _, err := ModulePackage(ctx, internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(map[string]any{"name": "socat", "pkg_mgr": "yum", "repo": "/tmp/kubekey/repository.iso"}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	assert.ErrorContains(t, err, `repo "/tmp/kubekey/repository.iso" not found`)
+}
+
+func TestVersionMatched(t *testing.T) {
+	assert.True(t, versionMatched("1.4.6-2.el8", ""))
+	assert.True(t, versionMatched("1.4.6-2.el8", "1.4.6"))
+	assert.True(t, versionMatched("1:1.4.6-2", "1.4.6-2"))
+	assert.True(t, versionMatched("1:1.4.6-2", "1:1.4.6"))
+	assert.False(t, versionMatched("1.4.60-1", "1.4.6"))
+	assert.False(t, versionMatched("", "1.4.6"))
+	assert.False(t, versionMatched("1:1.4.6-2", "2:1.4.6"))
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Service module manages systemd units on remote hosts.
+Unlike running "systemctl" in the command module, it only changes the unit when it is not in the desired state,
+and reports whether anything changed.
+
+Configuration:
+Users can specify the unit and its desired state:
+
+service:
+  name: containerd         # required: the name of the unit. Not required when only daemon_reload is set
+  state: started           # optional: started, stopped, restarted or reloaded
+  enabled: true            # optional: whether the unit should start on boot
+  masked: false            # optional: whether the unit should be masked
+  daemon_reload: true      # optional: run "systemctl daemon-reload" before any other action (default: false)
+
+Usage Examples in Playbook Tasks:
+1. Start and enable a service:
+   ```yaml
+   - name: Start containerd
+     service:
+       name: containerd
+       state: started
+       enabled: true
+   ```
+
+2. Restart after the unit file changed:
+   ```yaml
+   - name: Restart kubelet
+     service:
+       name: kubelet
+       state: restarted
+       daemon_reload: true
+   ```
+
+3. Check the result:
+   ```yaml
+   - name: Stop firewalld
+     service:
+       name: firewalld
+       state: stopped
+       enabled: false
+     register: firewalld_result
+     register_type: json
+   - name: Print result
+     debug:
+       msg: "{{ .firewalld_result.stdout.changed }}"
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "name" and the unit "status"
+- On failure: Returns error message in stderr. It fails when the unit is not found
+*/
+
+const (
+	stateStarted   = "started"
+	stateStopped   = "stopped"
+	stateRestarted = "restarted"
+	stateReloaded  = "reloaded"
+)
+
+// enabledUnitFileStates are the unit file states which start the unit on boot, or cannot be enabled at all.
+var enabledUnitFileStates = sets.New("enabled", "enabled-runtime", "alias", "static", "indirect", "generated", "transient")
+
+// disableUnitFileStates are the unit file states which can be disabled.
+var disableUnitFileStates = sets.New("enabled", "enabled-runtime", "alias", "indirect", "linked", "linked-runtime")
+
+// activeStates are the active states in which the unit is considered running.
+var activeStates = sets.New("active", "activating", "reloading")
+
+// serviceArgs holds the arguments for the service module.
+type serviceArgs struct {
+	name         string // Name of the unit
+	state        string // Desired state of the unit
+	enabled      *bool  // Whether the unit should start on boot
+	masked       *bool  // Whether the unit should be masked
+	daemonReload bool   // Whether to run daemon-reload before other actions
+}
+
+// unitStatus is the status of the unit read from "systemctl show".
+type unitStatus struct {
+	LoadState     string `json:"load_state"`
+	ActiveState   string `json:"active_state"`
+	SubState      string `json:"sub_state"`
+	UnitFileState string `json:"unit_file_state"`
+	MainPID       int    `json:"main_pid"`
+}
+
+// serviceResult is the output of the service module.
+type serviceResult struct {
+	Changed bool        `json:"changed"`
+	Name    string      `json:"name,omitempty"`
+	Actions []string    `json:"actions"`
+	Status  *unitStatus `json:"status,omitempty"`
+}
+
+// newServiceArgs parses and validates the arguments for the service module.
+func newServiceArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*serviceArgs, error) {
+	sa := &serviceArgs{}
+	args := variable.Extension2Variables(raw)
+	sa.name, _ = variable.StringVar(vars, args, "name")
+	sa.state, _ = variable.StringVar(vars, args, "state")
+	if _, ok := args["enabled"]; ok {
+		enabled, err := variable.BoolVar(vars, args, "enabled")
+		if err != nil {
+			return nil, errors.New("\"enabled\" in args should be bool")
+		}
+		sa.enabled = enabled
+	}
+	if _, ok := args["masked"]; ok {
+		masked, err := variable.BoolVar(vars, args, "masked")
+		if err != nil {
+			return nil, errors.New("\"masked\" in args should be bool")
+		}
+		sa.masked = masked
+	}
+	if _, ok := args["daemon_reload"]; ok {
+		daemonReload, err := variable.BoolVar(vars, args, "daemon_reload")
+		if err != nil {
+			return nil, errors.New("\"daemon_reload\" in args should be bool")
+		}
+		sa.daemonReload = *daemonReload
+	}
+
+	switch sa.state {
+	case "", stateStarted, stateStopped, stateRestarted, stateReloaded:
+	default:
+		return nil, errors.Errorf("\"state\" should be one of %q, %q, %q or %q, got %q", stateStarted, stateStopped, stateRestarted, stateReloaded, sa.state)
+	}
+	if sa.name == "" {
+		if sa.state != "" || sa.enabled != nil || sa.masked != nil || !sa.daemonReload {
+			return nil, errors.New("\"name\" in args should be string")
+		}
+	}
+
+	return sa, nil
+}
+
+// ModuleService handles the "service" module, managing systemd units on remote hosts.
+func ModuleService(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	sa, err := newServiceArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	result, err := sa.apply(ctx, conn)
+	if err != nil {
+		return internal.StdoutFailed, "failed to manage unit", err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal service result", err
+	}
+
+	return string(data), "", nil
+}
+
+// apply changes the unit to the desired state, in order of daemon-reload, mask, enable and state.
+// Each action only runs when the unit is not in the desired state, except daemon-reload and restart.
+func (sa serviceArgs) apply(ctx context.Context, conn connector.Connector) (*serviceResult, error) {
+	result := &serviceResult{Name: sa.name, Actions: make([]string, 0)}
+	if sa.daemonReload {
+		if err := systemctl(ctx, conn, "daemon-reload", ""); err != nil {
+			return nil, err
+		}
+		result.Actions = append(result.Actions, "daemon-reload")
+	}
+	if sa.name == "" {
+		return result, nil
+	}
+
+	status, err := queryUnit(ctx, conn, sa.name)
+	if err != nil {
+		return nil, err
+	}
+	// a unit which does not exist can still be masked.
+	if status.LoadState == "not-found" && (sa.masked == nil || !*sa.masked) {
+		return nil, errors.Errorf("unit %q not found", sa.name)
+	}
+
+	for _, action := range sa.actions(status) {
+		if err := systemctl(ctx, conn, action, sa.name); err != nil {
+			return nil, err
+		}
+		result.Actions = append(result.Actions, action)
+		result.Changed = true
+	}
+
+	if result.Status, err = queryUnit(ctx, conn, sa.name); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// actions returns the systemctl actions to change the unit from status to the desired state.
+func (sa serviceArgs) actions(status *unitStatus) []string {
+	var actions []string
+	masked := strings.HasPrefix(status.UnitFileState, "masked") || status.LoadState == "masked"
+	if sa.masked != nil {
+		switch {
+		case *sa.masked && !masked:
+			actions = append(actions, "mask")
+		case !*sa.masked && masked:
+			actions = append(actions, "unmask")
+		}
+	}
+	if sa.enabled != nil {
+		switch {
+		case *sa.enabled && !enabledUnitFileStates.Has(status.UnitFileState):
+			actions = append(actions, "enable")
+		case !*sa.enabled && disableUnitFileStates.Has(status.UnitFileState):
+			actions = append(actions, "disable")
+		}
+	}
+	active := activeStates.Has(status.ActiveState)
+	switch sa.state {
+	case stateStarted:
+		if !active {
+			actions = append(actions, "start")
+		}
+	case stateStopped:
+		if active {
+			actions = append(actions, "stop")
+		}
+	case stateRestarted:
+		actions = append(actions, "restart")
+	case stateReloaded:
+		if active {
+			actions = append(actions, "reload")
+		} else {
+			actions = append(actions, "start")
+		}
+	}
+
+	return actions
+}
+
+// queryUnit reads the status of the unit by "systemctl show".
+func queryUnit(ctx context.Context, conn connector.Connector, name string) (*unitStatus, error) {
+	cmd := fmt.Sprintf("systemctl show %s --property=LoadState,ActiveState,SubState,UnitFileState,MainPID --no-pager", quote(name))
+	stdout, stderr, err := conn.ExecuteCommand(ctx, cmd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query unit %q, stderr: %s", name, strings.TrimSpace(string(stderr)))
+	}
+
+	return parseUnitStatus(stdout), nil
+}
+
+// parseUnitStatus parses the "key=value" lines of "systemctl show".
+func parseUnitStatus(stdout []byte) *unitStatus {
+	status := &unitStatus{}
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "LoadState":
+			status.LoadState = value
+		case "ActiveState":
+			status.ActiveState = value
+		case "SubState":
+			status.SubState = value
+		case "UnitFileState":
+			status.UnitFileState = value
+		case "MainPID":
+			status.MainPID, _ = strconv.Atoi(value)
+		}
+	}
+
+	return status
+}
+
+// systemctl runs the systemctl action for the unit. name is empty for the actions without unit, e.g. daemon-reload.
+func systemctl(ctx context.Context, conn connector.Connector, action, name string) error {
+	cmd := "systemctl " + action
+	if name != "" {
+		cmd += " " + quote(name)
+	}
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		if name == "" {
+			return errors.Wrapf(err, "failed to %s, stderr: %s", action, strings.TrimSpace(string(stderr)))
+		}
+
+		return errors.Wrapf(err, "failed to %s unit %q, stderr: %s", action, name, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
+
+// quote the unit name for shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
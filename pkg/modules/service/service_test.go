@@ -0,0 +1,246 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakeSystemd simulates systemctl for a single unit and records the executed commands.
+type fakeSystemd struct {
+	status   unitStatus
+	executed []string
+}
+
+func (f *fakeSystemd) Init(context.Context) error { return nil }
+
+func (f *fakeSystemd) Close(context.Context) error { return nil }
+
+func (f *fakeSystemd) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (f *fakeSystemd) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakeSystemd) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	if strings.HasPrefix(cmd, "systemctl show ") {
+		return []byte(fmt.Sprintf("MainPID=%d\nLoadState=%s\nActiveState=%s\nSubState=%s\nUnitFileState=%s\n",
+			f.status.MainPID, f.status.LoadState, f.status.ActiveState, f.status.SubState, f.status.UnitFileState)), nil, nil
+	}
+	f.executed = append(f.executed, cmd)
+	action, _, _ := strings.Cut(strings.TrimPrefix(cmd, "systemctl "), " ")
+	switch action {
+	case "start", "restart":
+		f.status.ActiveState, f.status.SubState, f.status.MainPID = "active", "running", 100
+	case "stop":
+		f.status.ActiveState, f.status.SubState, f.status.MainPID = "inactive", "dead", 0
+	case "enable":
+		f.status.UnitFileState = "enabled"
+	case "disable":
+		f.status.UnitFileState = "disabled"
+	case "mask":
+		f.status.LoadState, f.status.UnitFileState = "masked", "masked"
+	case "unmask":
+		f.status.LoadState, f.status.UnitFileState = "loaded", "disabled"
+	case "reload", "daemon-reload":
+	default:
+		return nil, []byte("unknown command"), errors.New("exit status 1")
+	}
+
+	return nil, nil, nil
+}
+
+func TestServiceArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *serviceArgs
+		wantErr bool
+	}{
+		{
+			name:   "name and state",
+			args:   map[string]any{"name": "containerd", "state": "started"},
+			except: &serviceArgs{name: "containerd", state: stateStarted},
+		},
+		{
+			name:   "enabled and masked",
+			args:   map[string]any{"name": "containerd", "enabled": true, "masked": "false"},
+			except: &serviceArgs{name: "containerd", enabled: ptr.To(true), masked: ptr.To(false)},
+		},
+		{
+			name:   "only daemon_reload",
+			args:   map[string]any{"daemon_reload": true},
+			except: &serviceArgs{daemonReload: true},
+		},
+		{
+			name:    "missing name",
+			args:    map[string]any{"state": "started"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported state",
+			args:    map[string]any{"name": "containerd", "state": "running"},
+			wantErr: true,
+		},
+		{
+			name:    "enabled is not bool",
+			args:    map[string]any{"name": "containerd", "enabled": 1},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sa, err := newServiceArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, sa)
+		})
+	}
+}
+
+func TestModuleService(t *testing.T) {
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		status         unitStatus
+		exceptExecuted []string
+		exceptChanged  bool
+		exceptStatus   unitStatus
+		exceptErr      string
+	}{
+		{
+			name:           "start and enable inactive unit",
+			args:           map[string]any{"name": "containerd", "state": "started", "enabled": true},
+			status:         unitStatus{LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+			exceptExecuted: []string{"systemctl enable 'containerd'", "systemctl start 'containerd'"},
+			exceptChanged:  true,
+			exceptStatus:   unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 100},
+		},
+		{
+			name:          "unit already started",
+			args:          map[string]any{"name": "containerd", "state": "started", "enabled": true},
+			status:        unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 10},
+			exceptChanged: false,
+			exceptStatus:  unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 10},
+		},
+		{
+			name:          "static unit is enabled",
+			args:          map[string]any{"name": "systemd-journald", "enabled": true},
+			status:        unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "static"},
+			exceptChanged: false,
+			exceptStatus:  unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "static"},
+		},
+		{
+			name:           "stop and disable",
+			args:           map[string]any{"name": "firewalld", "state": "stopped", "enabled": false},
+			status:         unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 10},
+			exceptExecuted: []string{"systemctl disable 'firewalld'", "systemctl stop 'firewalld'"},
+			exceptChanged:  true,
+			exceptStatus:   unitStatus{LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+		},
+		{
+			name:           "restart always changes",
+			args:           map[string]any{"name": "kubelet", "state": "restarted", "daemon_reload": true},
+			status:         unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 10},
+			exceptExecuted: []string{"systemctl daemon-reload", "systemctl restart 'kubelet'"},
+			exceptChanged:  true,
+			exceptStatus:   unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 100},
+		},
+		{
+			name:           "reload inactive unit starts it",
+			args:           map[string]any{"name": "keepalived", "state": "reloaded"},
+			status:         unitStatus{LoadState: "loaded", ActiveState: "failed", SubState: "failed", UnitFileState: "enabled"},
+			exceptExecuted: []string{"systemctl start 'keepalived'"},
+			exceptChanged:  true,
+			exceptStatus:   unitStatus{LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 100},
+		},
+		{
+			name:           "mask unit which is not found",
+			args:           map[string]any{"name": "docker", "masked": true},
+			status:         unitStatus{LoadState: "not-found", ActiveState: "inactive", SubState: "dead"},
+			exceptExecuted: []string{"systemctl mask 'docker'"},
+			exceptChanged:  true,
+			exceptStatus:   unitStatus{LoadState: "masked", ActiveState: "inactive", SubState: "dead", UnitFileState: "masked"},
+		},
+		{
+			name:      "unit not found",
+			args:      map[string]any{"name": "docker", "state": "started"},
+			status:    unitStatus{LoadState: "not-found", ActiveState: "inactive", SubState: "dead"},
+			exceptErr: `unit "docker" not found`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &fakeSystemd{status: tc.status}
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			stdout, stderr, err := ModuleService(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err, stderr)
+			assert.Equal(t, tc.exceptExecuted, conn.executed)
+
+			var result serviceResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, tc.exceptChanged, result.Changed)
+			assert.Equal(t, tc.args["name"], result.Name)
+			assert.Equal(t, tc.exceptStatus, *result.Status)
+		})
+	}
+}
+
+func TestModuleServiceDaemonReload(t *testing.T) {
+	conn := &fakeSystemd{}
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	stdout, _, err := ModuleService(ctx, internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(map[string]any{"daemon_reload": true}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"systemctl daemon-reload"}, conn.executed)
+	assert.JSONEq(t, `{"changed":false,"actions":["daemon-reload"]}`, stdout)
+}
@@ -2,6 +2,11 @@ package setup
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
 
 	"github.com/kubesphere/kubekey/v4/pkg/connector"
 	_const "github.com/kubesphere/kubekey/v4/pkg/const"
@@ -23,6 +28,11 @@ It performs the following operations:
 Usage:
   - host: The target host to connect to
   - variable: Map of variables to be used for connection and fact gathering
+  - force: gather facts again and ignore the cache
+  - gather_subset: the fact subsets to gather. such as "all", "min", "network", "!hardware". default "hardware" and "pkg_mgr"
+  - gather_timeout: the timeout in seconds to gather facts
+  - show: print the facts and return them in stdout as json
+  - clear: remove the cached facts of the host without gathering
 */
 
 // ModuleSetup establishes a connection to a remote host and gathers facts about it.
@@ -33,9 +43,24 @@ func ModuleSetup(ctx context.Context, opts internal.ExecOptions) (string, string
 		return internal.StdoutFailed, internal.StderrGetHostVariable, err
 	}
 	args := variable.Extension2Variables(opts.Args)
+	if clear, err := variable.BoolVar(ha, args, "clear"); err == nil && *clear {
+		return clearFacts(opts)
+	}
+	show, _ := variable.BoolVar(ha, args, "show")
 	if force, err := variable.BoolVar(ha, args, "force"); err == nil && *force {
 		ctx = context.WithValue(ctx, _const.CTXSetupForceKey, true)
 	}
+	gatherSubset, _ := variable.StringSliceVar(ha, args, "gather_subset")
+	subsets, err := connector.ResolveGatherSubset(gatherSubset)
+	if err != nil {
+		return internal.StdoutFailed, "invalid gather_subset", err
+	}
+	ctx = context.WithValue(ctx, _const.CTXGatherSubsetKey, subsets)
+	if timeout, err := variable.IntVar(ha, args, "gather_timeout"); err == nil && *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*timeout)*time.Second)
+		defer cancel()
+	}
 	// get connector
 	conn, err := opts.GetConnector(ctx)
 	if err != nil {
@@ -49,11 +74,47 @@ func ModuleSetup(ctx context.Context, opts internal.ExecOptions) (string, string
 	if gf, ok := conn.(connector.GatherFacts); ok {
 		remoteInfo, err := gf.HostInfo(ctx)
 		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return internal.StdoutFailed, "gather facts timeout", err
+			}
 			return internal.StdoutFailed, "failed to get host info", err
 		}
 		if err := opts.Merge(variable.MergeRemoteVariable(remoteInfo, opts.Host)); err != nil {
 			return internal.StdoutFailed, "failed to merge setup variable", err
 		}
+		if show != nil && *show {
+			return showFacts(opts, remoteInfo)
+		}
+	}
+
+	return internal.StdoutSuccess, "", nil
+}
+
+// showFacts prints the facts to log output, and returns them as json.
+func showFacts(opts internal.ExecOptions, facts map[string]any) (string, string, error) {
+	data, err := json.MarshalIndent(facts, "", "  ")
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal facts", err
+	}
+	if opts.LogOutput != nil {
+		_, _ = fmt.Fprintf(opts.LogOutput, "FACTS [%s]: \n%s\n", opts.Host, data)
+	}
+
+	return string(data), "", nil
+}
+
+// clearFacts removes the cached facts of the host.
+func clearFacts(opts internal.ExecOptions) (string, string, error) {
+	wd, err := opts.Get(variable.GetWorkDir())
+	if err != nil {
+		return internal.StdoutFailed, "failed to get workdir", err
+	}
+	workdir, ok := wd.(string)
+	if !ok {
+		return internal.StdoutFailed, "failed to get workdir", errors.New("workdir in variable should be string")
+	}
+	if err := connector.ClearCachedFacts(workdir, opts.Host); err != nil {
+		return internal.StdoutFailed, "failed to clear cached facts", err
 	}
 
 	return internal.StdoutSuccess, "", nil
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Stat module returns the status of a path on remote hosts as structured data.
+It never changes anything, so the result can be used in "when" conditions of later tasks.
+
+Configuration:
+Users can specify the path and what to collect:
+
+stat:
+  path: /etc/kubernetes/admin.conf # required: the path in remote host
+  follow: false             # optional: follow symbolic links (default: false)
+  get_checksum: true        # optional: compute the checksum of a regular file (default: true)
+  checksum_algorithm: sha256 # optional: md5, sha1, sha256 or sha512 (default: sha256)
+
+Usage Examples in Playbook Tasks:
+1. Skip a task when a file exists:
+   ```yaml
+   - name: Stat admin.conf
+     stat:
+       path: /etc/kubernetes/admin.conf
+     register: admin_conf
+     register_type: json
+   - name: Init cluster
+     command: kubeadm init --config /etc/kubernetes/kubeadm-config.yaml
+     when: .admin_conf.stdout.stat.exists | not
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed" (always false) and "stat"
+- On failure: Returns error message in stderr
+*/
+
+// checksumCommands are the commands to compute checksum by algorithm.
+var checksumCommands = map[string]string{
+	"md5":    "md5sum",
+	"sha1":   "sha1sum",
+	"sha256": "sha256sum",
+	"sha512": "sha512sum",
+}
+
+// statArgs holds the arguments for the stat module.
+type statArgs struct {
+	path              string // Path in remote host
+	follow            bool   // Follow symbolic links
+	getChecksum       bool   // Compute the checksum of a regular file
+	checksumAlgorithm string // md5, sha1, sha256 or sha512
+}
+
+// pathStat is the status of the path in the output.
+type pathStat struct {
+	Exists     bool   `json:"exists"`
+	Path       string `json:"path"`
+	Type       string `json:"type,omitempty"`
+	IsFile     bool   `json:"isreg"`
+	IsDir      bool   `json:"isdir"`
+	IsLink     bool   `json:"islnk"`
+	Size       int64  `json:"size"`
+	Mode       string `json:"mode,omitempty"`
+	UID        int    `json:"uid"`
+	GID        int    `json:"gid"`
+	Owner      string `json:"owner,omitempty"`
+	Group      string `json:"group,omitempty"`
+	MTime      int64  `json:"mtime"`
+	Checksum   string `json:"checksum,omitempty"`
+	LinkTarget string `json:"lnk_target,omitempty"`
+}
+
+// statResult is the output of the stat module.
+type statResult struct {
+	Changed bool     `json:"changed"`
+	Stat    pathStat `json:"stat"`
+}
+
+// newStatArgs parses and validates the arguments for the stat module.
+func newStatArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*statArgs, error) {
+	var err error
+	sa := &statArgs{getChecksum: true, checksumAlgorithm: "sha256"}
+	args := variable.Extension2Variables(raw)
+	sa.path, err = variable.StringVar(vars, args, "path")
+	if err != nil || sa.path == "" {
+		return nil, errors.New("\"path\" in args should be string")
+	}
+	if sa.follow, err = internal.BoolArg(vars, args, "follow", false); err != nil {
+		return nil, err
+	}
+	if sa.getChecksum, err = internal.BoolArg(vars, args, "get_checksum", true); err != nil {
+		return nil, err
+	}
+	if algorithm, _ := variable.StringVar(vars, args, "checksum_algorithm"); algorithm != "" {
+		if _, ok := checksumCommands[algorithm]; !ok {
+			return nil, errors.Errorf("unsupported checksum_algorithm %q, should be one of md5, sha1, sha256 or sha512", algorithm)
+		}
+		sa.checksumAlgorithm = algorithm
+	}
+
+	return sa, nil
+}
+
+// ModuleStat handles the "stat" module, returning the status of a path on remote hosts.
+func ModuleStat(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	sa, err := newStatArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	st, err := internal.StatRemotePath(ctx, conn, sa.path, sa.follow)
+	if err != nil {
+		return internal.StdoutFailed, "failed to stat path", err
+	}
+	result := statResult{Stat: pathStat{Exists: st.Exists, Path: sa.path}}
+	if st.Exists {
+		result.Stat = pathStat{
+			Exists: true, Path: sa.path, Type: st.Type,
+			IsFile: st.Type == "file", IsDir: st.Type == "directory", IsLink: st.Type == "link",
+			Size: st.Size, Mode: fmt.Sprintf("%04o", st.Mode), UID: st.UID, GID: st.GID,
+			Owner: st.Owner, Group: st.Group, MTime: st.MTime, LinkTarget: st.LinkTarget,
+		}
+	}
+	if st.Exists && st.Type == "file" && sa.getChecksum {
+		stdout, stderr, err := conn.ExecuteCommand(ctx, checksumCommands[sa.checksumAlgorithm]+" "+internal.ShellQuote(sa.path))
+		if err != nil {
+			return internal.StdoutFailed, "failed to compute checksum", errors.Wrapf(err, "stderr: %s", strings.TrimSpace(string(stderr)))
+		}
+		result.Stat.Checksum, _, _ = strings.Cut(strings.TrimSpace(string(stdout)), " ")
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal stat result", err
+	}
+
+	return string(data), "", nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakeStat answers stat, readlink and checksum commands by the preset output.
+type fakeStat struct {
+	stat       string
+	followStat string
+	link       string
+	executed   []string
+}
+
+func (f *fakeStat) Init(context.Context) error { return nil }
+
+func (f *fakeStat) Close(context.Context) error { return nil }
+
+func (f *fakeStat) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (f *fakeStat) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakeStat) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	f.executed = append(f.executed, cmd)
+	switch {
+	case strings.Contains(cmd, "then stat -L "):
+		return []byte(f.followStat), nil, nil
+	case strings.Contains(cmd, "then stat "):
+		return []byte(f.stat), nil, nil
+	case strings.HasPrefix(cmd, "readlink "):
+		return []byte(f.link + "\n"), nil, nil
+	case strings.HasPrefix(cmd, "sha256sum "), strings.HasPrefix(cmd, "md5sum "):
+		return []byte("abc123  /etc/hosts\n"), nil, nil
+	}
+
+	return nil, nil, nil
+}
+
+func TestStatArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *statArgs
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			args:   map[string]any{"path": "/etc/hosts"},
+			except: &statArgs{path: "/etc/hosts", getChecksum: true, checksumAlgorithm: "sha256"},
+		},
+		{
+			name:   "follow without checksum",
+			args:   map[string]any{"path": "/etc/hosts", "follow": true, "get_checksum": "false", "checksum_algorithm": "md5"},
+			except: &statArgs{path: "/etc/hosts", follow: true, checksumAlgorithm: "md5"},
+		},
+		{
+			name:    "missing path",
+			args:    map[string]any{},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported checksum_algorithm",
+			args:    map[string]any{"path": "/etc/hosts", "checksum_algorithm": "crc32"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sa, err := newStatArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, sa)
+		})
+	}
+}
+
+func TestModuleStat(t *testing.T) {
+	testcases := []struct {
+		name   string
+		args   map[string]any
+		conn   *fakeStat
+		except pathStat
+	}{
+		{
+			name:   "path not exist",
+			args:   map[string]any{"path": "/etc/kubernetes/admin.conf"},
+			conn:   &fakeStat{},
+			except: pathStat{Path: "/etc/kubernetes/admin.conf"},
+		},
+		{
+			name: "regular file with checksum",
+			args: map[string]any{"path": "/etc/hosts"},
+			conn: &fakeStat{stat: "644 0 0 root root 120 1700000000 regular file\n"},
+			except: pathStat{Exists: true, Path: "/etc/hosts", Type: "file", IsFile: true, Size: 120, Mode: "0644",
+				Owner: "root", Group: "root", MTime: 1700000000, Checksum: "abc123"},
+		},
+		{
+			name: "directory",
+			args: map[string]any{"path": "/tmp"},
+			conn: &fakeStat{stat: "1777 0 0 root root 4096 1700000000 directory\n"},
+			except: pathStat{Exists: true, Path: "/tmp", Type: "directory", IsDir: true, Size: 4096, Mode: "1777",
+				Owner: "root", Group: "root", MTime: 1700000000},
+		},
+		{
+			name: "link",
+			args: map[string]any{"path": "/usr/bin/kubectl"},
+			conn: &fakeStat{stat: "777 0 0 root root 22 1700000000 symbolic link\n", link: "/usr/local/bin/kubectl"},
+			except: pathStat{Exists: true, Path: "/usr/bin/kubectl", Type: "link", IsLink: true, Size: 22, Mode: "0777",
+				Owner: "root", Group: "root", MTime: 1700000000, LinkTarget: "/usr/local/bin/kubectl"},
+		},
+		{
+			name: "follow link",
+			args: map[string]any{"path": "/usr/bin/kubectl", "follow": true, "get_checksum": false},
+			conn: &fakeStat{followStat: "755 1000 1000 kube kube 50000 1700000000 regular file\n"},
+			except: pathStat{Exists: true, Path: "/usr/bin/kubectl", Type: "file", IsFile: true, Size: 50000, Mode: "0755",
+				UID: 1000, GID: 1000, Owner: "kube", Group: "kube", MTime: 1700000000},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), internal.ConnKey, tc.conn)
+			stdout, _, err := ModuleStat(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			require.NoError(t, err)
+			var result statResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.False(t, result.Changed)
+			assert.Equal(t, tc.except, result.Stat)
+		})
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"k8s.io/klog/v2"
+)
+
+// magic numbers of the supported compressions and archives.
+var (
+	magicZip   = []byte("PK\x03\x04")
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2 = []byte("BZh")
+)
+
+// filter selects and renames the entries of the archive.
+type filter struct {
+	include         []string     // Glob patterns of the entries to extract
+	exclude         []string     // Glob patterns of the entries to skip
+	stripComponents int          // Number of leading path components to remove
+	mode            *fs.FileMode // Mode of the regular files
+}
+
+// convert reads the archive at src in any supported format, and writes the selected entries to w as a gzip tar,
+// which can be extracted by "tar -xzf" of both GNU and busybox. It returns the headers of the written entries.
+func convert(src string, w io.Writer, f filter) ([]*tar.Header, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open archive %q", src)
+	}
+	defer file.Close()
+	br := bufio.NewReader(file)
+	magic, _ := br.Peek(6)
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	var files []*tar.Header
+	if bytes.HasPrefix(magic, magicZip) {
+		stat, err := file.Stat()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat archive %q", src)
+		}
+		zr, err := zip.NewReader(file, stat.Size())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read zip archive %q", src)
+		}
+		files, err = convertZip(zr, tw, f)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := decompress(br, magic)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress archive %q", src)
+		}
+		if files, err = convertTar(tar.NewReader(r), tw, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to write tar")
+	}
+	if err := gw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to write gzip")
+	}
+
+	return files, nil
+}
+
+// decompress returns the reader of the tar stream by the magic number. An unknown magic is read as plain tar.
+func decompress(r io.Reader, magic []byte) (io.Reader, error) {
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		return gzip.NewReader(r)
+	case bytes.HasPrefix(magic, magicXz):
+		return xz.NewReader(r)
+	case bytes.HasPrefix(magic, magicZstd):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return zr.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, magicBzip2):
+		return bzip2.NewReader(r), nil
+	default:
+		return r, nil
+	}
+}
+
+// convertTar copies the selected entries of tr to tw.
+func convertTar(tr *tar.Reader, tw *tar.Writer, f filter) ([]*tar.Header, error) {
+	var files []*tar.Header
+	links := symlinks{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read tar archive")
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink, tar.TypeLink:
+		default:
+			klog.V(4).InfoS("skip unsupported entry in archive", "name", hdr.Name, "type", hdr.Typeflag)
+
+			continue
+		}
+		name, ok, err := f.rename(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		out := &tar.Header{Typeflag: hdr.Typeflag, Name: name, Mode: hdr.Mode, Size: hdr.Size, ModTime: hdr.ModTime, Linkname: hdr.Linkname}
+		if hdr.Typeflag == tar.TypeLink {
+			// a hard link refers to another entry in the archive.
+			if out.Linkname, ok, err = f.rename(hdr.Linkname); err != nil || !ok {
+				return nil, errors.Errorf("hard link %q refers to %q which is not extracted", hdr.Name, hdr.Linkname)
+			}
+		}
+		if err := links.check(out); err != nil {
+			return nil, err
+		}
+		if err := f.write(tw, out, tr); err != nil {
+			return nil, err
+		}
+		files = append(files, out)
+	}
+}
+
+// convertZip copies the selected entries of zr to tw.
+func convertZip(zr *zip.Reader, tw *tar.Writer, f filter) ([]*tar.Header, error) {
+	var files []*tar.Header
+	links := symlinks{}
+	for _, zf := range zr.File {
+		name, ok, err := f.rename(zf.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		info := zf.FileInfo()
+		out := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: int64(info.Mode().Perm()), ModTime: zf.Modified}
+		if err := func() error {
+			rc, err := zf.Open()
+			if err != nil {
+				return errors.Wrapf(err, "failed to open %q in zip archive", zf.Name)
+			}
+			defer rc.Close()
+			switch {
+			case info.IsDir():
+				out.Typeflag = tar.TypeDir
+			case info.Mode()&fs.ModeSymlink != 0:
+				target, err := io.ReadAll(rc)
+				if err != nil {
+					return errors.Wrapf(err, "failed to read %q in zip archive", zf.Name)
+				}
+				out.Typeflag, out.Linkname = tar.TypeSymlink, string(target)
+			case !info.Mode().IsRegular():
+				klog.V(4).InfoS("skip unsupported entry in archive", "name", zf.Name, "mode", info.Mode())
+
+				return nil
+			default:
+				out.Size = int64(zf.UncompressedSize64)
+			}
+			if err := links.check(out); err != nil {
+				return err
+			}
+			files = append(files, out)
+
+			return f.write(tw, out, rc)
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// write writes the entry to tw, owned by root and with the mode of filter for regular files.
+func (f filter) write(tw *tar.Writer, hdr *tar.Header, r io.Reader) error {
+	if hdr.Typeflag == tar.TypeReg && f.mode != nil {
+		hdr.Mode = int64(*f.mode)
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.Wrapf(err, "failed to write %q", hdr.Name)
+	}
+	if hdr.Typeflag == tar.TypeReg {
+		if _, err := io.Copy(tw, r); err != nil {
+			return errors.Wrapf(err, "failed to write %q", hdr.Name)
+		}
+	}
+
+	return nil
+}
+
+// maxSymlinkDepth is the max number of symlinks followed to resolve a path, the same as linux.
+const maxSymlinkDepth = 40
+
+// symlinks are the symlinks written to the converted archive, from the path to the target.
+type symlinks map[string]string
+
+// check returns error when the entry is written through a symlink written before, or it is a symlink which refers to
+// a path outside of dest. Otherwise a symlink is recorded to check the following entries.
+func (l symlinks) check(hdr *tar.Header) error {
+	names := []string{hdr.Name}
+	if hdr.Typeflag == tar.TypeLink {
+		names = append(names, hdr.Linkname)
+	}
+	for _, name := range names {
+		for p := path.Dir(name); p != "."; p = path.Dir(p) {
+			if _, ok := l[p]; ok {
+				return errors.Errorf("unsafe path %q in archive, it is under symlink %q", name, p)
+			}
+		}
+	}
+	if hdr.Typeflag != tar.TypeSymlink {
+		return nil
+	}
+	if path.IsAbs(hdr.Linkname) {
+		return errors.Errorf("unsafe symlink %q in archive, it refers to absolute path %q", hdr.Name, hdr.Linkname)
+	}
+	if _, ok := l.resolve(path.Dir(hdr.Name)+"/"+hdr.Linkname, 0); !ok {
+		return errors.Errorf("unsafe symlink %q in archive, it refers to %q outside of dest", hdr.Name, hdr.Linkname)
+	}
+	l[hdr.Name] = hdr.Linkname
+
+	return nil
+}
+
+// resolve resolves the relative path in dest by following the symlinks. The ".." is resolved after the symlinks
+// like the kernel, so the path is not cleaned before. It returns false when the path is outside of dest.
+func (l symlinks) resolve(name string, depth int) (string, bool) {
+	if depth > maxSymlinkDepth {
+		return "", false
+	}
+	resolved := "."
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "." {
+				return "", false
+			}
+			resolved = path.Dir(resolved)
+
+			continue
+		}
+		next := path.Join(resolved, part)
+		target, ok := l[next]
+		if !ok {
+			resolved = next
+
+			continue
+		}
+		if resolved, ok = l.resolve(resolved+"/"+target, depth+1); !ok {
+			return "", false
+		}
+	}
+
+	return resolved, true
+}
+
+// rename strips the leading path components of the entry, and returns false when it is filtered out.
+// It returns error when the entry is outside of dest.
+func (f filter) rename(name string) (string, bool, error) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(name) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false, errors.Errorf("unsafe path %q in archive", name)
+	}
+	parts := strings.Split(cleaned, "/")
+	if cleaned == "." || len(parts) <= f.stripComponents {
+		return "", false, nil
+	}
+	stripped := strings.Join(parts[f.stripComponents:], "/")
+	if len(f.include) > 0 && !matchAny(f.include, stripped) {
+		return "", false, nil
+	}
+	if matchAny(f.exclude, stripped) {
+		return "", false, nil
+	}
+
+	return stripped, true, nil
+}
+
+// matchAny returns true when the path, or any of its parent directories, matches one of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for p := name; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), p); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
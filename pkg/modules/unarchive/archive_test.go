@@ -0,0 +1,260 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+	"k8s.io/utils/ptr"
+)
+
+// testEntry is an entry of the test archives.
+type testEntry struct {
+	name    string
+	content string
+	dir     bool
+	link    string // the target of symlink
+}
+
+var testEntries = []testEntry{
+	{name: "linux-amd64/", dir: true},
+	{name: "linux-amd64/helm", content: "helm binary"},
+	{name: "linux-amd64/README.md", content: "readme"},
+	{name: "linux-amd64/licenses/LICENSE", content: "license"},
+}
+
+// writeTar writes the entries as tar.
+func writeTar(t *testing.T, w io.Writer, entries []testEntry) {
+	t.Helper()
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: e.name, Mode: 0o644, Size: int64(len(e.content))}
+		switch {
+		case e.dir:
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		case e.link != "":
+			hdr.Typeflag, hdr.Mode, hdr.Linkname = tar.TypeSymlink, 0o777, e.link
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+// createArchive creates the archive of the entries in the format, and returns its path.
+func createArchive(t *testing.T, format string, entries []testEntry) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	switch format {
+	case "tar":
+		writeTar(t, buf, entries)
+	case "tar.gz":
+		gw := gzip.NewWriter(buf)
+		writeTar(t, gw, entries)
+		require.NoError(t, gw.Close())
+	case "tar.xz":
+		xw, err := xz.NewWriter(buf)
+		require.NoError(t, err)
+		writeTar(t, xw, entries)
+		require.NoError(t, xw.Close())
+	case "tar.zst":
+		zw, err := zstd.NewWriter(buf)
+		require.NoError(t, err)
+		writeTar(t, zw, entries)
+		require.NoError(t, zw.Close())
+	case "zip":
+		zw := zip.NewWriter(buf)
+		for _, e := range entries {
+			hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+			hdr.SetMode(0o644)
+			content := e.content
+			switch {
+			case e.dir:
+				hdr.SetMode(fs.ModeDir | 0o755)
+			case e.link != "":
+				hdr.SetMode(fs.ModeSymlink | 0o777)
+				content = e.link
+			}
+			w, err := zw.CreateHeader(hdr)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+	}
+	path := filepath.Join(t.TempDir(), "archive."+format)
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	return path
+}
+
+// headerNames returns the names of the headers.
+func headerNames(hdrs []*tar.Header) []string {
+	names := make([]string, 0, len(hdrs))
+	for _, hdr := range hdrs {
+		names = append(names, hdr.Name)
+	}
+
+	return names
+}
+
+// readConverted returns the content of regular files and the mode of entries in the converted gzip tar.
+func readConverted(t *testing.T, data []byte) (map[string]string, map[string]int64) {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	contents, modes := make(map[string]string), make(map[string]int64)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		modes[hdr.Name] = hdr.Mode
+		if hdr.Typeflag == tar.TypeReg {
+			contents[hdr.Name] = string(content)
+		}
+	}
+
+	return contents, modes
+}
+
+func TestConvertFormats(t *testing.T) {
+	for _, format := range []string{"tar", "tar.gz", "tar.xz", "tar.zst", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			hdrs, err := convert(createArchive(t, format, testEntries), buf, filter{})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"linux-amd64", "linux-amd64/helm", "linux-amd64/README.md", "linux-amd64/licenses/LICENSE"}, headerNames(hdrs))
+			contents, _ := readConverted(t, buf.Bytes())
+			assert.Equal(t, map[string]string{"linux-amd64/helm": "helm binary", "linux-amd64/README.md": "readme", "linux-amd64/licenses/LICENSE": "license"}, contents)
+		})
+	}
+}
+
+func TestConvertFilter(t *testing.T) {
+	testcases := []struct {
+		name           string
+		filter         filter
+		exceptFiles    []string
+		exceptContents map[string]string
+		exceptModes    map[string]int64
+	}{
+		{
+			name:           "strip components and include",
+			filter:         filter{stripComponents: 1, include: []string{"helm"}, mode: ptr.To(fs.FileMode(0o755))},
+			exceptFiles:    []string{"helm"},
+			exceptContents: map[string]string{"helm": "helm binary"},
+			exceptModes:    map[string]int64{"helm": 0o755},
+		},
+		{
+			name:           "exclude directory and pattern",
+			filter:         filter{stripComponents: 1, exclude: []string{"licenses", "*.md"}},
+			exceptFiles:    []string{"helm"},
+			exceptContents: map[string]string{"helm": "helm binary"},
+			exceptModes:    map[string]int64{"helm": 0o644},
+		},
+		{
+			name:           "include directory",
+			filter:         filter{include: []string{"*/licenses"}},
+			exceptFiles:    []string{"linux-amd64/licenses/LICENSE"},
+			exceptContents: map[string]string{"linux-amd64/licenses/LICENSE": "license"},
+			exceptModes:    map[string]int64{"linux-amd64/licenses/LICENSE": 0o644},
+		},
+	}
+	src := createArchive(t, "tar.gz", testEntries)
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			hdrs, err := convert(src, buf, tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exceptFiles, headerNames(hdrs))
+			contents, modes := readConverted(t, buf.Bytes())
+			assert.Equal(t, tc.exceptContents, contents)
+			assert.Equal(t, tc.exceptModes, modes)
+		})
+	}
+}
+
+func TestConvertUnsafePath(t *testing.T) {
+	src := createArchive(t, "tar", []testEntry{{name: "../etc/passwd", content: "root"}})
+	_, err := convert(src, io.Discard, filter{})
+	require.ErrorContains(t, err, "unsafe path")
+}
+
+func TestConvertSymlink(t *testing.T) {
+	testcases := []struct {
+		name      string
+		entries   []testEntry
+		exceptErr string
+	}{
+		{
+			name:    "symlink in dest",
+			entries: []testEntry{{name: "bin/", dir: true}, {name: "bin/helm", content: "helm"}, {name: "helm", link: "bin/helm"}},
+		},
+		{
+			name:      "absolute symlink",
+			entries:   []testEntry{{name: "passwd", link: "/etc/passwd"}},
+			exceptErr: "absolute path",
+		},
+		{
+			name:      "symlink outside of dest",
+			entries:   []testEntry{{name: "bin/", dir: true}, {name: "bin/etc", link: "../../etc"}},
+			exceptErr: "outside of dest",
+		},
+		{
+			name: "symlink outside of dest through another symlink",
+			entries: []testEntry{{name: "a/b/", dir: true}, {name: "a/b/up", link: "../.."}, {name: "a/b/c/", dir: true},
+				{name: "a/b/c/escape", link: "../up/.."}},
+			exceptErr: "outside of dest",
+		},
+		{
+			name:      "entry through symlink",
+			entries:   []testEntry{{name: "etc", link: "."}, {name: "etc/passwd", content: "root"}},
+			exceptErr: "under symlink",
+		},
+	}
+	for _, tc := range testcases {
+		for _, format := range []string{"tar", "zip"} {
+			t.Run(tc.name+" "+format, func(t *testing.T) {
+				_, err := convert(createArchive(t, format, tc.entries), io.Discard, filter{})
+				if tc.exceptErr != "" {
+					require.ErrorContains(t, err, tc.exceptErr)
+
+					return
+				}
+				require.NoError(t, err)
+			})
+		}
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unarchive
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// tarDiffIgnored are the differences reported by "tar --diff" which are not changes, because the owner is not restored
+// by "--no-same-owner". "Mode differs" is also ignored when the mode of regular files is set by "mode".
+var tarDiffIgnored = []string{"Uid differs", "Gid differs"}
+
+// verifyRemoteChecksum checks the checksum of the archive in remote host by "<algorithm>sum" when it is set.
+func (ua unarchiveArgs) verifyRemoteChecksum(ctx context.Context, conn connector.Connector) error {
+	if ua.checksum == "" {
+		return nil
+	}
+	stdout, stderr, err := conn.ExecuteCommand(ctx, fmt.Sprintf("%ssum %s", ua.checksumAlgorithm, internal.ShellQuote(ua.src)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to compute checksum of %q, stderr: %s", ua.src, strings.TrimSpace(string(stderr)))
+	}
+	sum, _, _ := strings.Cut(strings.TrimSpace(string(stdout)), " ")
+	if sum = strings.ToLower(sum); sum != ua.checksum {
+		return errors.Errorf("%s checksum of %q is %s, expected %s", ua.checksumAlgorithm, ua.src, sum, ua.checksum)
+	}
+
+	return nil
+}
+
+// extractRemote extracts the archive in remote host to dest, by "unzip" for zip archives and by "tar" for the others,
+// so the archive is not transferred. It returns the entries in the archive, and false when all of them are the same in dest.
+func (ua unarchiveArgs) extractRemote(ctx context.Context, conn connector.Connector) ([]string, bool, error) {
+	stdout, stderr, err := conn.ExecuteCommand(ctx, "od -An -tx1 -N4 "+internal.ShellQuote(ua.src))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to read %q, stderr: %s", ua.src, strings.TrimSpace(string(stderr)))
+	}
+	if strings.Join(strings.Fields(string(stdout)), "") == hex.EncodeToString(magicZip) {
+		return ua.unzipRemote(ctx, conn)
+	}
+
+	return ua.untarRemote(ctx, conn)
+}
+
+// untarRemote extracts the tar archive in remote host. The filter is converted to the flags of GNU tar, which are only
+// used when it is set. The patterns are prefixed by a "*/" for each stripped component, so they are matched after strip_components.
+func (ua unarchiveArgs) untarRemote(ctx context.Context, conn connector.Connector) ([]string, bool, error) {
+	var flags, members []string
+	if ua.filter.stripComponents > 0 {
+		flags = append(flags, "--strip-components="+strconv.Itoa(ua.filter.stripComponents), "--show-transformed-names")
+	}
+	if len(ua.filter.include) > 0 || len(ua.filter.exclude) > 0 {
+		flags = append(flags, "--anchored", "--wildcards", "--no-wildcards-match-slash")
+	}
+	prefix := strings.Repeat("*/", ua.filter.stripComponents)
+	for _, p := range ua.filter.exclude {
+		flags = append(flags, internal.ShellQuote("--exclude="+prefix+strings.TrimSuffix(p, "/")))
+	}
+	for _, p := range ua.filter.include {
+		members = append(members, internal.ShellQuote(prefix+strings.TrimSuffix(p, "/")))
+	}
+	src, dest := internal.ShellQuote(ua.src), internal.ShellQuote(ua.dest)
+	args := strings.Join(append(flags, members...), " ")
+
+	stdout, stderr, err := conn.ExecuteCommand(ctx, fmt.Sprintf("tar -tf %s %s", src, args))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to list %q, stderr: %s", ua.src, strings.TrimSpace(string(stderr)))
+	}
+	files := entryNames(strings.Split(string(stdout), "\n"))
+	if len(files) == 0 {
+		return nil, false, nil
+	}
+	// "tar --diff" is only supported by GNU tar. The others fail and are taken as changed.
+	stdout, _, _ = conn.ExecuteCommand(ctx, fmt.Sprintf("tar -df %s -C %s %s 2>&1", src, dest, args))
+	ignored := slices.Clone(tarDiffIgnored)
+	if ua.filter.mode != nil {
+		ignored = append(ignored, "Mode differs")
+	}
+	if sameInTarDiff(string(stdout), ignored) {
+		return files, false, nil
+	}
+	cmd := fmt.Sprintf("mkdir -p %[1]s && tar -xf %[2]s -C %[1]s --no-same-owner %[3]s", dest, src, args)
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to extract to %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+	if err := ua.chmod(ctx, conn, files); err != nil {
+		return nil, false, err
+	}
+
+	return files, true, ua.chown(ctx, conn, files)
+}
+
+// sameInTarDiff returns true when "tar --diff" only reports the ignored differences.
+func sameInTarDiff(output string, ignored []string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if !slices.ContainsFunc(ignored, func(s string) bool { return strings.HasSuffix(line, ": "+s) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unzipRemote extracts the zip archive in remote host by Info-ZIP "unzip". Only the newer entries are extracted by "-u",
+// and the extracted entries are parsed from the output to know whether it is changed.
+func (ua unarchiveArgs) unzipRemote(ctx context.Context, conn connector.Connector) ([]string, bool, error) {
+	if ua.filter.stripComponents > 0 {
+		return nil, false, errors.New("\"strip_components\" is not supported for zip archives with \"remote_src\"")
+	}
+	var patterns []string
+	for _, p := range ua.filter.include {
+		p = strings.TrimSuffix(p, "/")
+		patterns = append(patterns, internal.ShellQuote(p), internal.ShellQuote(p+"/*"))
+	}
+	if len(ua.filter.exclude) > 0 {
+		patterns = append(patterns, "-x")
+		for _, p := range ua.filter.exclude {
+			p = strings.TrimSuffix(p, "/")
+			patterns = append(patterns, internal.ShellQuote(p), internal.ShellQuote(p+"/*"))
+		}
+	}
+	src, dest := internal.ShellQuote(ua.src), internal.ShellQuote(ua.dest)
+	args := strings.Join(patterns, " ")
+
+	stdout, stderr, err := conn.ExecuteCommand(ctx, fmt.Sprintf("unzip -Z1 %s %s", src, args))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to list %q, stderr: %s", ua.src, strings.TrimSpace(string(stderr)))
+	}
+	files := entryNames(strings.Split(string(stdout), "\n"))
+	if len(files) == 0 {
+		return nil, false, nil
+	}
+	stdout, stderr, err = conn.ExecuteCommand(ctx, fmt.Sprintf("mkdir -p %[1]s && unzip -o -u %[2]s %[3]s -d %[1]s", dest, src, args))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to extract to %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+	// unzip prints an action for each extracted entry, such as "  inflating: /opt/harbor/install.sh".
+	changed := false
+	for _, line := range strings.Split(string(stdout), "\n") {
+		action, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if slices.Contains([]string{"inflating", "extracting", "creating", "linking"}, action) {
+			changed = true
+		}
+	}
+	if !changed {
+		return files, false, nil
+	}
+	if err := ua.chmod(ctx, conn, files); err != nil {
+		return nil, false, err
+	}
+
+	return files, true, ua.chown(ctx, conn, files)
+}
+
+// chmod changes the mode of the regular files in the entries when it is set.
+func (ua unarchiveArgs) chmod(ctx context.Context, conn connector.Connector, files []string) error {
+	if ua.filter.mode == nil {
+		return nil
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, internal.ShellQuote(f))
+	}
+	cmd := fmt.Sprintf("cd %s && find %s -maxdepth 0 -type f -exec chmod %o {} +", internal.ShellQuote(ua.dest), strings.Join(names, " "), *ua.filter.mode)
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		return errors.Wrapf(err, "failed to change mode in %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
+
+// entryNames returns the entry names of the listed lines, without the empty lines and the trailing "/" of directories.
+func entryNames(lines []string) []string {
+	files := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if name := strings.TrimSuffix(strings.TrimRight(line, "\r"), "/"); name != "" && name != "." {
+			files = append(files, name)
+		}
+	}
+
+	return files
+}
@@ -0,0 +1,409 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unarchive
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/md5"  //nolint:gosec // md5 is only used to verify the checksum of archives
+	"crypto/sha1" //nolint:gosec // sha1 is only used to verify the checksum of archives
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The Unarchive module extracts an archive to a directory on remote hosts.
+The archive is read by kk, filtered and converted to a plain gzip tar, then extracted by "tar -xzf" on the remote host,
+so the flags which differ between GNU and busybox tar are not needed. The entries which are the same in dest are not extracted again.
+With remote_src, the archive is extracted in remote host by "tar" or "unzip" without being transferred.
+Supported formats are tar, tar.gz, tar.bz2, tar.xz, tar.zst and zip, which are detected by content.
+
+Configuration:
+Users can specify the archive and how to extract it:
+
+unarchive:
+  src: /path/to/archive.tar.gz # required: the archive in local, or in remote host when remote_src is true
+  dest: /usr/local/bin      # required: the directory in remote host
+  remote_src: false         # optional: src is in remote host (default: false)
+  checksum: "sha256:xxx"    # optional: verify the archive by "<algorithm>:<hex>", algorithm is md5, sha1, sha256 or sha512 (default: sha256)
+  creates: /usr/local/bin/helm # optional: skip when the path exists in remote host
+  include: [helm]           # optional: glob patterns of the entries to extract, matched after strip_components
+  exclude: ["*.md"]         # optional: glob patterns of the entries to skip
+  strip_components: 1       # optional: number of leading path components to remove (default: 0)
+  owner: root               # optional: owner of the extracted entries
+  group: root               # optional: group of the extracted entries
+  mode: 0755                # optional: mode of the extracted regular files
+
+Usage Examples in Playbook Tasks:
+1. Extract helm:
+   ```yaml
+   - name: Install helm
+     unarchive:
+       src: "{{ .binary_dir }}/helm/{{ .helm_version }}/amd64/helm-{{ .helm_version }}-linux-amd64.tar.gz"
+       dest: /usr/local/bin
+       strip_components: 1
+       include: [helm]
+       mode: 0755
+   ```
+
+2. Extract an archive in remote host:
+   ```yaml
+   - name: Extract harbor installer
+     unarchive:
+       src: /opt/harbor/harbor-offline-installer.tgz
+       dest: /opt/harbor
+       remote_src: true
+       creates: /opt/harbor/harbor/install.sh
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed", "dest" and the extracted "files"
+- On failure: Returns error message in stderr
+*/
+
+// uploadChunkSize is the size of each chunk to upload the converted archive.
+const uploadChunkSize = 32 << 20
+
+// checksumHashes are the supported checksum algorithms.
+var checksumHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// unarchiveArgs holds the arguments for the unarchive module.
+type unarchiveArgs struct {
+	src               string // The archive in local or remote host
+	dest              string // The directory in remote host
+	remoteSrc         bool   // src is in remote host
+	checksumAlgorithm string // The algorithm of checksum
+	checksum          string // The checksum in hex
+	creates           string // Skip when the path exists in remote host
+	owner             string // Owner of the extracted entries
+	group             string // Group of the extracted entries
+	filter            filter
+}
+
+// unarchiveResult is the output of the unarchive module.
+type unarchiveResult struct {
+	Changed bool     `json:"changed"`
+	Dest    string   `json:"dest"`
+	Files   []string `json:"files"`
+	Msg     string   `json:"msg,omitempty"`
+}
+
+// newUnarchiveArgs parses and validates the arguments for the unarchive module.
+func newUnarchiveArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*unarchiveArgs, error) {
+	var err error
+	ua := &unarchiveArgs{}
+	args := variable.Extension2Variables(raw)
+	ua.src, err = variable.StringVar(vars, args, "src")
+	if err != nil || ua.src == "" {
+		return nil, errors.New("\"src\" in args should be string")
+	}
+	ua.dest, err = variable.StringVar(vars, args, "dest")
+	if err != nil || !filepath.IsAbs(ua.dest) {
+		return nil, errors.New("\"dest\" in args should be an absolute path")
+	}
+	if ua.remoteSrc, err = internal.BoolArg(vars, args, "remote_src", false); err != nil {
+		return nil, err
+	}
+	if !ua.remoteSrc && !filepath.IsAbs(ua.src) {
+		return nil, errors.New("\"src\" in args should be an absolute path")
+	}
+	if checksum, _ := variable.StringVar(vars, args, "checksum"); checksum != "" {
+		ua.checksumAlgorithm, ua.checksum = "sha256", checksum
+		if algorithm, sum, ok := strings.Cut(checksum, ":"); ok {
+			ua.checksumAlgorithm, ua.checksum = algorithm, sum
+		}
+		if _, ok := checksumHashes[ua.checksumAlgorithm]; !ok {
+			return nil, errors.Errorf("unsupported checksum algorithm %q, should be one of md5, sha1, sha256 or sha512", ua.checksumAlgorithm)
+		}
+		ua.checksum = strings.ToLower(ua.checksum)
+	}
+	ua.creates, _ = variable.StringVar(vars, args, "creates")
+	if _, ok := args["include"]; ok {
+		if ua.filter.include, err = variable.StringSliceVar(vars, args, "include"); err != nil {
+			return nil, errors.New("\"include\" in args should be string slice")
+		}
+	}
+	if _, ok := args["exclude"]; ok {
+		if ua.filter.exclude, err = variable.StringSliceVar(vars, args, "exclude"); err != nil {
+			return nil, errors.New("\"exclude\" in args should be string slice")
+		}
+	}
+	if _, ok := args["strip_components"]; ok {
+		strip, err := variable.IntVar(vars, args, "strip_components")
+		if err != nil || *strip < 0 {
+			return nil, errors.New("\"strip_components\" in args should be a non-negative int")
+		}
+		ua.filter.stripComponents = *strip
+	}
+	ua.owner, _ = variable.StringVar(vars, args, "owner")
+	ua.group, _ = variable.StringVar(vars, args, "group")
+	if ua.filter.mode, err = internal.ModeArg(vars, args, "mode"); err != nil {
+		return nil, err
+	}
+
+	return ua, nil
+}
+
+// ModuleUnarchive handles the "unarchive" module, extracting an archive to a directory on remote hosts.
+func ModuleUnarchive(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	ua, err := newUnarchiveArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	// get connector
+	conn, err := opts.GetConnector(ctx)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetConnector, err
+	}
+	defer conn.Close(ctx)
+
+	result := &unarchiveResult{Dest: ua.dest, Files: []string{}}
+	if ua.creates != "" {
+		st, err := internal.StatRemotePath(ctx, conn, ua.creates, false)
+		if err != nil {
+			return internal.StdoutFailed, "failed to stat creates", err
+		}
+		if st.Exists {
+			result.Msg = fmt.Sprintf("skipped, %q exists", ua.creates)
+
+			return internal.MarshalResult(result)
+		}
+	}
+
+	var files []string
+	var changed bool
+	if ua.remoteSrc {
+		if err := ua.verifyRemoteChecksum(ctx, conn); err != nil {
+			return internal.StdoutFailed, "failed to verify checksum", err
+		}
+		files, changed, err = ua.extractRemote(ctx, conn)
+	} else {
+		if err := ua.verifyChecksum(ua.src); err != nil {
+			return internal.StdoutFailed, "failed to verify checksum", err
+		}
+		files, changed, err = ua.extract(ctx, conn, ua.src)
+	}
+	if err != nil {
+		return internal.StdoutFailed, "failed to extract archive", err
+	}
+	if len(files) == 0 {
+		result.Msg = "no file to extract"
+
+		return internal.MarshalResult(result)
+	}
+	result.Changed, result.Files = changed, files
+
+	return internal.MarshalResult(result)
+}
+
+// verifyChecksum checks the checksum of the archive when it is set.
+func (ua unarchiveArgs) verifyChecksum(src string) error {
+	if ua.checksum == "" {
+		return nil
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open archive %q", src)
+	}
+	defer file.Close()
+	h := checksumHashes[ua.checksumAlgorithm]()
+	if _, err := io.Copy(h, file); err != nil {
+		return errors.Wrapf(err, "failed to read archive %q", src)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != ua.checksum {
+		return errors.Errorf("%s checksum of %q is %s, expected %s", ua.checksumAlgorithm, ua.src, sum, ua.checksum)
+	}
+
+	return nil
+}
+
+// extract converts the archive, uploads it to the remote host and extracts it to dest.
+// It returns the extracted entries, and false when all of them are the same in dest.
+func (ua unarchiveArgs) extract(ctx context.Context, conn connector.Connector, src string) ([]string, bool, error) {
+	tmp, err := os.CreateTemp("", "kubekey-unarchive-*.tar.gz")
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to create temp file")
+	}
+	defer os.Remove(tmp.Name())
+	hdrs, err := convert(src, tmp, ua.filter)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || len(hdrs) == 0 {
+		return nil, false, err
+	}
+	files := make([]string, 0, len(hdrs))
+	for _, hdr := range hdrs {
+		files = append(files, hdr.Name)
+	}
+	if same, err := ua.sameInDest(ctx, conn, hdrs); err != nil || same {
+		return files, false, err
+	}
+
+	remote := "/tmp/kubekey-unarchive-" + rand.String(10) + ".tar.gz"
+	if err := upload(ctx, conn, tmp.Name(), remote); err != nil {
+		return nil, false, err
+	}
+	dest := internal.ShellQuote(ua.dest)
+	cmd := fmt.Sprintf("mkdir -p %[1]s && tar -xzf %[2]s -C %[1]s; rc=$?; rm -f %[2]s; exit $rc", dest, internal.ShellQuote(remote))
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to extract to %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+
+	return files, true, ua.chown(ctx, conn, files)
+}
+
+// upload uploads the local file to the remote path chunk by chunk, so the archive is not read into memory at once.
+func upload(ctx context.Context, conn connector.Connector, src, remote string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %q", src)
+	}
+	defer file.Close()
+	// use a relative path which is the same in PutFile and ExecuteCommand. See PutData.
+	part := ".kk.unarchive." + rand.String(10)
+	buf := make([]byte, uploadChunkSize)
+	redirect := ">"
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			if err := conn.PutFile(ctx, buf[:n], part, _const.PermFilePublic); err != nil {
+				return errors.Wrap(err, "failed to upload archive")
+			}
+			cmd := fmt.Sprintf("cat %[1]s %[2]s %[3]s; rc=$?; rm -f %[1]s; exit $rc", part, redirect, internal.ShellQuote(remote))
+			if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+				return errors.Wrapf(err, "failed to upload archive, stderr: %s", strings.TrimSpace(string(stderr)))
+			}
+			redirect = ">>"
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %q", src)
+		}
+	}
+}
+
+// sameInDest checks whether all the entries are the same in dest: regular files by type, size, mtime and mode,
+// symlinks by type and the length of target, and directories by type. The owner and group are checked when they are set.
+func (ua unarchiveArgs) sameInDest(ctx context.Context, conn connector.Connector, hdrs []*tar.Header) (bool, error) {
+	names := make([]string, 0, len(hdrs))
+	for _, hdr := range hdrs {
+		names = append(names, internal.ShellQuote(hdr.Name))
+	}
+	// the name is the last, which may have spaces.
+	cmd := fmt.Sprintf("cd %s 2>/dev/null && stat -c '%%s %%Y %%a %%u %%U %%g %%G|%%F|%%n' -- %s 2>/dev/null; true",
+		internal.ShellQuote(ua.dest), strings.Join(names, " "))
+	stdout, stderr, err := conn.ExecuteCommand(ctx, cmd)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to stat entries in %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+	stats := make(map[string][]string)
+	for _, line := range strings.Split(string(stdout), "\n") {
+		parts := strings.SplitN(line, "|", 3)
+		if fields := strings.Fields(parts[0]); len(parts) == 3 && len(fields) == 7 {
+			stats[parts[2]] = append(fields, parts[1])
+		}
+	}
+	for _, hdr := range hdrs {
+		st, ok := stats[hdr.Name]
+		if !ok || !ua.sameEntry(hdr, st) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// sameEntry checks the entry with the stat fields: size, mtime, mode, uid, user, gid, group and type.
+func (ua unarchiveArgs) sameEntry(hdr *tar.Header, st []string) bool {
+	if ua.owner != "" && ua.owner != st[3] && ua.owner != st[4] {
+		return false
+	}
+	if ua.group != "" && ua.group != st[5] && ua.group != st[6] {
+		return false
+	}
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return st[7] == "directory"
+	case tar.TypeSymlink:
+		return st[7] == "symbolic link" && st[0] == strconv.Itoa(len(hdr.Linkname))
+	case tar.TypeLink:
+		return st[7] == "regular file" || st[7] == "regular empty file"
+	default:
+		return (st[7] == "regular file" || st[7] == "regular empty file") && st[0] == strconv.FormatInt(hdr.Size, 10) &&
+			st[1] == strconv.FormatInt(hdr.ModTime.Unix(), 10) && st[2] == strconv.FormatInt(hdr.Mode&0o7777, 8)
+	}
+}
+
+// chown changes the owner and group of the top level entries recursively when they are set.
+func (ua unarchiveArgs) chown(ctx context.Context, conn connector.Connector, files []string) error {
+	if ua.owner == "" && ua.group == "" {
+		return nil
+	}
+	owner := ua.owner
+	if ua.group != "" {
+		owner += ":" + ua.group
+	}
+	var tops []string
+	for _, f := range files {
+		top, _, _ := strings.Cut(f, "/")
+		if !slices.Contains(tops, top) {
+			tops = append(tops, top)
+		}
+	}
+	for i := range tops {
+		tops[i] = internal.ShellQuote(tops[i])
+	}
+	cmd := fmt.Sprintf("cd %s && chown -R -h %s %s", internal.ShellQuote(ua.dest), internal.ShellQuote(owner), strings.Join(tops, " "))
+	if _, stderr, err := conn.ExecuteCommand(ctx, cmd); err != nil {
+		return errors.Wrapf(err, "failed to change owner in %q, stderr: %s", ua.dest, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
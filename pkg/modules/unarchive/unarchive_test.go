@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unarchive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+func TestUnarchiveArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *unarchiveArgs
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			args:   map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/usr/local/bin"},
+			except: &unarchiveArgs{src: "/tmp/helm.tar.gz", dest: "/usr/local/bin"},
+		},
+		{
+			name: "all arguments",
+			args: map[string]any{
+				"src": "helm.tar.gz", "dest": "/usr/local/bin", "remote_src": true, "checksum": "sha512:ABC",
+				"creates": "/usr/local/bin/helm", "include": []string{"*/helm"}, "exclude": []string{"*.md"},
+				"strip_components": 1, "owner": "root", "group": "root", "mode": "0755",
+			},
+			except: &unarchiveArgs{
+				src: "helm.tar.gz", dest: "/usr/local/bin", remoteSrc: true, checksumAlgorithm: "sha512", checksum: "abc",
+				creates: "/usr/local/bin/helm", owner: "root", group: "root",
+				filter: filter{include: []string{"*/helm"}, exclude: []string{"*.md"}, stripComponents: 1, mode: ptr.To(fs.FileMode(0o755))},
+			},
+		},
+		{
+			name:   "checksum without algorithm",
+			args:   map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/usr/local/bin", "checksum": "abc"},
+			except: &unarchiveArgs{src: "/tmp/helm.tar.gz", dest: "/usr/local/bin", checksumAlgorithm: "sha256", checksum: "abc"},
+		},
+		{
+			name:    "relative local src",
+			args:    map[string]any{"src": "helm.tar.gz", "dest": "/usr/local/bin"},
+			wantErr: true,
+		},
+		{
+			name:    "relative dest",
+			args:    map[string]any{"src": "/tmp/helm.tar.gz", "dest": "bin"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported checksum algorithm",
+			args:    map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/usr/local/bin", "checksum": "crc32:abc"},
+			wantErr: true,
+		},
+		{
+			name:    "negative strip components",
+			args:    map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/usr/local/bin", "strip_components": -1},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ua, err := newUnarchiveArgs(context.Background(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, ua)
+		})
+	}
+}
+
+func TestModuleUnarchive(t *testing.T) {
+	src := createArchive(t, "tar.xz", testEntries)
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		files          map[string]string
+		exceptStdout   string
+		exceptExecuted []string
+	}{
+		{
+			name: "extract local archive",
+			args: map[string]any{"src": src, "dest": "/usr/local/bin", "strip_components": 1, "include": []string{"helm"},
+				"owner": "root", "checksum": hex.EncodeToString(sum[:])},
+			exceptStdout: `{"changed":true,"dest":"/usr/local/bin","files":["helm"]}`,
+			exceptExecuted: []string{"cd '/usr/local/bin' 2>/dev/null && stat -c", "cat .kk.unarchive.",
+				"mkdir -p '/usr/local/bin' && tar -xzf", "cd '/usr/local/bin' && chown -R -h 'root' 'helm'"},
+		},
+		{
+			name:         "creates exists",
+			args:         map[string]any{"src": src, "dest": "/usr/local/bin", "creates": "/usr/local/bin/helm"},
+			files:        map[string]string{"/usr/local/bin/helm": "helm binary"},
+			exceptStdout: `{"changed":false,"dest":"/usr/local/bin","files":[],"msg":"skipped, \"/usr/local/bin/helm\" exists"}`,
+		},
+		{
+			name:         "nothing matched",
+			args:         map[string]any{"src": src, "dest": "/usr/local/bin", "include": []string{"kubectl"}},
+			exceptStdout: `{"changed":false,"dest":"/usr/local/bin","files":[],"msg":"no file to extract"}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := internal.NewTestFileConnector(tc.files)
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			stdout, _, err := ModuleUnarchive(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.exceptStdout, stdout)
+			require.Len(t, conn.Executed, len(tc.exceptExecuted))
+			for i, prefix := range tc.exceptExecuted {
+				assert.True(t, strings.HasPrefix(conn.Executed[i], prefix), conn.Executed[i])
+			}
+		})
+	}
+}
+
+func TestModuleUnarchiveChecksumMismatch(t *testing.T) {
+	conn := internal.NewTestFileConnector(nil)
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	stdout, stderr, err := ModuleUnarchive(ctx, internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(map[string]any{"src": createArchive(t, "zip", testEntries), "dest": "/opt", "checksum": "md5:0123"}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.Error(t, err)
+	assert.Equal(t, internal.StdoutFailed, stdout)
+	assert.Equal(t, "failed to verify checksum", stderr)
+	assert.Empty(t, conn.Executed)
+}
+
+// fakeRemote returns the output of the first command prefix which matches, and records the commands.
+type fakeRemote struct {
+	internal.TestFileConnector
+	outputs [][2]string
+}
+
+func (f *fakeRemote) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	f.Executed = append(f.Executed, cmd)
+	for _, o := range f.outputs {
+		if strings.HasPrefix(cmd, o[0]) {
+			return []byte(o[1]), nil, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+func TestModuleUnarchiveSame(t *testing.T) {
+	conn := &fakeRemote{outputs: [][2]string{{"cd '/usr/local/bin' 2>/dev/null && stat -c", "11 0 644 0 root 0 root|regular file|helm\n"}}}
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	stdout, _, err := ModuleUnarchive(ctx, internal.ExecOptions{
+		Host: "node1",
+		Args: createRawArgs(map[string]any{"src": createArchive(t, "tar.gz", testEntries), "dest": "/usr/local/bin",
+			"strip_components": 1, "include": []string{"helm"}, "owner": "root"}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"changed":false,"dest":"/usr/local/bin","files":["helm"]}`, stdout)
+	assert.Len(t, conn.Executed, 1)
+}
+
+func TestModuleUnarchiveRemote(t *testing.T) {
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		outputs        [][2]string
+		exceptStdout   string
+		exceptExecuted []string
+	}{
+		{
+			name: "extract tar",
+			args: map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/opt/helm", "strip_components": 1, "exclude": []string{"licenses"},
+				"checksum": "sha256:ABC"},
+			outputs: [][2]string{
+				{"sha256sum", "abc  /tmp/helm.tar.gz\n"}, {"od ", " 1f 8b 08 00\n"},
+				{"tar -tf", "helm\nREADME.md\n"}, {"tar -df", "helm: Mod time differs\nhelm: Uid differs\n"},
+			},
+			exceptStdout: `{"changed":true,"dest":"/opt/helm","files":["helm","README.md"]}`,
+			exceptExecuted: []string{"sha256sum '/tmp/helm.tar.gz'", "od -An -tx1 -N4 '/tmp/helm.tar.gz'",
+				"tar -tf '/tmp/helm.tar.gz' --strip-components=1 --show-transformed-names --anchored --wildcards --no-wildcards-match-slash '--exclude=*/licenses'",
+				"tar -df '/tmp/helm.tar.gz' -C '/opt/helm' --strip-components=1",
+				"mkdir -p '/opt/helm' && tar -xf '/tmp/helm.tar.gz' -C '/opt/helm' --no-same-owner --strip-components=1"},
+		},
+		{
+			name:           "same tar",
+			args:           map[string]any{"src": "/tmp/helm.tar.gz", "dest": "/opt/helm"},
+			outputs:        [][2]string{{"od ", " 1f 8b 08 00\n"}, {"tar -tf", "linux-amd64/\nlinux-amd64/helm\n"}, {"tar -df", "linux-amd64/helm: Uid differs\n"}},
+			exceptStdout:   `{"changed":false,"dest":"/opt/helm","files":["linux-amd64","linux-amd64/helm"]}`,
+			exceptExecuted: []string{"od ", "tar -tf '/tmp/helm.tar.gz' ", "tar -df '/tmp/helm.tar.gz' -C '/opt/helm' "},
+		},
+		{
+			name: "extract zip",
+			args: map[string]any{"src": "/tmp/harbor.zip", "dest": "/opt", "include": []string{"harbor"}, "mode": "0755"},
+			outputs: [][2]string{
+				{"od ", " 50 4b 03 04\n"}, {"unzip -Z1", "harbor/\nharbor/install.sh\n"},
+				{"mkdir -p '/opt' && unzip", "Archive:  /tmp/harbor.zip\n  inflating: /opt/harbor/install.sh\n"},
+			},
+			exceptStdout: `{"changed":true,"dest":"/opt","files":["harbor","harbor/install.sh"]}`,
+			exceptExecuted: []string{"od ", "unzip -Z1 '/tmp/harbor.zip' 'harbor' 'harbor/*'", "mkdir -p '/opt' && unzip -o -u '/tmp/harbor.zip' 'harbor' 'harbor/*' -d '/opt'",
+				"cd '/opt' && find 'harbor' 'harbor/install.sh' -maxdepth 0 -type f -exec chmod 755 {} +"},
+		},
+		{
+			name:           "same zip",
+			args:           map[string]any{"src": "/tmp/harbor.zip", "dest": "/opt"},
+			outputs:        [][2]string{{"od ", " 50 4b 03 04\n"}, {"unzip -Z1", "harbor/install.sh\n"}, {"mkdir -p '/opt' && unzip", "Archive:  /tmp/harbor.zip\n"}},
+			exceptStdout:   `{"changed":false,"dest":"/opt","files":["harbor/install.sh"]}`,
+			exceptExecuted: []string{"od ", "unzip -Z1 ", "mkdir -p '/opt' && unzip "},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &fakeRemote{outputs: tc.outputs}
+			ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+			tc.args["remote_src"] = true
+			stdout, _, err := ModuleUnarchive(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.exceptStdout, stdout)
+			require.Len(t, conn.Executed, len(tc.exceptExecuted))
+			for i, prefix := range tc.exceptExecuted {
+				assert.True(t, strings.HasPrefix(conn.Executed[i], prefix), conn.Executed[i])
+			}
+		})
+	}
+}
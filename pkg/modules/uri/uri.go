@@ -0,0 +1,518 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uri
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/converter/tmpl"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The URI module sends an HTTP request and checks the status code of the response.
+The request is sent from the target host by curl through the connector, or from the controller which runs kk.
+
+Configuration:
+Users can specify the request and the expected response:
+
+uri:
+  url: https://harbor.local/api/v2.0/projects # required: the url of the request
+  method: POST              # optional: the http method (default: GET)
+  headers:                  # optional: request headers
+    X-Request-Id: kubekey
+  body:                     # optional: request body, a string or an object
+    project_name: kubesphere
+  body_format: json         # optional: json, form or raw. An object body defaults to json (default: raw)
+  status_code: [200, 201]   # optional: expected status codes (default: 200)
+  username: admin           # optional: basic auth username
+  password: Harbor12345     # optional: basic auth password
+  token: xxx                # optional: bearer token
+  validate_certs: true      # optional: verify the server certificate (default: true)
+  ca_path: /etc/ssl/ca.crt  # optional: CA certificate to verify the server
+  client_cert: /path/to/client.crt # optional: client certificate
+  client_key: /path/to/client.key  # optional: client key
+  follow_redirects: true    # optional: follow redirects (default: true)
+  timeout: 30s              # optional: request timeout, in seconds or a duration (default: 30s)
+  from: target              # optional: send from the "target" host or the "controller" (default: target)
+
+Usage Examples in Playbook Tasks:
+1. Create a Harbor project:
+   ```yaml
+   - name: Create project
+     uri:
+       url: "https://{{ .image_registry.auth.registry }}/api/v2.0/projects"
+       method: POST
+       username: "{{ .image_registry.auth.username }}"
+       password: "{{ .image_registry.auth.password }}"
+       body:
+         project_name: kubesphereio
+         public: true
+       status_code: [201, 409]
+       validate_certs: false
+   ```
+
+2. Check a health endpoint:
+   ```yaml
+   - name: Check kube-apiserver health
+     uri:
+       url: https://127.0.0.1:6443/readyz
+       validate_certs: false
+     register: readyz
+     register_type: json
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "status", "url", "content_type", "content" and the "json" body
+- On failure: Returns error message in stderr
+*/
+
+const (
+	bodyFormatJSON = "json"
+	bodyFormatForm = "form"
+	bodyFormatRaw  = "raw"
+
+	fromTarget     = "target"
+	fromController = "controller"
+
+	// defaultTimeout is the default timeout of the request.
+	defaultTimeout = 30 * time.Second
+)
+
+// uriArgs holds the arguments for the uri module.
+type uriArgs struct {
+	url             string            // The url of the request
+	method          string            // The http method
+	headers         map[string]string // Request headers
+	body            []byte            // Request body
+	statusCode      []int             // Expected status codes
+	validateCerts   bool              // Verify the server certificate
+	caPath          string            // CA certificate to verify the server
+	clientCert      string            // Client certificate
+	clientKey       string            // Client key
+	followRedirects bool              // Follow redirects
+	timeout         time.Duration     // Request timeout
+	from            string            // target or controller
+}
+
+// uriResponse is the response of the request.
+type uriResponse struct {
+	status      int
+	url         string
+	contentType string
+	body        []byte
+}
+
+// uriResult is the output of the uri module.
+type uriResult struct {
+	Changed     bool   `json:"changed"`
+	Status      int    `json:"status"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+	JSON        any    `json:"json,omitempty"`
+}
+
+// newURIArgs parses and validates the arguments for the uri module.
+func newURIArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*uriArgs, error) {
+	var err error
+	ua := &uriArgs{headers: make(map[string]string), validateCerts: true, followRedirects: true, from: fromTarget}
+	args := variable.Extension2Variables(raw)
+	ua.url, err = variable.StringVar(vars, args, "url")
+	if err != nil || ua.url == "" {
+		return nil, errors.New("\"url\" in args should be string")
+	}
+	if u, err := url.Parse(ua.url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.Errorf("\"url\" %q should be a http or https url", ua.url)
+	}
+	ua.method, _ = variable.StringVar(vars, args, "method")
+	ua.method = strings.ToUpper(ua.method)
+	if ua.method == "" {
+		ua.method = http.MethodGet
+	}
+	if headers, ok := args["headers"].(map[string]any); ok {
+		for k := range headers {
+			if ua.headers[http.CanonicalHeaderKey(k)], err = variable.StringVar(vars, headers, k); err != nil {
+				return nil, errors.Errorf("header %q in args should be string", k)
+			}
+		}
+	}
+	if err := ua.parseBody(vars, args); err != nil {
+		return nil, err
+	}
+	if ua.statusCode, err = statusCodeArg(vars, args); err != nil {
+		return nil, err
+	}
+	username, _ := variable.StringVar(vars, args, "username")
+	password, _ := variable.StringVar(vars, args, "password")
+	token, _ := variable.StringVar(vars, args, "token")
+	_, hasAuthorization := ua.headers["Authorization"]
+	switch {
+	case username != "" && token != "":
+		return nil, errors.New("\"username\" and \"token\" are mutually exclusive")
+	case (username != "" || token != "") && hasAuthorization:
+		return nil, errors.New("\"username\" or \"token\" conflicts with the \"Authorization\" header")
+	case username != "":
+		ua.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	case token != "":
+		ua.headers["Authorization"] = "Bearer " + token
+	}
+	if ua.validateCerts, err = internal.BoolArg(vars, args, "validate_certs", true); err != nil {
+		return nil, err
+	}
+	if ua.followRedirects, err = internal.BoolArg(vars, args, "follow_redirects", true); err != nil {
+		return nil, err
+	}
+	ua.caPath, _ = variable.StringVar(vars, args, "ca_path")
+	ua.clientCert, _ = variable.StringVar(vars, args, "client_cert")
+	ua.clientKey, _ = variable.StringVar(vars, args, "client_key")
+	if (ua.clientCert == "") != (ua.clientKey == "") {
+		return nil, errors.New("\"client_cert\" and \"client_key\" should be set together")
+	}
+	if ua.timeout, err = internal.DurationArg(vars, args, "timeout", defaultTimeout); err != nil {
+		return nil, err
+	}
+	if ua.timeout == 0 {
+		return nil, errors.New("\"timeout\" in args should be greater than 0")
+	}
+	if from, _ := variable.StringVar(vars, args, "from"); from != "" {
+		if from != fromTarget && from != fromController {
+			return nil, errors.Errorf("\"from\" should be %q or %q, got %q", fromTarget, fromController, from)
+		}
+		ua.from = from
+	}
+
+	return ua, nil
+}
+
+// parseBody renders the body and encodes it by body_format, and sets the default Content-Type header.
+func (ua *uriArgs) parseBody(vars, args map[string]any) error {
+	body, ok := args["body"]
+	if !ok || body == nil {
+		return nil
+	}
+	body, err := renderAny(vars, body)
+	if err != nil {
+		return err
+	}
+	format, _ := variable.StringVar(vars, args, "body_format")
+	if format == "" {
+		format = bodyFormatRaw
+		if _, isString := body.(string); !isString {
+			format = bodyFormatJSON
+		}
+	}
+	contentType := ""
+	switch format {
+	case bodyFormatJSON:
+		contentType = "application/json"
+		if s, isString := body.(string); isString {
+			ua.body = []byte(s)
+		} else if ua.body, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "failed to marshal json body")
+		}
+	case bodyFormatForm:
+		contentType = "application/x-www-form-urlencoded"
+		fields, isMap := body.(map[string]any)
+		if !isMap {
+			return errors.New("\"body\" should be an object when body_format is form")
+		}
+		values := url.Values{}
+		for k, v := range fields {
+			values.Set(k, fmt.Sprint(v))
+		}
+		ua.body = []byte(values.Encode())
+	case bodyFormatRaw:
+		s, isString := body.(string)
+		if !isString {
+			return errors.New("\"body\" should be string when body_format is raw")
+		}
+		ua.body = []byte(s)
+	default:
+		return errors.Errorf("unsupported body_format %q, should be one of json, form or raw", format)
+	}
+	if _, ok := ua.headers["Content-Type"]; !ok && contentType != "" {
+		ua.headers["Content-Type"] = contentType
+	}
+
+	return nil
+}
+
+// renderAny renders the templates in the string values of the body.
+func renderAny(vars map[string]any, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return tmpl.ParseFunc(vars, v, tmpl.StringFunc)
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for k, item := range v {
+			r, err := renderAny(vars, item)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = r
+		}
+
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(v))
+		for i, item := range v {
+			r, err := renderAny(vars, item)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = r
+		}
+
+		return rendered, nil
+	default:
+		return v, nil
+	}
+}
+
+// statusCodeArg returns the expected status codes, which is a code or a list of codes.
+func statusCodeArg(vars, args map[string]any) ([]int, error) {
+	val, ok := args["status_code"]
+	if !ok {
+		return []int{http.StatusOK}, nil
+	}
+	items, isList := val.([]any)
+	if !isList {
+		items = []any{val}
+	}
+	codes := make([]int, 0, len(items))
+	for _, item := range items {
+		code, err := variable.IntVar(vars, map[string]any{"code": item}, "code")
+		if err != nil || *code < 100 || *code > 599 {
+			return nil, errors.Errorf("\"status_code\" should be http status codes, got %v", item)
+		}
+		codes = append(codes, *code)
+	}
+
+	return codes, nil
+}
+
+// ModuleURI handles the "uri" module, sending an HTTP request from the target host or the controller.
+func ModuleURI(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	ua, err := newURIArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	var resp *uriResponse
+	if ua.from == fromController {
+		resp, err = ua.doController(ctx)
+	} else {
+		// get connector
+		conn, err := opts.GetConnector(ctx)
+		if err != nil {
+			return internal.StdoutFailed, internal.StderrGetConnector, err
+		}
+		defer conn.Close(ctx)
+		resp, err = ua.doTarget(ctx, conn)
+	}
+	if err != nil {
+		return internal.StdoutFailed, "failed to send request", err
+	}
+	if !slices.Contains(ua.statusCode, resp.status) {
+		return internal.StdoutFailed, "unexpected status code", errors.Errorf("status code %d is not in %v, body: %s", resp.status, ua.statusCode, truncate(resp.body, 512))
+	}
+
+	result := uriResult{
+		Changed:     ua.method != http.MethodGet && ua.method != http.MethodHead && ua.method != http.MethodOptions,
+		Status:      resp.status,
+		URL:         resp.url,
+		ContentType: resp.contentType,
+		Content:     string(resp.body),
+	}
+	if len(bytes.TrimSpace(resp.body)) > 0 && (strings.Contains(resp.contentType, "json") || json.Valid(resp.body)) {
+		if err := json.Unmarshal(resp.body, &result.JSON); err != nil {
+			return internal.StdoutFailed, "failed to parse json body", err
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal uri result", err
+	}
+
+	return string(data), "", nil
+}
+
+// truncate returns the body for error messages.
+func truncate(body []byte, size int) string {
+	if len(body) <= size {
+		return string(body)
+	}
+
+	return string(body[:size]) + "..."
+}
+
+// doController sends the request by the http client of the controller.
+func (ua uriArgs) doController(ctx context.Context) (*uriResponse, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: !ua.validateCerts} //nolint:gosec // disabled by validate_certs
+	if ua.caPath != "" {
+		ca, err := os.ReadFile(ua.caPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read ca %q", ua.caPath)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificate found in ca %q", ua.caPath)
+		}
+	}
+	if ua.clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(ua.clientCert, ua.clientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client := &http.Client{
+		Timeout:   ua.timeout,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+	}
+	if !ua.followRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+
+	req, err := http.NewRequestWithContext(ctx, ua.method, ua.url, bytes.NewReader(ua.body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	for k, v := range ua.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request %q", ua.url)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return &uriResponse{status: resp.StatusCode, url: resp.Request.URL.String(), contentType: resp.Header.Get("Content-Type"), body: body}, nil
+}
+
+// doTarget sends the request by curl in the target host. The headers and the body may have credentials, so they are
+// uploaded as a curl config file and a data file instead of being on the command line, and removed after the request.
+// The status, url and content type are written after the response body.
+func (ua uriArgs) doTarget(ctx context.Context, conn connector.Connector) (*uriResponse, error) {
+	// use a relative path which is the same in PutFile and ExecuteCommand. See PutData.
+	name := ".kk.uri." + rand.String(10)
+	var files []string
+	defer func() {
+		if len(files) == 0 {
+			return
+		}
+		if _, stderr, err := conn.ExecuteCommand(ctx, "rm -f "+strings.Join(files, " ")); err != nil {
+			klog.V(4).ErrorS(err, "failed to remove uri request files", "stderr", string(stderr))
+		}
+	}()
+	put := func(content []byte, file string) error {
+		if err := conn.PutFile(ctx, content, file, 0o600); err != nil {
+			return errors.Wrapf(err, "failed to upload %q", file)
+		}
+		files = append(files, "./"+file)
+
+		return nil
+	}
+
+	cmd := []string{"curl", "-sS", "-X", internal.ShellQuote(ua.method), "--max-time", strconv.Itoa(max(int(ua.timeout.Seconds()), 1))}
+	if len(ua.headers) > 0 {
+		headers := make([]string, 0, len(ua.headers))
+		for k, v := range ua.headers {
+			headers = append(headers, "header = "+curlConfigQuote(k+": "+v)+"\n")
+		}
+		slices.Sort(headers)
+		if err := put([]byte(strings.Join(headers, "")), name+".conf"); err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, "-K", "./"+name+".conf")
+	}
+	if !ua.validateCerts {
+		cmd = append(cmd, "-k")
+	}
+	if ua.caPath != "" {
+		cmd = append(cmd, "--cacert", internal.ShellQuote(ua.caPath))
+	}
+	if ua.clientCert != "" {
+		cmd = append(cmd, "--cert", internal.ShellQuote(ua.clientCert), "--key", internal.ShellQuote(ua.clientKey))
+	}
+	if ua.followRedirects {
+		cmd = append(cmd, "-L")
+	}
+	if ua.body != nil {
+		if err := put(ua.body, name+".body"); err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, "--data-binary", "@./"+name+".body")
+	}
+	cmd = append(cmd, "-w", internal.ShellQuote(`\n%{http_code} %{url_effective} %{content_type}`), internal.ShellQuote(ua.url))
+	command := strings.Join(cmd, " ")
+
+	stdout, stderr, err := conn.ExecuteCommand(ctx, command)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request %q, stderr: %s", ua.url, strings.TrimSpace(string(stderr)))
+	}
+	// the body is empty when there is no newline.
+	i := bytes.LastIndexByte(stdout, '\n')
+	fields := strings.SplitN(strings.TrimRight(string(stdout[i+1:]), "\r\n"), " ", 3)
+	status, err := strconv.Atoi(fields[0])
+	if err != nil || len(fields) < 2 {
+		return nil, errors.Errorf("unexpected output of curl: %q", truncate(stdout[i+1:], 512))
+	}
+	resp := &uriResponse{status: status, url: fields[1], body: stdout[:max(i, 0)]}
+	if len(fields) == 3 {
+		resp.contentType = fields[2]
+	}
+
+	return resp, nil
+}
+
+// curlConfigQuote quotes the value for a curl config file.
+func curlConfigQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + `"`
+}
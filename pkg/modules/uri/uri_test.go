@@ -0,0 +1,269 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uri
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakeCurl returns the preset output of curl and records the commands and the uploaded files.
+type fakeCurl struct {
+	stdout   string
+	executed []string
+	files    map[string]string
+}
+
+func (f *fakeCurl) Init(context.Context) error { return nil }
+
+func (f *fakeCurl) Close(context.Context) error { return nil }
+
+func (f *fakeCurl) PutFile(_ context.Context, src []byte, dst string, _ fs.FileMode) error {
+	if f.files == nil {
+		f.files = make(map[string]string)
+	}
+	f.files[dst] = string(src)
+
+	return nil
+}
+
+func (f *fakeCurl) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakeCurl) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	f.executed = append(f.executed, cmd)
+
+	return []byte(f.stdout), nil, nil
+}
+
+func TestURIArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *uriArgs
+		wantErr bool
+	}{
+		{
+			name: "default",
+			args: map[string]any{"url": "http://127.0.0.1/healthz"},
+			except: &uriArgs{url: "http://127.0.0.1/healthz", method: http.MethodGet, headers: map[string]string{}, statusCode: []int{200},
+				validateCerts: true, followRedirects: true, timeout: defaultTimeout, from: fromTarget},
+		},
+		{
+			name: "json body with basic auth",
+			args: map[string]any{"url": "https://harbor.local/api/v2.0/projects", "method": "post", "body": map[string]any{"project_name": "{{ .name }}"},
+				"status_code": []any{201, "409"}, "username": "admin", "password": "pass", "validate_certs": false, "timeout": 10, "from": "controller"},
+			except: &uriArgs{url: "https://harbor.local/api/v2.0/projects", method: http.MethodPost,
+				headers: map[string]string{"Content-Type": "application/json", "Authorization": "Basic YWRtaW46cGFzcw=="},
+				body:    []byte(`{"project_name":"kubesphere"}`), statusCode: []int{201, 409}, followRedirects: true, timeout: 10 * time.Second, from: fromController},
+		},
+		{
+			name: "form body",
+			args: map[string]any{"url": "http://127.0.0.1/form", "method": "PUT", "body": map[string]any{"a": "1", "b": 2}, "body_format": "form",
+				"headers": map[string]any{"content-type": "application/x-www-form-urlencoded; charset=utf-8"}, "status_code": 204, "timeout": "1m"},
+			except: &uriArgs{url: "http://127.0.0.1/form", method: http.MethodPut, headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+				body: []byte("a=1&b=2"), statusCode: []int{204}, validateCerts: true, followRedirects: true, timeout: time.Minute, from: fromTarget},
+		},
+		{
+			name:    "missing url",
+			args:    map[string]any{"method": "GET"},
+			wantErr: true,
+		},
+		{
+			name:    "token with authorization header",
+			args:    map[string]any{"url": "http://127.0.0.1", "token": "abc", "headers": map[string]any{"authorization": "Basic YTpi"}},
+			wantErr: true,
+		},
+		{
+			name:    "username with token",
+			args:    map[string]any{"url": "http://127.0.0.1", "username": "admin", "token": "abc"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			args:    map[string]any{"url": "ftp://127.0.0.1/file"},
+			wantErr: true,
+		},
+		{
+			name:    "object body with raw format",
+			args:    map[string]any{"url": "http://127.0.0.1", "body": map[string]any{"a": 1}, "body_format": "raw"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid status code",
+			args:    map[string]any{"url": "http://127.0.0.1", "status_code": []any{"ok"}},
+			wantErr: true,
+		},
+		{
+			name:    "client cert without key",
+			args:    map[string]any{"url": "https://127.0.0.1", "client_cert": "/etc/client.crt"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ua, err := newURIArgs(context.TODO(), createRawArgs(tc.args), map[string]any{"name": "kubesphere"})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, ua)
+		})
+	}
+}
+
+func TestModuleURIController(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/projects", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || string(body) != `{"name":"kubesphere"}` {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/healthz", http.StatusFound)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	testcases := []struct {
+		name      string
+		args      map[string]any
+		except    uriResult
+		exceptErr string
+	}{
+		{
+			name: "post json with ca",
+			args: map[string]any{"url": server.URL + "/api/projects", "method": "POST", "body": map[string]any{"name": "kubesphere"},
+				"username": "admin", "password": "pass", "status_code": 201, "ca_path": caPath},
+			except: uriResult{Changed: true, Status: http.StatusCreated, URL: server.URL + "/api/projects", ContentType: "application/json",
+				Content: `{"id":1}`, JSON: map[string]any{"id": float64(1)}},
+		},
+		{
+			name: "follow redirects without validate certs",
+			args: map[string]any{"url": server.URL + "/redirect", "validate_certs": false},
+			except: uriResult{Status: http.StatusOK, URL: server.URL + "/healthz", ContentType: "text/plain; charset=utf-8",
+				Content: "ok"},
+		},
+		{
+			name:   "not follow redirects",
+			args:   map[string]any{"url": server.URL + "/redirect", "validate_certs": false, "follow_redirects": false, "status_code": 302},
+			except: uriResult{Status: http.StatusFound, URL: server.URL + "/redirect", ContentType: "text/html; charset=utf-8", Content: "<a href=\"/healthz\">Found</a>.\n\n"},
+		},
+		{
+			name:      "unexpected status code",
+			args:      map[string]any{"url": server.URL + "/api/projects", "method": "POST", "validate_certs": false},
+			exceptErr: "status code 400 is not in [200]",
+		},
+		{
+			name:      "unknown certificate",
+			args:      map[string]any{"url": server.URL + "/healthz"},
+			exceptErr: "certificate",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["from"] = fromController
+			stdout, _, err := ModuleURI(context.Background(), internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err)
+			var result uriResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, tc.except, result)
+		})
+	}
+}
+
+func TestModuleURITarget(t *testing.T) {
+	conn := &fakeCurl{stdout: "{\"status\":\"ok\"}\n200 https://127.0.0.1:6443/readyz application/json"}
+	ctx := context.WithValue(context.Background(), internal.ConnKey, conn)
+	stdout, _, err := ModuleURI(ctx, internal.ExecOptions{
+		Host: "node1",
+		Args: createRawArgs(map[string]any{"url": "https://127.0.0.1:6443/readyz", "method": "PUT", "body": "{}", "body_format": "json",
+			"token": "abc", "ca_path": "/etc/kubernetes/pki/ca.crt", "timeout": 5}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.NoError(t, err)
+	var result uriResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, uriResult{Changed: true, Status: http.StatusOK, URL: "https://127.0.0.1:6443/readyz", ContentType: "application/json",
+		Content: `{"status":"ok"}`, JSON: map[string]any{"status": "ok"}}, result)
+	require.Len(t, conn.executed, 2)
+	require.Len(t, conn.files, 2)
+	var conf, body string
+	for name, content := range conn.files {
+		switch {
+		case strings.HasSuffix(name, ".conf"):
+			conf = name
+			assert.Equal(t, "header = \"Authorization: Bearer abc\"\nheader = \"Content-Type: application/json\"\n", content)
+		case strings.HasSuffix(name, ".body"):
+			body = name
+			assert.Equal(t, "{}", content)
+		}
+	}
+	assert.Equal(t, "curl -sS -X 'PUT' --max-time 5 -K ./"+conf+" --cacert '/etc/kubernetes/pki/ca.crt' -L --data-binary @./"+body+
+		" -w '\\n%{http_code} %{url_effective} %{content_type}' 'https://127.0.0.1:6443/readyz'", conn.executed[0])
+	assert.NotContains(t, conn.executed[0], "abc")
+	assert.Equal(t, "rm -f ./"+conf+" ./"+body, conn.executed[1])
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait_for
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/kubesphere/kubekey/v4/pkg/connector"
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+	"github.com/kubesphere/kubekey/v4/pkg/variable"
+)
+
+/*
+The WaitFor module waits until a TCP port is open or closed, or a file is present or absent, optionally containing a regex match.
+The condition is checked every "sleep" until it is met or "timeout" is exceeded. The task is cancelled with the playbook context.
+
+Configuration:
+Users can specify one of port or path:
+
+wait_for:
+  host: 127.0.0.1           # optional: the host of the port (default: 127.0.0.1)
+  port: 6443                # optional: the TCP port to check
+  path: /etc/kubernetes/admin.conf # optional: the file to check
+  search_regex: "started"   # optional: the regex to match in the file
+  state: started            # optional: started or stopped for port, present or absent for path (default: started)
+  from: target              # optional: check from the "target" host or the "controller" (default: target)
+  timeout: 300              # optional: max wait time, in seconds or a duration such as 5m (default: 300)
+  delay: 0                  # optional: wait time before the first check (default: 0)
+  sleep: 1                  # optional: wait time between checks (default: 1)
+  connect_timeout: 5        # optional: timeout of each TCP connection (default: 5)
+
+Usage Examples in Playbook Tasks:
+1. Wait for kube-apiserver:
+   ```yaml
+   - name: Wait for kube-apiserver
+     wait_for:
+       port: 6443
+       timeout: 5m
+   ```
+
+2. Wait for a log line:
+   ```yaml
+   - name: Wait for etcd ready
+     wait_for:
+       path: /var/log/etcd.log
+       search_regex: "ready to serve client requests"
+   ```
+
+Return Values:
+- On success: Returns a json object in stdout with "changed" (always false), the "elapsed" seconds and the "match" of search_regex
+- On failure: Returns error message in stderr
+*/
+
+const (
+	stateStarted = "started"
+	stateStopped = "stopped"
+	statePresent = "present"
+	stateAbsent  = "absent"
+
+	fromTarget     = "target"
+	fromController = "controller"
+)
+
+// waitForArgs holds the arguments for the wait_for module.
+type waitForArgs struct {
+	host           string         // The host of the port
+	port           int            // The TCP port to check
+	path           string         // The file to check
+	searchRegex    *regexp.Regexp // The regex to match in the file
+	state          string         // started, stopped, present or absent
+	from           string         // target or controller
+	timeout        time.Duration  // Max wait time
+	delay          time.Duration  // Wait time before the first check
+	sleep          time.Duration  // Wait time between checks
+	connectTimeout time.Duration  // Timeout of each TCP connection
+}
+
+// waitForResult is the output of the wait_for module.
+type waitForResult struct {
+	Changed bool    `json:"changed"`
+	Elapsed float64 `json:"elapsed"`
+	Match   string  `json:"match,omitempty"`
+}
+
+// newWaitForArgs parses and validates the arguments for the wait_for module.
+func newWaitForArgs(_ context.Context, raw runtime.RawExtension, vars map[string]any) (*waitForArgs, error) {
+	var err error
+	wa := &waitForArgs{host: "127.0.0.1", from: fromTarget}
+	args := variable.Extension2Variables(raw)
+	if host, _ := variable.StringVar(vars, args, "host"); host != "" {
+		// the host is used in the /dev/tcp path of the check script.
+		if net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(strings.ToLower(host))) > 0 {
+			return nil, errors.Errorf("\"host\" in args should be a hostname or an IP address, got %q", host)
+		}
+		wa.host = host
+	}
+	if _, ok := args["port"]; ok {
+		port, err := variable.IntVar(vars, args, "port")
+		if err != nil || *port <= 0 || *port > math.MaxUint16 {
+			return nil, errors.New("\"port\" in args should be a valid port")
+		}
+		wa.port = *port
+	}
+	wa.path, _ = variable.StringVar(vars, args, "path")
+	switch {
+	case wa.port == 0 && wa.path == "":
+		return nil, errors.New("one of \"port\" or \"path\" is required")
+	case wa.port != 0 && wa.path != "":
+		return nil, errors.New("\"port\" and \"path\" are mutually exclusive")
+	}
+	if exp, _ := variable.StringVar(vars, args, "search_regex"); exp != "" {
+		if wa.path == "" {
+			return nil, errors.New("\"search_regex\" is only supported with \"path\"")
+		}
+		if wa.searchRegex, err = regexp.Compile(exp); err != nil {
+			return nil, errors.Wrapf(err, "\"search_regex\" %q is invalid", exp)
+		}
+	}
+	wa.state, _ = variable.StringVar(vars, args, "state")
+	switch wa.state {
+	case "":
+		wa.state = stateStarted
+	case stateStarted, statePresent:
+		wa.state = stateStarted
+	case stateStopped, stateAbsent:
+		wa.state = stateStopped
+	default:
+		return nil, errors.Errorf("unsupported state %q, should be one of started, stopped, present or absent", wa.state)
+	}
+	if from, _ := variable.StringVar(vars, args, "from"); from != "" {
+		if from != fromTarget && from != fromController {
+			return nil, errors.Errorf("\"from\" should be %q or %q, got %q", fromTarget, fromController, from)
+		}
+		wa.from = from
+	}
+	for _, d := range []struct {
+		key   string
+		dest  *time.Duration
+		value time.Duration
+	}{
+		{key: "timeout", dest: &wa.timeout, value: 300 * time.Second},
+		{key: "delay", dest: &wa.delay},
+		{key: "sleep", dest: &wa.sleep, value: time.Second},
+		{key: "connect_timeout", dest: &wa.connectTimeout, value: 5 * time.Second},
+	} {
+		if *d.dest, err = internal.DurationArg(vars, args, d.key, d.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return wa, nil
+}
+
+// ModuleWaitFor handles the "wait_for" module, waiting for a port or a file on the target host or the controller.
+func ModuleWaitFor(ctx context.Context, opts internal.ExecOptions) (string, string, error) {
+	// get host variable
+	ha, err := opts.GetAllVariables()
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrGetHostVariable, err
+	}
+
+	wa, err := newWaitForArgs(ctx, opts.Args, ha)
+	if err != nil {
+		return internal.StdoutFailed, internal.StderrParseArgument, err
+	}
+
+	var conn connector.Connector
+	if wa.from == fromTarget {
+		// get connector
+		conn, err = opts.GetConnector(ctx)
+		if err != nil {
+			return internal.StdoutFailed, internal.StderrGetConnector, err
+		}
+		defer conn.Close(ctx)
+	}
+
+	start := time.Now()
+	match, err := wa.wait(ctx, conn)
+	result := waitForResult{Elapsed: math.Round(time.Since(start).Seconds()*1000) / 1000, Match: match}
+	if err != nil {
+		return internal.StdoutFailed, fmt.Sprintf("failed to wait after %.3fs", result.Elapsed), err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return internal.StdoutFailed, "failed to marshal wait_for result", err
+	}
+
+	return string(data), "", nil
+}
+
+// wait checks the condition every sleep until it is met, timeout is exceeded or ctx is done.
+// It returns the match of search_regex.
+func (wa waitForArgs) wait(ctx context.Context, conn connector.Connector) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wa.timeout)
+	defer cancel()
+
+	if err := sleepContext(ctx, wa.delay); err != nil {
+		return "", errors.Wrapf(err, "failed to wait for %s", wa)
+	}
+	for {
+		met, match, err := wa.check(ctx, conn)
+		if err != nil {
+			return "", err
+		}
+		if met {
+			return match, nil
+		}
+		if err := sleepContext(ctx, wa.sleep); err != nil {
+			return "", errors.Wrapf(err, "failed to wait for %s", wa)
+		}
+	}
+}
+
+// String describes the condition which is waited for.
+func (wa waitForArgs) String() string {
+	if wa.port != 0 {
+		return fmt.Sprintf("port %s to be %s", net.JoinHostPort(wa.host, strconv.Itoa(wa.port)), wa.state)
+	}
+	target := "path " + strconv.Quote(wa.path)
+	if wa.searchRegex != nil {
+		target += " matching " + strconv.Quote(wa.searchRegex.String())
+	}
+	if wa.state == stateStarted {
+		return target + " to be present"
+	}
+
+	return target + " to be absent"
+}
+
+// sleepContext sleeps for d, and returns the error of ctx when it is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// check returns whether the condition is met, and the match of search_regex.
+// A failed check is not an error, only the cancellation of ctx is.
+func (wa waitForArgs) check(ctx context.Context, conn connector.Connector) (bool, string, error) {
+	if wa.port != 0 {
+		open := wa.portOpen(ctx, conn)
+		if ctx.Err() != nil {
+			return false, "", errors.Wrapf(ctx.Err(), "failed to wait for %s", wa)
+		}
+
+		return open == (wa.state == stateStarted), "", nil
+	}
+
+	content, exists := wa.readFile(ctx, conn)
+	if ctx.Err() != nil {
+		return false, "", errors.Wrapf(ctx.Err(), "failed to wait for %s", wa)
+	}
+	var match string
+	found := exists
+	if exists && wa.searchRegex != nil {
+		if loc := wa.searchRegex.FindIndex(content); loc != nil {
+			match = string(content[loc[0]:loc[1]])
+		} else {
+			found = false
+		}
+	}
+
+	return found == (wa.state == stateStarted), match, nil
+}
+
+// portOpen returns whether the TCP port can be connected.
+func (wa waitForArgs) portOpen(ctx context.Context, conn connector.Connector) bool {
+	if wa.from == fromController {
+		dialer := &net.Dialer{Timeout: wa.connectTimeout}
+		c, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(wa.host, strconv.Itoa(wa.port)))
+		if err != nil {
+			return false
+		}
+		_ = c.Close()
+
+		return true
+	}
+	// /dev/tcp is supported by bash, which is not always the login shell.
+	script := "cat < /dev/null > " + internal.ShellQuote(fmt.Sprintf("/dev/tcp/%s/%d", wa.host, wa.port))
+	seconds := max(int(math.Ceil(wa.connectTimeout.Seconds())), 1)
+	_, _, err := conn.ExecuteCommand(ctx, fmt.Sprintf("timeout %d bash -c %s", seconds, internal.ShellQuote(script)))
+
+	return err == nil
+}
+
+// readFile returns the content of the file and whether it exists. The content is only read when search_regex is set.
+func (wa waitForArgs) readFile(ctx context.Context, conn connector.Connector) ([]byte, bool) {
+	if wa.from == fromController {
+		if wa.searchRegex == nil {
+			_, err := os.Stat(wa.path)
+
+			return nil, err == nil
+		}
+		content, err := os.ReadFile(wa.path)
+
+		return content, err == nil
+	}
+	path := internal.ShellQuote(wa.path)
+	if wa.searchRegex == nil {
+		_, _, err := conn.ExecuteCommand(ctx, fmt.Sprintf("[ -e %s ]", path))
+
+		return nil, err == nil
+	}
+	stdout, _, err := conn.ExecuteCommand(ctx, fmt.Sprintf("[ -f %[1]s ] && cat %[1]s", path))
+
+	return stdout, err == nil
+}
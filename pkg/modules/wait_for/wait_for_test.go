@@ -0,0 +1,258 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wait_for
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubesphere/kubekey/v4/pkg/modules/internal"
+)
+
+// createRawArgs creates a runtime.RawExtension from a map
+func createRawArgs(data map[string]any) runtime.RawExtension {
+	raw, _ := json.Marshal(data)
+	return runtime.RawExtension{Raw: raw}
+}
+
+// fakeTarget fails the commands until they are executed "ready" times, then returns stdout.
+type fakeTarget struct {
+	ready    int
+	stdout   string
+	executed []string
+}
+
+func (f *fakeTarget) Init(context.Context) error { return nil }
+
+func (f *fakeTarget) Close(context.Context) error { return nil }
+
+func (f *fakeTarget) PutFile(context.Context, []byte, string, fs.FileMode) error { return nil }
+
+func (f *fakeTarget) FetchFile(context.Context, string, io.Writer) error { return nil }
+
+func (f *fakeTarget) ExecuteCommand(_ context.Context, cmd string) ([]byte, []byte, error) {
+	f.executed = append(f.executed, cmd)
+	if len(f.executed) < f.ready || f.ready < 0 {
+		return nil, nil, errors.New("exit status 1")
+	}
+
+	return []byte(f.stdout), nil, nil
+}
+
+func TestWaitForArgsParse(t *testing.T) {
+	testcases := []struct {
+		name    string
+		args    map[string]any
+		except  *waitForArgs
+		wantErr bool
+	}{
+		{
+			name: "port with default",
+			args: map[string]any{"port": 6443},
+			except: &waitForArgs{host: "127.0.0.1", port: 6443, state: stateStarted, from: fromTarget,
+				timeout: 300 * time.Second, sleep: time.Second, connectTimeout: 5 * time.Second},
+		},
+		{
+			name: "path with regex and durations",
+			args: map[string]any{"path": "/var/log/etcd.log", "search_regex": "ready", "state": "absent", "from": "controller",
+				"timeout": "5m", "delay": "10", "sleep": 2, "connect_timeout": "500ms"},
+			except: &waitForArgs{host: "127.0.0.1", path: "/var/log/etcd.log", searchRegex: regexp.MustCompile("ready"), state: stateStopped,
+				from: fromController, timeout: 5 * time.Minute, delay: 10 * time.Second, sleep: 2 * time.Second, connectTimeout: 500 * time.Millisecond},
+		},
+		{
+			name: "port with ipv6 host",
+			args: map[string]any{"port": 6443, "host": "::1"},
+			except: &waitForArgs{host: "::1", port: 6443, state: stateStarted, from: fromTarget,
+				timeout: 300 * time.Second, sleep: time.Second, connectTimeout: 5 * time.Second},
+		},
+		{
+			name:    "invalid host",
+			args:    map[string]any{"port": 6443, "host": "lb.local/0; reboot"},
+			wantErr: true,
+		},
+		{
+			name:    "missing port and path",
+			args:    map[string]any{"timeout": 10},
+			wantErr: true,
+		},
+		{
+			name:    "port and path",
+			args:    map[string]any{"port": 6443, "path": "/etc/hosts"},
+			wantErr: true,
+		},
+		{
+			name:    "search_regex without path",
+			args:    map[string]any{"port": 6443, "search_regex": "a"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			args:    map[string]any{"port": 70000},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			args:    map[string]any{"port": 6443, "timeout": "forever"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported from",
+			args:    map[string]any{"port": 6443, "from": "localhost"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			wa, err := newWaitForArgs(context.TODO(), createRawArgs(tc.args), map[string]any{})
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.except, wa)
+		})
+	}
+}
+
+func TestModuleWaitForTarget(t *testing.T) {
+	testcases := []struct {
+		name           string
+		args           map[string]any
+		conn           *fakeTarget
+		exceptExecuted int
+		exceptMatch    string
+		exceptErr      string
+	}{
+		{
+			name:           "port is open after retries",
+			args:           map[string]any{"port": 6443, "sleep": "10ms"},
+			conn:           &fakeTarget{ready: 3},
+			exceptExecuted: 3,
+		},
+		{
+			name:           "port is closed",
+			args:           map[string]any{"port": 2379, "state": "stopped", "sleep": "10ms"},
+			conn:           &fakeTarget{ready: -1},
+			exceptExecuted: 1,
+		},
+		{
+			name:           "file matches regex",
+			args:           map[string]any{"path": "/var/log/etcd.log", "search_regex": "ready to serve \\w+", "sleep": "10ms"},
+			conn:           &fakeTarget{ready: 2, stdout: "starting\nready to serve clients\n"},
+			exceptExecuted: 2,
+			exceptMatch:    "ready to serve clients",
+		},
+		{
+			name:      "timeout",
+			args:      map[string]any{"path": "/etc/kubernetes/admin.conf", "timeout": "50ms", "sleep": "10ms"},
+			conn:      &fakeTarget{ready: -1},
+			exceptErr: "context deadline exceeded",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), internal.ConnKey, tc.conn)
+			stdout, _, err := ModuleWaitFor(ctx, internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			if tc.exceptErr != "" {
+				require.ErrorContains(t, err, tc.exceptErr)
+				assert.Equal(t, internal.StdoutFailed, stdout)
+
+				return
+			}
+			require.NoError(t, err)
+			var result waitForResult
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.False(t, result.Changed)
+			assert.Equal(t, tc.exceptMatch, result.Match)
+			assert.Len(t, tc.conn.executed, tc.exceptExecuted)
+		})
+	}
+}
+
+func TestModuleWaitForController(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	file := filepath.Join(t.TempDir(), "ready")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(file, []byte("ok"), 0o600)
+	}()
+
+	testcases := []struct {
+		name string
+		args map[string]any
+	}{
+		{
+			name: "port is open",
+			args: map[string]any{"port": port, "from": "controller", "timeout": "1s"},
+		},
+		{
+			name: "file appears",
+			args: map[string]any{"path": file, "from": "controller", "timeout": "1s", "sleep": "10ms"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, _, err := ModuleWaitFor(context.Background(), internal.ExecOptions{
+				Host:     "node1",
+				Args:     createRawArgs(tc.args),
+				Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+			})
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(stdout, `{"changed":false,"elapsed":`), stdout)
+		})
+	}
+}
+
+func TestModuleWaitForCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	_, _, err := ModuleWaitFor(ctx, internal.ExecOptions{
+		Host:     "node1",
+		Args:     createRawArgs(map[string]any{"path": "/not/exist/" + strconv.Itoa(os.Getpid()), "from": "controller"}),
+		Variable: internal.NewTestVariable([]string{"node1"}, map[string]any{}),
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+)
+
+// htmlTemplate is a standalone page without external resources.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>KubeKey Report - {{ .Playbook }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #24292f; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 32px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+pre { margin: 0; white-space: pre-wrap; word-break: break-all; max-height: 240px; overflow: auto; }
+.summary span { margin-right: 16px; }
+.timeline { position: relative; height: 20px; background: #f6f8fa; }
+.bar { position: absolute; top: 2px; height: 16px; min-width: 2px; }
+.success { background: #2da44e; }
+.failed { background: #cf222e; }
+.ignored { background: #bf8700; }
+.skipped { background: #8c959f; }
+td.status-success { color: #2da44e; }
+td.status-failed { color: #cf222e; }
+td.status-ignored { color: #bf8700; }
+td.status-skipped { color: #8c959f; }
+</style>
+</head>
+<body>
+<h1>Playbook {{ .Playbook }}: {{ .Status }}</h1>
+<div class="summary">
+<span>Start: {{ .Start }}</span>
+<span>Duration: {{ .Duration }}</span>
+<span>Total: {{ .Statistics.Total }}</span>
+<span>Success: {{ .Statistics.Success }}</span>
+<span>Ignored: {{ .Statistics.Ignored }}</span>
+<span>Failed: {{ .Statistics.Failed }}</span>
+</div>
+{{- if .Error }}
+<pre class="failed-message">{{ .Error }}</pre>
+{{- end }}
+<h2>Timeline</h2>
+<table>
+<tr><th style="width: 160px">Host</th><th>Tasks</th></tr>
+{{- range .Hosts }}
+<tr><td>{{ .Name }}</td><td><div class="timeline">
+{{- range .Bars }}
+<div class="bar {{ .Status }}" style="left: {{ .Left }}%; width: {{ .Width }}%" title="{{ .Title }}"></div>
+{{- end }}
+</div></td></tr>
+{{- end }}
+</table>
+{{- range .Plays }}
+<h2>Play {{ .Name }}</h2>
+<table>
+<tr><th>Task</th><th>Host</th><th>Status</th><th>Duration</th><th>Stdout</th><th>Stderr</th><th>Error</th></tr>
+{{- range .Results }}
+<tr><td>{{ .Task }}</td><td>{{ .Host }}</td><td class="status-{{ .Status }}">{{ .Status }}</td><td>{{ .Duration }}</td><td><pre>{{ .Stdout }}</pre></td><td><pre>{{ .Stderr }}</pre></td><td><pre>{{ .Error }}</pre></td></tr>
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`))
+
+// htmlReport is the data of htmlTemplate.
+type htmlReport struct {
+	Playbook   string
+	Status     string
+	Error      string
+	Start      string
+	Duration   string
+	Statistics kkcorev1.PlaybookStatistics
+	Hosts      []htmlHost
+	Plays      []htmlPlay
+}
+
+// htmlHost is the timeline of a host.
+type htmlHost struct {
+	Name string
+	Bars []htmlBar
+}
+
+// htmlBar is a task in timeline. Left and Width are percentages of the whole run.
+type htmlBar struct {
+	Status string
+	Left   string
+	Width  string
+	Title  string
+}
+
+// htmlPlay is the results of a play.
+type htmlPlay struct {
+	Name    string
+	Results []htmlResult
+}
+
+// htmlResult is the result of a task in a host.
+type htmlResult struct {
+	Task     string
+	Host     string
+	Status   string
+	Duration string
+	Stdout   string
+	Stderr   string
+	Error    string
+}
+
+// writeHTML writes the run as a standalone HTML page, with a timeline for each host and results of each play.
+func writeHTML(w io.Writer, r *run) error {
+	total := r.end.Sub(r.start)
+	data := htmlReport{
+		Playbook:   r.playbook,
+		Status:     r.status,
+		Error:      r.err,
+		Start:      r.start.Format(time.RFC3339),
+		Duration:   total.Round(time.Millisecond).String(),
+		Statistics: r.statistics,
+	}
+	percent := func(d time.Duration) string {
+		if total <= 0 {
+			return "0"
+		}
+
+		return fmt.Sprintf("%.2f", float64(d)/float64(total)*100)
+	}
+	for _, h := range r.hosts {
+		host := htmlHost{Name: h}
+		for _, p := range r.plays {
+			for _, res := range p.results {
+				if res.host != h {
+					continue
+				}
+				host.Bars = append(host.Bars, htmlBar{
+					Status: res.status,
+					Left:   percent(res.start.Sub(r.start)),
+					Width:  percent(res.duration),
+					Title:  fmt.Sprintf("%s (%s, %s)", res.task, res.status, res.duration),
+				})
+			}
+		}
+		data.Hosts = append(data.Hosts, host)
+	}
+	for _, p := range r.plays {
+		hp := htmlPlay{Name: p.name}
+		for _, res := range p.results {
+			hp.Results = append(hp.Results, htmlResult{
+				Task:     res.task,
+				Host:     res.host,
+				Status:   res.status,
+				Duration: res.duration.String(),
+				Stdout:   res.stdout,
+				Stderr:   res.stderr,
+				Error:    res.err,
+			})
+		}
+		data.Plays = append(data.Plays, hp)
+	}
+
+	return errors.Wrap(htmlTemplate.Execute(w, data), "failed to render html report")
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
+)
+
+// junitTestSuites is the root element of JUnit XML.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a play.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a task in a host.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+// junitMessage is the failure or skipped element of testcase.
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Content string `xml:",chardata"`
+}
+
+// writeJUnit writes the run as JUnit XML. Each play is a testsuite and each task in a host is a testcase.
+// Ignored failures are reported as passed testcases, the same as ansible.
+func writeJUnit(w io.Writer, r *run) error {
+	suites := junitTestSuites{
+		Name: r.playbook,
+		Time: junitSeconds(r.end.Sub(r.start)),
+	}
+	for _, p := range r.plays {
+		suite := junitTestSuite{
+			Name:      p.name,
+			Timestamp: p.start.UTC().Format(time.RFC3339),
+		}
+		var total time.Duration
+		for _, res := range p.results {
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("[%s] %s", res.host, res.task),
+				Classname: res.role,
+				Time:      junitSeconds(res.duration),
+				SystemOut: res.stdout,
+				SystemErr: res.stderr,
+			}
+			if tc.Classname == "" {
+				tc.Classname = p.name
+			}
+			switch res.status {
+			case executor.EventStatusFailed:
+				tc.Failure = &junitMessage{
+					Message: res.err,
+					Type:    res.module,
+					Content: fmt.Sprintf("stdout: %s\nstderr: %s\nerror: %s", res.stdout, res.stderr, res.err),
+				}
+				suite.Failures++
+			case executor.EventStatusSkipped:
+				tc.Skipped = &junitMessage{Message: "skipped"}
+				suite.Skipped++
+			}
+			suite.Tests++
+			total += res.duration
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Time = junitSeconds(total)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "failed to write junit header")
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return errors.Wrap(err, "failed to encode junit")
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return errors.Wrap(err, "failed to write junit")
+}
+
+// junitSeconds formats duration as seconds, which is the time unit of JUnit.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"k8s.io/klog/v2"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
+)
+
+// supported report formats.
+const (
+	// FormatJUnit generates JUnit XML, one testcase per task and host.
+	FormatJUnit = "junit"
+	// FormatHTML generates a standalone HTML page with per-host timelines.
+	FormatHTML = "html"
+)
+
+// writeFunc writes the run to w in a report format.
+type writeFunc func(w io.Writer, r *run) error
+
+// formats of report.
+var formats = map[string]writeFunc{
+	FormatJUnit: writeJUnit,
+	FormatHTML:  writeHTML,
+}
+
+// run is the collected result of a playbook execution.
+type run struct {
+	playbook   string
+	start      time.Time
+	end        time.Time
+	status     string
+	err        string
+	statistics kkcorev1.PlaybookStatistics
+	plays      []*play
+	// hosts in the order of their first result.
+	hosts []string
+}
+
+// play is the collected results of a play.
+type play struct {
+	name    string
+	start   time.Time
+	results []result
+}
+
+// result of a task in a host.
+type result struct {
+	task     string
+	role     string
+	module   string
+	host     string
+	status   string
+	stdout   string
+	stderr   string
+	err      string
+	start    time.Time
+	duration time.Duration
+}
+
+// NewCallback returns a executor.Callback which writes the report in format to filename when the playbook ends.
+func NewCallback(format, filename string) (executor.Callback, error) {
+	write, ok := formats[format]
+	if !ok {
+		return nil, errors.Errorf("unsupported report format %q. support %s and %s", format, FormatJUnit, FormatHTML)
+	}
+	if filename == "" {
+		return nil, errors.Errorf("report file of %q is empty", format)
+	}
+
+	return &reporter{filename: filename, write: write}, nil
+}
+
+// ParseCallbacks parses report specifications like "junit=path,html=path" to callbacks.
+func ParseCallbacks(specs []string) ([]executor.Callback, error) {
+	var callbacks []executor.Callback
+	for _, spec := range specs {
+		for _, s := range strings.Split(spec, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			format, filename, ok := strings.Cut(s, "=")
+			if !ok {
+				return nil, errors.Errorf("report %q should be format=path", s)
+			}
+			cb, err := NewCallback(strings.TrimSpace(format), strings.TrimSpace(filename))
+			if err != nil {
+				return nil, err
+			}
+			callbacks = append(callbacks, cb)
+		}
+	}
+
+	return callbacks, nil
+}
+
+// reporter collects events and writes the report when the playbook ends.
+type reporter struct {
+	sync.Mutex
+	run      run
+	filename string
+	write    writeFunc
+}
+
+// OnEvent collects event. the report is written in playbook_end.
+func (r *reporter) OnEvent(_ context.Context, event executor.Event) {
+	r.Lock()
+	defer r.Unlock()
+
+	switch event.Type {
+	case executor.EventPlaybookStart:
+		r.run = run{playbook: event.Playbook, start: event.Time}
+	case executor.EventPlayStart:
+		r.run.plays = append(r.run.plays, &play{name: event.Play, start: event.Time})
+	case executor.EventHostResult:
+		// tasks before the first play. such as gather_facts.
+		if len(r.run.plays) == 0 {
+			r.run.plays = append(r.run.plays, &play{name: event.Playbook, start: event.Time})
+		}
+		p := r.run.plays[len(r.run.plays)-1]
+		duration := time.Duration(event.Duration) * time.Millisecond
+		p.results = append(p.results, result{
+			task:     event.Task,
+			role:     event.Role,
+			module:   event.Module,
+			host:     event.Host,
+			status:   event.Status,
+			stdout:   event.Stdout,
+			stderr:   event.Stderr,
+			err:      event.Error,
+			start:    event.Time.Add(-duration),
+			duration: duration,
+		})
+		if !slices.Contains(r.run.hosts, event.Host) {
+			r.run.hosts = append(r.run.hosts, event.Host)
+		}
+	case executor.EventPlaybookEnd:
+		r.run.end = event.Time
+		r.run.status = event.Status
+		r.run.err = event.Error
+		if event.Statistics != nil {
+			r.run.statistics = *event.Statistics
+		}
+		if err := r.writeFile(); err != nil {
+			klog.ErrorS(err, "failed to write report", "file", r.filename)
+		}
+	}
+}
+
+// writeFile writes the report to filename. the parent dir is created if not exists.
+func (r *reporter) writeFile() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), _const.PermDirPublic); err != nil {
+		return errors.Wrapf(err, "failed to create dir of report %q", r.filename)
+	}
+	file, err := os.Create(r.filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create report %q", r.filename)
+	}
+	defer file.Close()
+
+	return r.write(file, &r.run)
+}
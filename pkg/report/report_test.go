@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kkcorev1 "github.com/kubesphere/kubekey/api/core/v1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kubesphere/kubekey/v4/pkg/executor"
+)
+
+// sendTestEvents sends the events of a playbook with a play which has a failed, an ignored and a skipped result.
+func sendTestEvents(cb executor.Callback) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []executor.Event{
+		{Type: executor.EventPlaybookStart, Time: start, Playbook: "default/test"},
+		{Type: executor.EventPlayStart, Time: start, Playbook: "default/test", Play: "precheck", Hosts: []string{"node1", "node2"}},
+		{Type: executor.EventTaskStart, Time: start, Playbook: "default/test", Task: "check os", Hosts: []string{"node1", "node2"}},
+		{Type: executor.EventHostResult, Time: start.Add(time.Second), Playbook: "default/test", Task: "check os", Role: "precheck/os", Module: "assert", Host: "node1", Status: executor.EventStatusSuccess, Stdout: "success", Duration: 1000},
+		{Type: executor.EventHostResult, Time: start.Add(2 * time.Second), Playbook: "default/test", Task: "check os", Role: "precheck/os", Module: "assert", Host: "node2", Status: executor.EventStatusFailed, Stdout: "failed", Stderr: "<os not supported>", Error: "module run failed", Duration: 2000},
+		{Type: executor.EventHostResult, Time: start.Add(3 * time.Second), Playbook: "default/test", Task: "check gpu", Module: "command", Host: "node1", Status: executor.EventStatusSkipped, Stdout: "skip", Duration: 0},
+		{Type: executor.EventHostResult, Time: start.Add(3 * time.Second), Playbook: "default/test", Task: "check gpu", Module: "command", Host: "node2", Status: executor.EventStatusIgnored, Error: "exit 1", Duration: 500},
+		{Type: executor.EventPlaybookEnd, Time: start.Add(4 * time.Second), Playbook: "default/test", Status: "Failed", Error: "task check os run failed",
+			Statistics: &kkcorev1.PlaybookStatistics{Total: 2, Success: 0, Failed: 1, Ignored: 1}},
+	}
+	for _, event := range events {
+		cb.OnEvent(context.TODO(), event)
+	}
+}
+
+func TestParseCallbacks(t *testing.T) {
+	testcases := []struct {
+		name    string
+		specs   []string
+		count   int
+		wantErr bool
+	}{
+		{
+			name:  "junit and html",
+			specs: []string{"junit=report.xml,html=report.html"},
+			count: 2,
+		},
+		{
+			name:  "multiple flags",
+			specs: []string{"junit=report.xml", "html=report.html"},
+			count: 2,
+		},
+		{
+			name:    "unsupported format",
+			specs:   []string{"pdf=report.pdf"},
+			wantErr: true,
+		},
+		{
+			name:    "without path",
+			specs:   []string{"junit"},
+			wantErr: true,
+		},
+		{
+			name:    "empty path",
+			specs:   []string{"junit="},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cbs, err := ParseCallbacks(tc.specs)
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, cbs, tc.count)
+		})
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	cb, err := NewCallback(FormatJUnit, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendTestEvents(cb)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "default/test", suites.Name)
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, "4.000", suites.Time)
+	if assert.Len(t, suites.Suites, 1) {
+		suite := suites.Suites[0]
+		assert.Equal(t, "precheck", suite.Name)
+		if assert.Len(t, suite.Cases, 4) {
+			assert.Equal(t, "[node1] check os", suite.Cases[0].Name)
+			assert.Equal(t, "precheck/os", suite.Cases[0].Classname)
+			assert.Nil(t, suite.Cases[0].Failure)
+			assert.Equal(t, "success", suite.Cases[0].SystemOut)
+			// failed
+			if assert.NotNil(t, suite.Cases[1].Failure) {
+				assert.Equal(t, "module run failed", suite.Cases[1].Failure.Message)
+				assert.Contains(t, suite.Cases[1].Failure.Content, "<os not supported>")
+			}
+			assert.Equal(t, "<os not supported>", suite.Cases[1].SystemErr)
+			// skipped use play name as classname when not in role.
+			assert.Equal(t, "precheck", suite.Cases[2].Classname)
+			assert.NotNil(t, suite.Cases[2].Skipped)
+			// ignored is passed.
+			assert.Nil(t, suite.Cases[3].Failure)
+			assert.Nil(t, suite.Cases[3].Skipped)
+		}
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.html")
+	cb, err := NewCallback(FormatHTML, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendTestEvents(cb)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	assert.Contains(t, html, "Playbook default/test: Failed")
+	assert.Contains(t, html, "Play precheck")
+	// stderr is escaped.
+	assert.Contains(t, html, "&lt;os not supported&gt;")
+	assert.NotContains(t, html, "<os not supported>")
+	// one timeline for each host, in the order of results.
+	assert.Less(t, strings.Index(html, "<tr><td>node1</td>"), strings.Index(html, "<tr><td>node2</td>"))
+	// node2 check os starts at 0s and takes 2s of 4s.
+	assert.Contains(t, html, `class="bar failed" style="left: 0.00%; width: 50.00%"`)
+	// node2 check gpu starts at 2.5s and takes 0.5s.
+	assert.Contains(t, html, `class="bar ignored" style="left: 62.50%; width: 12.50%"`)
+	// no external resources.
+	assert.NotContains(t, html, "<script src=")
+	assert.NotContains(t, html, "<link ")
+}
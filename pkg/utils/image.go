@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ImageRewrite is a rule which rewrites the repository of an image reference, e.g. from "docker.io/calico"
+// to "harbor.local/mirror/calico". A rule matches either by Prefix, which matches whole path components
+// (a trailing "/*" is allowed), or by Regex, which must match the whole repository. The matched part is
+// replaced by Target, which may refer to the submatches of Regex as "$1" or "${name}".
+type ImageRewrite struct {
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	Target string `json:"target"`
+}
+
+// Validate checks that the rule has exactly one of prefix and regex, and a target.
+func (r ImageRewrite) Validate() error {
+	if (r.Prefix == "") == (r.Regex == "") {
+		return errors.New("image rewrite should have exactly one of \"prefix\" and \"regex\"")
+	}
+	if r.Target == "" {
+		return errors.New("image rewrite should have a \"target\"")
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return errors.Wrapf(err, "invalid image rewrite regex %q", r.Regex)
+		}
+	}
+
+	return nil
+}
+
+// rewrite returns the rewritten repository and whether the rule matches repo.
+func (r ImageRewrite) rewrite(repo string) (string, bool, error) {
+	if r.Regex != "" {
+		re, err := regexp.Compile("^(?:" + r.Regex + ")$")
+		if err != nil {
+			return "", false, errors.Wrapf(err, "invalid image rewrite regex %q", r.Regex)
+		}
+		match := re.FindStringSubmatchIndex(repo)
+		if match == nil {
+			return "", false, nil
+		}
+
+		return string(re.ExpandString(nil, r.Target, repo, match)), true, nil
+	}
+	prefix := strings.TrimSuffix(strings.TrimSuffix(r.Prefix, "/*"), "/")
+	target := strings.TrimSuffix(strings.TrimSuffix(r.Target, "/*"), "/")
+	if repo == prefix || strings.HasPrefix(repo, prefix+"/") {
+		return target + repo[len(prefix):], true, nil
+	}
+
+	return "", false, nil
+}
+
+// RewriteImage rewrites the repository of image (e.g. "docker.io/calico/node:v3.28.0") by the first
+// matching rule and keeps its tag and digest. The image is returned unchanged when no rule matches.
+// Rules match the image as given, so images should be fully qualified, including "docker.io/library".
+func RewriteImage(rules []ImageRewrite, image string) (string, error) {
+	repo, suffix := image, ""
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, suffix = repo[:i], repo[i:]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, suffix = repo[:i], repo[i:]+suffix
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return "", err
+		}
+		rewritten, ok, err := rule.rewrite(repo)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return rewritten + suffix, nil
+		}
+	}
+
+	return image, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteImage(t *testing.T) {
+	testcases := []struct {
+		name     string
+		rules    []ImageRewrite
+		image    string
+		excepted string
+	}{
+		{
+			name:     "no rules",
+			image:    "docker.io/calico/node:v3.28.0",
+			excepted: "docker.io/calico/node:v3.28.0",
+		},
+		{
+			name:     "prefix with wildcard",
+			rules:    []ImageRewrite{{Prefix: "docker.io/calico/*", Target: "harbor.local/mirror/calico/*"}},
+			image:    "docker.io/calico/node:v3.28.0",
+			excepted: "harbor.local/mirror/calico/node:v3.28.0",
+		},
+		{
+			name:     "prefix matches whole path components",
+			rules:    []ImageRewrite{{Prefix: "docker.io/calico", Target: "harbor.local/calico"}},
+			image:    "docker.io/calicoctl/node:v3.28.0",
+			excepted: "docker.io/calicoctl/node:v3.28.0",
+		},
+		{
+			name:     "prefix of registry with port and digest",
+			rules:    []ImageRewrite{{Prefix: "registry.local:5000", Target: "harbor.local/k8s"}},
+			image:    "registry.local:5000/pause@sha256:abc",
+			excepted: "harbor.local/k8s/pause@sha256:abc",
+		},
+		{
+			name:     "regex with submatch",
+			rules:    []ImageRewrite{{Regex: `registry\.k8s\.io/(kube-.*|pause|etcd)`, Target: "harbor.local/kubernetes/$1"}},
+			image:    "registry.k8s.io/kube-apiserver:v1.33.1",
+			excepted: "harbor.local/kubernetes/kube-apiserver:v1.33.1",
+		},
+		{
+			name:     "regex matches whole repository",
+			rules:    []ImageRewrite{{Regex: `registry\.k8s\.io/kube`, Target: "harbor.local/kube"}},
+			image:    "registry.k8s.io/kube-apiserver:v1.33.1",
+			excepted: "registry.k8s.io/kube-apiserver:v1.33.1",
+		},
+		{
+			name: "first matching rule wins",
+			rules: []ImageRewrite{
+				{Prefix: "docker.io/calico/node", Target: "harbor.local/node"},
+				{Prefix: "docker.io", Target: "harbor.local/dockerhub"},
+			},
+			image:    "docker.io/calico/cni:v3.28.0",
+			excepted: "harbor.local/dockerhub/calico/cni:v3.28.0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := RewriteImage(tc.rules, tc.image)
+			require.NoError(t, err)
+			assert.Equal(t, tc.excepted, actual)
+		})
+	}
+}
+
+func TestImageRewriteValidate(t *testing.T) {
+	testcases := []struct {
+		name string
+		rule ImageRewrite
+	}{
+		{
+			name: "both prefix and regex",
+			rule: ImageRewrite{Prefix: "docker.io", Regex: "docker.io/.*", Target: "harbor.local"},
+		},
+		{
+			name: "no target",
+			rule: ImageRewrite{Prefix: "docker.io"},
+		},
+		{
+			name: "invalid regex",
+			rule: ImageRewrite{Regex: "docker.io/(", Target: "harbor.local"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Error(t, tc.rule.Validate())
+			_, err := RewriteImage([]ImageRewrite{tc.rule}, "docker.io/library/nginx:latest")
+			require.Error(t, err)
+		})
+	}
+}
@@ -123,7 +123,7 @@ func CombineSlice(g1, g2 []string) []string {
 }
 
 // ConvertGroup converts the inventory into a map of groups with their respective hosts.
-// It ensures that all hosts are included in the "all" group and adds a default localhost if not present.
+// It ensures that all hosts are included in the "all" group (sorted by hostname) and adds a default localhost if not present.
 // It also creates an "ungrouped" group for hosts that are not part of any specific group.
 //
 // Parameters:
@@ -140,6 +140,8 @@ func ConvertGroup(inv kkcorev1.Inventory) map[string][]string {
 	for hn := range inv.Spec.Hosts {
 		all = append(all, hn)
 	}
+	// inventory hosts is a map, sort it to keep the order of "all" stable.
+	slices.Sort(all)
 
 	ungrouped := make([]string, len(all))
 	copy(ungrouped, all)
@@ -398,7 +400,7 @@ func AnyVar(ctx, args map[string]any, dest any, keys ...string) error {
 	switch valv := val.(type) {
 	case string:
 		valBytes = []byte(valv)
-	case []any:
+	case []any, map[string]any:
 		valBytes, err = json.Marshal(valv)
 		if err != nil {
 			return errors.Wrapf(err, "failed to marshal variable %q", strings.Join(keys, "."))
@@ -162,12 +162,25 @@ func TestConvertGroup(t *testing.T) {
 			},
 			wantAll: []string{"localhost"},
 		},
+		{
+			name: "all is sorted by hostname",
+			inventory: kkcorev1.Inventory{
+				Spec: kkcorev1.InventorySpec{
+					Hosts: kkcorev1.InventoryHost{
+						"node3": {},
+						"node1": {},
+						"node2": {},
+					},
+				},
+			},
+			wantAll: []string{"node1", "node2", "node3", "localhost"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			groups := ConvertGroup(tt.inventory)
-			assert.ElementsMatch(t, tt.wantAll, groups["all"])
+			assert.Equal(t, tt.wantAll, groups["all"])
 		})
 	}
 }
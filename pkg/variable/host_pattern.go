@@ -0,0 +1,214 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package variable
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	_const "github.com/kubesphere/kubekey/v4/pkg/const"
+)
+
+var (
+	// regexForIndex matches indexed and sliced group access. e.g. "group[0]", "group[-1]", "group[0:2]", "group[1:]".
+	regexForIndex = regexp.MustCompile(`^(.*?)\[(-?\d*)(:(-?\d*))?]$`)
+	// regexForRandom matches random host selection from group. e.g. "group|random".
+	regexForRandom = regexp.MustCompile(`^(.+?)\s*\|\s*random$`)
+)
+
+// splitHostPattern splits a host pattern expression into terms.
+// Terms are separated by ":" or "," outside brackets, so "group[0:2]" and "~node-\d{1,3}" stay intact.
+// e.g. "workers:&zone_a:!gpu" -> ["workers", "&zone_a", "!gpu"]
+func splitHostPattern(pattern string) []string {
+	var terms []string
+	var depth int
+	var start int
+	for i, c := range pattern {
+		switch c {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			if depth > 0 {
+				depth--
+			}
+		case ':', ',':
+			if depth == 0 {
+				if term := strings.TrimSpace(pattern[start:i]); term != "" {
+					terms = append(terms, term)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if term := strings.TrimSpace(pattern[start:]); term != "" {
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// matchHostPattern resolves a single host pattern term (without "&" or "!" prefix) to hostnames.
+// It supports:
+//   - direct hostname or group name
+//   - regex against hostnames (e.g. "~node-\d+")
+//   - indexed group access (e.g. "group[0]", "group[-1]")
+//   - sliced group access, end inclusive (e.g. "group[0:2]", "group[1:]")
+//   - random host selection from group (e.g. "group|random")
+func matchHostPattern(pattern string, hosts map[string]host, groups map[string][]string) ([]string, error) {
+	var hs []string
+	// Handle regex against all hostnames (e.g. "~node-\d+"), in the order of "all" group.
+	if expr, ok := strings.CutPrefix(pattern, "~"); ok {
+		reg, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compile host pattern %q", pattern)
+		}
+		for _, hn := range groups[_const.VariableGroupsAll] {
+			if reg.MatchString(hn) {
+				hs = append(hs, hn)
+			}
+		}
+
+		return hs, nil
+	}
+	// Add direct hostname if it exists in the hosts map
+	if _, exists := hosts[pattern]; exists {
+		hs = append(hs, pattern)
+	}
+	// Add all hosts from matching groups
+	if gv, ok := groups[pattern]; ok {
+		hs = CombineSlice(hs, gv)
+	}
+	// Handle indexed or sliced group access (e.g., "group[0]", "group[0:2]")
+	if match := regexForIndex.FindStringSubmatch(pattern); match != nil {
+		if group, ok := groups[match[1]]; ok {
+			selected, err := sliceGroup(group, match[2], match[3] != "", match[4])
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to select hosts by %q", pattern)
+			}
+			hs = CombineSlice(hs, selected)
+		}
+	}
+	// Handle random host selection from group (e.g., "group|random")
+	if match := regexForRandom.FindStringSubmatch(pattern); match != nil {
+		if group, ok := groups[match[1]]; ok && len(group) > 0 {
+			hs = CombineSlice(hs, []string{group[rand.Intn(len(group))]})
+		}
+	}
+
+	return hs, nil
+}
+
+// sliceGroup selects hosts from group by index or by slice. Negative index counts from the end.
+// Slice end is inclusive, the same as ansible. e.g. group[0:1] select the first two hosts.
+func sliceGroup(group []string, start string, isSlice bool, end string) ([]string, error) {
+	parseIndex := func(s string, def int) (int, error) {
+		if s == "" {
+			return def, nil
+		}
+		index, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to convert %q to int", s)
+		}
+		if index < 0 {
+			index += len(group)
+		}
+
+		return index, nil
+	}
+
+	if !isSlice {
+		if start == "" {
+			return nil, errors.New("index is empty")
+		}
+		index, err := parseIndex(start, 0)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(group) {
+			return nil, errors.Errorf("index %v out of range for group %s", start, group)
+		}
+
+		return []string{group[index]}, nil
+	}
+
+	from, err := parseIndex(start, 0)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseIndex(end, len(group)-1)
+	if err != nil {
+		return nil, err
+	}
+	from = max(from, 0)
+	to = min(to, len(group)-1)
+	if from > to {
+		return []string{}, nil
+	}
+
+	return slices.Clone(group[from : to+1]), nil
+}
+
+// resolveHostPattern resolves host pattern terms to hostnames.
+// Terms without prefix are unions, terms prefixed by "&" are intersections and terms prefixed by "!" are exclusions.
+// Like ansible, unions are evaluated first, then intersections, then exclusions, regardless of their position.
+// If no union term is given, it's based on base, which is usually the "all" group without implicit localhost.
+func resolveHostPattern(terms []string, base []string, hosts map[string]host, groups map[string][]string) ([]string, error) {
+	var union []string
+	var hasUnion bool
+	var intersections, exclusions [][]string
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(term, "&"):
+			hs, err := matchHostPattern(strings.TrimSpace(term[1:]), hosts, groups)
+			if err != nil {
+				return nil, err
+			}
+			intersections = append(intersections, hs)
+		case strings.HasPrefix(term, "!"):
+			hs, err := matchHostPattern(strings.TrimSpace(term[1:]), hosts, groups)
+			if err != nil {
+				return nil, err
+			}
+			exclusions = append(exclusions, hs)
+		default:
+			hs, err := matchHostPattern(term, hosts, groups)
+			if err != nil {
+				return nil, err
+			}
+			hasUnion = true
+			union = CombineSlice(union, hs)
+		}
+	}
+	if !hasUnion {
+		union = CombineSlice(union, base)
+	}
+
+	result := make([]string, 0, len(union))
+	for _, h := range union {
+		if !slices.ContainsFunc(intersections, func(hs []string) bool { return !slices.Contains(hs, h) }) &&
+			!slices.ContainsFunc(exclusions, func(hs []string) bool { return slices.Contains(hs, h) }) {
+			result = append(result, h)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package variable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitHostPattern(t *testing.T) {
+	testcases := []struct {
+		name    string
+		pattern string
+		except  []string
+	}{
+		{
+			name:    "single term",
+			pattern: "workers",
+			except:  []string{"workers"},
+		},
+		{
+			name:    "union intersection and exclusion",
+			pattern: "workers:&zone_a:!gpu",
+			except:  []string{"workers", "&zone_a", "!gpu"},
+		},
+		{
+			name:    "comma separator",
+			pattern: "n1, n2",
+			except:  []string{"n1", "n2"},
+		},
+		{
+			name:    "slice is not split",
+			pattern: "workers[0:2]:!gpu",
+			except:  []string{"workers[0:2]", "!gpu"},
+		},
+		{
+			name:    "regex is not split",
+			pattern: `~node-\d{1,3}:!gpu`,
+			except:  []string{`~node-\d{1,3}`, "!gpu"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.except, splitHostPattern(tc.pattern))
+		})
+	}
+}
@@ -2,13 +2,10 @@ package variable
 
 import (
 	"net"
-	"regexp"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/cockroachdb/errors"
-	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/klog/v2"
 
 	_const "github.com/kubesphere/kubekey/v4/pkg/const"
@@ -18,8 +15,10 @@ import (
 // ***************************** GetFunc ***************************** //
 
 // GetHostnames retrieves all hostnames from specified groups or hosts.
-// It supports various hostname patterns including direct hostnames, group names,
-// indexed group access (e.g., "group[0]"), and random selection (e.g., "group|random").
+// Each name is a host pattern expression, which supports direct hostnames, group names,
+// indexed group access (e.g., "group[0]"), sliced group access (e.g., "group[0:2]"),
+// regex against hostnames (e.g., "~node-\d+") and random selection (e.g., "group|random").
+// Terms can be combined by ":" or ",": unions ("a:b"), intersections ("a:&b") and exclusions ("a:!b").
 // The function also supports template parsing for hostnames using configuration variables.
 var GetHostnames = func(name []string) GetFunc {
 	if len(name) == 0 {
@@ -31,49 +30,31 @@ var GetHostnames = func(name []string) GetFunc {
 		if !ok {
 			return nil, errors.New("variable type error")
 		}
-		var hs []string
+		groups := ConvertGroup(vv.value.Inventory)
+		var terms []string
 		for _, n := range name {
 			// Try to parse hostname using configuration variables as template context
 			if pn, err := tmpl.ParseFunc(Extension2Variables(vv.value.Config.Spec), n, tmpl.StringFunc); err == nil {
 				n = pn
 			}
-			// Add direct hostname if it exists in the hosts map
-			if _, exists := vv.value.Hosts[n]; exists {
-				hs = append(hs, n)
-			}
-			// Add all hosts from matching groups
-			for gn, gv := range ConvertGroup(vv.value.Inventory) {
-				if gn == n {
-					hs = CombineSlice(hs, gv)
-					break
-				}
-			}
-
-			// Handle indexed group access (e.g., "group[0]")
-			regexForIndex := regexp.MustCompile(`^(.*?)\[(\d+)]$`)
-			if match := regexForIndex.FindStringSubmatch(strings.TrimSpace(n)); match != nil {
-				index, err := strconv.Atoi(match[2])
-				if err != nil {
-					return nil, errors.Wrapf(err, "failed to convert %q to int", match[2])
-				}
-				if group, ok := ConvertGroup(vv.value.Inventory)[match[1]]; ok {
-					if index >= len(group) {
-						return nil, errors.Errorf("index %v out of range for group %s", index, group)
-					}
-					hs = append(hs, group[index])
-				}
-			}
-
-			// Handle random host selection from group (e.g., "group|random")
-			regexForRandom := regexp.MustCompile(`^(.+?)\s*\|\s*random$`)
-			if match := regexForRandom.FindStringSubmatch(strings.TrimSpace(n)); match != nil {
-				if group, ok := ConvertGroup(vv.value.Inventory)[match[1]]; ok {
-					hs = append(hs, group[rand.Intn(len(group))])
-				}
+			n = strings.TrimSpace(n)
+			// hostname or group name which contains separator should not be split.
+			_, isHost := vv.value.Hosts[n]
+			_, isGroup := groups[n]
+			if isHost || isGroup {
+				terms = append(terms, n)
+				continue
 			}
+			terms = append(terms, splitHostPattern(n)...)
 		}
 
-		return hs, nil
+		// patterns without union terms are based on "all" group. the implicit localhost is not included, the same as ansible.
+		base := slices.DeleteFunc(slices.Clone(groups[_const.VariableGroupsAll]), func(h string) bool {
+			_, declared := vv.value.Inventory.Spec.Hosts[h]
+			return h == _const.VariableLocalHost && !declared
+		})
+
+		return resolveHostPattern(terms, base, vv.value.Hosts, groups)
 	}
 }
 
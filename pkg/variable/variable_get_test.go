@@ -26,6 +26,35 @@ import (
 )
 
 func TestGetHostnames(t *testing.T) {
+	patternVariable := &variable{
+		value: &value{
+			Inventory: kkcorev1.Inventory{
+				Spec: kkcorev1.InventorySpec{
+					Hosts: map[string]runtime.RawExtension{
+						"n1": {},
+						"n2": {},
+						"n3": {},
+						"n4": {},
+					},
+					Groups: map[string]kkcorev1.InventoryGroup{
+						"g1": {
+							Hosts: []string{"n1", "n2", "n3"},
+						},
+						"g2": {
+							Hosts: []string{"n2", "n3", "n4"},
+						},
+					},
+				},
+			},
+			Hosts: map[string]host{
+				"n1":        {},
+				"n2":        {},
+				"n3":        {},
+				"n4":        {},
+				"localhost": {},
+			},
+		},
+	}
 	testcases := []struct {
 		name     string
 		hosts    []string
@@ -124,6 +153,72 @@ func TestGetHostnames(t *testing.T) {
 			},
 			except: []string{"test", "localhost"},
 		},
+		{
+			name:     "union pattern",
+			hosts:    []string{"g1:n4"},
+			variable: patternVariable,
+			except:   []string{"n1", "n2", "n3", "n4"},
+		},
+		{
+			name:     "intersection pattern",
+			hosts:    []string{"g1:&g2"},
+			variable: patternVariable,
+			except:   []string{"n2", "n3"},
+		},
+		{
+			name:     "exclusion pattern",
+			hosts:    []string{"g1:!g2"},
+			variable: patternVariable,
+			except:   []string{"n1"},
+		},
+		{
+			name:     "exclusion pattern in list",
+			hosts:    []string{"g2", "!n3"},
+			variable: patternVariable,
+			except:   []string{"n2", "n4"},
+		},
+		{
+			name:     "slice pattern",
+			hosts:    []string{"g1[0:1]"},
+			variable: patternVariable,
+			except:   []string{"n1", "n2"},
+		},
+		{
+			name:     "open slice pattern",
+			hosts:    []string{"g1[1:]"},
+			variable: patternVariable,
+			except:   []string{"n2", "n3"},
+		},
+		{
+			name:     "negative index pattern",
+			hosts:    []string{"g1[-1]"},
+			variable: patternVariable,
+			except:   []string{"n3"},
+		},
+		{
+			name:     "regex pattern",
+			hosts:    []string{"~n[12]"},
+			variable: patternVariable,
+			except:   []string{"n1", "n2"},
+		},
+		{
+			name:     "all slice pattern",
+			hosts:    []string{"all[1:2]"},
+			variable: patternVariable,
+			except:   []string{"n2", "n3"},
+		},
+		{
+			name:     "all group in inventory order",
+			hosts:    []string{"all"},
+			variable: patternVariable,
+			except:   []string{"n1", "n2", "n3", "n4", "localhost"},
+		},
+		{
+			name:     "exclusion only pattern",
+			hosts:    []string{"!g1"},
+			variable: patternVariable,
+			except:   []string{"n4"},
+		},
 	}
 
 	for _, tc := range testcases {